@@ -4,6 +4,7 @@ import (
 	"api/database"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -12,6 +13,7 @@ type fakeRepository struct {
 	fails        int
 	mutex        sync.Mutex
 	transactions map[int64][]*Transaction
+	paid         map[string]bool
 }
 
 func NewFakeRepository() Repository {
@@ -40,7 +42,7 @@ func NewFakeRepository() Repository {
 		},
 	}
 
-	return &fakeRepository{fails: 0, transactions: transactions}
+	return &fakeRepository{fails: 0, transactions: transactions, paid: make(map[string]bool)}
 }
 
 func (r *fakeRepository) GetPeriodResults(ctx context.Context, start, end time.Time) ([]*IncomeResult, error) {
@@ -74,12 +76,16 @@ func (r *fakeRepository) GetPeriodResults(ctx context.Context, start, end time.T
 	return results, nil
 }
 
-func (r *fakeRepository) SaveTaxes(ctx context.Context, taxes, companyId int64) error {
+func (r *fakeRepository) SaveTaxes(ctx context.Context, taxes, companyId int64, period time.Time) error {
 	if companyId == 3 && r.fails == 0 {
 		r.fails++
 		return errors.New("bip bop bup")
 	}
 
+	if marked, _ := r.MarkPeriodPaid(nil, PERIOD_TAXES, uint64(companyId), period); !marked {
+		return nil
+	}
+
 	r.mutex.Lock()
 
 	if _, ok := r.transactions[companyId]; ok {
@@ -116,6 +122,23 @@ func (r *fakeRepository) RegisterTransaction(tx *database.DB, transaction Transa
 	return 0, nil
 }
 
+func (r *fakeRepository) HoldCash(tx *database.DB, companyId uint64, amount int64) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeRepository) MarkPeriodPaid(tx *database.DB, kind string, companyId uint64, period time.Time) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := fmt.Sprintf("%s_%d_%s", kind, companyId, period.UTC())
+	if r.paid[key] {
+		return false, nil
+	}
+
+	r.paid[key] = true
+	return true, nil
+}
+
 func (r *fakeRepository) GetIncomeTransactions(ctx context.Context, start, end time.Time, companyId int64) ([]*Transaction, error) {
 	incomeTransactions := make([]*Transaction, 0)
 
@@ -135,3 +158,32 @@ func (r *fakeRepository) GetIncomeTransactions(ctx context.Context, start, end t
 
 	return incomeTransactions, nil
 }
+
+func (r *fakeRepository) GetLedger(ctx context.Context, companyId uint64, filter LedgerFilter) ([]*LedgerEntry, error) {
+	entries := make([]*LedgerEntry, 0)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	transactions := r.transactions[int64(companyId)]
+	for i := len(transactions) - 1; i >= 0; i-- {
+		entries = append(entries, &LedgerEntry{
+			Id:             uint64(i + 1),
+			Value:          int64(transactions[i].Value),
+			Description:    transactions[i].Description,
+			Classification: transactions[i].Classification,
+		})
+	}
+
+	start := min(int(filter.Page*filter.Limit), len(entries))
+	end := min(start+int(filter.Limit), len(entries))
+	return entries[start:end], nil
+}
+
+func (r *fakeRepository) CheckpointBalances(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeRepository) ReconcileBalances(ctx context.Context) ([]*BalanceMismatch, error) {
+	return []*BalanceMismatch{}, nil
+}
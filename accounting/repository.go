@@ -1,8 +1,10 @@
 package accounting
 
 import (
+	"api/clock"
 	"api/database"
 	"context"
+	"sort"
 	"time"
 
 	"github.com/doug-martin/goqu/v9"
@@ -31,6 +33,13 @@ const (
 	BOND_BUY_BACK         = 19
 	TAXES_PAID            = 20
 	TAXES_DEFERRED        = 21
+	TERRAIN_SALE          = 22
+)
+
+// Kinds of payments made once for each company and period
+const (
+	PERIOD_TAXES   = "taxes"
+	PERIOD_PAYROLL = "payroll"
 )
 
 var INCOME_STATEMENT_CLASSIFICATIONS = []int{
@@ -47,20 +56,26 @@ var INCOME_STATEMENT_CLASSIFICATIONS = []int{
 
 type (
 	Repository interface {
-		SaveTaxes(ctx context.Context, taxes, companyId int64) error
+		SaveTaxes(ctx context.Context, taxes, companyId int64, period time.Time) error
 		GetPeriodResults(ctx context.Context, start, end time.Time) ([]*IncomeResult, error)
 		RegisterTransaction(tx *database.DB, transaction Transaction, companyId uint64) (int64, error)
+		HoldCash(tx *database.DB, companyId uint64, amount int64) (bool, error)
+		MarkPeriodPaid(tx *database.DB, kind string, companyId uint64, period time.Time) (bool, error)
 		GetIncomeTransactions(ctx context.Context, start, end time.Time, companyId int64) ([]*Transaction, error)
+		GetLedger(ctx context.Context, companyId uint64, filter LedgerFilter) ([]*LedgerEntry, error)
+		CheckpointBalances(ctx context.Context) error
+		ReconcileBalances(ctx context.Context) ([]*BalanceMismatch, error)
 	}
 
 	goquRepository struct {
 		builder *goqu.Database
+		clock   clock.Clock
 	}
 )
 
-func NewRepository(conn *database.Connection) Repository {
+func NewRepository(conn *database.Connection, clock clock.Clock) Repository {
 	builder := goqu.New(conn.Driver, conn.DB)
-	return &goquRepository{builder}
+	return &goquRepository{builder, clock}
 }
 
 func (r *goquRepository) GetPeriodResults(ctx context.Context, start, end time.Time) ([]*IncomeResult, error) {
@@ -70,7 +85,7 @@ func (r *goquRepository) GetPeriodResults(ctx context.Context, start, end time.T
 		Select(
 			goqu.I("c.id").As("company_id"),
 			goqu.COALESCE(goqu.SUM(goqu.I("t.value")), 0).As("taxable_income"),
-			goqu.
+			r.builder.
 				Select(goqu.COALESCE(goqu.SUM(goqu.I("value")), 0)).
 				From(goqu.T("transactions")).
 				Where(goqu.And(
@@ -136,14 +151,45 @@ func (r *goquRepository) GetIncomeTransactions(ctx context.Context, start, end t
 	return transactions, nil
 }
 
-func (r *goquRepository) SaveTaxes(ctx context.Context, taxes int64, companyId int64) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+func (r *goquRepository) GetLedger(ctx context.Context, companyId uint64, filter LedgerFilter) ([]*LedgerEntry, error) {
+	entries := make([]*LedgerEntry, 0)
+
+	err := r.builder.
+		Select(
+			goqu.I("id"),
+			goqu.I("value"),
+			goqu.I("description"),
+			goqu.I("classification_id"),
+			goqu.I("created_at"),
+		).
+		From(goqu.T("transactions")).
+		Where(goqu.I("company_id").Eq(companyId)).
+		Order(goqu.I("created_at").Desc(), goqu.I("id").Desc()).
+		Limit(filter.Limit).
+		Offset(filter.Page*filter.Limit).
+		ScanStructsContext(ctx, &entries)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Saves the taxes of the period starting at period, unless they were
+// already paid, like when a catch up runs the period again
+func (r *goquRepository) SaveTaxes(ctx context.Context, taxes int64, companyId int64, period time.Time) error {
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
 
 	defer tx.Rollback()
 
+	if marked, err := r.MarkPeriodPaid(tx.DB, PERIOD_TAXES, uint64(companyId), period); err != nil || !marked {
+		return err
+	}
+
 	description := "Taxes paid"
 	classification := TAXES_PAID
 
@@ -151,6 +197,18 @@ func (r *goquRepository) SaveTaxes(ctx context.Context, taxes int64, companyId i
 		description = "Deferred taxes"
 		classification = TAXES_DEFERRED
 	} else {
+		var deferred int64
+		if _, err := tx.
+			From(goqu.T("transactions")).
+			Select(goqu.COALESCE(goqu.SUM("value"), 0)).
+			Where(goqu.And(
+				goqu.I("company_id").Eq(companyId),
+				goqu.I("classification_id").Eq(TAXES_DEFERRED),
+			)).
+			ScanValContext(ctx, &deferred); err != nil {
+			return err
+		}
+
 		// If there are taxes to be paid, remove deferred cause they are
 		// included on the taxes to be paid
 		_, err = tx.
@@ -165,10 +223,14 @@ func (r *goquRepository) SaveTaxes(ctx context.Context, taxes int64, companyId i
 		if err != nil {
 			return err
 		}
+
+		if err := r.adjustBalance(tx.DB, uint64(companyId), -deferred); err != nil {
+			return err
+		}
 	}
 
 	if _, err := r.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		Transaction{
 			Value:          -int(taxes),
 			Classification: uint64(classification),
@@ -183,25 +245,228 @@ func (r *goquRepository) SaveTaxes(ctx context.Context, taxes int64, companyId i
 }
 
 func (r *goquRepository) RegisterTransaction(tx *database.DB, transaction Transaction, companyId uint64) (int64, error) {
-	result, err := tx.
+	id, err := database.InsertId(context.Background(), tx.
 		Insert(goqu.T("transactions")).
 		Rows(goqu.Record{
 			"company_id":        companyId,
 			"classification_id": transaction.Classification,
 			"description":       transaction.Description,
 			"value":             transaction.Value,
+		}))
+
+	if err != nil {
+		return -1, err
+	}
+
+	if err := r.adjustBalance(tx, companyId, int64(transaction.Value)); err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// Reports whether the company has at least amount in cash, locking its
+// balance until tx ends so concurrent debits wait for it instead of
+// spending the same cash
+func (r *goquRepository) HoldCash(tx *database.DB, companyId uint64, amount int64) (bool, error) {
+	result, err := tx.
+		Update(goqu.T("company_balances")).
+		Set(goqu.Record{"updated_at": r.clock.Now().UTC()}).
+		Where(
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("cash").Gte(amount),
+		).
+		Executor().
+		Exec()
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// Records that the company paid the kind of payment for the period,
+// reporting false if it already had. It must run in the same transaction
+// as the payment so they're both saved or neither is.
+func (r *goquRepository) MarkPeriodPaid(tx *database.DB, kind string, companyId uint64, period time.Time) (bool, error) {
+	result, err := tx.
+		Insert(goqu.T("period_payments")).
+		Rows(goqu.Record{
+			"kind":       kind,
+			"company_id": companyId,
+			"period":     period.UTC(),
+			"created_at": r.clock.Now().UTC(),
 		}).
+		OnConflict(goqu.DoNothing()).
 		Executor().
 		Exec()
 
 	if err != nil {
-		return -1, err
+		return false, err
 	}
 
-	id, err := result.LastInsertId()
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return -1, err
+		return false, err
 	}
 
-	return id, nil
+	return affected > 0, nil
+}
+
+// Keeps the company's cached cash in sync with its ledger. It must run in
+// the same transaction that changes the ledger.
+func (r *goquRepository) adjustBalance(tx *database.DB, companyId uint64, delta int64) error {
+	result, err := tx.
+		Update(goqu.T("company_balances")).
+		Set(goqu.Record{
+			"cash":       goqu.L("? + ?", goqu.I("cash"), delta),
+			"updated_at": r.clock.Now().UTC(),
+		}).
+		Where(goqu.I("company_id").Eq(companyId)).
+		Executor().
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+
+	_, err = tx.
+		Insert(goqu.T("company_balances")).
+		Rows(goqu.Record{
+			"company_id": companyId,
+			"cash":       delta,
+			"updated_at": r.clock.Now().UTC(),
+		}).
+		Executor().
+		Exec()
+
+	return err
+}
+
+// Records every company's current cash along with the last transaction
+// it accounts for
+func (r *goquRepository) CheckpointBalances(ctx context.Context) error {
+	tx, err := database.BeginTx(ctx, r.builder)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var lastTransaction int64
+	if _, err := tx.
+		From(goqu.T("transactions")).
+		Select(goqu.COALESCE(goqu.MAX("id"), 0)).
+		ScanValContext(ctx, &lastTransaction); err != nil {
+		return err
+	}
+
+	var balances []struct {
+		CompanyId int64 `db:"company_id"`
+		Cash      int64 `db:"cash"`
+	}
+
+	if err := tx.
+		From(goqu.T("company_balances")).
+		Select(goqu.I("company_id"), goqu.I("cash")).
+		ScanStructsContext(ctx, &balances); err != nil {
+		return err
+	}
+
+	if len(balances) == 0 {
+		return nil
+	}
+
+	now := r.clock.Now().UTC()
+	checkpoints := make([]any, 0, len(balances))
+
+	for _, balance := range balances {
+		checkpoints = append(checkpoints, goqu.Record{
+			"company_id":     balance.CompanyId,
+			"cash":           balance.Cash,
+			"transaction_id": lastTransaction,
+			"created_at":     now,
+		})
+	}
+
+	if _, err := tx.
+		Insert(goqu.T("balance_checkpoints")).
+		Rows(checkpoints...).
+		Executor().
+		ExecContext(ctx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Compares every company's cached cash against the sum of its ledger,
+// returning the ones that don't match
+func (r *goquRepository) ReconcileBalances(ctx context.Context) ([]*BalanceMismatch, error) {
+	tx, err := database.BeginTx(ctx, r.builder)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var ledger []struct {
+		CompanyId int64 `db:"company_id"`
+		Cash      int64 `db:"cash"`
+	}
+
+	if err := tx.
+		From(goqu.T("transactions")).
+		Select(goqu.I("company_id"), goqu.SUM("value").As("cash")).
+		GroupBy(goqu.I("company_id")).
+		ScanStructsContext(ctx, &ledger); err != nil {
+		return nil, err
+	}
+
+	var cached []struct {
+		CompanyId int64 `db:"company_id"`
+		Cash      int64 `db:"cash"`
+	}
+
+	if err := tx.
+		From(goqu.T("company_balances")).
+		Select(goqu.I("company_id"), goqu.I("cash")).
+		ScanStructsContext(ctx, &cached); err != nil {
+		return nil, err
+	}
+
+	balances := make(map[int64]*BalanceMismatch)
+	for _, balance := range ledger {
+		balances[balance.CompanyId] = &BalanceMismatch{CompanyId: balance.CompanyId, Ledger: balance.Cash}
+	}
+
+	for _, balance := range cached {
+		if _, ok := balances[balance.CompanyId]; !ok {
+			balances[balance.CompanyId] = &BalanceMismatch{CompanyId: balance.CompanyId}
+		}
+		balances[balance.CompanyId].Cached = balance.Cash
+	}
+
+	mismatches := make([]*BalanceMismatch, 0)
+	for _, balance := range balances {
+		if balance.Cached != balance.Ledger {
+			mismatches = append(mismatches, balance)
+		}
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].CompanyId < mismatches[j].CompanyId
+	})
+
+	return mismatches, nil
 }
@@ -2,6 +2,7 @@ package accounting_test
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/database"
 	"context"
 	"testing"
@@ -9,7 +10,7 @@ import (
 )
 
 func TestAccountRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		t.Fatalf("could not open database: %s", err)
 	}
@@ -21,39 +22,46 @@ func TestAccountRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password) VALUES
-        (1, "Foo", "bar", "bazz"), (2, "Bar", "foo", "bazz"), (3, "Bazz", "bar", "foo")
+        (1, 'Foo', 'bar', 'bazz'), (2, 'Bar', 'foo', 'bazz'), (3, 'Bazz', 'bar', 'foo')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO classifications (id, name) VALUES
-        (1, "Wages"), (3, "Transport fee"), (4, "Refunds"), (5, "Market outflow"),
-        (6, "Market inflow"), (7, "Market fee"), (19, "Taxes paid"), (20, "Deferred taxes")
+        (1, 'Wages'), (3, 'Transport fee'), (4, 'Refunds'), (5, 'Market outflow'),
+        (6, 'Market inflow'), (7, 'Market fee'), (19, 'Taxes paid'), (20, 'Deferred taxes')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO transactions (company_id, classification_id, value, created_at) VALUES
-        (3, 6, 5000000, "2023-12-29 18:55:33"),
-        (3, 6, 5000000, "2023-12-28 05:25:33"),
-        (3, 7, -700000, "2023-12-27 23:15:53"),
-        (3, 7, -1500000, "2023-12-26 11:15:53"),
-        (3, 7, -1500000, "2023-12-25 15:15:53"),
-        (3, 5, -850000, "2023-12-24 17:35:59"),
-
-        (2, 5, -850000, "2023-12-23 15:15:53"),
-        (2, 3, -700000, "2023-12-20 15:15:53"),
-        (2, 1, -1500000, "2023-12-18 15:15:53"),
-
-        (1, 1, -1850000, "2023-12-14 05:15:53"),
-        (1, 3, -500000, "2023-12-13 13:15:53"),
-        (1, 5, -8500000, "2023-12-12 01:15:53"),
-        (1, 7, -1500000, "2023-12-12 08:15:53"),
-        (1, 21, 700000, "2023-11-11 00:15:53"),
-        (1, 21, 700000, "2023-11-11 11:15:53"),
-        (1, 21, 1500000, "2023-11-10 00:15:53")
+        (3, 6, 5000000, '2023-12-29 18:55:33'),
+        (3, 6, 5000000, '2023-12-28 05:25:33'),
+        (3, 7, -700000, '2023-12-27 23:15:53'),
+        (3, 7, -1500000, '2023-12-26 11:15:53'),
+        (3, 7, -1500000, '2023-12-25 15:15:53'),
+        (3, 5, -850000, '2023-12-24 17:35:59'),
+
+        (2, 5, -850000, '2023-12-23 15:15:53'),
+        (2, 3, -700000, '2023-12-20 15:15:53'),
+        (2, 1, -1500000, '2023-12-18 15:15:53'),
+
+        (1, 1, -1850000, '2023-12-14 05:15:53'),
+        (1, 3, -500000, '2023-12-13 13:15:53'),
+        (1, 5, -8500000, '2023-12-12 01:15:53'),
+        (1, 7, -1500000, '2023-12-12 08:15:53'),
+        (1, 21, 700000, '2023-11-11 00:15:53'),
+        (1, 21, 700000, '2023-11-11 11:15:53'),
+        (1, 21, 1500000, '2023-11-10 00:15:53')
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
+	if _, err := tx.Exec(`
+        INSERT INTO company_balances (company_id, cash)
+        SELECT company_id, SUM(value) FROM transactions GROUP BY company_id
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -66,6 +74,15 @@ func TestAccountRepository(t *testing.T) {
 		if _, err := conn.DB.Exec(`DELETE FROM transactions`); err != nil {
 			t.Errorf("could not clean up table: %s", err)
 		}
+		if _, err := conn.DB.Exec(`DELETE FROM company_balances`); err != nil {
+			t.Errorf("could not clean up table: %s", err)
+		}
+		if _, err := conn.DB.Exec(`DELETE FROM balance_checkpoints`); err != nil {
+			t.Errorf("could not clean up table: %s", err)
+		}
+		if _, err := conn.DB.Exec(`DELETE FROM period_payments`); err != nil {
+			t.Errorf("could not clean up table: %s", err)
+		}
 		if _, err := conn.DB.Exec(`DELETE FROM classifications`); err != nil {
 			t.Errorf("could not clean up table: %s", err)
 		}
@@ -74,7 +91,7 @@ func TestAccountRepository(t *testing.T) {
 		}
 	})
 
-	repository := accounting.NewRepository(conn)
+	repository := accounting.NewRepository(conn, clock.New())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -224,7 +241,7 @@ func TestAccountRepository(t *testing.T) {
 
 	t.Run("SaveTaxes", func(t *testing.T) {
 		t.Run("should save deferred", func(t *testing.T) {
-			err := repository.SaveTaxes(ctx, -3250000, 3)
+			err := repository.SaveTaxes(ctx, -3250000, 3, time.Date(2023, 12, 17, 0, 0, 0, 0, time.UTC))
 			if err != nil {
 				t.Fatalf("could not save taxes: %s", err)
 			}
@@ -234,7 +251,7 @@ func TestAccountRepository(t *testing.T) {
 				t.Fatalf("could not get transactions: %s", err)
 			}
 
-			income := accounting.NewIncomeStatement(transactions)
+			income := accounting.NewIncomeStatement(transactions, accounting.DEFAULT_TAX_RATE)
 			if income.GetTaxes() != 0 {
 				t.Errorf("expected taxes %d, got %d", 0, income.GetTaxes())
 			}
@@ -244,7 +261,7 @@ func TestAccountRepository(t *testing.T) {
 		})
 
 		t.Run("should remove deferred when saving incurred", func(t *testing.T) {
-			err := repository.SaveTaxes(ctx, 3250000, 3)
+			err := repository.SaveTaxes(ctx, 3250000, 3, time.Date(2023, 12, 24, 0, 0, 0, 0, time.UTC))
 			if err != nil {
 				t.Fatalf("could not save taxes: %s", err)
 			}
@@ -261,7 +278,7 @@ func TestAccountRepository(t *testing.T) {
 				t.Fatal("should retrieve transactions")
 			}
 
-			income := accounting.NewIncomeStatement(transactions)
+			income := accounting.NewIncomeStatement(transactions, accounting.DEFAULT_TAX_RATE)
 			if income.GetTaxes() != -3250000 {
 				t.Errorf("expected taxes %d, got %d", 3250000, income.GetTaxes())
 			}
@@ -269,5 +286,69 @@ func TestAccountRepository(t *testing.T) {
 				t.Errorf("expected deferred taxes %d, got %d", 0, income.GetDeferredTaxes())
 			}
 		})
+
+		t.Run("should skip periods already paid", func(t *testing.T) {
+			before, _ := repository.GetLedger(ctx, 3, accounting.LedgerFilter{Limit: 100})
+
+			err := repository.SaveTaxes(ctx, 3250000, 3, time.Date(2023, 12, 24, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatalf("could not save taxes: %s", err)
+			}
+
+			if after, _ := repository.GetLedger(ctx, 3, accounting.LedgerFilter{Limit: 100}); len(after) != len(before) {
+				t.Errorf("expected %d transactions, got %d", len(before), len(after))
+			}
+		})
+	})
+
+	t.Run("ReconcileBalances", func(t *testing.T) {
+		t.Run("should keep balances in sync with the ledger", func(t *testing.T) {
+			mismatches, err := repository.ReconcileBalances(ctx)
+			if err != nil {
+				t.Fatalf("could not reconcile balances: %s", err)
+			}
+
+			if len(mismatches) != 0 {
+				t.Errorf("expected no mismatches, got %+v", mismatches[0])
+			}
+		})
+
+		t.Run("should report mismatches", func(t *testing.T) {
+			if _, err := conn.DB.Exec(`UPDATE company_balances SET cash = cash + 100 WHERE company_id = 2`); err != nil {
+				t.Fatalf("could not update balance: %s", err)
+			}
+
+			mismatches, err := repository.ReconcileBalances(ctx)
+			if err != nil {
+				t.Fatalf("could not reconcile balances: %s", err)
+			}
+
+			if len(mismatches) != 1 {
+				t.Fatalf("expected %d mismatch, got %d", 1, len(mismatches))
+			}
+
+			if mismatches[0].CompanyId != 2 {
+				t.Errorf("expected company %d, got %d", 2, mismatches[0].CompanyId)
+			}
+
+			if diff := mismatches[0].Cached - mismatches[0].Ledger; diff != 100 {
+				t.Errorf("expected difference of %d, got %d", 100, diff)
+			}
+		})
+	})
+
+	t.Run("CheckpointBalances", func(t *testing.T) {
+		if err := repository.CheckpointBalances(ctx); err != nil {
+			t.Fatalf("could not checkpoint balances: %s", err)
+		}
+
+		var checkpoints int
+		if err := conn.DB.QueryRow(`SELECT COUNT(*) FROM balance_checkpoints WHERE transaction_id > 0`).Scan(&checkpoints); err != nil {
+			t.Fatalf("could not count checkpoints: %s", err)
+		}
+
+		if checkpoints != 3 {
+			t.Errorf("expected %d checkpoints, got %d", 3, checkpoints)
+		}
 	})
 }
@@ -2,7 +2,9 @@ package accounting
 
 import (
 	"api/auth"
+	"api/server"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
@@ -10,17 +12,38 @@ import (
 func CreateEndpoints(e *echo.Echo, service Service) {
 	group := e.Group("/accounting")
 
-	group.POST("/taxes", func(c echo.Context) error {
-		id, err := auth.ParseToken(c.Get("user"))
+	ledger := group.GET("/transactions", func(c echo.Context) error {
+		companyId, err := auth.ParseToken(c.Get("user"))
 		if err != nil {
 			return err
 		}
 
-		if id != 0 {
-			return echo.NewHTTPError(http.StatusUnauthorized)
+		page, err := strconv.ParseUint(c.QueryParam("page"), 10, 64)
+		if err != nil || page == 0 {
+			page = 1
 		}
 
-		start, end := GetCurrentPeriod()
-		return service.PayTaxes(c.Request().Context(), start, end)
+		limit, err := strconv.ParseUint(c.QueryParam("limit"), 10, 64)
+		if err != nil {
+			limit = LEDGER_PAGE_SIZE
+		}
+
+		entries, err := service.GetLedger(c.Request().Context(), companyId, LedgerFilter{
+			Page:  uint(page - 1),
+			Limit: uint(limit),
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, entries)
 	})
+
+	group.POST("/taxes", func(c echo.Context) error {
+		start, end := service.GetCurrentPeriod()
+		return service.PayTaxes(c.Request().Context(), start, end)
+	}, server.RequireRole(auth.ROLE_ADMIN))
+
+	server.AllowApiKeys(auth.SCOPE_FINANCE_READ, ledger)
+	server.AllowMembers(auth.SCOPE_FINANCE_READ, ledger)
 }
@@ -5,22 +5,21 @@ import (
 	"api/auth"
 	"api/scheduler"
 	"api/server"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 )
 
 func TestAccountingRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
 	token, err := auth.GenerateToken(1, "secret")
 	if err != nil {
 		t.Fatalf("could not generate jwt token: %s", err)
 	}
 
-	svc := accounting.NewService(accounting.NewFakeRepository(), scheduler.NewScheduler())
+	svc := accounting.NewService(accounting.NewFakeRepository(), accounting.DEFAULT_TAX_RATE, scheduler.NewScheduler())
 
-	svr := server.NewServer()
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
 	accounting.CreateEndpoints(svr, svc)
 
 	t.Run("Taxes", func(t *testing.T) {
@@ -57,8 +56,8 @@ func TestAccountingRoutes(t *testing.T) {
 			rec := httptest.NewRecorder()
 			svr.ServeHTTP(rec, req)
 
-			if rec.Code != http.StatusUnauthorized {
-				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
 			}
 		})
 
@@ -74,6 +73,65 @@ func TestAccountingRoutes(t *testing.T) {
 				t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
 			}
 		})
+
+		t.Run("admin token", func(t *testing.T) {
+			adminToken, err := auth.GenerateToken(3, "secret", auth.ROLE_ADMIN)
+			if err != nil {
+				t.Fatalf("could not generate jwt token: %s", err)
+			}
+
+			req := httptest.NewRequest("POST", "/accounting/taxes", nil)
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+			req.Header.Set("Accept", "application/json")
+
+			rec := httptest.NewRecorder()
+			svr.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+		})
 	})
 
+	t.Run("Ledger", func(t *testing.T) {
+		t.Run("should return the transactions of the company, newest first", func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounting/transactions?limit=2", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Accept", "application/json")
+
+			rec := httptest.NewRecorder()
+			svr.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			var entries []accounting.LedgerEntry
+			if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+				t.Fatalf("could not parse ledger: %s", err)
+			}
+
+			if len(entries) != 2 || entries[0].Id < entries[1].Id {
+				t.Errorf("expected the 2 latest transactions, got %+v", entries)
+			}
+		})
+
+		t.Run("should let accountants read it", func(t *testing.T) {
+			accountant, err := auth.GenerateMemberToken(1, 0, 7, auth.MEMBER_ACCOUNTANT, "secret")
+			if err != nil {
+				t.Fatalf("could not generate jwt token: %s", err)
+			}
+
+			req := httptest.NewRequest("GET", "/accounting/transactions", nil)
+			req.Header.Set("Authorization", "Bearer "+accountant)
+			req.Header.Set("Accept", "application/json")
+
+			rec := httptest.NewRecorder()
+			svr.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+		})
+	})
 }
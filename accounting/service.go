@@ -7,11 +7,20 @@ import (
 	"time"
 )
 
-const TAX_RATE = 0.15
+// Share of the taxable income paid in taxes, unless configured otherwise
+const DEFAULT_TAX_RATE = 0.15
+
+const PAY_TAXES_JOB = "accounting.taxes"
+
+const (
+	LEDGER_PAGE_SIZE     = 50
+	MAX_LEDGER_PAGE_SIZE = 100
+)
 
 type (
 	IncomeStatement struct {
 		categories map[uint64]int
+		taxRate    float64
 	}
 
 	IncomeResult struct {
@@ -20,28 +29,62 @@ type (
 		DeferredTaxes int64 `db:"deferred_taxes"`
 	}
 
+	// BalanceMismatch is a company whose cached cash differs from the sum
+	// of its transactions
+	BalanceMismatch struct {
+		CompanyId int64 `json:"company_id"`
+		Cached    int64 `json:"cached"`
+		Ledger    int64 `json:"ledger"`
+	}
+
 	Transaction struct {
 		Value          int    `db:"value"`
 		Description    string `db:"description"`
 		Classification uint64 `db:"classification_id"`
 	}
 
+	// LedgerEntry is a transaction as companies see it in their ledger
+	LedgerEntry struct {
+		Id             uint64    `db:"id" json:"id"`
+		Value          int64     `db:"value" json:"value"`
+		Description    string    `db:"description" json:"description"`
+		Classification uint64    `db:"classification_id" json:"classification"`
+		CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	}
+
+	LedgerFilter struct {
+		// Pages start at 0
+		Page  uint
+		Limit uint
+	}
+
 	Service interface {
+		GetCurrentPeriod() (start, end time.Time)
+		GetLedger(ctx context.Context, companyId uint64, filter LedgerFilter) ([]*LedgerEntry, error)
 		PayTaxes(ctx context.Context, start, end time.Time) error
+		CheckpointBalances(ctx context.Context) error
+		ReconcileBalances(ctx context.Context) ([]*BalanceMismatch, error)
 	}
 
 	service struct {
 		repository Repository
+		taxRate    float64
 		timer      *scheduler.Scheduler
 	}
+
+	taxesJob struct {
+		Taxes     int64     `json:"taxes"`
+		CompanyId int64     `json:"company_id"`
+		Period    time.Time `json:"period"`
+	}
 )
 
-func NewIncomeStatement(transactions []*Transaction) *IncomeStatement {
+func NewIncomeStatement(transactions []*Transaction, taxRate float64) *IncomeStatement {
 	categories := make(map[uint64]int)
 	for _, transaction := range transactions {
 		categories[transaction.Classification] = transaction.Value
 	}
-	return &IncomeStatement{categories}
+	return &IncomeStatement{categories, taxRate}
 }
 
 func (s *IncomeStatement) GetTaxableIncome() int64 {
@@ -62,7 +105,7 @@ func (s *IncomeStatement) GetTaxes() int64 {
 	}
 
 	taxableIncome := s.GetTaxableIncome()
-	taxes := int64(float64(taxableIncome) * TAX_RATE)
+	taxes := int64(float64(taxableIncome) * s.taxRate)
 
 	if taxes > 0 {
 		taxes -= s.GetDeferredTaxes()
@@ -71,12 +114,15 @@ func (s *IncomeStatement) GetTaxes() int64 {
 	return int64(taxes)
 }
 
-func NewService(repository Repository, timer *scheduler.Scheduler) Service {
-	return &service{repository, timer}
+func NewService(repository Repository, taxRate float64, timer *scheduler.Scheduler) Service {
+	service := &service{repository, taxRate, timer}
+	timer.Register(PAY_TAXES_JOB, service.saveTaxes)
+	return service
 }
 
-func GetCurrentPeriod() (start, end time.Time) {
-	now := time.Now().UTC()
+// Returns the week before the one containing now, from sunday to saturday
+func GetPeriod(now time.Time) (start, end time.Time) {
+	now = now.UTC()
 	year, month, day := now.Date()
 
 	start = time.Date(year, month, day-int(now.Weekday())-7, 0, 0, 0, 0, time.UTC)
@@ -85,14 +131,30 @@ func GetCurrentPeriod() (start, end time.Time) {
 	return start, end
 }
 
+func (s *service) GetCurrentPeriod() (start, end time.Time) {
+	return GetPeriod(s.timer.Now())
+}
+
+// Returns the transactions of the company, newest first
+func (s *service) GetLedger(ctx context.Context, companyId uint64, filter LedgerFilter) ([]*LedgerEntry, error) {
+	if filter.Limit == 0 {
+		filter.Limit = LEDGER_PAGE_SIZE
+	}
+	filter.Limit = min(filter.Limit, MAX_LEDGER_PAGE_SIZE)
+
+	return s.repository.GetLedger(ctx, companyId, filter)
+}
+
 func (s *service) GetIncomeStatement(ctx context.Context, start, end time.Time, companyId int64) (*IncomeStatement, error) {
 	transactions, err := s.repository.GetIncomeTransactions(ctx, start, end, companyId)
 	if err != nil {
 		return nil, err
 	}
-	return NewIncomeStatement(transactions), nil
+	return NewIncomeStatement(transactions, s.taxRate), nil
 }
 
+// Pays the taxes of the period for every company. Companies that already
+// paid them are skipped, so running the period again is harmless.
 func (s *service) PayTaxes(ctx context.Context, start, end time.Time) error {
 	results, err := s.repository.GetPeriodResults(ctx, start, end)
 	if err != nil {
@@ -100,15 +162,48 @@ func (s *service) PayTaxes(ctx context.Context, start, end time.Time) error {
 	}
 
 	for _, result := range results {
-		companyId := result.CompanyId
-		taxes := int64(float64(result.TaxableIncome)*TAX_RATE) - result.DeferredTaxes
-
-		if err := s.repository.SaveTaxes(ctx, taxes, result.CompanyId); err != nil {
-			s.timer.Add(fmt.Sprintf("TAXES_%d", result.CompanyId), 3*time.Second, func() error {
-				return s.repository.SaveTaxes(ctx, taxes, companyId)
-			})
+		taxes := int64(float64(result.TaxableIncome)*s.taxRate) - result.DeferredTaxes
+
+		if err := s.repository.SaveTaxes(ctx, taxes, result.CompanyId, start); err != nil {
+			// Retries of each period are kept apart, so one failing before
+			// the previous is retried doesn't replace it
+			job, err := scheduler.NewJob(
+				fmt.Sprintf("TAXES_%d_%s", result.CompanyId, start.Format(time.DateOnly)),
+				PAY_TAXES_JOB,
+				result.CompanyId,
+				taxesJob{taxes, result.CompanyId, start},
+				s.timer.Now().Add(3*time.Second),
+			)
+
+			if err != nil {
+				return err
+			}
+
+			if err := s.timer.Schedule(ctx, job); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
+
+func (s *service) CheckpointBalances(ctx context.Context) error {
+	return s.repository.CheckpointBalances(ctx)
+}
+
+func (s *service) ReconcileBalances(ctx context.Context) ([]*BalanceMismatch, error) {
+	return s.repository.ReconcileBalances(ctx)
+}
+
+func (s *service) saveTaxes(job *scheduler.Job) error {
+	var payload taxesJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return s.repository.SaveTaxes(ctx, payload.Taxes, payload.CompanyId, payload.Period)
+}
@@ -10,7 +10,8 @@ import (
 
 func TestAccountingService(t *testing.T) {
 	repository := accounting.NewFakeRepository()
-	service := accounting.NewService(repository, scheduler.NewScheduler())
+	timer := scheduler.NewScheduler()
+	service := accounting.NewService(repository, accounting.DEFAULT_TAX_RATE, timer)
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
@@ -31,6 +32,13 @@ func TestAccountingService(t *testing.T) {
 			t.Fatalf("could not pay taxes: %s", err)
 		}
 
+		t.Run("should retry per period", func(t *testing.T) {
+			jobs := timer.GetPendingJobs(scheduler.JobFilter{CompanyId: 3})
+			if len(jobs) != 1 || jobs[0].Id != "TAXES_3_2023-12-24" {
+				t.Errorf("expected retry of the period's taxes, got %+v", jobs)
+			}
+		})
+
 		t.Run("deferred", func(t *testing.T) {
 			transactions, err := repository.GetIncomeTransactions(ctx, start, end, 1)
 			if err != nil {
@@ -109,6 +117,19 @@ func TestAccountingService(t *testing.T) {
 				}
 			}
 		})
+
+		t.Run("should not pay the period twice", func(t *testing.T) {
+			if err := service.PayTaxes(ctx, start, end); err != nil {
+				t.Fatalf("could not pay taxes: %s", err)
+			}
+
+			for companyId, expected := range map[int64]int{1: 6, 2: 6, 3: 7} {
+				transactions, _ := repository.GetIncomeTransactions(ctx, start, end, companyId)
+				if len(transactions) != expected {
+					t.Errorf("expected %d transactions of company %d, got %d", expected, companyId, len(transactions))
+				}
+			}
+		})
 	})
 
 }
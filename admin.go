@@ -0,0 +1,55 @@
+package main
+
+import (
+	"api/company"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+const adminUsage = "usage: admin create-company -name <name> -email <email> -password <password> [-admin]"
+
+// Runs the admin subcommand, for tasks the HTTP API doesn't allow, like
+// creating admin companies
+func runAdmin(ctx context.Context, companySvc company.Service, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "create-company" {
+		return errors.New(adminUsage)
+	}
+
+	registration := new(company.Registration)
+
+	flags := flag.NewFlagSet("admin create-company", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&registration.Name, "name", "", "name of the company")
+	flags.StringVar(&registration.Email, "email", "", "email the company logs in with")
+	flags.StringVar(&registration.Password, "password", "", "password the company logs in with")
+	flags.BoolVar(&registration.Admin, "admin", false, "whether the company is an admin")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if registration.Name == "" || registration.Email == "" || registration.Password == "" {
+		return errors.New(adminUsage)
+	}
+
+	existing, err := companySvc.GetByEmail(ctx, registration.Email)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return fmt.Errorf("a company with email %s already exists", registration.Email)
+	}
+
+	registration.Confirm = registration.Password
+	created, err := companySvc.Register(ctx, registration)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "created company %d\n", created.Id)
+	return nil
+}
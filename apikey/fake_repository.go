@@ -0,0 +1,79 @@
+package apikey
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type fakeRepository struct {
+	mutex sync.Mutex
+	data  map[uint64]*ApiKey
+}
+
+func NewFakeRepository() Repository {
+	return &fakeRepository{data: make(map[uint64]*ApiKey)}
+}
+
+func (r *fakeRepository) Save(ctx context.Context, apiKey *ApiKey) (*ApiKey, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	saved := *apiKey
+	saved.Id = uint64(len(r.data) + 1)
+	r.data[saved.Id] = &saved
+
+	copied := saved
+	return &copied, nil
+}
+
+func (r *fakeRepository) GetAll(ctx context.Context, companyId uint64) ([]*ApiKey, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	apiKeys := make([]*ApiKey, 0)
+	for id := uint64(len(r.data)); id > 0; id-- {
+		if apiKey := r.data[id]; apiKey.CompanyId == companyId {
+			copied := *apiKey
+			apiKeys = append(apiKeys, &copied)
+		}
+	}
+	return apiKeys, nil
+}
+
+func (r *fakeRepository) GetByHash(ctx context.Context, hash string) (*ApiKey, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, apiKey := range r.data {
+		if apiKey.Hash == hash {
+			copied := *apiKey
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) Revoke(ctx context.Context, companyId, keyId uint64) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	apiKey, ok := r.data[keyId]
+	if !ok || apiKey.CompanyId != companyId || apiKey.RevokedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	return true, nil
+}
+
+func (r *fakeRepository) Touch(ctx context.Context, keyId uint64, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if apiKey, ok := r.data[keyId]; ok {
+		apiKey.LastUsedAt = &at
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package apikey
+
+import (
+	"api/database"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type (
+	Repository interface {
+		Save(ctx context.Context, apiKey *ApiKey) (*ApiKey, error)
+		GetAll(ctx context.Context, companyId uint64) ([]*ApiKey, error)
+
+		// Keys of blocked or deleted companies aren't found
+		GetByHash(ctx context.Context, hash string) (*ApiKey, error)
+
+		// Returns false when the company has no such key left to revoke
+		Revoke(ctx context.Context, companyId, keyId uint64) (bool, error)
+
+		// Records when the key was last used
+		Touch(ctx context.Context, keyId uint64, at time.Time) error
+	}
+
+	goquRepository struct {
+		builder *goqu.Database
+	}
+)
+
+func NewRepository(conn *database.Connection) Repository {
+	builder := goqu.New(conn.Driver, conn.DB)
+	return &goquRepository{builder}
+}
+
+func (r *goquRepository) Save(ctx context.Context, apiKey *ApiKey) (*ApiKey, error) {
+	id, err := database.InsertId(ctx, database.Query(ctx, r.builder).
+		Insert(goqu.T("api_keys")).
+		Rows(apiKey))
+
+	if err != nil {
+		return nil, err
+	}
+
+	saved := *apiKey
+	saved.Id = uint64(id)
+	return &saved, nil
+}
+
+func (r *goquRepository) GetAll(ctx context.Context, companyId uint64) ([]*ApiKey, error) {
+	apiKeys := make([]*ApiKey, 0)
+
+	err := database.Query(ctx, r.builder).
+		From(goqu.T("api_keys")).
+		Where(goqu.I("company_id").Eq(companyId)).
+		Order(goqu.I("id").Desc()).
+		ScanStructsContext(ctx, &apiKeys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (r *goquRepository) GetByHash(ctx context.Context, hash string) (*ApiKey, error) {
+	apiKey := new(ApiKey)
+
+	found, err := database.Query(ctx, r.builder).
+		Select(goqu.T("k").All()).
+		From(goqu.T("api_keys").As("k")).
+		InnerJoin(goqu.T("companies").As("c"), goqu.On(goqu.I("c.id").Eq(goqu.I("k.company_id")))).
+		Where(
+			goqu.I("k.key_hash").Eq(hash),
+			goqu.I("c.blocked_at").IsNull(),
+			goqu.I("c.deleted_at").IsNull(),
+		).
+		ScanStructContext(ctx, apiKey)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+func (r *goquRepository) Revoke(ctx context.Context, companyId, keyId uint64) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
+		Update(goqu.T("api_keys")).
+		Set(goqu.Record{"revoked_at": time.Now().UTC()}).
+		Where(
+			goqu.I("id").Eq(keyId),
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("revoked_at").IsNull(),
+		).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *goquRepository) Touch(ctx context.Context, keyId uint64, at time.Time) error {
+	_, err := database.Query(ctx, r.builder).
+		Update(goqu.T("api_keys")).
+		Set(goqu.Record{"last_used_at": at}).
+		Where(goqu.I("id").Eq(keyId)).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
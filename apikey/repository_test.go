@@ -0,0 +1,134 @@
+package apikey_test
+
+import (
+	"api/apikey"
+	"api/database"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestApiKeyRepository(t *testing.T) {
+	conn, err := database.GetTestConnection("../test.db")
+	if err != nil {
+		t.Fatalf("could not open database: %s", err)
+	}
+
+	if _, err := conn.DB.Exec(`
+        INSERT INTO companies (id, name, email, password) VALUES
+        (1, 'Foo', 'bar', 'baz'), (2, 'Bar', 'baz', 'foo')
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := conn.DB.Exec(`DELETE FROM api_keys`); err != nil {
+			t.Errorf("could not clean up database: %s", err)
+		}
+		if _, err := conn.DB.Exec(`DELETE FROM companies`); err != nil {
+			t.Errorf("could not clean up database: %s", err)
+		}
+	})
+
+	repository := apikey.NewRepository(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	saved, err := repository.Save(ctx, &apikey.ApiKey{
+		CompanyId: 1,
+		Name:      "bot",
+		Prefix:    "etp_abcdefgh",
+		Hash:      "hash",
+		Scopes:    apikey.Scopes{"market:read", "market:trade"},
+		ExpiresAt: &expiresAt,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	if err != nil {
+		t.Fatalf("could not save api key: %s", err)
+	}
+
+	t.Run("GetByHash", func(t *testing.T) {
+		apiKey, err := repository.GetByHash(ctx, "hash")
+		if err != nil {
+			t.Fatalf("could not get api key: %s", err)
+		}
+
+		if apiKey == nil || apiKey.Id != saved.Id || apiKey.CompanyId != 1 {
+			t.Fatalf("expected saved key, got %v", apiKey)
+		}
+
+		if len(apiKey.Scopes) != 2 || apiKey.Scopes[1] != "market:trade" {
+			t.Errorf("expected both scopes, got %v", apiKey.Scopes)
+		}
+
+		if apiKey.ExpiresAt == nil || !apiKey.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("expected expiry %s, got %v", expiresAt, apiKey.ExpiresAt)
+		}
+
+		missing, err := repository.GetByHash(ctx, "unknown")
+		if err != nil || missing != nil {
+			t.Errorf("expected no key, got %v %v", missing, err)
+		}
+	})
+
+	t.Run("GetByHash should not find keys of blocked companies", func(t *testing.T) {
+		if _, err := repository.Save(ctx, &apikey.ApiKey{
+			CompanyId: 2,
+			Name:      "bot",
+			Prefix:    "etp_ijklmnop",
+			Hash:      "blocked",
+			CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatalf("could not save api key: %s", err)
+		}
+
+		if _, err := conn.DB.Exec(`UPDATE companies SET blocked_at = CURRENT_TIMESTAMP WHERE id = 2`); err != nil {
+			t.Fatalf("could not block company: %s", err)
+		}
+
+		apiKey, err := repository.GetByHash(ctx, "blocked")
+		if err != nil || apiKey != nil {
+			t.Errorf("expected no key, got %v %v", apiKey, err)
+		}
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		if err := repository.Touch(ctx, saved.Id, time.Now().UTC()); err != nil {
+			t.Fatalf("could not touch api key: %s", err)
+		}
+
+		apiKeys, err := repository.GetAll(ctx, 1)
+		if err != nil {
+			t.Fatalf("could not get api keys: %s", err)
+		}
+
+		if len(apiKeys) != 1 || apiKeys[0].LastUsedAt == nil {
+			t.Errorf("expected key to have been used, got %v", apiKeys)
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		revoked, err := repository.Revoke(ctx, 2, saved.Id)
+		if err != nil || revoked {
+			t.Errorf("expected other company not to revoke key, got %t %v", revoked, err)
+		}
+
+		revoked, err = repository.Revoke(ctx, 1, saved.Id)
+		if err != nil || !revoked {
+			t.Fatalf("expected key to be revoked, got %t %v", revoked, err)
+		}
+
+		revoked, err = repository.Revoke(ctx, 1, saved.Id)
+		if err != nil || revoked {
+			t.Errorf("expected key to be revoked once, got %t %v", revoked, err)
+		}
+
+		apiKey, err := repository.GetByHash(ctx, "hash")
+		if err != nil || apiKey.RevokedAt == nil {
+			t.Errorf("expected revoked key, got %v %v", apiKey, err)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package apikey
+
+import (
+	"api/server"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func CreateEndpoints(e *echo.Echo, service Service) {
+	group := e.Group("/companies/:id/api-keys", server.RequireOwner(":id"))
+
+	group.GET("", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		apiKeys, err := service.GetAll(c.Request().Context(), companyId)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, apiKeys)
+	})
+
+	group.POST("", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		request := new(Request)
+		if err := c.Bind(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(request); err != nil {
+			return err
+		}
+
+		created, err := service.Create(c.Request().Context(), companyId, request)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, created)
+	})
+
+	group.DELETE("/:keyId", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		keyId, err := strconv.ParseUint(c.Param("keyId"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if err := service.Revoke(c.Request().Context(), companyId, keyId); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+}
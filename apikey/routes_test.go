@@ -0,0 +1,181 @@
+package apikey_test
+
+import (
+	"api/apikey"
+	"api/auth"
+	"api/server"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestApiKeyRoutes(t *testing.T) {
+	token, err := auth.GenerateToken(1, "secret")
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
+	}
+
+	repository := apikey.NewFakeRepository()
+	svc := apikey.NewService(repository)
+	svr := server.NewServer(server.Config{JwtSecret: "secret", AuthenticateApiKey: svc.Authenticate})
+
+	apikey.CreateEndpoints(svr, svc)
+
+	whoami := func(c echo.Context) error {
+		companyId, err := auth.ParseToken(c.Get("user"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, companyId)
+	}
+
+	server.AllowApiKeys(auth.SCOPE_MARKET_READ, svr.GET("/test/read", whoami))
+	server.AllowApiKeys(auth.SCOPE_MARKET_TRADE, svr.POST("/test/trade", whoami))
+	svr.GET("/test/closed", whoami)
+
+	send := func(method, path, token, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if key != "" {
+			req.Header.Set(server.API_KEY_HEADER, key)
+		}
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	create := func(t *testing.T, body string) *apikey.Created {
+		rec := send("POST", "/companies/1/api-keys", token, "", body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("could not create api key: %d %s", rec.Code, rec.Body.String())
+		}
+
+		created := new(apikey.Created)
+		if err := json.Unmarshal(rec.Body.Bytes(), created); err != nil {
+			t.Fatalf("could not parse api key: %s", err)
+		}
+		return created
+	}
+
+	t.Run("should validate scopes", func(t *testing.T) {
+		rec := send("POST", "/companies/1/api-keys", token, "", `{"name":"bot","scopes":["market:read","admin"]}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should not create keys for other companies", func(t *testing.T) {
+		rec := send("POST", "/companies/2/api-keys", token, "", `{"name":"bot","scopes":["market:read"]}`)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("should reject expiry in the past", func(t *testing.T) {
+		rec := send("POST", "/companies/1/api-keys", token, "", `{"name":"bot","scopes":["market:read"],"expires_at":"2020-01-01T00:00:00Z"}`)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("should authenticate with scoped keys", func(t *testing.T) {
+		created := create(t, `{"name":"bot","scopes":["market:read"]}`)
+
+		if !strings.HasPrefix(created.Key, apikey.KEY_PREFIX) || !strings.HasPrefix(created.Key, created.Prefix) {
+			t.Errorf("expected key to start with its prefix, got %s", created.Key)
+		}
+
+		rec := send("GET", "/test/read", "", created.Key, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if rec.Body.String() != "1\n" {
+			t.Errorf("expected key to authenticate company 1, got %s", rec.Body.String())
+		}
+
+		if rec := send("POST", "/test/trade", "", created.Key, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d without scope, got %d", http.StatusForbidden, rec.Code)
+		}
+
+		if rec := send("GET", "/test/closed", "", created.Key, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d on closed route, got %d", http.StatusForbidden, rec.Code)
+		}
+
+		if rec := send("GET", "/companies/1/api-keys", "", created.Key, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected keys not to manage keys, got %d", rec.Code)
+		}
+	})
+
+	t.Run("should refuse unknown keys", func(t *testing.T) {
+		if rec := send("GET", "/test/read", "", "etp_unknown", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should refuse expired keys", func(t *testing.T) {
+		expiredAt := time.Now().Add(-time.Minute)
+		_, err := repository.Save(context.Background(), &apikey.ApiKey{
+			CompanyId: 1,
+			Name:      "expired",
+			Hash:      auth.HashSecret("etp_expired"),
+			Scopes:    apikey.Scopes{auth.SCOPE_MARKET_READ},
+			ExpiresAt: &expiredAt,
+		})
+		if err != nil {
+			t.Fatalf("could not save api key: %s", err)
+		}
+
+		if rec := send("GET", "/test/read", "", "etp_expired", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should list and revoke keys", func(t *testing.T) {
+		created := create(t, `{"name":"revoked","scopes":["market:read","market:trade"]}`)
+
+		if rec := send("POST", "/test/trade", "", created.Key, ""); rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		rec := send("GET", "/companies/1/api-keys", token, "", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		if strings.Contains(rec.Body.String(), created.Key) {
+			t.Error("expected listed keys not to include the key")
+		}
+
+		var apiKeys []apikey.ApiKey
+		if err := json.Unmarshal(rec.Body.Bytes(), &apiKeys); err != nil {
+			t.Fatalf("could not parse api keys: %s", err)
+		}
+		if len(apiKeys) == 0 || apiKeys[0].Id != created.Id || apiKeys[0].LastUsedAt == nil {
+			t.Errorf("expected newest key to be first and used, got %v", apiKeys)
+		}
+
+		if rec := send("DELETE", "/companies/1/api-keys/"+strconv.FormatUint(created.Id, 10), token, "", ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		if rec := send("POST", "/test/trade", "", created.Key, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected revoked key to be refused, got %d", rec.Code)
+		}
+
+		if rec := send("DELETE", "/companies/1/api-keys/"+strconv.FormatUint(created.Id, 10), token, "", ""); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d revoking twice, got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+// Package apikey lets companies create keys for their bots and integrations,
+// so they don't have to share a player's login. Keys only hold the scopes
+// they were granted and are sent in the X-API-Key header.
+package apikey
+
+import (
+	"api/auth"
+	"api/server"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Every key starts with it, so leaked keys are easy to search for
+const KEY_PREFIX = "etp_"
+
+type (
+	ApiKey struct {
+		Id         uint64     `db:"id" json:"id" goqu:"skipinsert,skipupdate"`
+		CompanyId  uint64     `db:"company_id" json:"-"`
+		Name       string     `db:"name" json:"name"`
+		Prefix     string     `db:"prefix" json:"prefix"`
+		Hash       string     `db:"key_hash" json:"-"`
+		Scopes     Scopes     `db:"scopes" json:"scopes"`
+		ExpiresAt  *time.Time `db:"expires_at" json:"expires_at"`
+		LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+		RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at"`
+		CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	}
+
+	// Scopes are stored as a comma separated list
+	Scopes []string
+
+	Request struct {
+		Name      string     `json:"name" validate:"required,max=255"`
+		Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=market:read market:trade production:write finance:read"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	// Created is the only time the key itself is returned, only its hash
+	// is kept
+	Created struct {
+		*ApiKey
+		Key string `json:"key"`
+	}
+
+	Service interface {
+		Create(ctx context.Context, companyId uint64, request *Request) (*Created, error)
+		GetAll(ctx context.Context, companyId uint64) ([]*ApiKey, error)
+		Revoke(ctx context.Context, companyId, keyId uint64) error
+
+		// Returns the claims of the key, or nil when it doesn't exist, was
+		// revoked or expired
+		Authenticate(ctx context.Context, key string) (*auth.Claims, error)
+	}
+
+	service struct {
+		repository Repository
+	}
+)
+
+func NewService(repository Repository) Service {
+	return &service{repository}
+}
+
+func (s *service) Create(ctx context.Context, companyId uint64, request *Request) (*Created, error) {
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, server.NewBusinessRuleError("expiry must be in the future")
+	}
+
+	secret, _, err := auth.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	key := KEY_PREFIX + secret
+	apiKey, err := s.repository.Save(ctx, &ApiKey{
+		CompanyId: companyId,
+		Name:      request.Name,
+		Prefix:    key[:len(KEY_PREFIX)+8],
+		Hash:      auth.HashSecret(key),
+		Scopes:    request.Scopes,
+		ExpiresAt: request.ExpiresAt,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Created{apiKey, key}, nil
+}
+
+func (s *service) GetAll(ctx context.Context, companyId uint64) ([]*ApiKey, error) {
+	return s.repository.GetAll(ctx, companyId)
+}
+
+func (s *service) Revoke(ctx context.Context, companyId, keyId uint64) error {
+	revoked, err := s.repository.Revoke(ctx, companyId, keyId)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return server.NewBusinessRuleError("api key not found")
+	}
+
+	return nil
+}
+
+func (s *service) Authenticate(ctx context.Context, key string) (*auth.Claims, error) {
+	apiKey, err := s.repository.GetByHash(ctx, auth.HashSecret(key))
+	if err != nil || apiKey == nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now)) {
+		return nil, nil
+	}
+
+	if err := s.repository.Touch(ctx, apiKey.Id, now); err != nil {
+		return nil, err
+	}
+
+	return &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: strconv.FormatUint(apiKey.CompanyId, 10)},
+		ApiKeyId:         apiKey.Id,
+		Scopes:           apiKey.Scopes,
+	}, nil
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+func (s *Scopes) Scan(src any) error {
+	var value string
+
+	switch src := src.(type) {
+	case string:
+		value = src
+	case []byte:
+		value = string(src)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into scopes", src)
+	}
+
+	*s = make(Scopes, 0)
+	if value != "" {
+		*s = strings.Split(value, ",")
+	}
+
+	return nil
+}
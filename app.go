@@ -0,0 +1,228 @@
+package main
+
+import (
+	"api/accounting"
+	"api/apikey"
+	"api/building"
+	"api/catalog"
+	"api/clock"
+	"api/company"
+	companyBuilding "api/company/building"
+	"api/company/building/production"
+	"api/config"
+	"api/database"
+	"api/financing"
+	"api/financing/bonds"
+	"api/financing/loans"
+	"api/mail"
+	"api/market"
+	"api/migrations"
+	"api/moderation"
+	"api/notification"
+	"api/research"
+	"api/research/staff"
+	"api/resource"
+	"api/scheduler"
+	"api/valuation"
+	"api/warehouse"
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// app holds every service, wired together, so commands can call them
+// directly instead of going through the HTTP API
+type app struct {
+	conn     *database.Connection
+	uow      database.UnitOfWork
+	clock    clock.Clock
+	timer    *scheduler.Scheduler
+	logger   *log.Logger
+	notifier notification.Notifier
+
+	resourceSvc            resource.Service
+	warehouseSvc           warehouse.Service
+	buildingSvc            building.Service
+	catalogSvc             catalog.Service
+	accountingSvc          accounting.Service
+	companySvc             company.Service
+	scheduledBuildingSvc   companyBuilding.BuildingService
+	scheduledProductionSvc production.ProductionService
+	staffSvc               staff.Service
+	marketSvc              market.Service
+	financingSvc           financing.Service
+	scheduledLoansSvc      loans.Service
+	scheduledBondsSvc      bonds.Service
+	notificationSvc        notification.Service
+	apiKeySvc              apikey.Service
+	moderationSvc          moderation.Service
+	valuationSvc           valuation.Service
+}
+
+// Connects to the configured database and loads the migrations for it
+func connect(cfg *config.Config) (*database.Connection, *database.Migrator, error) {
+	conn, err := database.GetConnection(cfg.Database.Driver, cfg.Database.Url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source, err := migrations.For(conn.Driver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := database.NewMigrator(conn, source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, migrator, nil
+}
+
+// Wires every service together. The schema must be up to date, otherwise
+// the services would run against tables written by another version.
+func newApp(ctx context.Context, cfg *config.Config, conn *database.Connection, migrator *database.Migrator) (*app, error) {
+	if err := migrator.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	gameClock, err := clock.FromSpeed(cfg.Clock.Speed, cfg.Clock.Epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := newLogger(cfg.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	uow := database.NewUnitOfWork(conn)
+	timer := scheduler.NewPersistentScheduler(scheduler.NewRepository(conn), gameClock)
+
+	notificationRepo := notification.NewRepository(conn)
+	notifier := notification.NewNotifier(notificationRepo)
+
+	resourceRepo := resource.NewRepository(conn)
+	resourceSvc := resource.NewService(resourceRepo)
+
+	warehouseRepo := warehouse.NewRepository(conn)
+	warehouseSvc := warehouse.NewService(warehouseRepo)
+
+	buildingRepo := building.NewRepository(conn, resourceRepo)
+	buildingSvc := building.NewService(buildingRepo)
+
+	catalogSvc := catalog.NewService(resourceRepo, buildingRepo, uow)
+
+	accountingRepo := accounting.NewRepository(conn, gameClock)
+	accountingSvc := accounting.NewService(accountingRepo, cfg.Game.TaxRate, timer)
+
+	companyRepo := company.NewRepository(conn, accountingRepo, gameClock)
+	// Lockouts guard against people guessing passwords, so they run on
+	// real time whatever the game speed. Terrains age in game time.
+	companySvc := company.NewService(companyRepo, cfg.Server.JwtSecret, cfg.Game.Terrains, newMailer(cfg.Mail), logger, uow, clock.New(), gameClock)
+
+	companyBuildingRepo := companyBuilding.NewBuildingRepository(conn, resourceRepo, warehouseRepo, gameClock)
+	companyBuildingSvc := companyBuilding.NewBuildingService(companyBuildingRepo, companySvc, warehouseSvc, buildingSvc, uow, gameClock)
+	scheduledBuildingSvc := companyBuilding.NewScheduledBuildingService(companyBuildingSvc, timer)
+
+	researchSvc := research.NewService(research.NewRepository(conn, accountingRepo), companySvc, uow, timer)
+	productionRepo := production.NewProductionRepository(conn, accountingRepo, companyBuildingRepo, warehouseRepo)
+	productionSvc := production.NewProductionService(productionRepo, companySvc, companyBuildingSvc, warehouseSvc, researchSvc, uow, gameClock)
+	scheduledProductionSvc := production.NewScheduledProductionService(productionSvc, timer)
+
+	staffRepo := staff.NewRepository(conn, accountingRepo, gameClock)
+	staffSvc := staff.NewService(staffRepo, time.Duration(cfg.Game.StaffSearchDuration), timer, notifier, logger)
+
+	marketRepo := market.NewRepository(conn, companyRepo, warehouseRepo, accountingRepo, gameClock)
+	marketSvc := market.NewService(marketRepo, companySvc, warehouseSvc, notifier, logger, cfg.Game.TransportFee, uow)
+
+	financingSvc := financing.NewService(financing.NewRepository(conn), notifier, logger, gameClock)
+
+	loansRepo := loans.NewRepository(conn, accountingRepo, gameClock)
+	loansSvc := loans.NewService(loansRepo, companySvc, financingSvc, notifier, logger, int8(cfg.Game.MaxDelayedPayments), uow, gameClock)
+	scheduledLoansSvc := loans.NewScheduledService(loansSvc, timer)
+
+	bondsRepo := bonds.NewRepository(conn, accountingRepo)
+	bondsSvc := bonds.NewService(bondsRepo, companySvc, notifier, logger, uow, gameClock)
+	scheduledBondsSvc := bonds.NewScheduledService(bondsSvc, timer)
+
+	notificationSvc := notification.NewService(notificationRepo)
+	apiKeySvc := apikey.NewService(apikey.NewRepository(conn))
+	moderationSvc := moderation.NewService(moderation.NewRepository(conn), companySvc, marketSvc, timer, logger, uow, gameClock)
+	valuationSvc := valuation.NewService(valuation.NewRepository(conn), companySvc, warehouseSvc, buildingSvc, logger, gameClock)
+
+	return &app{
+		conn:     conn,
+		uow:      uow,
+		clock:    gameClock,
+		timer:    timer,
+		logger:   logger,
+		notifier: notifier,
+
+		resourceSvc:            resourceSvc,
+		warehouseSvc:           warehouseSvc,
+		buildingSvc:            buildingSvc,
+		catalogSvc:             catalogSvc,
+		accountingSvc:          accountingSvc,
+		companySvc:             companySvc,
+		scheduledBuildingSvc:   scheduledBuildingSvc,
+		scheduledProductionSvc: scheduledProductionSvc,
+		staffSvc:               staffSvc,
+		marketSvc:              marketSvc,
+		financingSvc:           financingSvc,
+		scheduledLoansSvc:      scheduledLoansSvc,
+		scheduledBondsSvc:      scheduledBondsSvc,
+		notificationSvc:        notificationSvc,
+		apiKeySvc:              apiKeySvc,
+		moderationSvc:          moderationSvc,
+		valuationSvc:           valuationSvc,
+	}, nil
+}
+
+// Appends to the configured log file, or writes to stderr without one
+func newLogger(cfg config.Log) (*log.Logger, error) {
+	if cfg.File == "" {
+		return log.New(os.Stderr, cfg.Prefix, log.Flags()), nil
+	}
+
+	logFile, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0664)
+	if err != nil {
+		return nil, err
+	}
+
+	return log.New(logFile, cfg.Prefix, log.Flags()), nil
+}
+
+// Sends emails through the configured SMTP server, or appends them to the
+// outbox file during development
+func newMailer(cfg config.Mail) mail.Mailer {
+	if cfg.Driver == mail.SMTP {
+		return mail.NewSMTPMailer(cfg.SMTP, cfg.From)
+	}
+	return mail.NewFileMailer(cfg.Outbox, cfg.From)
+}
+
+// Returns the lifecycle of the parts every command shares. The database is
+// closed last, once the scheduler has waited for its running jobs and the
+// notifier has delivered what they sent.
+func (a *app) lifecycle() *lifecycle {
+	lc := newLifecycle(a.logger)
+
+	lc.Append(hook{
+		name: "database",
+		stop: func(ctx context.Context) error { return a.conn.Close() },
+	})
+
+	lc.Append(hook{
+		name: "notifier",
+		stop: a.notifier.Close,
+	})
+
+	lc.Append(hook{
+		name: "scheduler",
+		stop: a.timer.Shutdown,
+	})
+
+	return lc
+}
@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strconv"
@@ -10,14 +14,117 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func GenerateToken(userId uint64, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
-		Issuer:    "entrepreneur-api",
-		Subject:   fmt.Sprintf("%d", userId),
-		Audience:  jwt.ClaimStrings{"entrepreneur-webclient"},
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(10 * time.Minute)),
-	})
+const (
+	// Role of the companies that operate the game. The system token, used
+	// by cron jobs, holds every role.
+	ROLE_ADMIN = "admin"
+
+	// Scopes API keys can be granted. Tokens issued on login hold them all.
+	SCOPE_MARKET_READ      = "market:read"
+	SCOPE_MARKET_TRADE     = "market:trade"
+	SCOPE_PRODUCTION_WRITE = "production:write"
+	SCOPE_FINANCE_READ     = "finance:read"
+
+	// Scopes only members are granted, through their role
+	SCOPE_FINANCE_WRITE  = "finance:write"
+	SCOPE_COMPANY_READ   = "company:read"
+	SCOPE_COMPANY_WRITE  = "company:write"
+	SCOPE_MEMBERS_MANAGE = "members:manage"
+
+	// Roles users hold in the companies they're members of
+	MEMBER_OWNER      = "owner"
+	MEMBER_MANAGER    = "manager"
+	MEMBER_TRADER     = "trader"
+	MEMBER_ACCOUNTANT = "accountant"
+	MEMBER_VIEWER     = "viewer"
+
+	// How long access tokens are accepted for
+	TOKEN_DURATION = 10 * time.Minute
+
+	// How long a refresh token can be exchanged for a new access token
+	REFRESH_TOKEN_DURATION = 30 * 24 * time.Hour
+)
+
+// Claims are what access tokens carry: who they were issued for, in which
+// session, and the roles the company held then
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+
+	// Set when the token was issued to a member of the company, who only
+	// holds the scopes of the role
+	UserId uint64 `json:"uid,omitempty"`
+	Member string `json:"member,omitempty"`
+
+	// Set when the request was authenticated with an API key, which only
+	// holds the scopes it was granted
+	ApiKeyId uint64   `json:"-"`
+	Scopes   []string `json:"-"`
+}
+
+// Scopes each member role holds. Owners hold every scope.
+var MEMBER_SCOPES = map[string][]string{
+	MEMBER_MANAGER: {
+		SCOPE_MARKET_READ,
+		SCOPE_MARKET_TRADE,
+		SCOPE_PRODUCTION_WRITE,
+		SCOPE_FINANCE_READ,
+		SCOPE_FINANCE_WRITE,
+		SCOPE_COMPANY_READ,
+		SCOPE_COMPANY_WRITE,
+	},
+	MEMBER_TRADER:     {SCOPE_MARKET_READ, SCOPE_MARKET_TRADE, SCOPE_COMPANY_READ},
+	MEMBER_ACCOUNTANT: {SCOPE_FINANCE_READ},
+	MEMBER_VIEWER:     {SCOPE_MARKET_READ, SCOPE_FINANCE_READ, SCOPE_COMPANY_READ},
+}
+
+func IsMemberRole(role string) bool {
+	_, ok := MEMBER_SCOPES[role]
+	return ok || role == MEMBER_OWNER
+}
+
+func GenerateToken(userId uint64, secret string, roles ...string) (string, error) {
+	return GenerateSessionToken(userId, 0, roles, secret)
+}
+
+// Generates an access token for the session, carried as the token id, so
+// revoking the session revokes the token too
+func GenerateSessionToken(userId, sessionId uint64, roles []string, secret string) (string, error) {
+	return signToken(newClaims(userId, sessionId, roles), secret)
+}
+
+// Generates an access token for a user acting as member of the company.
+// The token is issued for the company, so it's accepted wherever the
+// company's are, but only holds the scopes of the role.
+func GenerateMemberToken(companyId, sessionId, userId uint64, role string, secret string) (string, error) {
+	claims := newClaims(companyId, sessionId, nil)
+	claims.UserId = userId
+	claims.Member = role
+
+	return signToken(claims, secret)
+}
+
+func newClaims(subject, sessionId uint64, roles []string) *Claims {
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "entrepreneur-api",
+			Subject:   fmt.Sprintf("%d", subject),
+			Audience:  jwt.ClaimStrings{"entrepreneur-webclient"},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TOKEN_DURATION)),
+		},
+		Roles: roles,
+	}
+
+	if sessionId > 0 {
+		claims.ID = strconv.FormatUint(sessionId, 10)
+	}
+
+	return claims
+}
+
+func signToken(claims *Claims, secret string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	signedToken, err := token.SignedString([]byte(secret))
 	if err != nil {
@@ -27,21 +134,33 @@ func GenerateToken(userId uint64, secret string) (string, error) {
 	return signedToken, nil
 }
 
-func ParseToken(token any) (uint64, error) {
+// Returns the claims of a parsed token, like the one the JWT middleware
+// stores in the request context
+func ParseClaims(token any) (*Claims, error) {
 	user, ok := token.(*jwt.Token)
 	if !ok {
-		return 0, errors.New("not a valid token instance")
+		return nil, errors.New("not a valid token instance")
 	}
 
-	claims, ok := user.Claims.(*jwt.RegisteredClaims)
-	if !ok {
-		return 0, errors.New("not a valid claims instance")
+	switch claims := user.Claims.(type) {
+	case *Claims:
+		return claims, nil
+	case *jwt.RegisteredClaims:
+		return &Claims{RegisteredClaims: *claims}, nil
+	default:
+		return nil, errors.New("not a valid claims instance")
 	}
+}
 
-	for _, aud := range claims.Audience {
-		if aud == "cronjob" {
-			return 0, nil
-		}
+// Returns the company the token was issued for, or 0 for the system token
+func ParseToken(token any) (uint64, error) {
+	claims, err := ParseClaims(token)
+	if err != nil {
+		return 0, err
+	}
+
+	if claims.IsSystem() {
+		return 0, nil
 	}
 
 	id, err := strconv.ParseUint(claims.Subject, 10, 64)
@@ -52,6 +171,84 @@ func ParseToken(token any) (uint64, error) {
 	return id, nil
 }
 
+// Returns the session the token was issued for, or 0 when it wasn't
+// issued for one, like cron tokens
+func ParseSessionId(token any) (uint64, error) {
+	claims, err := ParseClaims(token)
+	if err != nil {
+		return 0, err
+	}
+
+	return GetSessionId(&claims.RegisteredClaims)
+}
+
+func GetSessionId(claims *jwt.RegisteredClaims) (uint64, error) {
+	if claims.ID == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(claims.ID, 10, 64)
+}
+
+// The system token is issued to cron jobs rather than to a company
+func (c *Claims) IsSystem() bool {
+	for _, aud := range c.Audience {
+		if aud == "cronjob" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Claims) HasRole(role string) bool {
+	if c.IsSystem() {
+		return true
+	}
+
+	for _, held := range c.Roles {
+		if held == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	scopes := c.Scopes
+	if c.ApiKeyId == 0 {
+		if c.Member == "" || c.Member == MEMBER_OWNER {
+			return true
+		}
+		scopes = MEMBER_SCOPES[c.Member]
+	}
+
+	for _, granted := range scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Generates an opaque secret, like a refresh token or an API key, returning
+// it along with the hash that is stored in its place
+func GenerateSecret() (string, string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buffer)
+	return token, HashSecret(token), nil
+}
+
+// Secrets are random enough that a fast hash is enough, unlike passwords,
+// and it lets them be looked up by their hash
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
 func HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
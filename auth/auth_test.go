@@ -47,6 +47,146 @@ func TestAuth(t *testing.T) {
 		}
 	})
 
+	t.Run("generate session token", func(t *testing.T) {
+		t.Parallel()
+
+		signed, err := auth.GenerateSessionToken(1, 42, []string{auth.ROLE_ADMIN}, "secret")
+		if err != nil {
+			t.Fatalf("could not generate token: %s", err)
+		}
+
+		token, err := jwt.ParseWithClaims(signed, new(auth.Claims), func(t *jwt.Token) (any, error) {
+			return []byte("secret"), nil
+		})
+		if err != nil {
+			t.Fatalf("could not parse token: %s", err)
+		}
+
+		sessionId, err := auth.ParseSessionId(token)
+		if err != nil {
+			t.Fatalf("could not parse session: %s", err)
+		}
+
+		if sessionId != 42 {
+			t.Errorf("expected session %d, got %d", 42, sessionId)
+		}
+
+		claims, err := auth.ParseClaims(token)
+		if err != nil {
+			t.Fatalf("could not parse claims: %s", err)
+		}
+
+		if !claims.HasRole(auth.ROLE_ADMIN) {
+			t.Errorf("expected role %s, got %v", auth.ROLE_ADMIN, claims.Roles)
+		}
+	})
+
+	t.Run("generate member token", func(t *testing.T) {
+		t.Parallel()
+
+		signed, err := auth.GenerateMemberToken(1, 42, 7, auth.MEMBER_ACCOUNTANT, "secret")
+		if err != nil {
+			t.Fatalf("could not generate token: %s", err)
+		}
+
+		token, err := jwt.ParseWithClaims(signed, new(auth.Claims), func(t *jwt.Token) (any, error) {
+			return []byte("secret"), nil
+		})
+		if err != nil {
+			t.Fatalf("could not parse token: %s", err)
+		}
+
+		companyId, err := auth.ParseToken(token)
+		if err != nil {
+			t.Fatalf("could not parse company: %s", err)
+		}
+
+		claims, _ := auth.ParseClaims(token)
+		if companyId != 1 || claims.UserId != 7 || claims.Member != auth.MEMBER_ACCOUNTANT || len(claims.Roles) != 0 {
+			t.Errorf("expected accountant 7 of company 1, got %+v", claims)
+		}
+	})
+
+	t.Run("HasRole", func(t *testing.T) {
+		t.Run("company without role", func(t *testing.T) {
+			claims := &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}}
+
+			if claims.HasRole(auth.ROLE_ADMIN) {
+				t.Error("should not hold admin role")
+			}
+		})
+
+		t.Run("system token", func(t *testing.T) {
+			claims := &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Audience: jwt.ClaimStrings{"cronjob"}}}
+
+			if !claims.HasRole(auth.ROLE_ADMIN) {
+				t.Error("expected system token to hold every role")
+			}
+		})
+	})
+
+	t.Run("HasScope", func(t *testing.T) {
+		t.Run("session token", func(t *testing.T) {
+			claims := &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}}
+
+			if !claims.HasScope(auth.SCOPE_MARKET_TRADE) {
+				t.Error("expected session token to hold every scope")
+			}
+		})
+
+		t.Run("member token", func(t *testing.T) {
+			trader := &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}, UserId: 7, Member: auth.MEMBER_TRADER}
+
+			if !trader.HasScope(auth.SCOPE_MARKET_TRADE) {
+				t.Errorf("expected scope %s", auth.SCOPE_MARKET_TRADE)
+			}
+
+			if trader.HasScope(auth.SCOPE_FINANCE_WRITE) {
+				t.Errorf("should not hold scope %s", auth.SCOPE_FINANCE_WRITE)
+			}
+
+			owner := &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}, UserId: 7, Member: auth.MEMBER_OWNER}
+
+			if !owner.HasScope(auth.SCOPE_MEMBERS_MANAGE) {
+				t.Error("expected owners to hold every scope")
+			}
+		})
+
+		t.Run("API key", func(t *testing.T) {
+			claims := &auth.Claims{ApiKeyId: 1, Scopes: []string{auth.SCOPE_MARKET_READ}}
+
+			if !claims.HasScope(auth.SCOPE_MARKET_READ) {
+				t.Errorf("expected scope %s", auth.SCOPE_MARKET_READ)
+			}
+
+			if claims.HasScope(auth.SCOPE_MARKET_TRADE) {
+				t.Errorf("should not hold scope %s", auth.SCOPE_MARKET_TRADE)
+			}
+		})
+	})
+
+	t.Run("generate refresh token", func(t *testing.T) {
+		t.Parallel()
+
+		token, hash, err := auth.GenerateSecret()
+		if err != nil {
+			t.Fatalf("could not generate refresh token: %s", err)
+		}
+
+		if token == hash {
+			t.Error("should not store the token itself")
+		}
+
+		if auth.HashSecret(token) != hash {
+			t.Errorf("expected hash %s, got %s", hash, auth.HashSecret(token))
+		}
+
+		other, _, _ := auth.GenerateSecret()
+		if other == token {
+			t.Error("expected tokens to be random")
+		}
+	})
+
 	t.Run("ParseToken", func(t *testing.T) {
 		t.Run("company token", func(t *testing.T) {
 			token := jwt.NewWithClaims(jwt.SigningMethodES256, &jwt.RegisteredClaims{
@@ -91,6 +231,22 @@ func TestAuth(t *testing.T) {
 			}
 		})
 
+		t.Run("token without session", func(t *testing.T) {
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, &jwt.RegisteredClaims{
+				Subject:  "1535",
+				Audience: jwt.ClaimStrings{"entrepreneur-client"},
+			})
+
+			sessionId, err := auth.ParseSessionId(token)
+			if err != nil {
+				t.Fatalf("could not parse session: %s", err)
+			}
+
+			if sessionId != 0 {
+				t.Errorf("expected no session, got %d", sessionId)
+			}
+		})
+
 		t.Run("invalid cron token", func(t *testing.T) {
 			token := jwt.NewWithClaims(jwt.SigningMethodES256, &jwt.RegisteredClaims{
 				Subject:  "120",
@@ -45,3 +45,17 @@ func (r *fakeRepository) GetById(ctx context.Context, id uint64) (*Building, err
 	}
 	return building, nil
 }
+
+func (r *fakeRepository) SaveBuilding(ctx context.Context, building *Building) (*Building, error) {
+	building.Id = uint64(len(r.data) + 1)
+	r.data[building.Id] = building
+	return building, nil
+}
+
+func (r *fakeRepository) UpdateBuilding(ctx context.Context, building *Building) (*Building, error) {
+	if _, ok := r.data[building.Id]; !ok {
+		return nil, errors.New("building not found")
+	}
+	r.data[building.Id] = building
+	return building, nil
+}
@@ -15,6 +15,12 @@ type (
 
 		// Get building by ID
 		GetById(ctx context.Context, id uint64) (*Building, error)
+
+		// Creates a building with what it requires and produces
+		SaveBuilding(ctx context.Context, building *Building) (*Building, error)
+
+		// Updates a building, replacing what it requires and produces
+		UpdateBuilding(ctx context.Context, building *Building) (*Building, error)
 	}
 
 	goquRepository struct {
@@ -31,7 +37,7 @@ func NewRepository(conn *database.Connection, resources resource.Repository) Rep
 func (r *goquRepository) GetAll(ctx context.Context) ([]*Building, error) {
 	buildings := make([]*Building, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("id"),
 			goqu.I("name"),
@@ -69,7 +75,7 @@ func (r *goquRepository) GetAll(ctx context.Context) ([]*Building, error) {
 func (r *goquRepository) GetById(ctx context.Context, id uint64) (*Building, error) {
 	building := new(Building)
 
-	found, err := r.builder.
+	found, err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("id"),
 			goqu.I("name"),
@@ -109,7 +115,7 @@ func (r *goquRepository) GetById(ctx context.Context, id uint64) (*Building, err
 func (r *goquRepository) GetResources(ctx context.Context, buildingId uint64) ([]*BuildingResource, error) {
 	resources := make([]*BuildingResource, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("br.qty_per_hour"),
 			goqu.I("r.id").As(goqu.C("resource.id")),
@@ -140,7 +146,7 @@ func (r *goquRepository) GetResources(ctx context.Context, buildingId uint64) ([
 func (r *goquRepository) GetRequirements(ctx context.Context, buildingId uint64) ([]*resource.Item, error) {
 	requirements := make([]*resource.Item, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("req.qty").As("quantity"),
 			goqu.I("r.id").As(goqu.C("resource.id")),
@@ -157,3 +163,117 @@ func (r *goquRepository) GetRequirements(ctx context.Context, buildingId uint64)
 
 	return requirements, err
 }
+
+func (r *goquRepository) SaveBuilding(ctx context.Context, building *Building) (*Building, error) {
+	tx, err := database.BeginTx(ctx, r.builder)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	id, err := database.InsertId(ctx, tx.
+		Insert(goqu.T("buildings")).
+		Rows(r.getRecord(building)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.saveComposition(ctx, tx.DB, uint64(id), building); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetById(ctx, uint64(id))
+}
+
+func (r *goquRepository) UpdateBuilding(ctx context.Context, building *Building) (*Building, error) {
+	tx, err := database.BeginTx(ctx, r.builder)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.
+		Update(goqu.T("buildings")).
+		Set(r.getRecord(building)).
+		Where(goqu.I("id").Eq(building.Id)).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.saveComposition(ctx, tx.DB, building.Id, building); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetById(ctx, building.Id)
+}
+
+func (r *goquRepository) getRecord(building *Building) goqu.Record {
+	return goqu.Record{
+		"name":                 building.Name,
+		"downtime":             building.Downtime,
+		"wages_per_hour":       building.WagesHour,
+		"admin_per_hour":       building.AdminHour,
+		"maintenance_per_hour": building.MaintenanceHour,
+	}
+}
+
+// Replaces the resources required to construct the building and the ones
+// it produces
+func (r *goquRepository) saveComposition(ctx context.Context, tx *database.DB, id uint64, building *Building) error {
+	for _, table := range []string{"buildings_requirements", "buildings_resources"} {
+		_, err := tx.Delete(goqu.T(table)).
+			Where(goqu.I("building_id").Eq(id)).
+			Executor().
+			ExecContext(ctx)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(building.Requirements) > 0 {
+		rows := []goqu.Record{}
+		for _, requirement := range building.Requirements {
+			rows = append(rows, goqu.Record{
+				"building_id": id,
+				"resource_id": requirement.ResourceId,
+				"qty":         requirement.Qty,
+			})
+		}
+
+		if _, err := tx.Insert(goqu.T("buildings_requirements")).Rows(rows).Executor().ExecContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	if len(building.Resources) > 0 {
+		rows := []goqu.Record{}
+		for _, resource := range building.Resources {
+			rows = append(rows, goqu.Record{
+				"building_id":  id,
+				"resource_id":  resource.Resource.Id,
+				"qty_per_hour": resource.QtyPerHours,
+			})
+		}
+
+		if _, err := tx.Insert(goqu.T("buildings_resources")).Rows(rows).Executor().ExecContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
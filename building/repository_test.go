@@ -12,7 +12,7 @@ import (
 )
 
 func TestMain(t *testing.M) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		log.Fatalf("could not connect to database: %s", err)
 	}
@@ -24,14 +24,14 @@ func TestMain(t *testing.M) {
 
 	defer tx.Rollback()
 
-	tx.Exec(`INSERT INTO categories (id, name) VALUES (1, "Construction"), (2, "Food")`)
+	tx.Exec(`INSERT INTO categories (id, name) VALUES (1, 'Construction'), (2, 'Food')`)
 
 	tx.Exec(`
         INSERT INTO resources (id, name, category_id)
-        VALUES (1, "Metal", 1), (2, "Concrete", 1), (3, "Glass", 1), (4, "Seeds", 2)
+        VALUES (1, 'Metal', 1), (2, 'Concrete', 1), (3, 'Glass', 1), (4, 'Seeds', 2)
     `)
 
-	tx.Exec(`INSERT INTO buildings (id, name, downtime) VALUES (1, "Plantation", 60), (2, "Factory", 120)`)
+	tx.Exec(`INSERT INTO buildings (id, name, downtime) VALUES (1, 'Plantation', 60), (2, 'Factory', 120)`)
 
 	tx.Exec(`
         INSERT INTO buildings_requirements (building_id, resource_id, qty)
@@ -68,7 +68,7 @@ func TestMain(t *testing.M) {
 }
 
 func TestBuildingRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		log.Fatalf("could not connect to database: %s", err)
 	}
@@ -165,4 +165,48 @@ func TestBuildingRepository(t *testing.T) {
 			t.Errorf("expected %d resources, got %d", 1, len(building.Resources))
 		}
 	})
+
+	t.Run("should save with requirements and resources", func(t *testing.T) {
+		downtime := uint16(30)
+		saved, err := repository.SaveBuilding(ctx, &building.Building{
+			Name:      "Mine",
+			WagesHour: 1000,
+			Downtime:  &downtime,
+			Requirements: []*resource.Item{
+				{ResourceId: 1, Qty: 200},
+			},
+			Resources: []*building.BuildingResource{
+				{Resource: &resource.Resource{Id: 1}, QtyPerHours: 50},
+			},
+		})
+
+		if err != nil {
+			t.Fatalf("could not save building: %s", err)
+		}
+
+		if saved.Id == 0 || saved.WagesHour != 1000 {
+			t.Errorf("expected saved building, got %+v", saved)
+		}
+
+		if len(saved.Requirements) != 1 || len(saved.Resources) != 1 {
+			t.Fatalf("expected 1 requirement and resource, got %d and %d", len(saved.Requirements), len(saved.Resources))
+		}
+
+		saved.Name = "Deep mine"
+		saved.Requirements = []*resource.Item{{ResourceId: 1, Qty: 300}, {ResourceId: 2, Qty: 100}}
+		saved.Resources = nil
+
+		updated, err := repository.UpdateBuilding(ctx, saved)
+		if err != nil {
+			t.Fatalf("could not update building: %s", err)
+		}
+
+		if updated.Name != "Deep mine" {
+			t.Errorf("expected name %s, got %s", "Deep mine", updated.Name)
+		}
+
+		if len(updated.Requirements) != 2 || len(updated.Resources) != 0 {
+			t.Errorf("expected requirements and resources replaced, got %d and %d", len(updated.Requirements), len(updated.Resources))
+		}
+	})
 }
@@ -1,6 +1,7 @@
 package building
 
 import (
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -10,7 +11,7 @@ import (
 func CreateEndpoints(e *echo.Echo, service Service) {
 	group := e.Group("/buildings")
 
-	group.GET("", func(c echo.Context) error {
+	list := group.GET("", func(c echo.Context) error {
 		buildings, err := service.GetAll(c.Request().Context())
 		if err != nil {
 			return err
@@ -18,7 +19,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusOK, buildings)
 	})
 
-	group.GET("/:id", func(c echo.Context) error {
+	get := group.GET("/:id", func(c echo.Context) error {
 		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -31,4 +32,6 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 
 		return c.JSON(http.StatusOK, buildings)
 	})
+
+	server.AllowMembers("", list, get)
 }
@@ -10,9 +10,7 @@ import (
 )
 
 func TestBuildingRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
-	svr := server.NewServer()
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
 	svc := building.NewService(building.NewFakeRepository())
 	building.CreateEndpoints(svr, svc)
 
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"api/catalog"
+	"context"
+	"errors"
+	"flag"
+	"io"
+)
+
+const catalogUsage = "usage: catalog export [-format json | yaml]"
+
+// Runs the catalog subcommand, which writes the current categories,
+// resources and buildings in the format seed reads
+func runCatalog(ctx context.Context, catalogSvc catalog.Service, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "export" {
+		return errors.New(catalogUsage)
+	}
+
+	var format string
+
+	flags := flag.NewFlagSet("catalog export", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&format, "format", catalog.YAML, "format to write: json or yaml")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	exported, err := catalogSvc.Export(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := exported.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+	return err
+}
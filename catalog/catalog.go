@@ -0,0 +1,185 @@
+// Package catalog describes the world's categories, resources and buildings
+// in a file, so a playable world can be seeded without inserting rows by
+// hand. Entries reference each other by name, which is also how they're
+// matched against what's already in the database.
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	JSON = "json"
+	YAML = "yaml"
+)
+
+type (
+	Catalog struct {
+		Categories []string    `json:"categories" yaml:"categories"`
+		Resources  []*Resource `json:"resources" yaml:"resources"`
+		Buildings  []*Building `json:"buildings" yaml:"buildings"`
+	}
+
+	Resource struct {
+		Name     string  `json:"name" yaml:"name"`
+		Category string  `json:"category" yaml:"category"`
+		Image    *string `json:"image,omitempty" yaml:"image,omitempty"`
+
+		// What is consumed to produce one unit of the resource
+		Recipe []*Ingredient `json:"recipe,omitempty" yaml:"recipe,omitempty"`
+	}
+
+	Building struct {
+		Name               string  `json:"name" yaml:"name"`
+		WagesPerHour       uint64  `json:"wages_per_hour" yaml:"wages_per_hour"`
+		AdminPerHour       uint64  `json:"admin_per_hour" yaml:"admin_per_hour"`
+		MaintenancePerHour uint64  `json:"maintenance_per_hour" yaml:"maintenance_per_hour"`
+		Downtime           *uint16 `json:"downtime,omitempty" yaml:"downtime,omitempty"`
+
+		// What is consumed to construct the building
+		Requirements []*Ingredient `json:"requirements,omitempty" yaml:"requirements,omitempty"`
+
+		// What the building produces
+		Outputs []*Output `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	}
+
+	Ingredient struct {
+		Resource string `json:"resource" yaml:"resource"`
+		Quantity uint64 `json:"quantity" yaml:"quantity"`
+	}
+
+	Output struct {
+		Resource   string `json:"resource" yaml:"resource"`
+		QtyPerHour uint64 `json:"qty_per_hour" yaml:"qty_per_hour"`
+	}
+)
+
+// Returns the format of a catalog file from its extension
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON, nil
+	case ".yaml", ".yml":
+		return YAML, nil
+	default:
+		return "", fmt.Errorf("unknown catalog format for %s", path)
+	}
+}
+
+// Parses a catalog written in the given format and validates it
+func Parse(data []byte, format string) (*Catalog, error) {
+	catalog := new(Catalog)
+
+	var err error
+	switch format {
+	case JSON:
+		err = json.Unmarshal(data, catalog)
+	case YAML:
+		err = yaml.Unmarshal(data, catalog)
+	default:
+		return nil, fmt.Errorf("unknown catalog format %q", format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse catalog: %w", err)
+	}
+
+	if err := catalog.Validate(); err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Writes the catalog in the given format
+func (c *Catalog) Marshal(format string) ([]byte, error) {
+	switch format {
+	case JSON:
+		return json.MarshalIndent(c, "", "  ")
+	case YAML:
+		return yaml.Marshal(c)
+	default:
+		return nil, fmt.Errorf("unknown catalog format %q", format)
+	}
+}
+
+// Checks that names are unique and that every reference points to an
+// entry of the catalog
+func (c *Catalog) Validate() error {
+	var errs []error
+
+	categories := make(map[string]bool)
+	for _, name := range c.Categories {
+		if name == "" {
+			errs = append(errs, errors.New("category without a name"))
+		}
+		if categories[name] {
+			errs = append(errs, fmt.Errorf("category %q is declared more than once", name))
+		}
+		categories[name] = true
+	}
+
+	resources := make(map[string]bool)
+	for _, resource := range c.Resources {
+		if resource.Name == "" {
+			errs = append(errs, errors.New("resource without a name"))
+		}
+		if resources[resource.Name] {
+			errs = append(errs, fmt.Errorf("resource %q is declared more than once", resource.Name))
+		}
+		resources[resource.Name] = true
+
+		if !categories[resource.Category] {
+			errs = append(errs, fmt.Errorf("resource %q has unknown category %q", resource.Name, resource.Category))
+		}
+	}
+
+	checkIngredients := func(owner string, ingredients []*Ingredient) {
+		for _, ingredient := range ingredients {
+			if !resources[ingredient.Resource] {
+				errs = append(errs, fmt.Errorf("%s requires unknown resource %q", owner, ingredient.Resource))
+			}
+			if ingredient.Quantity == 0 {
+				errs = append(errs, fmt.Errorf("%s requires no %q", owner, ingredient.Resource))
+			}
+		}
+	}
+
+	for _, resource := range c.Resources {
+		checkIngredients(fmt.Sprintf("resource %q", resource.Name), resource.Recipe)
+	}
+
+	buildings := make(map[string]bool)
+	for _, building := range c.Buildings {
+		if building.Name == "" {
+			errs = append(errs, errors.New("building without a name"))
+		}
+		if buildings[building.Name] {
+			errs = append(errs, fmt.Errorf("building %q is declared more than once", building.Name))
+		}
+		buildings[building.Name] = true
+
+		checkIngredients(fmt.Sprintf("building %q", building.Name), building.Requirements)
+
+		for _, output := range building.Outputs {
+			if !resources[output.Resource] {
+				errs = append(errs, fmt.Errorf("building %q produces unknown resource %q", building.Name, output.Resource))
+			}
+			if output.QtyPerHour == 0 {
+				errs = append(errs, fmt.Errorf("building %q produces no %q", building.Name, output.Resource))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid catalog: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
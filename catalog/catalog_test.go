@@ -0,0 +1,103 @@
+package catalog_test
+
+import (
+	"api/catalog"
+	"strings"
+	"testing"
+)
+
+const catalogYaml = `
+categories: [Food, Construction]
+resources:
+  - name: Apple
+    category: Food
+    recipe:
+      - {resource: Water, quantity: 10}
+  - name: Water
+    category: Food
+buildings:
+  - name: Plantation
+    wages_per_hour: 1000
+    downtime: 60
+    requirements:
+      - {resource: Water, quantity: 500}
+    outputs:
+      - {resource: Apple, qty_per_hour: 100}
+`
+
+func TestCatalog(t *testing.T) {
+	t.Run("FormatOf", func(t *testing.T) {
+		for path, expected := range map[string]string{"world.json": catalog.JSON, "world.yaml": catalog.YAML, "world.YML": catalog.YAML} {
+			if format, err := catalog.FormatOf(path); err != nil || format != expected {
+				t.Errorf("expected format %s for %s, got %s (%v)", expected, path, format, err)
+			}
+		}
+
+		if _, err := catalog.FormatOf("world.sql"); err == nil {
+			t.Error("expected error for unknown extension")
+		}
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		t.Run("should parse yaml", func(t *testing.T) {
+			parsed, err := catalog.Parse([]byte(catalogYaml), catalog.YAML)
+			if err != nil {
+				t.Fatalf("could not parse catalog: %s", err)
+			}
+
+			if len(parsed.Categories) != 2 || len(parsed.Resources) != 2 || len(parsed.Buildings) != 1 {
+				t.Fatalf("expected 2 categories, 2 resources and 1 building, got %+v", parsed)
+			}
+
+			plantation := parsed.Buildings[0]
+			if plantation.Downtime == nil || *plantation.Downtime != 60 {
+				t.Errorf("expected downtime 60, got %v", plantation.Downtime)
+			}
+
+			if plantation.Outputs[0].Resource != "Apple" || plantation.Outputs[0].QtyPerHour != 100 {
+				t.Errorf("expected 100 apples per hour, got %+v", plantation.Outputs[0])
+			}
+		})
+
+		t.Run("should round trip through json", func(t *testing.T) {
+			parsed, err := catalog.Parse([]byte(catalogYaml), catalog.YAML)
+			if err != nil {
+				t.Fatalf("could not parse catalog: %s", err)
+			}
+
+			data, err := parsed.Marshal(catalog.JSON)
+			if err != nil {
+				t.Fatalf("could not marshal catalog: %s", err)
+			}
+
+			reparsed, err := catalog.Parse(data, catalog.JSON)
+			if err != nil {
+				t.Fatalf("could not parse marshaled catalog: %s", err)
+			}
+
+			if reparsed.Resources[0].Recipe[0].Quantity != 10 {
+				t.Errorf("expected recipe to survive, got %+v", reparsed.Resources[0])
+			}
+		})
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		cases := map[string]string{
+			"unknown category":    `{"categories": ["Food"], "resources": [{"name": "Metal", "category": "Construction"}]}`,
+			"duplicated resource": `{"categories": ["Food"], "resources": [{"name": "Water", "category": "Food"}, {"name": "Water", "category": "Food"}]}`,
+			"unknown ingredient":  `{"categories": ["Food"], "resources": [{"name": "Apple", "category": "Food", "recipe": [{"resource": "Water", "quantity": 1}]}]}`,
+			"no quantity":         `{"categories": ["Food"], "resources": [{"name": "Water", "category": "Food"}, {"name": "Ice", "category": "Food", "recipe": [{"resource": "Water"}]}]}`,
+			"unknown output":      `{"buildings": [{"name": "Farm", "outputs": [{"resource": "Apple", "qty_per_hour": 1}]}]}`,
+			"duplicated building": `{"buildings": [{"name": "Farm"}, {"name": "Farm"}]}`,
+		}
+
+		for name, data := range cases {
+			t.Run(name, func(t *testing.T) {
+				_, err := catalog.Parse([]byte(data), catalog.JSON)
+				if err == nil || !strings.HasPrefix(err.Error(), "invalid catalog") {
+					t.Errorf("expected invalid catalog, got %v", err)
+				}
+			})
+		}
+	})
+}
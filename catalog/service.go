@@ -0,0 +1,268 @@
+package catalog
+
+import (
+	"api/building"
+	"api/database"
+	"api/resource"
+	"context"
+)
+
+type (
+	Service interface {
+		// Creates what's missing from the catalog and updates the rest, so
+		// importing the same catalog twice changes nothing
+		Import(ctx context.Context, catalog *Catalog) error
+
+		// Returns the current categories, resources and buildings
+		Export(ctx context.Context) (*Catalog, error)
+	}
+
+	service struct {
+		resourceRepo resource.Repository
+		buildingRepo building.Repository
+		uow          database.UnitOfWork
+	}
+)
+
+func NewService(resourceRepo resource.Repository, buildingRepo building.Repository, uow database.UnitOfWork) Service {
+	return &service{resourceRepo, buildingRepo, uow}
+}
+
+func (s *service) Import(ctx context.Context, catalog *Catalog) error {
+	if err := catalog.Validate(); err != nil {
+		return err
+	}
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		categories, err := s.importCategories(ctx, catalog.Categories)
+		if err != nil {
+			return err
+		}
+
+		resources, err := s.importResources(ctx, catalog.Resources, categories)
+		if err != nil {
+			return err
+		}
+
+		return s.importBuildings(ctx, catalog.Buildings, resources)
+	})
+}
+
+// Creates the missing categories, returning the ids of every category by name
+func (s *service) importCategories(ctx context.Context, names []string) (map[string]uint64, error) {
+	existing, err := s.resourceRepo.FetchCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uint64)
+	for _, category := range existing {
+		ids[category.Name] = category.Id
+	}
+
+	for _, name := range names {
+		if _, ok := ids[name]; ok {
+			continue
+		}
+
+		category, err := s.resourceRepo.SaveCategory(ctx, &resource.Category{Name: name})
+		if err != nil {
+			return nil, err
+		}
+
+		ids[name] = category.Id
+	}
+
+	return ids, nil
+}
+
+// Upserts the resources, returning the ids of every resource by name.
+// Recipes can reference resources declared after them, so every resource
+// is created before any recipe is saved.
+func (s *service) importResources(ctx context.Context, entries []*Resource, categories map[string]uint64) (map[string]uint64, error) {
+	existing, err := s.resourceRepo.FetchResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uint64)
+	for _, resource := range existing {
+		ids[resource.Name] = resource.Id
+	}
+
+	for _, entry := range entries {
+		if _, ok := ids[entry.Name]; ok {
+			continue
+		}
+
+		created, err := s.resourceRepo.SaveResource(ctx, &resource.Resource{
+			Name:       entry.Name,
+			Image:      entry.Image,
+			CategoryId: categories[entry.Category],
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		ids[entry.Name] = created.Id
+	}
+
+	for _, entry := range entries {
+		requirements := make([]*resource.Requirement, 0, len(entry.Recipe))
+		for _, ingredient := range entry.Recipe {
+			requirements = append(requirements, &resource.Requirement{
+				ResourceId: ids[ingredient.Resource],
+				Qty:        ingredient.Quantity,
+			})
+		}
+
+		_, err := s.resourceRepo.UpdateResource(ctx, &resource.Resource{
+			Id:           ids[entry.Name],
+			Name:         entry.Name,
+			Image:        entry.Image,
+			CategoryId:   categories[entry.Category],
+			Requirements: requirements,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *service) importBuildings(ctx context.Context, entries []*Building, resources map[string]uint64) error {
+	existing, err := s.buildingRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]uint64)
+	for _, building := range existing {
+		ids[building.Name] = building.Id
+	}
+
+	for _, entry := range entries {
+		toSave := &building.Building{
+			Name:            entry.Name,
+			WagesHour:       entry.WagesPerHour,
+			AdminHour:       entry.AdminPerHour,
+			MaintenanceHour: entry.MaintenancePerHour,
+			Downtime:        entry.Downtime,
+		}
+
+		for _, ingredient := range entry.Requirements {
+			toSave.Requirements = append(toSave.Requirements, &resource.Item{
+				ResourceId: resources[ingredient.Resource],
+				Qty:        ingredient.Quantity,
+			})
+		}
+
+		for _, output := range entry.Outputs {
+			toSave.Resources = append(toSave.Resources, &building.BuildingResource{
+				Resource:    &resource.Resource{Id: resources[output.Resource]},
+				QtyPerHours: output.QtyPerHour,
+			})
+		}
+
+		if id, ok := ids[entry.Name]; ok {
+			toSave.Id = id
+			_, err = s.buildingRepo.UpdateBuilding(ctx, toSave)
+		} else {
+			_, err = s.buildingRepo.SaveBuilding(ctx, toSave)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *service) Export(ctx context.Context) (*Catalog, error) {
+	categories, err := s.resourceRepo.FetchCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := s.resourceRepo.FetchResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buildings, err := s.buildingRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryNames := make(map[uint64]string)
+	catalog := &Catalog{
+		Categories: make([]string, 0, len(categories)),
+		Resources:  make([]*Resource, 0, len(resources)),
+		Buildings:  make([]*Building, 0, len(buildings)),
+	}
+
+	// Categories are matched by name, so duplicated ones are exported once
+	seen := make(map[string]bool)
+	for _, category := range categories {
+		categoryNames[category.Id] = category.Name
+		if !seen[category.Name] {
+			seen[category.Name] = true
+			catalog.Categories = append(catalog.Categories, category.Name)
+		}
+	}
+
+	resourceNames := make(map[uint64]string)
+	for _, resource := range resources {
+		resourceNames[resource.Id] = resource.Name
+	}
+
+	for _, resource := range resources {
+		entry := &Resource{
+			Name:     resource.Name,
+			Category: categoryNames[resource.CategoryId],
+			Image:    resource.Image,
+		}
+
+		for _, requirement := range resource.Requirements {
+			id := requirement.ResourceId
+			if requirement.Resource != nil {
+				id = requirement.Resource.Id
+			}
+
+			entry.Recipe = append(entry.Recipe, &Ingredient{resourceNames[id], requirement.Qty})
+		}
+
+		catalog.Resources = append(catalog.Resources, entry)
+	}
+
+	for _, building := range buildings {
+		entry := &Building{
+			Name:               building.Name,
+			WagesPerHour:       building.WagesHour,
+			AdminPerHour:       building.AdminHour,
+			MaintenancePerHour: building.MaintenanceHour,
+			Downtime:           building.Downtime,
+		}
+
+		for _, requirement := range building.Requirements {
+			id := requirement.ResourceId
+			if requirement.Resource != nil {
+				id = requirement.Resource.Id
+			}
+
+			entry.Requirements = append(entry.Requirements, &Ingredient{resourceNames[id], requirement.Qty})
+		}
+
+		for _, output := range building.Resources {
+			entry.Outputs = append(entry.Outputs, &Output{resourceNames[output.Resource.Id], output.QtyPerHours})
+		}
+
+		catalog.Buildings = append(catalog.Buildings, entry)
+	}
+
+	return catalog, nil
+}
@@ -0,0 +1,95 @@
+package catalog_test
+
+import (
+	"api/building"
+	"api/catalog"
+	"api/database"
+	"api/resource"
+	"context"
+	"testing"
+)
+
+func TestCatalogService(t *testing.T) {
+	ctx := context.Background()
+
+	resourceRepo := resource.NewFakeRepository()
+	buildingRepo := building.NewFakeRepository()
+	service := catalog.NewService(resourceRepo, buildingRepo, database.NewFakeUnitOfWork())
+
+	world, err := catalog.Parse([]byte(catalogYaml), catalog.YAML)
+	if err != nil {
+		t.Fatalf("could not parse catalog: %s", err)
+	}
+
+	t.Run("should import", func(t *testing.T) {
+		if err := service.Import(ctx, world); err != nil {
+			t.Fatalf("could not import catalog: %s", err)
+		}
+
+		categories, _ := resourceRepo.FetchCategories(ctx)
+		if len(categories) != 2 {
+			t.Errorf("expected Construction added to Food, got %d categories", len(categories))
+		}
+
+		resources, _ := resourceRepo.FetchResources(ctx)
+		if len(resources) != 3 {
+			t.Errorf("expected Water and Apple to be reused, got %d resources", len(resources))
+		}
+
+		apple, _ := resourceRepo.GetById(ctx, 3)
+		if len(apple.Requirements) != 1 || apple.Requirements[0].ResourceId != 1 || apple.Requirements[0].Qty != 10 {
+			t.Errorf("expected apple to require 10 water, got %+v", apple.Requirements)
+		}
+
+		buildings, _ := buildingRepo.GetAll(ctx)
+		if len(buildings) != 2 {
+			t.Errorf("expected Plantation to be updated, got %d buildings", len(buildings))
+		}
+
+		plantation, _ := buildingRepo.GetById(ctx, 1)
+		if plantation.WagesHour != 1000 || len(plantation.Resources) != 1 || plantation.Resources[0].Resource.Id != 3 {
+			t.Errorf("expected plantation to produce apples, got %+v", plantation)
+		}
+	})
+
+	t.Run("should be idempotent", func(t *testing.T) {
+		if err := service.Import(ctx, world); err != nil {
+			t.Fatalf("could not import catalog: %s", err)
+		}
+
+		resources, _ := resourceRepo.FetchResources(ctx)
+		buildings, _ := buildingRepo.GetAll(ctx)
+		if len(resources) != 3 || len(buildings) != 2 {
+			t.Errorf("expected nothing new, got %d resources and %d buildings", len(resources), len(buildings))
+		}
+	})
+
+	t.Run("should export what was imported", func(t *testing.T) {
+		exported, err := service.Export(ctx)
+		if err != nil {
+			t.Fatalf("could not export catalog: %s", err)
+		}
+
+		if err := exported.Validate(); err != nil {
+			t.Fatalf("expected a valid catalog, got %s", err)
+		}
+
+		var apple *catalog.Resource
+		for _, resource := range exported.Resources {
+			if resource.Name == "Apple" {
+				apple = resource
+			}
+		}
+
+		if apple == nil || apple.Category != "Food" || len(apple.Recipe) != 1 || apple.Recipe[0].Resource != "Water" {
+			t.Errorf("expected apple made of water, got %+v", apple)
+		}
+	})
+
+	t.Run("should not import invalid catalogs", func(t *testing.T) {
+		invalid := &catalog.Catalog{Resources: []*catalog.Resource{{Name: "Gold", Category: "Metals"}}}
+		if err := service.Import(ctx, invalid); err == nil {
+			t.Error("expected error importing invalid catalog")
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package clock
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type (
+	// Clock is the source of game time. Services and the scheduler should
+	// never call time.Now or time.AfterFunc directly so the world can run
+	// faster than real time and tests can control time.
+	Clock interface {
+		Now() time.Time
+		AfterFunc(duration time.Duration, callback func()) Timer
+		NewTicker(duration time.Duration) Ticker
+	}
+
+	Timer interface {
+		Stop() bool
+	}
+
+	Ticker interface {
+		C() <-chan time.Time
+		Stop()
+	}
+
+	realClock struct{}
+
+	realTicker struct {
+		ticker *time.Ticker
+	}
+
+	// A clock where game time runs speed times faster than real time,
+	// counting from epoch. The same epoch must be used across restarts,
+	// otherwise game time would jump back to the current real time.
+	scaledClock struct {
+		epoch time.Time
+		speed float64
+	}
+
+	scaledTicker struct {
+		once    sync.Once
+		ticker  *time.Ticker
+		channel chan time.Time
+		done    chan bool
+		clock   *scaledClock
+	}
+)
+
+// Creates a clock that follows real time
+func New() Clock {
+	return realClock{}
+}
+
+// Creates a clock where game time runs speed times faster than real time
+func NewScaled(epoch time.Time, speed float64) (Clock, error) {
+	if speed <= 0 {
+		return nil, errors.New("speed must be greater than zero")
+	}
+	return &scaledClock{epoch, speed}, nil
+}
+
+// Creates a clock running at the given speed, counting game time from
+// epoch. At normal speed the real clock is used, otherwise the epoch is
+// required since game time would jump back on every restart without it.
+func FromSpeed(speed float64, epoch time.Time) (Clock, error) {
+	if speed == 1 {
+		return New(), nil
+	}
+
+	if epoch.IsZero() {
+		return nil, errors.New("epoch is required when speed isn't 1")
+	}
+
+	return NewScaled(epoch, speed)
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(duration time.Duration, callback func()) Timer {
+	return time.AfterFunc(duration, callback)
+}
+
+func (realClock) NewTicker(duration time.Duration) Ticker {
+	return &realTicker{time.NewTicker(duration)}
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+func (c *scaledClock) Now() time.Time {
+	elapsed := time.Since(c.epoch)
+	return c.epoch.Add(time.Duration(float64(elapsed) * c.speed))
+}
+
+func (c *scaledClock) AfterFunc(duration time.Duration, callback func()) Timer {
+	return time.AfterFunc(c.real(duration), callback)
+}
+
+func (c *scaledClock) NewTicker(duration time.Duration) Ticker {
+	ticker := &scaledTicker{
+		ticker:  time.NewTicker(c.real(duration)),
+		channel: make(chan time.Time, 1),
+		done:    make(chan bool),
+		clock:   c,
+	}
+
+	go ticker.forward()
+
+	return ticker
+}
+
+// Converts a game time duration into the real time it takes to elapse
+func (c *scaledClock) real(duration time.Duration) time.Duration {
+	return time.Duration(float64(duration) / c.speed)
+}
+
+// Forwards real ticks as game time ticks
+func (t *scaledTicker) forward() {
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-t.ticker.C:
+			select {
+			case t.channel <- t.clock.Now():
+			default:
+			}
+		}
+	}
+}
+
+func (t *scaledTicker) C() <-chan time.Time {
+	return t.channel
+}
+
+func (t *scaledTicker) Stop() {
+	t.once.Do(func() {
+		t.ticker.Stop()
+		close(t.done)
+	})
+}
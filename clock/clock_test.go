@@ -0,0 +1,196 @@
+package clock_test
+
+import (
+	"api/clock"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	t.Run("FromSpeed", func(t *testing.T) {
+		t.Run("real clock at normal speed", func(t *testing.T) {
+			gameClock, err := clock.FromSpeed(1, time.Time{})
+			if err != nil {
+				t.Fatalf("could not create clock: %s", err)
+			}
+
+			if diff := time.Since(gameClock.Now()); diff.Abs() > time.Second {
+				t.Errorf("expected real time, got %s", gameClock.Now())
+			}
+		})
+
+		t.Run("invalid speed", func(t *testing.T) {
+			if _, err := clock.FromSpeed(0, time.Time{}); err == nil {
+				t.Error("expected error with zero speed")
+			}
+		})
+
+		t.Run("should require an epoch", func(t *testing.T) {
+			if _, err := clock.FromSpeed(60, time.Time{}); err == nil {
+				t.Error("expected error without epoch")
+			}
+		})
+
+		t.Run("counts from epoch", func(t *testing.T) {
+			epoch := time.Now().Add(-time.Minute)
+
+			gameClock, err := clock.FromSpeed(60, epoch)
+			if err != nil {
+				t.Fatalf("could not create clock: %s", err)
+			}
+
+			if elapsed := gameClock.Now().Sub(epoch); elapsed < time.Hour {
+				t.Errorf("expected about an hour of game time, got %s", elapsed)
+			}
+		})
+	})
+
+	t.Run("Scaled", func(t *testing.T) {
+		t.Run("should not accept zero speed", func(t *testing.T) {
+			if _, err := clock.NewScaled(time.Now(), 0); err == nil {
+				t.Error("expected error with zero speed")
+			}
+		})
+
+		t.Run("Now", func(t *testing.T) {
+			epoch := time.Now().Add(-time.Minute)
+
+			gameClock, err := clock.NewScaled(epoch, 60)
+			if err != nil {
+				t.Fatalf("could not create clock: %s", err)
+			}
+
+			elapsed := gameClock.Now().Sub(epoch)
+			if elapsed < time.Hour || elapsed > time.Hour+time.Minute {
+				t.Errorf("expected about an hour of game time, got %s", elapsed)
+			}
+		})
+
+		t.Run("AfterFunc", func(t *testing.T) {
+			fired := make(chan bool)
+
+			gameClock, err := clock.NewScaled(time.Now(), 3600)
+			if err != nil {
+				t.Fatalf("could not create clock: %s", err)
+			}
+
+			gameClock.AfterFunc(time.Minute, func() {
+				fired <- true
+			})
+
+			select {
+			case <-fired:
+			case <-time.After(100 * time.Millisecond):
+				t.Error("should fire a game minute in a few real milliseconds")
+			}
+		})
+
+		t.Run("NewTicker", func(t *testing.T) {
+			gameClock, err := clock.NewScaled(time.Now(), 3600)
+			if err != nil {
+				t.Fatalf("could not create clock: %s", err)
+			}
+
+			ticker := gameClock.NewTicker(time.Minute)
+			defer ticker.Stop()
+
+			for i := 0; i < 3; i++ {
+				select {
+				case <-ticker.C():
+				case <-time.After(100 * time.Millisecond):
+					t.Fatalf("expected tick %d", i+1)
+				}
+			}
+		})
+	})
+
+	t.Run("Fake", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		t.Run("Advance", func(t *testing.T) {
+			gameClock := clock.NewFakeClock(now)
+			gameClock.Advance(time.Hour)
+
+			if !gameClock.Now().Equal(now.Add(time.Hour)) {
+				t.Errorf("expected %s, got %s", now.Add(time.Hour), gameClock.Now())
+			}
+		})
+
+		t.Run("AfterFunc", func(t *testing.T) {
+			fired := make(chan bool, 1)
+			gameClock := clock.NewFakeClock(now)
+
+			gameClock.AfterFunc(time.Hour, func() {
+				fired <- true
+			})
+
+			gameClock.Advance(59 * time.Minute)
+
+			select {
+			case <-fired:
+				t.Fatal("should not fire before the hour")
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			gameClock.Advance(time.Minute)
+
+			select {
+			case <-fired:
+			case <-time.After(100 * time.Millisecond):
+				t.Error("should fire after the hour")
+			}
+		})
+
+		t.Run("Stop", func(t *testing.T) {
+			fired := make(chan bool, 1)
+			gameClock := clock.NewFakeClock(now)
+
+			timer := gameClock.AfterFunc(time.Hour, func() {
+				fired <- true
+			})
+
+			if !timer.Stop() {
+				t.Error("should stop pending timer")
+			}
+
+			gameClock.Advance(time.Hour)
+
+			select {
+			case <-fired:
+				t.Error("should not fire stopped timer")
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			if timer.Stop() {
+				t.Error("should not stop timer twice")
+			}
+		})
+
+		t.Run("NewTicker", func(t *testing.T) {
+			gameClock := clock.NewFakeClock(now)
+			ticker := gameClock.NewTicker(time.Hour)
+
+			for i := 1; i <= 3; i++ {
+				gameClock.Advance(time.Hour)
+
+				select {
+				case tick := <-ticker.C():
+					if !tick.Equal(now.Add(time.Duration(i) * time.Hour)) {
+						t.Errorf("expected tick at %s, got %s", now.Add(time.Duration(i)*time.Hour), tick)
+					}
+				default:
+					t.Fatalf("expected tick %d", i)
+				}
+			}
+
+			ticker.Stop()
+			gameClock.Advance(time.Hour)
+
+			select {
+			case <-ticker.C():
+				t.Error("should not tick after stopped")
+			default:
+			}
+		})
+	})
+}
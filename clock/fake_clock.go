@@ -0,0 +1,132 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// FakeClock only moves when told to. Timers and tickers fire, each on
+	// its own goroutine like the real ones, as time is advanced past them.
+	FakeClock struct {
+		mutex   sync.Mutex
+		now     time.Time
+		waiters []*fakeTimer
+	}
+
+	fakeTimer struct {
+		clock    *FakeClock
+		at       time.Time
+		period   time.Duration
+		callback func()
+		channel  chan time.Time
+	}
+
+	fakeTicker struct {
+		*fakeTimer
+	}
+)
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) AfterFunc(duration time.Duration, callback func()) Timer {
+	timer := &fakeTimer{clock: c, callback: callback}
+	c.add(timer, duration)
+	return timer
+}
+
+func (c *FakeClock) NewTicker(duration time.Duration) Ticker {
+	timer := &fakeTimer{clock: c, period: duration, channel: make(chan time.Time, 1)}
+	c.add(timer, duration)
+	return &fakeTicker{timer}
+}
+
+// Moves the clock forward, firing every timer that is due
+func (c *FakeClock) Advance(duration time.Duration) {
+	c.Set(c.Now().Add(duration))
+}
+
+// Moves the clock to the given time, firing every timer that is due
+func (c *FakeClock) Set(now time.Time) {
+	c.mutex.Lock()
+
+	c.now = now
+	due := make([]*fakeTimer, 0)
+	pending := make([]*fakeTimer, 0, len(c.waiters))
+
+	for _, timer := range c.waiters {
+		if timer.at.After(now) {
+			pending = append(pending, timer)
+			continue
+		}
+
+		due = append(due, timer)
+
+		if timer.period > 0 {
+			for !timer.at.After(now) {
+				timer.at = timer.at.Add(timer.period)
+			}
+			pending = append(pending, timer)
+		}
+	}
+
+	c.waiters = pending
+	c.mutex.Unlock()
+
+	for _, timer := range due {
+		if timer.channel != nil {
+			select {
+			case timer.channel <- now:
+			default:
+			}
+		} else {
+			go timer.callback()
+		}
+	}
+}
+
+func (c *FakeClock) add(timer *fakeTimer, duration time.Duration) {
+	c.mutex.Lock()
+	timer.at = c.now.Add(duration)
+	c.waiters = append(c.waiters, timer)
+	c.mutex.Unlock()
+
+	if duration <= 0 {
+		c.Advance(0)
+	}
+}
+
+func (c *FakeClock) remove(timer *fakeTimer) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, waiter := range c.waiters {
+		if waiter == timer {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t)
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.channel
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.remove(t.fakeTimer)
+}
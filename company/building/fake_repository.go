@@ -163,7 +163,7 @@ func (r *fakeBuildingRepository) getRequirements(companyBuilding *CompanyBuildin
 	}
 }
 
-func (r *fakeBuildingRepository) AddBuilding(ctx context.Context, companyId uint64, inventory *warehouse.Inventory, buildingToConstruct *building.Building, position uint8) (*CompanyBuilding, error) {
+func (r *fakeBuildingRepository) AddBuilding(ctx context.Context, companyId uint64, inventory *warehouse.Inventory, buildingToConstruct *building.Building, position uint8, completesAt time.Time) (*CompanyBuilding, error) {
 	r.lastId++
 
 	companyBuilding := &CompanyBuilding{
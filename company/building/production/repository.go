@@ -33,14 +33,14 @@ func NewProductionRepository(conn *database.Connection, accountingRepo accountin
 }
 
 func (r *productionRepository) SaveProduction(ctx context.Context, production *Production, inventory *warehouse.Inventory, companyId uint64) (*Production, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
 
 	defer tx.Rollback()
 
-	dbTx := &database.DB{TxDatabase: tx}
+	dbTx := tx.DB
 	if err := r.warehouseRepo.UpdateInventory(dbTx, inventory); err != nil {
 		return nil, err
 	}
@@ -57,7 +57,7 @@ func (r *productionRepository) SaveProduction(ctx context.Context, production *P
 		return nil, err
 	}
 
-	result, err := tx.
+	id, err := database.InsertId(ctx, tx.
 		Insert(goqu.T("productions")).
 		Rows(goqu.Record{
 			"qty":           production.Qty,
@@ -66,9 +66,8 @@ func (r *productionRepository) SaveProduction(ctx context.Context, production *P
 			"building_id":   production.Building.Id,
 			"resource_id":   production.Resource.Id,
 			"finishes_at":   production.FinishesAt,
-		}).
-		Executor().
-		Exec()
+			"created_at":    production.StartedAt,
+		}))
 
 	if err != nil {
 		return nil, err
@@ -78,11 +77,6 @@ func (r *productionRepository) SaveProduction(ctx context.Context, production *P
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
 	production.Id = uint64(id)
 	production.SourcingCost = production.CalculateSourcingCost()
 
@@ -92,7 +86,7 @@ func (r *productionRepository) SaveProduction(ctx context.Context, production *P
 func (r *productionRepository) GetProduction(ctx context.Context, id, buildingId, companyId uint64) (*Production, error) {
 	production := new(Production)
 
-	found, err := r.builder.
+	found, err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("p.id"),
 			goqu.I("p.quality"),
@@ -139,14 +133,14 @@ func (r *productionRepository) GetProduction(ctx context.Context, id, buildingId
 }
 
 func (r *productionRepository) CancelProduction(ctx context.Context, production *Production, inventory *warehouse.Inventory) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
 
 	defer tx.Rollback()
 
-	err = r.warehouseRepo.UpdateInventory(&database.DB{TxDatabase: tx}, inventory)
+	err = r.warehouseRepo.UpdateInventory(tx.DB, inventory)
 	if err != nil {
 		return err
 	}
@@ -168,14 +162,14 @@ func (r *productionRepository) CancelProduction(ctx context.Context, production
 }
 
 func (r *productionRepository) CollectResource(ctx context.Context, production *Production, inventory *warehouse.Inventory) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
 
 	defer tx.Rollback()
 
-	err = r.warehouseRepo.UpdateInventory(&database.DB{TxDatabase: tx}, inventory)
+	err = r.warehouseRepo.UpdateInventory(tx.DB, inventory)
 	if err != nil {
 		return err
 	}
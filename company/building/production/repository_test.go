@@ -2,6 +2,7 @@ package production_test
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/company"
 	companyBuilding "api/company/building"
 	"api/company/building/production"
@@ -9,6 +10,7 @@ import (
 	"api/resource"
 	"api/warehouse"
 	"context"
+	"fmt"
 	"log"
 	"math"
 	"os"
@@ -17,7 +19,7 @@ import (
 )
 
 func TestMain(t *testing.M) {
-	conn, err := database.GetConnection(database.SQLITE, "../../../test.db")
+	conn, err := database.GetTestConnection("../../../test.db")
 	if err != nil {
 		log.Fatalf("could not connect to database: %s", err)
 	}
@@ -34,34 +36,34 @@ func TestMain(t *testing.M) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password, created_at, blocked_at, deleted_at) VALUES
-        (1, "Coca-Cola", "coke@email.com", "aoeu", "2023-10-22T01:11:53Z", NULL, NULL),
-        (2, "Blocked", "blocked@email.com", "aoeu", "2023-10-22T01:11:53Z", "2023-10-22T01:11:53Z", NULL),
-        (3, "Deleted", "deleted@email.com", "aoeu", "2023-10-22T01:11:53Z", NULL, "2023-10-22T01:11:53Z");
+        (1, 'Coca-Cola', 'coke@email.com', 'aoeu', '2023-10-22T01:11:53Z', NULL, NULL),
+        (2, 'Blocked', 'blocked@email.com', 'aoeu', '2023-10-22T01:11:53Z', '2023-10-22T01:11:53Z', NULL),
+        (3, 'Deleted', 'deleted@email.com', 'aoeu', '2023-10-22T01:11:53Z', NULL, '2023-10-22T01:11:53Z');
     `); err != nil {
 		log.Fatalf("could not seed database: %s", err)
 	}
 
-	if _, err := tx.Exec(`INSERT INTO categories (id, name) VALUES (1, "Construction"), (2, "Food")`); err != nil {
+	if _, err := tx.Exec(`INSERT INTO categories (id, name) VALUES (1, 'Construction'), (2, 'Food')`); err != nil {
 		log.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO resources (id, name, category_id)
-        VALUES (1, "Metal", 1), (2, "Concrete", 1), (3, "Glass", 1), (4, "Seeds", 2)
+        VALUES (1, 'Metal', 1), (2, 'Concrete', 1), (3, 'Glass', 1), (4, 'Seeds', 2)
     `); err != nil {
 		log.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO buildings (id, name, wages_per_hour, admin_per_hour, maintenance_per_hour)
-        VALUES (1, "Plantation", 500, 1000, 200), (2, "Factory", 1500, 5000, 500)
+        VALUES (1, 'Plantation', 500, 1000, 200), (2, 'Factory', 1500, 5000, 500)
     `); err != nil {
 		log.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO companies_buildings (id, name, company_id, building_id, level, demolished_at)
-        VALUES (1, "Plantation", 1, 1, 2, NULL), (2, "Factory", 1, 2, 3, NULL), (3, "Plantation", 1, 1, 1, "2023-10-25 22:36:21")
+        VALUES (1, 'Plantation', 1, 1, 2, NULL), (2, 'Factory', 1, 2, 3, NULL), (3, 'Plantation', 1, 1, 1, '2023-10-25 22:36:21')
     `); err != nil {
 		log.Fatalf("could not seed database: %s", err)
 	}
@@ -84,6 +86,13 @@ func TestMain(t *testing.M) {
 		log.Fatalf("could not seed database: %s", err)
 	}
 
+	if _, err := tx.Exec(`
+        INSERT INTO company_balances (company_id, cash)
+        SELECT company_id, SUM(value) FROM transactions GROUP BY company_id
+    `); err != nil {
+		log.Fatalf("could not seed database: %s", err)
+	}
+
 	if _, err := tx.Exec(`
         INSERT INTO productions (id, resource_id, building_id, qty, quality, finishes_at, created_at, sourcing_cost)
         VALUES (1, 3, 2, 1500, 1, '` + productionEnd + `', '` + productionStart + `', 1352)
@@ -108,7 +117,7 @@ func TestMain(t *testing.M) {
 }
 
 func TestProductionRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../../../test.db")
+	conn, err := database.GetTestConnection("../../../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -127,6 +136,9 @@ func TestProductionRepository(t *testing.T) {
 		if _, err := conn.DB.Exec("DELETE FROM transactions"); err != nil {
 			log.Fatalf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec("DELETE FROM company_balances"); err != nil {
+			log.Fatalf("could not cleanup database: %s", err)
+		}
 		if _, err := conn.DB.Exec("DELETE FROM buildings_requirements"); err != nil {
 			log.Fatalf("could not cleanup database: %s", err)
 		}
@@ -150,11 +162,11 @@ func TestProductionRepository(t *testing.T) {
 		}
 	})
 
-	accountingRepo := accounting.NewRepository(conn)
-	companyRepo := company.NewRepository(conn, accountingRepo)
+	accountingRepo := accounting.NewRepository(conn, clock.New())
+	companyRepo := company.NewRepository(conn, accountingRepo, clock.New())
 	warehouseRepo := warehouse.NewRepository(conn)
 	resourceRepo := resource.NewRepository(conn)
-	buildingRepo := companyBuilding.NewBuildingRepository(conn, resourceRepo, warehouseRepo)
+	buildingRepo := companyBuilding.NewBuildingRepository(conn, resourceRepo, warehouseRepo, clock.New())
 
 	repository := production.NewProductionRepository(conn, accountingRepo, buildingRepo, warehouseRepo)
 
@@ -295,4 +307,61 @@ func TestProductionRepository(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("should not spend the same cash twice", func(t *testing.T) {
+		uow := database.NewUnitOfWork(conn)
+		produced := make(chan error, 2)
+
+		// Cash left is 500000, enough for only one of them
+		for _, buildingId := range []uint64{1, 2} {
+			go func(buildingId uint64) {
+				produced <- uow.Do(ctx, func(ctx context.Context) error {
+					held, err := companyRepo.HoldCash(ctx, 1, 300000)
+					if err != nil || !held {
+						return fmt.Errorf("could not hold cash: %v", err)
+					}
+
+					companyBuilding, err := buildingRepo.GetById(ctx, buildingId, 1)
+					if err != nil {
+						return err
+					}
+
+					inventory, err := warehouseRepo.FetchInventory(ctx, 1)
+					if err != nil {
+						return err
+					}
+
+					_, err = repository.SaveProduction(ctx, &production.Production{
+						Item:           &resource.Item{Qty: 10, Quality: 0, Resource: &resource.Resource{Id: 4, Name: "Test"}},
+						Building:       companyBuilding,
+						ProductionCost: 300000,
+						FinishesAt:     time.Now().Add(time.Hour),
+						StartedAt:      time.Now(),
+					}, inventory, 1)
+
+					return err
+				})
+			}(buildingId)
+		}
+
+		succeeded := 0
+		for i := 0; i < 2; i++ {
+			if err := <-produced; err == nil {
+				succeeded++
+			}
+		}
+
+		if succeeded != 1 {
+			t.Errorf("expected a single production, got %d", succeeded)
+		}
+
+		company, err := companyRepo.GetById(ctx, 1)
+		if err != nil {
+			t.Fatalf("could not get company: %s", err)
+		}
+
+		if company.AvailableCash != 200000 {
+			t.Errorf("expected %d cash, got %d", 200000, company.AvailableCash)
+		}
+	})
 }
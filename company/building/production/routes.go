@@ -5,6 +5,7 @@ import (
 	"api/company"
 	"api/company/building"
 	"api/resource"
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -15,8 +16,9 @@ func CreateEndpoints(e *echo.Echo, service ProductionService, buildingSvc buildi
 	g := building.CreateEndpoints(e, buildingSvc, companySvc)
 
 	group := g.Group("/:building/productions")
+	group.Use(server.RequireOwner(":id"))
 
-	group.POST("", func(c echo.Context) error {
+	start := group.POST("", func(c echo.Context) error {
 		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -36,15 +38,6 @@ func CreateEndpoints(e *echo.Echo, service ProductionService, buildingSvc buildi
 			return err
 		}
 
-		authenticated, err := auth.ParseToken(c.Get("user"))
-		if err != nil {
-			return err
-		}
-
-		if companyId != authenticated {
-			return echo.NewHTTPError(http.StatusUnauthorized)
-		}
-
 		production, err := service.Produce(c.Request().Context(), companyId, buildingId, item)
 		if err != nil {
 			return err
@@ -53,7 +46,7 @@ func CreateEndpoints(e *echo.Echo, service ProductionService, buildingSvc buildi
 		return c.JSON(http.StatusCreated, production)
 	})
 
-	group.DELETE("/:production", func(c echo.Context) error {
+	cancel := group.DELETE("/:production", func(c echo.Context) error {
 		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -69,15 +62,6 @@ func CreateEndpoints(e *echo.Echo, service ProductionService, buildingSvc buildi
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
 
-		authenticated, err := auth.ParseToken(c.Get("user"))
-		if err != nil {
-			return err
-		}
-
-		if companyId != authenticated {
-			return echo.NewHTTPError(http.StatusUnauthorized)
-		}
-
 		err = service.CancelProduction(c.Request().Context(), companyId, buildingId, productionId)
 		if err != nil {
 			return err
@@ -86,7 +70,7 @@ func CreateEndpoints(e *echo.Echo, service ProductionService, buildingSvc buildi
 		return c.JSON(http.StatusNoContent, nil)
 	})
 
-	group.POST("/:production/collect", func(c echo.Context) error {
+	collect := group.POST("/:production/collect", func(c echo.Context) error {
 		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -102,15 +86,6 @@ func CreateEndpoints(e *echo.Echo, service ProductionService, buildingSvc buildi
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
 
-		authenticated, err := auth.ParseToken(c.Get("user"))
-		if err != nil {
-			return err
-		}
-
-		if companyId != authenticated {
-			return echo.NewHTTPError(http.StatusUnauthorized)
-		}
-
 		collected, err := service.CollectResource(
 			c.Request().Context(),
 			productionId,
@@ -124,4 +99,7 @@ func CreateEndpoints(e *echo.Echo, service ProductionService, buildingSvc buildi
 
 		return c.JSON(http.StatusOK, collected)
 	})
+
+	server.AllowApiKeys(auth.SCOPE_PRODUCTION_WRITE, start, cancel, collect)
+	server.AllowMembers(auth.SCOPE_PRODUCTION_WRITE, start, cancel, collect)
 }
@@ -3,12 +3,17 @@ package production_test
 import (
 	"api/auth"
 	"api/building"
+	"api/clock"
 	"api/company"
 	companyBuilding "api/company/building"
 	"api/company/building/production"
+	"api/database"
+	"api/mail"
 	"api/research"
+	"api/scheduler"
 	"api/server"
 	"api/warehouse"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -16,22 +21,20 @@ import (
 )
 
 func TestProductionRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
 	token, err := auth.GenerateToken(1, "secret")
 	if err != nil {
 		t.Fatalf("could not generate jwt token: %s", err)
 	}
 
-	companySvc := company.NewService(company.NewFakeRepository())
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 	buildingSvc := building.NewService(building.NewFakeRepository())
 	warehouseSvc := warehouse.NewService(warehouse.NewFakeRepository())
 
-	researchSvc := research.NewService(research.NewFakeRepository(), companySvc)
-	companyBuildingSvc := companyBuilding.NewBuildingService(companyBuilding.NewFakeBuildingRepository(), warehouseSvc, buildingSvc)
-	svc := production.NewProductionService(production.NewFakeProductionRepository(), companySvc, companyBuildingSvc, warehouseSvc, researchSvc)
+	researchSvc := research.NewService(research.NewFakeRepository(), companySvc, database.NewFakeUnitOfWork(), scheduler.NewScheduler())
+	companyBuildingSvc := companyBuilding.NewBuildingService(companyBuilding.NewFakeBuildingRepository(), companySvc, warehouseSvc, buildingSvc, database.NewFakeUnitOfWork(), clock.New())
+	svc := production.NewProductionService(production.NewFakeProductionRepository(), companySvc, companyBuildingSvc, warehouseSvc, researchSvc, database.NewFakeUnitOfWork(), clock.New())
 
-	svr := server.NewServer()
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
 	production.CreateEndpoints(svr, svc, companyBuildingSvc, companySvc)
 
 	t.Run("should return 422 producing on building that does not exist", func(t *testing.T) {
@@ -154,7 +157,7 @@ func TestProductionRoutes(t *testing.T) {
 			}
 		})
 
-		t.Run("should return 401 when other company", func(t *testing.T) {
+		t.Run("should return 403 when other company", func(t *testing.T) {
 			req := httptest.NewRequest("DELETE", "/companies/2/buildings/2/productions/1", nil)
 			req.Header.Set("Accept", "application/json")
 			req.Header.Set("Content-Type", "application/json")
@@ -163,8 +166,8 @@ func TestProductionRoutes(t *testing.T) {
 			rec := httptest.NewRecorder()
 			svr.ServeHTTP(rec, req)
 
-			if rec.Code != http.StatusUnauthorized {
-				t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rec.Code, rec.Body.String())
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
 			}
 		})
 
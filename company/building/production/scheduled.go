@@ -5,19 +5,34 @@ import (
 	"api/scheduler"
 	"api/warehouse"
 	"context"
+	"fmt"
 	"time"
 )
 
-type ScheduledProductionService struct {
-	timer   *scheduler.Scheduler
-	service ProductionService
-}
+const COLLECT_RESOURCE_JOB = "production.collect"
+
+type (
+	ScheduledProductionService struct {
+		timer   *scheduler.Scheduler
+		service ProductionService
+	}
+
+	collectResourceJob struct {
+		CompanyId    uint64 `json:"company_id"`
+		BuildingId   uint64 `json:"building_id"`
+		ProductionId uint64 `json:"production_id"`
+	}
+)
 
 func NewScheduledProductionService(service ProductionService, timer *scheduler.Scheduler) ProductionService {
-	return &ScheduledProductionService{
+	scheduled := &ScheduledProductionService{
 		timer:   timer,
 		service: service,
 	}
+
+	timer.Register(COLLECT_RESOURCE_JOB, scheduled.collectResource)
+
+	return scheduled
 }
 
 func (s *ScheduledProductionService) Produce(ctx context.Context, companyId, companyBuildingId uint64, item *resource.Item) (*Production, error) {
@@ -26,15 +41,21 @@ func (s *ScheduledProductionService) Produce(ctx context.Context, companyId, com
 		return nil, err
 	}
 
-	duration := startedProduction.FinishesAt.Sub(time.Now())
-	s.timer.Add(startedProduction.Id, duration, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
+	job, err := scheduler.NewJob(
+		productionJobId(startedProduction.Id),
+		COLLECT_RESOURCE_JOB,
+		int64(companyId),
+		collectResourceJob{companyId, companyBuildingId, startedProduction.Id},
+		startedProduction.FinishesAt,
+	)
 
-		_, err := s.service.CollectResource(ctx, companyId, companyBuildingId, startedProduction.Id)
+	if err != nil {
+		return nil, err
+	}
 
-		return err
-	})
+	if err := s.timer.Schedule(ctx, job); err != nil {
+		return nil, err
+	}
 
 	return startedProduction, nil
 }
@@ -45,10 +66,28 @@ func (s *ScheduledProductionService) CancelProduction(ctx context.Context, compa
 		return err
 	}
 
-	s.timer.Remove(productionId)
+	s.timer.Remove(productionJobId(productionId))
 	return nil
 }
 
 func (s *ScheduledProductionService) CollectResource(ctx context.Context, companyId, companyBuildingId, productionId uint64) (*warehouse.StockItem, error) {
 	return s.service.CollectResource(ctx, companyId, companyBuildingId, productionId)
 }
+
+func (s *ScheduledProductionService) collectResource(job *scheduler.Job) error {
+	var payload collectResourceJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := s.service.CollectResource(ctx, payload.CompanyId, payload.BuildingId, payload.ProductionId)
+
+	return err
+}
+
+func productionJobId(productionId uint64) string {
+	return fmt.Sprintf("PRODUCTION_%d", productionId)
+}
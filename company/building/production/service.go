@@ -1,8 +1,10 @@
 package production
 
 import (
+	"api/clock"
 	"api/company"
 	"api/company/building"
+	"api/database"
 	"api/research"
 	"api/resource"
 	"api/server"
@@ -37,6 +39,8 @@ type (
 		buildingSvc  building.BuildingService
 		warehouseSvc warehouse.Service
 		researchSvc  research.Service
+		uow          database.UnitOfWork
+		clock        clock.Clock
 	}
 )
 
@@ -71,11 +75,25 @@ func (p *Production) ProducedUntil(t time.Time) (*warehouse.StockItem, error) {
 	}, nil
 }
 
-func NewProductionService(repository ProductionRepository, companySvc company.Service, buildingSvc building.BuildingService, warehouseSvc warehouse.Service, researchSvc research.Service) ProductionService {
-	return &productionService{repository, companySvc, buildingSvc, warehouseSvc, researchSvc}
+func NewProductionService(repository ProductionRepository, companySvc company.Service, buildingSvc building.BuildingService, warehouseSvc warehouse.Service, researchSvc research.Service, uow database.UnitOfWork, clock clock.Clock) ProductionService {
+	return &productionService{repository, companySvc, buildingSvc, warehouseSvc, researchSvc, uow, clock}
 }
 
+// Checks the building, resources and cash and starts the production in a
+// single transaction, so concurrent requests can't spend them twice
 func (s *productionService) Produce(ctx context.Context, companyId, buildingId uint64, item *resource.Item) (*Production, error) {
+	var production *Production
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		production, err = s.produce(ctx, companyId, buildingId, item)
+		return err
+	})
+
+	return production, err
+}
+
+func (s *productionService) produce(ctx context.Context, companyId, buildingId uint64, item *resource.Item) (*Production, error) {
 	buildingToProduce, err := s.buildingSvc.GetBuilding(ctx, companyId, buildingId)
 	if err != nil {
 		return nil, err
@@ -121,12 +139,12 @@ func (s *productionService) Produce(ctx context.Context, companyId, buildingId u
 		return nil, err
 	}
 
-	company, err := s.companySvc.GetById(ctx, companyId)
+	held, err := s.companySvc.HoldCash(ctx, companyId, int(productionCost))
 	if err != nil {
 		return nil, err
 	}
 
-	if company.AvailableCash < int(productionCost) {
+	if !held {
 		return nil, server.NewBusinessRuleError("not enough cash")
 	}
 
@@ -135,11 +153,12 @@ func (s *productionService) Produce(ctx context.Context, companyId, buildingId u
 		return nil, err
 	}
 
+	now := s.clock.Now()
 	production := &Production{
 		Item:           item,
-		FinishesAt:     time.Now().Add(time.Second * time.Duration(timeToProduce)),
+		FinishesAt:     now.Add(time.Second * time.Duration(timeToProduce)),
 		Building:       buildingToProduce,
-		StartedAt:      time.Now(),
+		StartedAt:      now,
 		ProductionCost: productionCost,
 		ResourcesCost:  inventory.ReduceStock(requirements),
 	}
@@ -148,6 +167,12 @@ func (s *productionService) Produce(ctx context.Context, companyId, buildingId u
 }
 
 func (s *productionService) CancelProduction(ctx context.Context, companyId, buildingId, productionId uint64) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		return s.cancelProduction(ctx, companyId, buildingId, productionId)
+	})
+}
+
+func (s *productionService) cancelProduction(ctx context.Context, companyId, buildingId, productionId uint64) error {
 	companyBuilding, err := s.buildingSvc.GetBuilding(ctx, companyId, buildingId)
 	if err != nil {
 		return err
@@ -170,7 +195,7 @@ func (s *productionService) CancelProduction(ctx context.Context, companyId, bui
 		return server.NewBusinessRuleError("production not found")
 	}
 
-	now := time.Now()
+	now := s.clock.Now()
 	production.CanceledAt = &now
 
 	resourceProduced, err := production.ProducedUntil(now)
@@ -189,6 +214,18 @@ func (s *productionService) CancelProduction(ctx context.Context, companyId, bui
 }
 
 func (s *productionService) CollectResource(ctx context.Context, companyId, buildingId, productionId uint64) (*warehouse.StockItem, error) {
+	var collected *warehouse.StockItem
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		collected, err = s.collectResource(ctx, companyId, buildingId, productionId)
+		return err
+	})
+
+	return collected, err
+}
+
+func (s *productionService) collectResource(ctx context.Context, companyId, buildingId, productionId uint64) (*warehouse.StockItem, error) {
 	companyBuilding, err := s.buildingSvc.GetBuilding(ctx, companyId, buildingId)
 	if err != nil {
 		return nil, err
@@ -211,7 +248,7 @@ func (s *productionService) CollectResource(ctx context.Context, companyId, buil
 		return nil, server.NewBusinessRuleError("production not found")
 	}
 
-	now := time.Now()
+	now := s.clock.Now()
 
 	resourceProduced, err := production.ProducedUntil(now)
 	if err != nil {
@@ -2,26 +2,31 @@ package production_test
 
 import (
 	"api/building"
+	"api/clock"
 	"api/company"
 	companyBuilding "api/company/building"
 	"api/company/building/production"
+	"api/database"
+	"api/mail"
 	"api/research"
 	"api/resource"
+	"api/scheduler"
 	"api/warehouse"
 	"context"
+	"log"
 	"testing"
 )
 
 func TestProductionService(t *testing.T) {
-	companySvc := company.NewService(company.NewFakeRepository())
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 	warehouseSvc := warehouse.NewService(warehouse.NewFakeRepository())
 
 	buildingSvc := building.NewService(building.NewFakeRepository())
-	companyBuildingSvc := companyBuilding.NewBuildingService(companyBuilding.NewFakeBuildingRepository(), warehouseSvc, buildingSvc)
+	companyBuildingSvc := companyBuilding.NewBuildingService(companyBuilding.NewFakeBuildingRepository(), companySvc, warehouseSvc, buildingSvc, database.NewFakeUnitOfWork(), clock.New())
 
 	repository := production.NewFakeProductionRepository()
-	researchSvc := research.NewService(research.NewFakeRepository(), companySvc)
-	service := production.NewProductionService(repository, companySvc, companyBuildingSvc, warehouseSvc, researchSvc)
+	researchSvc := research.NewService(research.NewFakeRepository(), companySvc, database.NewFakeUnitOfWork(), scheduler.NewScheduler())
+	service := production.NewProductionService(repository, companySvc, companyBuildingSvc, warehouseSvc, researchSvc, database.NewFakeUnitOfWork(), clock.New())
 
 	ctx := context.Background()
 
@@ -2,6 +2,7 @@ package building
 
 import (
 	"api/building"
+	"api/clock"
 	"api/database"
 	"api/resource"
 	"api/warehouse"
@@ -16,7 +17,7 @@ type (
 	BuildingRepository interface {
 		GetAll(ctx context.Context, companyId uint64) ([]*CompanyBuilding, error)
 		GetById(ctx context.Context, buildingId, companyId uint64) (*CompanyBuilding, error)
-		AddBuilding(ctx context.Context, companyId uint64, inventory *warehouse.Inventory, building *building.Building, position uint8) (*CompanyBuilding, error)
+		AddBuilding(ctx context.Context, companyId uint64, inventory *warehouse.Inventory, building *building.Building, position uint8, completesAt time.Time) (*CompanyBuilding, error)
 		Demolish(ctx context.Context, companyId, building uint64) error
 		Upgrade(ctx context.Context, inventory *warehouse.Inventory, building *CompanyBuilding) error
 		Update(ctx context.Context, companyId uint64, companyBuilding *CompanyBuilding) error
@@ -26,18 +27,19 @@ type (
 		builder   *goqu.Database
 		resources resource.Repository
 		warehouse warehouse.Repository
+		clock     clock.Clock
 	}
 )
 
-func NewBuildingRepository(conn *database.Connection, resources resource.Repository, warehouse warehouse.Repository) BuildingRepository {
+func NewBuildingRepository(conn *database.Connection, resources resource.Repository, warehouse warehouse.Repository, clock clock.Clock) BuildingRepository {
 	builder := goqu.New(conn.Driver, conn.DB)
-	return &buildingRepository{builder, resources, warehouse}
+	return &buildingRepository{builder, resources, warehouse, clock}
 }
 
 func (r *buildingRepository) GetAll(ctx context.Context, companyId uint64) ([]*CompanyBuilding, error) {
 	buildings := make([]*CompanyBuilding, 0)
 
-	err := r.getSelectDataset().
+	err := r.getSelectDataset(ctx).
 		Where(r.getSelectConditions(companyId)).
 		ScanStructsContext(ctx, &buildings)
 
@@ -66,7 +68,7 @@ func (r *buildingRepository) GetAll(ctx context.Context, companyId uint64) ([]*C
 func (r *buildingRepository) GetById(ctx context.Context, id, companyId uint64) (*CompanyBuilding, error) {
 	companyBuilding := new(CompanyBuilding)
 
-	found, err := r.getSelectDataset().
+	found, err := r.getSelectDataset(ctx).
 		Where(goqu.And(
 			r.getSelectConditions(companyId).Append(
 				goqu.I("cb.id").Eq(id),
@@ -94,36 +96,29 @@ func (r *buildingRepository) GetById(ctx context.Context, id, companyId uint64)
 	return companyBuilding, nil
 }
 
-func (r *buildingRepository) AddBuilding(ctx context.Context, companyId uint64, inventory *warehouse.Inventory, buildingToConstruct *building.Building, position uint8) (*CompanyBuilding, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+func (r *buildingRepository) AddBuilding(ctx context.Context, companyId uint64, inventory *warehouse.Inventory, buildingToConstruct *building.Building, position uint8, completesAt time.Time) (*CompanyBuilding, error) {
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
 
 	defer tx.Rollback()
 
-	err = r.warehouse.UpdateInventory(&database.DB{TxDatabase: tx}, inventory)
+	err = r.warehouse.UpdateInventory(tx.DB, inventory)
 	if err != nil {
 		return nil, err
 	}
 
-	result, err := tx.
+	id, err := database.InsertId(ctx, tx.
 		Insert(goqu.T("companies_buildings")).
 		Rows(goqu.Record{
 			"position":     position,
 			"company_id":   companyId,
 			"building_id":  buildingToConstruct.Id,
 			"name":         buildingToConstruct.Name,
-			"completes_at": time.Now().Add(time.Minute * time.Duration(*buildingToConstruct.Downtime)),
-		}).
-		Executor().
-		Exec()
-
-	if err != nil {
-		return nil, err
-	}
+			"completes_at": completesAt,
+		}))
 
-	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
@@ -136,9 +131,9 @@ func (r *buildingRepository) AddBuilding(ctx context.Context, companyId uint64,
 }
 
 func (r *buildingRepository) Demolish(ctx context.Context, companyId, buildingId uint64) error {
-	_, err := r.builder.
+	_, err := database.Query(ctx, r.builder).
 		Update(goqu.T("companies_buildings")).
-		Set(goqu.Record{"demolished_at": time.Now()}).
+		Set(goqu.Record{"demolished_at": r.clock.Now()}).
 		Where(goqu.And(
 			goqu.I("id").Eq(buildingId),
 			goqu.I("company_id").Eq(companyId),
@@ -150,7 +145,7 @@ func (r *buildingRepository) Demolish(ctx context.Context, companyId, buildingId
 }
 
 func (r *buildingRepository) Update(ctx context.Context, companyId uint64, companyBuilding *CompanyBuilding) error {
-	_, err := r.builder.
+	_, err := database.Query(ctx, r.builder).
 		Update(goqu.T("companies_buildings")).
 		Set(goqu.Record{
 			"name":         companyBuilding.Name,
@@ -169,14 +164,14 @@ func (r *buildingRepository) Update(ctx context.Context, companyId uint64, compa
 }
 
 func (r *buildingRepository) Upgrade(ctx context.Context, inventory *warehouse.Inventory, companyBuilding *CompanyBuilding) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
 
 	defer tx.Rollback()
 
-	err = r.warehouse.UpdateInventory(&database.DB{TxDatabase: tx}, inventory)
+	err = r.warehouse.UpdateInventory(tx.DB, inventory)
 	if err != nil {
 		return err
 	}
@@ -203,7 +198,7 @@ func (r *buildingRepository) Upgrade(ctx context.Context, inventory *warehouse.I
 func (r *buildingRepository) getResources(ctx context.Context, buildingId uint64) ([]*building.BuildingResource, error) {
 	resources := make([]*building.BuildingResource, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.L("? * ?", goqu.I("cb.level"), goqu.I("br.qty_per_hour")).As("qty_per_hour"),
 			goqu.I("r.id").As(goqu.C("resource.id")),
@@ -237,7 +232,7 @@ func (r *buildingRepository) getResources(ctx context.Context, buildingId uint64
 func (r *buildingRepository) getRequirements(ctx context.Context, buildingId uint64) ([]*resource.Item, error) {
 	requirements := make([]*resource.Item, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("r.id").As(goqu.C("resource.id")),
 			goqu.I("r.name").As(goqu.C("resource.name")),
@@ -260,8 +255,8 @@ func (r *buildingRepository) getRequirements(ctx context.Context, buildingId uin
 	return requirements, err
 }
 
-func (r *buildingRepository) getSelectDataset() *goqu.SelectDataset {
-	return r.builder.
+func (r *buildingRepository) getSelectDataset(ctx context.Context) *goqu.SelectDataset {
+	return database.Query(ctx, r.builder).
 		Select(
 			// building generic information
 			goqu.I("cb.id"),
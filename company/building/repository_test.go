@@ -2,6 +2,7 @@ package building_test
 
 import (
 	"api/building"
+	"api/clock"
 	companyBuilding "api/company/building"
 	"api/database"
 	"api/resource"
@@ -15,7 +16,7 @@ import (
 )
 
 func TestMain(t *testing.M) {
-	conn, err := database.GetConnection(database.SQLITE, "../../test.db")
+	conn, err := database.GetTestConnection("../../test.db")
 	if err != nil {
 		log.Fatalf("could not connect to database: %s", err)
 	}
@@ -29,34 +30,34 @@ func TestMain(t *testing.M) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password, created_at, blocked_at, deleted_at) VALUES
-        (1, "Coca-Cola", "coke@email.com", "aoeu", "2023-10-22T01:11:53Z", NULL, NULL),
-        (2, "Blocked", "blocked@email.com", "aoeu", "2023-10-22T01:11:53Z", "2023-10-22T01:11:53Z", NULL),
-        (3, "Deleted", "deleted@email.com", "aoeu", "2023-10-22T01:11:53Z", NULL, "2023-10-22T01:11:53Z")
+        (1, 'Coca-Cola', 'coke@email.com', 'aoeu', '2023-10-22T01:11:53Z', NULL, NULL),
+        (2, 'Blocked', 'blocked@email.com', 'aoeu', '2023-10-22T01:11:53Z', '2023-10-22T01:11:53Z', NULL),
+        (3, 'Deleted', 'deleted@email.com', 'aoeu', '2023-10-22T01:11:53Z', NULL, '2023-10-22T01:11:53Z')
     `); err != nil {
 		log.Fatalf("could not seed database 1: %s", err)
 	}
 
-	if _, err := tx.Exec(`INSERT INTO categories (id, name) VALUES (1, "Construction"), (2, "Food")`); err != nil {
+	if _, err := tx.Exec(`INSERT INTO categories (id, name) VALUES (1, 'Construction'), (2, 'Food')`); err != nil {
 		log.Fatalf("could not seed database 2: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO resources (id, name, category_id)
-        VALUES (1, "Metal", 1), (2, "Concrete", 1), (3, "Glass", 1), (4, "Seeds", 2)
+        VALUES (1, 'Metal', 1), (2, 'Concrete', 1), (3, 'Glass', 1), (4, 'Seeds', 2)
     `); err != nil {
 		log.Fatalf("could not seed database 3: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO buildings (id, name, wages_per_hour, admin_per_hour, maintenance_per_hour, downtime)
-        VALUES (1, "Plantation", 500, 1000, 200, 60), (2, "Factory", 1500, 5000, 500, 120)
+        VALUES (1, 'Plantation', 500, 1000, 200, 60), (2, 'Factory', 1500, 5000, 500, 120)
     `); err != nil {
 		log.Fatalf("could not seed database 4: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO companies_buildings (id, name, company_id, building_id, level, demolished_at)
-        VALUES (1, "Plantation", 1, 1, 2, NULL), (2, "Factory", 1, 2, 3, NULL), (3, "Plantation", 1, 1, 1, "2023-10-25 22:36:21")
+        VALUES (1, 'Plantation', 1, 1, 2, NULL), (2, 'Factory', 1, 2, 3, NULL), (3, 'Plantation', 1, 1, 1, '2023-10-25 22:36:21')
     `); err != nil {
 		log.Fatalf("could not seed database 5: %s", err)
 	}
@@ -99,7 +100,7 @@ func TestMain(t *testing.M) {
 }
 
 func TestBuildingRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../../test.db")
+	conn, err := database.GetTestConnection("../../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -140,7 +141,7 @@ func TestBuildingRepository(t *testing.T) {
 
 	resourceRepo := resource.NewRepository(conn)
 	warehouseRepo := warehouse.NewRepository(conn)
-	repository := companyBuilding.NewBuildingRepository(conn, resourceRepo, warehouseRepo)
+	repository := companyBuilding.NewBuildingRepository(conn, resourceRepo, warehouseRepo, clock.New())
 
 	t.Run("GetAll", func(t *testing.T) {
 		t.Run("should return empty list when no buildings are found", func(t *testing.T) {
@@ -383,7 +384,7 @@ func TestBuildingRepository(t *testing.T) {
 				t.Fatal("could not fetch inventory")
 			}
 
-			buildingConstructed, err := repository.AddBuilding(ctx, 1, inventory, plantation, 1)
+			buildingConstructed, err := repository.AddBuilding(ctx, 1, inventory, plantation, 1, time.Now().Add(time.Duration(downtime)*time.Minute))
 			if err != nil {
 				t.Fatalf("could not insert building: %s", err)
 			}
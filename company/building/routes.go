@@ -3,6 +3,7 @@ package building
 import (
 	"api/auth"
 	"api/company"
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -13,8 +14,9 @@ func CreateEndpoints(e *echo.Echo, service BuildingService, companySvc company.S
 	g := company.CreateEndpoints(e, companySvc)
 
 	group := g.Group("/:id/buildings")
+	owner := server.RequireOwner(":id")
 
-	group.GET("", func(c echo.Context) error {
+	list := group.GET("", func(c echo.Context) error {
 		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -28,7 +30,7 @@ func CreateEndpoints(e *echo.Echo, service BuildingService, companySvc company.S
 		return c.JSON(http.StatusOK, buildings)
 	})
 
-	group.POST("", func(c echo.Context) error {
+	add := group.POST("", func(c echo.Context) error {
 		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -43,24 +45,15 @@ func CreateEndpoints(e *echo.Echo, service BuildingService, companySvc company.S
 			return err
 		}
 
-		authenticated, err := auth.ParseToken(c.Get("user"))
-		if err != nil {
-			return err
-		}
-
-		if companyId != authenticated {
-			return echo.NewHTTPError(http.StatusUnauthorized)
-		}
-
 		companyBuilding, err := service.AddBuilding(c.Request().Context(), companyId, data.BuildingId, data.Position)
 		if err != nil {
 			return err
 		}
 
 		return c.JSON(http.StatusCreated, companyBuilding)
-	})
+	}, owner)
 
-	group.DELETE("/:buildingId", func(c echo.Context) error {
+	demolish := group.DELETE("/:buildingId", func(c echo.Context) error {
 		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -71,24 +64,15 @@ func CreateEndpoints(e *echo.Echo, service BuildingService, companySvc company.S
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
 
-		authenticated, err := auth.ParseToken(c.Get("user"))
-		if err != nil {
-			return err
-		}
-
-		if companyId != authenticated {
-			return echo.NewHTTPError(http.StatusUnauthorized)
-		}
-
 		err = service.Demolish(c.Request().Context(), companyId, buildingId)
 		if err != nil {
 			return err
 		}
 
 		return c.NoContent(http.StatusOK)
-	})
+	}, owner)
 
-	group.POST("/:buildingId/upgrade", func(c echo.Context) error {
+	upgrade := group.POST("/:buildingId/upgrade", func(c echo.Context) error {
 		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -99,22 +83,16 @@ func CreateEndpoints(e *echo.Echo, service BuildingService, companySvc company.S
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
 
-		authenticated, err := auth.ParseToken(c.Get("user"))
-		if err != nil {
-			return err
-		}
-
-		if companyId != authenticated {
-			return echo.NewHTTPError(http.StatusUnauthorized)
-		}
-
 		completesAt, err := service.Upgrade(c.Request().Context(), companyId, buildingId)
 		if err != nil {
 			return err
 		}
 
 		return c.JSON(http.StatusOK, completesAt)
-	})
+	}, owner)
+
+	server.AllowMembers(auth.SCOPE_COMPANY_READ, list)
+	server.AllowMembers(auth.SCOPE_COMPANY_WRITE, add, demolish, upgrade)
 
 	return group
 }
@@ -3,10 +3,14 @@ package building_test
 import (
 	"api/auth"
 	"api/building"
+	"api/clock"
 	"api/company"
 	companyBuilding "api/company/building"
+	"api/database"
+	"api/mail"
 	"api/server"
 	"api/warehouse"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -14,19 +18,17 @@ import (
 )
 
 func TestCompanyBuildingRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
 	token, err := auth.GenerateToken(1, "secret")
 	if err != nil {
 		t.Fatalf("could not generate jwt token: %s", err)
 	}
 
-	companySvc := company.NewService(company.NewFakeRepository())
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 	buildingSvc := building.NewService(building.NewFakeRepository())
 	warehouseSvc := warehouse.NewService(warehouse.NewFakeRepository())
-	svc := companyBuilding.NewBuildingService(companyBuilding.NewFakeBuildingRepository(), warehouseSvc, buildingSvc)
+	svc := companyBuilding.NewBuildingService(companyBuilding.NewFakeBuildingRepository(), companySvc, warehouseSvc, buildingSvc, database.NewFakeUnitOfWork(), clock.New())
 
-	svr := server.NewServer()
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
 	companyBuilding.CreateEndpoints(svr, svc, companySvc)
 
 	t.Run("AddBuilding", func(t *testing.T) {
@@ -63,7 +65,7 @@ func TestCompanyBuildingRoutes(t *testing.T) {
 
 		})
 
-		t.Run("should return unauthorized", func(t *testing.T) {
+		t.Run("should return forbidden", func(t *testing.T) {
 			body := strings.NewReader(`{"building_id":1,"position":1}`)
 
 			req := httptest.NewRequest("POST", "/companies/2/buildings", body)
@@ -74,8 +76,8 @@ func TestCompanyBuildingRoutes(t *testing.T) {
 			rec := httptest.NewRecorder()
 			svr.ServeHTTP(rec, req)
 
-			if rec.Code != http.StatusUnauthorized {
-				t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rec.Code, rec.Body.String())
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
 			}
 		})
 
@@ -100,7 +102,7 @@ func TestCompanyBuildingRoutes(t *testing.T) {
 	})
 
 	t.Run("Demolish", func(t *testing.T) {
-		t.Run("should return unauthorized", func(t *testing.T) {
+		t.Run("should return forbidden", func(t *testing.T) {
 			req := httptest.NewRequest("DELETE", "/companies/2/buildings/2", nil)
 			req.Header.Set("Authorization", "Bearer "+token)
 			req.Header.Set("Accept", "application/json")
@@ -108,8 +110,8 @@ func TestCompanyBuildingRoutes(t *testing.T) {
 			rec := httptest.NewRecorder()
 			svr.ServeHTTP(rec, req)
 
-			if rec.Code != http.StatusUnauthorized {
-				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
 			}
 		})
 
@@ -141,7 +143,7 @@ func TestCompanyBuildingRoutes(t *testing.T) {
 	})
 
 	t.Run("Upgrade", func(t *testing.T) {
-		t.Run("should return unauthorized", func(t *testing.T) {
+		t.Run("should return forbidden", func(t *testing.T) {
 			req := httptest.NewRequest("POST", "/companies/2/buildings/2/upgrade", nil)
 			req.Header.Set("Authorization", "Bearer "+token)
 			req.Header.Set("Accept", "application/json")
@@ -149,8 +151,8 @@ func TestCompanyBuildingRoutes(t *testing.T) {
 			rec := httptest.NewRecorder()
 			svr.ServeHTTP(rec, req)
 
-			if rec.Code != http.StatusUnauthorized {
-				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
 			}
 		})
 
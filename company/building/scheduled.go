@@ -2,20 +2,35 @@ package building
 
 import (
 	"api/scheduler"
+	"api/server"
 	"context"
+	"fmt"
 	"time"
 )
 
-type ScheduledBuildingService struct {
-	timer   *scheduler.Scheduler
-	service BuildingService
-}
+const COMPLETE_CONSTRUCTION_JOB = "building.construction"
+
+type (
+	ScheduledBuildingService struct {
+		timer   *scheduler.Scheduler
+		service BuildingService
+	}
+
+	constructionJob struct {
+		CompanyId  uint64 `json:"company_id"`
+		BuildingId uint64 `json:"building_id"`
+	}
+)
 
 func NewScheduledBuildingService(buildingSvc BuildingService, timer *scheduler.Scheduler) BuildingService {
-	return &ScheduledBuildingService{
+	scheduled := &ScheduledBuildingService{
 		timer:   timer,
 		service: buildingSvc,
 	}
+
+	timer.Register(COMPLETE_CONSTRUCTION_JOB, scheduled.completeConstruction)
+
+	return scheduled
 }
 
 func (s *ScheduledBuildingService) GetBuilding(ctx context.Context, companyId, buildingId uint64) (*CompanyBuilding, error) {
@@ -36,10 +51,9 @@ func (s *ScheduledBuildingService) AddBuilding(ctx context.Context, companyId, b
 		return nil, err
 	}
 
-	duration := companyBuilding.CompletesAt.Sub(time.Now())
-	s.timer.Add(companyBuilding.Id, duration, func() error {
-		return s.completeConstruction(companyId, companyBuilding)
-	})
+	if err := s.scheduleConstruction(ctx, companyId, companyBuilding); err != nil {
+		return nil, err
+	}
 
 	return companyBuilding, nil
 }
@@ -50,7 +64,7 @@ func (s *ScheduledBuildingService) Demolish(ctx context.Context, companyId, buil
 		return err
 	}
 
-	s.timer.Remove(buildingId)
+	s.timer.Remove(constructionJobId(buildingId))
 	return nil
 }
 
@@ -60,18 +74,51 @@ func (s *ScheduledBuildingService) Upgrade(ctx context.Context, companyId, build
 		return nil, err
 	}
 
-	duration := companyBuilding.CompletesAt.Sub(time.Now())
-	s.timer.Add(buildingId, duration, func() error {
-		return s.completeConstruction(companyId, companyBuilding)
-	})
+	if err := s.scheduleConstruction(ctx, companyId, companyBuilding); err != nil {
+		return nil, err
+	}
 
 	return companyBuilding, nil
 }
 
-func (s *ScheduledBuildingService) completeConstruction(companyId uint64, companyBuilding *CompanyBuilding) error {
+func (s *ScheduledBuildingService) scheduleConstruction(ctx context.Context, companyId uint64, companyBuilding *CompanyBuilding) error {
+	job, err := scheduler.NewJob(
+		constructionJobId(companyBuilding.Id),
+		COMPLETE_CONSTRUCTION_JOB,
+		int64(companyId),
+		constructionJob{companyId, companyBuilding.Id},
+		*companyBuilding.CompletesAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return s.timer.Schedule(ctx, job)
+}
+
+func (s *ScheduledBuildingService) completeConstruction(job *scheduler.Job) error {
+	var payload constructionJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
+	companyBuilding, err := s.service.GetBuilding(ctx, payload.CompanyId, payload.BuildingId)
+	if err != nil {
+		return err
+	}
+
+	if companyBuilding == nil {
+		return server.NewBusinessRuleError("building not found")
+	}
+
 	companyBuilding.CompletesAt = nil
-	return s.service.Update(ctx, companyId, companyBuilding)
+	return s.service.Update(ctx, payload.CompanyId, companyBuilding)
+}
+
+func constructionJobId(buildingId uint64) string {
+	return fmt.Sprintf("CONSTRUCTION_%d", buildingId)
 }
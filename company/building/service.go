@@ -2,6 +2,9 @@ package building
 
 import (
 	"api/building"
+	"api/clock"
+	"api/company"
+	"api/database"
 	"api/resource"
 	"api/server"
 	"api/warehouse"
@@ -35,8 +38,11 @@ type (
 
 	buildingService struct {
 		repository   BuildingRepository
+		companySvc   company.Service
 		warehouseSvc warehouse.Service
 		buildingSvc  building.Service
+		uow          database.UnitOfWork
+		clock        clock.Clock
 	}
 )
 
@@ -105,8 +111,8 @@ func (b *CompanyBuilding) GetProductionCost(item *resource.Item) (uint64, error)
 	return uint64(adminCost + wagesCost), nil
 }
 
-func NewBuildingService(repository BuildingRepository, warehouseSvc warehouse.Service, buildingSvc building.Service) BuildingService {
-	return &buildingService{repository, warehouseSvc, buildingSvc}
+func NewBuildingService(repository BuildingRepository, companySvc company.Service, warehouseSvc warehouse.Service, buildingSvc building.Service, uow database.UnitOfWork, clock clock.Clock) BuildingService {
+	return &buildingService{repository, companySvc, warehouseSvc, buildingSvc, uow, clock}
 }
 
 func (s *buildingService) GetBuilding(ctx context.Context, companyId, buildingId uint64) (*CompanyBuilding, error) {
@@ -122,6 +128,18 @@ func (s *buildingService) Update(ctx context.Context, companyId uint64, companyB
 }
 
 func (s *buildingService) AddBuilding(ctx context.Context, companyId, buildingId uint64, position uint8) (*CompanyBuilding, error) {
+	var companyBuilding *CompanyBuilding
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		companyBuilding, err = s.addBuilding(ctx, companyId, buildingId, position)
+		return err
+	})
+
+	return companyBuilding, err
+}
+
+func (s *buildingService) addBuilding(ctx context.Context, companyId, buildingId uint64, position uint8) (*CompanyBuilding, error) {
 	buildingToConstruct, err := s.buildingSvc.GetById(ctx, buildingId)
 	if err != nil {
 		return nil, err
@@ -140,9 +158,27 @@ func (s *buildingService) AddBuilding(ctx context.Context, companyId, buildingId
 		return nil, server.NewBusinessRuleError("not enough resources")
 	}
 
+	terrain, err := s.companySvc.GetTerrain(ctx, companyId, position)
+	if err != nil {
+		return nil, err
+	}
+
+	if terrain == nil {
+		return nil, server.NewBusinessRuleError("terrain not owned")
+	}
+
+	if !terrain.IsEmpty() {
+		return nil, server.NewBusinessRuleError("terrain is occupied")
+	}
+
 	inventory.ReduceStock(buildingToConstruct.Requirements)
 
-	return s.repository.AddBuilding(ctx, companyId, inventory, buildingToConstruct, position)
+	completesAt := s.clock.Now()
+	if buildingToConstruct.Downtime != nil {
+		completesAt = completesAt.Add(time.Minute * time.Duration(*buildingToConstruct.Downtime))
+	}
+
+	return s.repository.AddBuilding(ctx, companyId, inventory, buildingToConstruct, position, completesAt)
 }
 
 func (s *buildingService) Demolish(ctx context.Context, companyId, buildingId uint64) error {
@@ -163,6 +199,18 @@ func (s *buildingService) Demolish(ctx context.Context, companyId, buildingId ui
 }
 
 func (s *buildingService) Upgrade(ctx context.Context, companyId, buildingId uint64) (*CompanyBuilding, error) {
+	var companyBuilding *CompanyBuilding
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		companyBuilding, err = s.upgrade(ctx, companyId, buildingId)
+		return err
+	})
+
+	return companyBuilding, err
+}
+
+func (s *buildingService) upgrade(ctx context.Context, companyId, buildingId uint64) (*CompanyBuilding, error) {
 	buildingToUpgrade, err := s.GetBuilding(ctx, companyId, buildingId)
 	if err != nil {
 		return nil, err
@@ -191,7 +239,7 @@ func (s *buildingService) Upgrade(ctx context.Context, companyId, buildingId uin
 
 	inventory.ReduceStock(buildingToUpgrade.Requirements)
 
-	completesAt := time.Now().Add(time.Minute * time.Duration(*buildingToUpgrade.Downtime))
+	completesAt := s.clock.Now().Add(time.Minute * time.Duration(*buildingToUpgrade.Downtime))
 
 	buildingToUpgrade.Level++
 	buildingToUpgrade.CompletesAt = &completesAt
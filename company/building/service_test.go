@@ -2,10 +2,15 @@ package building_test
 
 import (
 	"api/building"
+	"api/clock"
+	"api/company"
 	companyBuilding "api/company/building"
+	"api/database"
+	"api/mail"
 	"api/resource"
 	"api/warehouse"
 	"context"
+	"log"
 	"math"
 	"testing"
 	"time"
@@ -171,7 +176,8 @@ func TestBuildingService(t *testing.T) {
 	repository := companyBuilding.NewFakeBuildingRepository()
 	warehouseSvc := warehouse.NewService(warehouse.NewFakeRepository())
 	buildingSvc := building.NewService(building.NewFakeRepository())
-	service := companyBuilding.NewBuildingService(repository, warehouseSvc, buildingSvc)
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	service := companyBuilding.NewBuildingService(repository, companySvc, warehouseSvc, buildingSvc, database.NewFakeUnitOfWork(), clock.New())
 
 	ctx := context.Background()
 
@@ -197,6 +203,13 @@ func TestBuildingService(t *testing.T) {
 			}
 		})
 
+		t.Run("should error if terrain is not owned", func(t *testing.T) {
+			_, err := service.AddBuilding(ctx, 1, 1, company.STARTING_TERRAINS+1)
+			if err == nil || err.Error() != "terrain not owned" {
+				t.Errorf("should not build on terrains of others: %v", err)
+			}
+		})
+
 		t.Run("should reduce stocks and set construction downtime", func(t *testing.T) {
 			_, err := service.AddBuilding(ctx, 1, 1, 1)
 			if err != nil {
@@ -0,0 +1,186 @@
+package company
+
+import (
+	"api/auth"
+	"api/mail"
+	"api/server"
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	VERIFICATION_TOKEN_DURATION = 48 * time.Hour
+	RESET_TOKEN_DURATION        = time.Hour
+
+	PURPOSE_VERIFY = "verify"
+	PURPOSE_RESET  = "reset"
+)
+
+type (
+	// EmailToken is the stored side of a token mailed to a company, only its
+	// hash is kept. Tokens can only be used once, for the purpose they were
+	// mailed for.
+	EmailToken struct {
+		Id        uint64     `db:"id" goqu:"skipinsert,skipupdate"`
+		CompanyId uint64     `db:"company_id"`
+		Purpose   string     `db:"purpose"`
+		Hash      string     `db:"token_hash"`
+		ExpiresAt time.Time  `db:"expires_at"`
+		UsedAt    *time.Time `db:"used_at"`
+		CreatedAt time.Time  `db:"created_at"`
+	}
+
+	EmailVerification struct {
+		Token string `json:"token" validate:"required"`
+	}
+
+	ForgotPassword struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	PasswordReset struct {
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required"`
+		Confirm  string `json:"confirm_password" validate:"required,eqfield=Password"`
+	}
+)
+
+var ErrInvalidEmailToken = server.NewBusinessRuleError("invalid or expired token")
+
+// Saves a new token for the company, returning it to be mailed
+func (s *service) issueEmailToken(ctx context.Context, companyId uint64, purpose string, duration time.Duration) (string, error) {
+	token, hash, err := auth.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	err = s.repository.SaveEmailToken(ctx, &EmailToken{
+		CompanyId: companyId,
+		Purpose:   purpose,
+		Hash:      hash,
+		ExpiresAt: now.Add(duration),
+		CreatedAt: now,
+	})
+
+	return token, err
+}
+
+// Uses the token up, returning ErrInvalidEmailToken when it's unknown,
+// expired, already used or was mailed for something else
+func (s *service) useEmailToken(ctx context.Context, purpose, token string) (*EmailToken, error) {
+	stored, err := s.repository.GetEmailToken(ctx, auth.HashSecret(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if stored == nil || stored.Purpose != purpose || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidEmailToken
+	}
+
+	used, err := s.repository.UseEmailToken(ctx, stored.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		return nil, ErrInvalidEmailToken
+	}
+
+	return stored, nil
+}
+
+func (s *service) sendVerification(ctx context.Context, company *Company) error {
+	token, err := s.issueEmailToken(ctx, company.Id, PURPOSE_VERIFY, VERIFICATION_TOKEN_DURATION)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &mail.Message{
+		To:      company.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Welcome to the game, %s!\n\nVerify your email with this token, it expires in %.0f hours:\n\n%s\n",
+			company.Name, VERIFICATION_TOKEN_DURATION.Hours(), token,
+		),
+	})
+}
+
+func (s *service) ResendVerification(ctx context.Context, companyId uint64) error {
+	company, err := s.repository.GetById(ctx, companyId)
+	if err != nil {
+		return err
+	}
+
+	if company == nil {
+		return server.NewBusinessRuleError("company not found")
+	}
+
+	if company.EmailVerifiedAt != nil {
+		return server.NewBusinessRuleError("email is already verified")
+	}
+
+	return s.sendVerification(ctx, company)
+}
+
+func (s *service) VerifyEmail(ctx context.Context, token string) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		stored, err := s.useEmailToken(ctx, PURPOSE_VERIFY, token)
+		if err != nil {
+			return err
+		}
+
+		return s.repository.VerifyEmail(ctx, stored.CompanyId)
+	})
+}
+
+// Mails a reset token to the company registered with the email. Nothing
+// tells whether there is one, so emails can't be probed for.
+func (s *service) ForgotPassword(ctx context.Context, email string) error {
+	company, err := s.repository.GetByEmail(ctx, email)
+	if err != nil || company == nil {
+		return err
+	}
+
+	token, err := s.issueEmailToken(ctx, company.Id, PURPOSE_RESET, RESET_TOKEN_DURATION)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &mail.Message{
+		To:      company.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Reset the password of %s with this token, it expires in %.0f minutes:\n\n%s\n\nIf you didn't ask to reset it, ignore this email.\n",
+			company.Name, RESET_TOKEN_DURATION.Minutes(), token,
+		),
+	})
+}
+
+// Sets the new password and revokes every session, in case the password
+// is reset because someone else got hold of it. Receiving the token proves
+// the email is the company's, so it's verified too.
+func (s *service) ResetPassword(ctx context.Context, reset *PasswordReset) error {
+	hashedPassword, err := auth.HashPassword(reset.Password)
+	if err != nil {
+		return err
+	}
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		stored, err := s.useEmailToken(ctx, PURPOSE_RESET, reset.Token)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repository.UpdatePassword(ctx, stored.CompanyId, hashedPassword); err != nil {
+			return err
+		}
+
+		if err := s.repository.RevokeSessions(ctx, stored.CompanyId); err != nil {
+			return err
+		}
+
+		return s.repository.VerifyEmail(ctx, stored.CompanyId)
+	})
+}
@@ -1,9 +1,29 @@
 package company
 
-import "context"
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
 
 type fakeRepository struct {
-	data map[uint64]*Company
+	data        map[uint64]*Company
+	sessions    map[uint64]*fakeSession
+	tokens      map[string]*RefreshToken
+	emailTokens map[string]*EmailToken
+	attempts    []LoginAttempt
+	terrains    []*Terrain
+	users       []*User
+	members     []*Member
+	invitations []*Invitation
+	audit       []*AuditEntry
+}
+
+type fakeSession struct {
+	companyId uint64
+	userId    *uint64
+	revokedAt *time.Time
 }
 
 func NewFakeRepository() Repository {
@@ -12,18 +32,30 @@ func NewFakeRepository() Repository {
 		2: {Id: 2, Name: "Test 2", Email: "admin@test2.com", Pass: "$2a$10$OBo6gtRDtR2g8X6S9Qn/Z.1r33jf6QYRSxavEIjG8UfrJ8MLQWRzy", AvailableCash: 255720, AvailableTerrains: 3},
 		3: {Id: 3, Name: "Test 3", Email: "admin@test3.com", Pass: "$2a$10$OBo6gtRDtR2g8X6S9Qn/Z.1r33jf6QYRSxavEIjG8UfrJ8MLQWRzy", AvailableCash: 125572000, AvailableTerrains: 3, Admin: true},
 	}
-	return &fakeRepository{data}
+	terrains := make([]*Terrain, 0)
+	for companyId := uint64(1); companyId <= uint64(len(data)); companyId++ {
+		for position := uint8(1); position <= STARTING_TERRAINS; position++ {
+			terrains = append(terrains, &Terrain{Id: uint64(len(terrains) + 1), CompanyId: companyId, Position: position})
+		}
+	}
+	return &fakeRepository{data, make(map[uint64]*fakeSession), make(map[string]*RefreshToken), make(map[string]*EmailToken), nil, terrains, nil, nil, nil, nil}
 }
 
 func (r *fakeRepository) Register(ctx context.Context, registration *Registration) (*Company, error) {
 	id := uint64(len(r.data) + 1)
 	company := &Company{
-		Id:    id,
-		Name:  registration.Name,
-		Email: registration.Email,
-		Pass:  registration.Password,
+		Id:                id,
+		Name:              registration.Name,
+		Email:             registration.Email,
+		Pass:              registration.Password,
+		Admin:             registration.Admin,
+		AvailableTerrains: STARTING_TERRAINS,
 	}
 	r.data[id] = company
+
+	for position := uint8(1); position <= STARTING_TERRAINS; position++ {
+		r.terrains = append(r.terrains, &Terrain{Id: uint64(len(r.terrains) + 1), CompanyId: id, Position: position})
+	}
 	return r.GetById(ctx, id)
 }
 
@@ -31,6 +63,11 @@ func (r *fakeRepository) GetById(ctx context.Context, id uint64) (*Company, erro
 	return r.data[id], nil
 }
 
+func (r *fakeRepository) HoldCash(ctx context.Context, companyId uint64, amount int) (bool, error) {
+	company, ok := r.data[companyId]
+	return ok && company.AvailableCash >= amount, nil
+}
+
 func (r *fakeRepository) GetByEmail(ctx context.Context, email string) (*Company, error) {
 	for _, company := range r.data {
 		if company.Email == email {
@@ -40,7 +77,430 @@ func (r *fakeRepository) GetByEmail(ctx context.Context, email string) (*Company
 	return nil, nil
 }
 
-func (r *fakeRepository) PurchaseTerrain(ctx context.Context, total int, companyId uint64) error {
+func (r *fakeRepository) GetProfile(ctx context.Context, companyId uint64) (*Profile, error) {
+	company, ok := r.data[companyId]
+	if !ok {
+		return nil, nil
+	}
+	return toProfile(company), nil
+}
+
+func (r *fakeRepository) GetProfiles(ctx context.Context, filter DirectoryFilter) ([]*Profile, error) {
+	profiles := make([]*Profile, 0)
+	for id := uint64(1); id <= uint64(len(r.data)); id++ {
+		company := r.data[id]
+		if strings.Contains(strings.ToLower(company.Name), strings.ToLower(filter.Search)) {
+			profiles = append(profiles, toProfile(company))
+		}
+	}
+
+	sort.SliceStable(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+
+	start := min(int(filter.Page*filter.Limit), len(profiles))
+	end := min(start+int(filter.Limit), len(profiles))
+	return profiles[start:end], nil
+}
+
+func (r *fakeRepository) UpdateProfile(ctx context.Context, companyId uint64, update *ProfileUpdate) (bool, error) {
+	company, ok := r.data[companyId]
+	if !ok {
+		return false, nil
+	}
+
+	company.Name = update.Name
+	company.LogoUrl = update.LogoUrl
+	company.Description = update.Description
+	return true, nil
+}
+
+func toProfile(company *Company) *Profile {
+	return &Profile{
+		Id:          company.Id,
+		Name:        company.Name,
+		LogoUrl:     company.LogoUrl,
+		Description: company.Description,
+		FoundedAt:   company.CreatedAt,
+		Terrains:    company.AvailableTerrains,
+	}
+}
+
+func (r *fakeRepository) GetTerrains(ctx context.Context, companyId uint64) ([]*Terrain, error) {
+	terrains := make([]*Terrain, 0)
+	for _, terrain := range r.terrains {
+		if terrain.CompanyId == companyId {
+			terrains = append(terrains, terrain)
+		}
+	}
+
+	sort.Slice(terrains, func(i, j int) bool { return terrains[i].Position < terrains[j].Position })
+	return terrains, nil
+}
+
+func (r *fakeRepository) GetTerrain(ctx context.Context, companyId uint64, position uint8) (*Terrain, error) {
+	for _, terrain := range r.terrains {
+		if terrain.CompanyId == companyId && terrain.Position == position {
+			return terrain, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) PurchaseTerrain(ctx context.Context, companyId uint64, position uint8, total int) error {
+	r.terrains = append(r.terrains, &Terrain{
+		Id:            uint64(len(r.terrains) + 1),
+		CompanyId:     companyId,
+		Position:      position,
+		PurchasePrice: int64(total),
+		PurchasedAt:   time.Now(),
+	})
 	r.data[companyId].AvailableTerrains++
+	r.data[companyId].AvailableCash -= total
+	return nil
+}
+
+func (r *fakeRepository) SellTerrain(ctx context.Context, companyId uint64, position uint8, refund int) (bool, error) {
+	for i, terrain := range r.terrains {
+		if terrain.CompanyId == companyId && terrain.Position == position {
+			r.terrains = append(r.terrains[:i], r.terrains[i+1:]...)
+			r.data[companyId].AvailableTerrains--
+			r.data[companyId].AvailableCash += refund
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) TransferTerrain(ctx context.Context, companyId uint64, position uint8, toCompanyId uint64, toPosition uint8) (bool, error) {
+	terrain, _ := r.GetTerrain(ctx, companyId, position)
+	if terrain == nil {
+		return false, nil
+	}
+
+	terrain.CompanyId = toCompanyId
+	terrain.Position = toPosition
+	r.data[companyId].AvailableTerrains--
+	r.data[toCompanyId].AvailableTerrains++
+	return true, nil
+}
+
+func (r *fakeRepository) UpdatePassword(ctx context.Context, companyId uint64, password string) error {
+	r.data[companyId].Pass = password
+	return nil
+}
+
+func (r *fakeRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	company, err := r.GetByEmail(ctx, email)
+	return company != nil, err
+}
+
+func (r *fakeRepository) VerifyEmail(ctx context.Context, companyId uint64) error {
+	if company, ok := r.data[companyId]; ok && company.EmailVerifiedAt == nil {
+		now := time.Now()
+		company.EmailVerifiedAt = &now
+	}
+	return nil
+}
+
+func (r *fakeRepository) SaveEmailToken(ctx context.Context, token *EmailToken) error {
+	stored := *token
+	stored.Id = uint64(len(r.emailTokens) + 1)
+	r.emailTokens[token.Hash] = &stored
+	return nil
+}
+
+func (r *fakeRepository) GetEmailToken(ctx context.Context, hash string) (*EmailToken, error) {
+	stored, ok := r.emailTokens[hash]
+	if !ok {
+		return nil, nil
+	}
+
+	token := *stored
+	return &token, nil
+}
+
+func (r *fakeRepository) UseEmailToken(ctx context.Context, id uint64) (bool, error) {
+	for _, token := range r.emailTokens {
+		if token.Id == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) SaveLoginAttempt(ctx context.Context, attempt *LoginAttempt) error {
+	saved := *attempt
+	saved.Id = uint64(len(r.attempts) + 1)
+	r.attempts = append(r.attempts, saved)
+	return nil
+}
+
+func (r *fakeRepository) UpdateLastLogin(ctx context.Context, companyId uint64, at time.Time) error {
+	if company, ok := r.data[companyId]; ok {
+		company.LastLogin = &at
+	}
+	return nil
+}
+
+func (r *fakeRepository) GetLoginAttempts(ctx context.Context, companyId uint64, limit uint) ([]*LoginAttempt, error) {
+	attempts := make([]*LoginAttempt, 0)
+	for i := len(r.attempts) - 1; i >= 0 && uint(len(attempts)) < limit; i-- {
+		if attempt := r.attempts[i]; attempt.CompanyId != nil && *attempt.CompanyId == companyId {
+			attempts = append(attempts, &attempt)
+		}
+	}
+	return attempts, nil
+}
+
+func (r *fakeRepository) GetFailedLoginsByEmail(ctx context.Context, email string, since time.Time) ([]time.Time, error) {
+	return r.getFailedLogins(func(attempt LoginAttempt) bool { return attempt.Email == email }, since, true), nil
+}
+
+func (r *fakeRepository) GetFailedLoginsByIP(ctx context.Context, ip string, since time.Time) ([]time.Time, error) {
+	return r.getFailedLogins(func(attempt LoginAttempt) bool { return attempt.IP == ip }, since, false), nil
+}
+
+func (r *fakeRepository) getFailedLogins(matches func(LoginAttempt) bool, since time.Time, untilSuccess bool) []time.Time {
+	failures := make([]time.Time, 0)
+	for i := len(r.attempts) - 1; i >= 0; i-- {
+		attempt := r.attempts[i]
+		if !matches(attempt) || !attempt.CreatedAt.After(since) {
+			continue
+		}
+		if attempt.Success && untilSuccess {
+			break
+		}
+		if !attempt.Success && !attempt.Locked {
+			failures = append(failures, attempt.CreatedAt)
+		}
+	}
+	return failures
+}
+
+func (r *fakeRepository) CreateSession(ctx context.Context, companyId uint64) (uint64, error) {
+	id := uint64(len(r.sessions) + 1)
+	r.sessions[id] = &fakeSession{companyId: companyId}
+	return id, nil
+}
+
+func (r *fakeRepository) RevokeSession(ctx context.Context, sessionId uint64) error {
+	if session, ok := r.sessions[sessionId]; ok && session.revokedAt == nil {
+		now := time.Now()
+		session.revokedAt = &now
+	}
 	return nil
 }
+
+func (r *fakeRepository) RevokeSessions(ctx context.Context, companyId uint64) error {
+	for id, session := range r.sessions {
+		if session.companyId == companyId {
+			r.RevokeSession(ctx, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) IsSessionRevoked(ctx context.Context, sessionId uint64) (bool, error) {
+	session, ok := r.sessions[sessionId]
+	return !ok || session.revokedAt != nil, nil
+}
+
+func (r *fakeRepository) SaveRefreshToken(ctx context.Context, sessionId uint64, hash string, expiresAt time.Time) error {
+	r.tokens[hash] = &RefreshToken{
+		Id:        uint64(len(r.tokens) + 1),
+		SessionId: sessionId,
+		CompanyId: r.sessions[sessionId].companyId,
+		UserId:    r.sessions[sessionId].userId,
+		ExpiresAt: expiresAt,
+	}
+	return nil
+}
+
+func (r *fakeRepository) GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	stored, ok := r.tokens[hash]
+	if !ok {
+		return nil, nil
+	}
+
+	token := *stored
+	token.RevokedAt = r.sessions[token.SessionId].revokedAt
+	return &token, nil
+}
+
+func (r *fakeRepository) UseRefreshToken(ctx context.Context, id uint64) (bool, error) {
+	for _, token := range r.tokens {
+		if token.Id == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) CreateUser(ctx context.Context, user *User) (uint64, error) {
+	created := *user
+	created.Id = uint64(len(r.users) + 1)
+	r.users = append(r.users, &created)
+	return created.Id, nil
+}
+
+func (r *fakeRepository) GetMembers(ctx context.Context, companyId uint64) ([]*Member, error) {
+	members := make([]*Member, 0)
+	for _, member := range r.members {
+		if member.CompanyId == companyId {
+			members = append(members, r.withUser(member))
+		}
+	}
+	return members, nil
+}
+
+func (r *fakeRepository) GetMember(ctx context.Context, companyId, userId uint64) (*Member, error) {
+	for _, member := range r.members {
+		if member.CompanyId == companyId && member.UserId == userId {
+			return r.withUser(member), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) GetMemberships(ctx context.Context, userId uint64) ([]*Member, error) {
+	members := make([]*Member, 0)
+	for _, member := range r.members {
+		if _, ok := r.data[member.CompanyId]; ok && member.UserId == userId {
+			members = append(members, r.withUser(member))
+		}
+	}
+	return members, nil
+}
+
+func (r *fakeRepository) withUser(member *Member) *Member {
+	joined := *member
+	for _, user := range r.users {
+		if user.Id == member.UserId {
+			joined.Name = user.Name
+			joined.Email = user.Email
+		}
+	}
+	return &joined
+}
+
+func (r *fakeRepository) AddMember(ctx context.Context, member *Member) error {
+	added := *member
+	r.members = append(r.members, &added)
+	return nil
+}
+
+func (r *fakeRepository) UpdateMemberRole(ctx context.Context, companyId, userId uint64, role string) (bool, error) {
+	for _, member := range r.members {
+		if member.CompanyId == companyId && member.UserId == userId {
+			member.Role = role
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) RemoveMember(ctx context.Context, companyId, userId uint64) (bool, error) {
+	for i, member := range r.members {
+		if member.CompanyId == companyId && member.UserId == userId {
+			r.members = append(r.members[:i], r.members[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) SaveInvitation(ctx context.Context, invitation *Invitation) (uint64, error) {
+	saved := *invitation
+	saved.Id = uint64(len(r.invitations) + 1)
+	r.invitations = append(r.invitations, &saved)
+	return saved.Id, nil
+}
+
+func (r *fakeRepository) GetInvitation(ctx context.Context, hash string) (*Invitation, error) {
+	for _, invitation := range r.invitations {
+		if invitation.Hash == hash {
+			found := *invitation
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) GetInvitations(ctx context.Context, companyId uint64) ([]*Invitation, error) {
+	invitations := make([]*Invitation, 0)
+	for i := len(r.invitations) - 1; i >= 0; i-- {
+		if invitation := r.invitations[i]; invitation.CompanyId == companyId && invitation.AcceptedAt == nil {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (r *fakeRepository) AcceptInvitation(ctx context.Context, id uint64) (bool, error) {
+	for _, invitation := range r.invitations {
+		if invitation.Id == id && invitation.AcceptedAt == nil {
+			now := time.Now()
+			invitation.AcceptedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) DeleteInvitation(ctx context.Context, companyId, id uint64) (bool, error) {
+	for i, invitation := range r.invitations {
+		if invitation.Id == id && invitation.CompanyId == companyId && invitation.AcceptedAt == nil {
+			r.invitations = append(r.invitations[:i], r.invitations[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) CreateMemberSession(ctx context.Context, companyId, userId uint64) (uint64, error) {
+	id := uint64(len(r.sessions) + 1)
+	r.sessions[id] = &fakeSession{companyId: companyId, userId: &userId}
+	return id, nil
+}
+
+func (r *fakeRepository) RevokeMemberSessions(ctx context.Context, companyId, userId uint64) error {
+	for id, session := range r.sessions {
+		if session.companyId == companyId && session.userId != nil && *session.userId == userId {
+			r.RevokeSession(ctx, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) SaveAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	saved := *entry
+	saved.Id = uint64(len(r.audit) + 1)
+	r.audit = append(r.audit, &saved)
+	return nil
+}
+
+func (r *fakeRepository) GetAuditLog(ctx context.Context, companyId uint64, filter AuditFilter) ([]*AuditEntry, error) {
+	entries := make([]*AuditEntry, 0)
+	for i := len(r.audit) - 1; i >= 0; i-- {
+		entry := r.audit[i]
+		if entry.CompanyId == companyId && (filter.UserId == 0 || (entry.UserId != nil && *entry.UserId == filter.UserId)) {
+			entries = append(entries, entry)
+		}
+	}
+
+	start := min(int(filter.Page*filter.Limit), len(entries))
+	end := min(start+int(filter.Limit), len(entries))
+	return entries[start:end], nil
+}
@@ -0,0 +1,119 @@
+package company
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// Failed logins for an email before they're locked. Successful logins
+	// start the count over.
+	MAX_EMAIL_FAILURES = 5
+
+	// Failed logins from an address before they're locked, whichever
+	// emails they were for. Addresses can be shared, so more are allowed.
+	MAX_IP_FAILURES = 20
+
+	// Logins are locked for LOCKOUT_DURATION once the failures reach the
+	// maximum, twice as long with every failure after that, up to
+	// MAX_LOCKOUT_DURATION. Failures older than LOCKOUT_WINDOW don't count.
+	LOCKOUT_DURATION     = time.Minute
+	MAX_LOCKOUT_DURATION = time.Hour
+	LOCKOUT_WINDOW       = 24 * time.Hour
+
+	// Attempts shown to owners of the company
+	LOGIN_HISTORY_SIZE = 50
+)
+
+type (
+	// LoginAttempt is recorded for every login, whether it succeeded,
+	// failed or was refused because logins were locked
+	LoginAttempt struct {
+		Id        uint64    `db:"id" json:"id" goqu:"skipinsert,skipupdate"`
+		CompanyId *uint64   `db:"company_id" json:"-"`
+		Email     string    `db:"email" json:"email"`
+		IP        string    `db:"ip" json:"ip"`
+		UserAgent string    `db:"user_agent" json:"user_agent"`
+		Success   bool      `db:"success" json:"success"`
+		Locked    bool      `db:"locked" json:"locked"`
+		CreatedAt time.Time `db:"created_at" json:"created_at"`
+	}
+
+	LockedError struct {
+		RetryAfter time.Duration
+	}
+)
+
+func (e LockedError) Error() string {
+	return fmt.Sprintf("too many failed logins, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Returns how long logins stay locked after the failures, newest first,
+// or zero when they aren't
+func lockout(failures []time.Time, limit int, now time.Time) time.Duration {
+	if len(failures) < limit {
+		return 0
+	}
+
+	duration := MAX_LOCKOUT_DURATION
+	if exceeded := len(failures) - limit; exceeded < 6 {
+		duration = min(LOCKOUT_DURATION<<exceeded, MAX_LOCKOUT_DURATION)
+	}
+
+	return max(failures[0].Add(duration).Sub(now), 0)
+}
+
+// Returns how long logins for the email or from the address stay locked
+func (s *service) checkLockout(ctx context.Context, email, ip string) (time.Duration, error) {
+	now := s.clock.Now().UTC()
+	since := now.Add(-LOCKOUT_WINDOW)
+
+	byEmail, err := s.repository.GetFailedLoginsByEmail(ctx, email, since)
+	if err != nil {
+		return 0, err
+	}
+
+	byIP, err := s.repository.GetFailedLoginsByIP(ctx, ip, since)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(lockout(byEmail, MAX_EMAIL_FAILURES, now), lockout(byIP, MAX_IP_FAILURES, now)), nil
+}
+
+func (s *service) recordLogin(ctx context.Context, credentials Credentials, company *Company, success, locked bool) error {
+	attempt := &LoginAttempt{
+		Email:     credentials.Email,
+		IP:        credentials.IP,
+		UserAgent: truncate(credentials.UserAgent, 255),
+		Success:   success,
+		Locked:    locked,
+		CreatedAt: s.clock.Now().UTC(),
+	}
+
+	if company != nil {
+		attempt.CompanyId = &company.Id
+	}
+
+	if err := s.repository.SaveLoginAttempt(ctx, attempt); err != nil {
+		return err
+	}
+
+	if success {
+		return s.repository.UpdateLastLogin(ctx, company.Id, attempt.CreatedAt)
+	}
+
+	return nil
+}
+
+func (s *service) GetLoginAttempts(ctx context.Context, companyId uint64) ([]*LoginAttempt, error) {
+	return s.repository.GetLoginAttempts(ctx, companyId, LOGIN_HISTORY_SIZE)
+}
+
+func truncate(value string, length int) string {
+	if runes := []rune(value); len(runes) > length {
+		return string(runes[:length])
+	}
+	return value
+}
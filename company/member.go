@@ -0,0 +1,475 @@
+package company
+
+import (
+	"api/auth"
+	"api/mail"
+	"api/server"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	INVITATION_DURATION = 7 * 24 * time.Hour
+
+	AUDIT_LOG_PAGE_SIZE     = 50
+	MAX_AUDIT_LOG_PAGE_SIZE = 100
+)
+
+type (
+	// User is a person who logs in to act for the companies they're a
+	// member of, as opposed to logging in as the company itself
+	User struct {
+		Id        uint64    `db:"id" json:"id" goqu:"skipinsert,skipupdate"`
+		Name      string    `db:"name" json:"name"`
+		Email     string    `db:"email" json:"email"`
+		Pass      string    `db:"password" json:"-"`
+		CreatedAt time.Time `db:"created_at" json:"created_at"`
+	}
+
+	// Member is a user holding a role in the company. The role decides
+	// which endpoints the user can call for it, see auth.MEMBER_SCOPES.
+	Member struct {
+		CompanyId uint64    `db:"company_id" json:"company_id"`
+		UserId    uint64    `db:"user_id" json:"user_id"`
+		Name      string    `db:"name" json:"name"`
+		Email     string    `db:"email" json:"email"`
+		Role      string    `db:"role" json:"role"`
+		InvitedBy *uint64   `db:"invited_by" json:"invited_by"`
+		JoinedAt  time.Time `db:"created_at" json:"joined_at"`
+	}
+
+	// Invitation is mailed to join the company with the role. Only the hash
+	// of its token is kept, like email tokens.
+	Invitation struct {
+		Id         uint64     `db:"id" json:"id" goqu:"skipinsert,skipupdate"`
+		CompanyId  uint64     `db:"company_id" json:"-"`
+		Email      string     `db:"email" json:"email"`
+		Role       string     `db:"role" json:"role"`
+		Hash       string     `db:"token_hash" json:"-"`
+		InvitedBy  *uint64    `db:"invited_by" json:"invited_by"`
+		ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+		AcceptedAt *time.Time `db:"accepted_at" json:"accepted_at"`
+		CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	}
+
+	InvitationRequest struct {
+		Email string `json:"email" validate:"required,email,max=255"`
+		Role  string `json:"role" validate:"required,oneof=owner manager trader accountant viewer"`
+	}
+
+	InvitationAcceptance struct {
+		Token string `json:"token" validate:"required"`
+
+		// Only needed when the invitation signs a new user up. Existing
+		// users accept it with their password.
+		Name     string `json:"name" validate:"max=255"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	MemberCredentials struct {
+		Credentials
+
+		// Company to act for, only needed when the user is a member of
+		// several
+		CompanyId uint64 `json:"company_id"`
+	}
+
+	RoleChange struct {
+		Role string `json:"role" validate:"required,oneof=owner manager trader accountant viewer"`
+	}
+
+	// AuditEntry records a change made to the company and who made it: a
+	// member, an API key or, when neither is set, the company itself
+	AuditEntry struct {
+		Id        uint64    `db:"id" json:"id" goqu:"skipinsert,skipupdate"`
+		CompanyId uint64    `db:"company_id" json:"-"`
+		UserId    *uint64   `db:"user_id" json:"user_id"`
+		ApiKeyId  *uint64   `db:"api_key_id" json:"api_key_id"`
+		Role      string    `db:"role" json:"role"`
+		Method    string    `db:"method" json:"method"`
+		Path      string    `db:"path" json:"path"`
+		CreatedAt time.Time `db:"created_at" json:"created_at"`
+	}
+
+	AuditFilter struct {
+		// Only changes made by the member, when set
+		UserId uint64
+
+		// Pages start at 0
+		Page  uint
+		Limit uint
+	}
+)
+
+var ErrInvalidInvitation = server.NewBusinessRuleError("invalid or expired invitation")
+
+// Logs the user in as member of the company, recording the attempt like
+// company logins, which share their lockouts
+func (s *service) LoginMember(ctx context.Context, credentials MemberCredentials) (*Tokens, error) {
+	user, err := s.repository.GetUserByEmail(ctx, credentials.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	retryAfter, err := s.checkLockout(ctx, credentials.Email, credentials.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	if retryAfter > 0 {
+		if err := s.recordLogin(ctx, credentials.Credentials, nil, false, true); err != nil {
+			return nil, err
+		}
+		return nil, LockedError{retryAfter}
+	}
+
+	if user == nil || auth.ComparePassword(user.Pass, credentials.Pass) != nil {
+		if err := s.recordLogin(ctx, credentials.Credentials, nil, false, false); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	member, err := s.getMembership(ctx, user.Id, credentials.CompanyId)
+	if err != nil {
+		return nil, err
+	}
+
+	company, err := s.repository.GetById(ctx, member.CompanyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if company == nil {
+		return nil, server.NewBusinessRuleError("company not found")
+	}
+
+	if err := s.recordLogin(ctx, credentials.Credentials, company, true, false); err != nil {
+		return nil, err
+	}
+
+	return s.startMemberSession(ctx, company, member)
+}
+
+// Returns the membership of the user in the company, which can be left out
+// when the user is a member of a single one
+func (s *service) getMembership(ctx context.Context, userId, companyId uint64) (*Member, error) {
+	memberships, err := s.repository.GetMemberships(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if companyId == 0 {
+		switch len(memberships) {
+		case 0:
+			return nil, server.NewBusinessRuleError("user is not a member of any company")
+		case 1:
+			return memberships[0], nil
+		default:
+			return nil, server.NewBusinessRuleError("company_id is required, the user is a member of several companies")
+		}
+	}
+
+	for _, membership := range memberships {
+		if membership.CompanyId == companyId {
+			return membership, nil
+		}
+	}
+
+	return nil, server.NewBusinessRuleError("user is not a member of the company")
+}
+
+// Mails an invitation to join the company with the role. Members can't be
+// invited again, their role is changed instead.
+func (s *service) InviteMember(ctx context.Context, companyId, inviterId uint64, request *InvitationRequest) (*Invitation, error) {
+	var invitation *Invitation
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		company, err := s.repository.GetById(ctx, companyId)
+		if err != nil {
+			return err
+		}
+
+		if company == nil {
+			return server.NewBusinessRuleError("company not found")
+		}
+
+		user, err := s.repository.GetUserByEmail(ctx, request.Email)
+		if err != nil {
+			return err
+		}
+
+		if user != nil {
+			member, err := s.repository.GetMember(ctx, companyId, user.Id)
+			if err != nil {
+				return err
+			}
+
+			if member != nil {
+				return server.NewBusinessRuleError("user is already a member of the company")
+			}
+		}
+
+		token, hash, err := auth.GenerateSecret()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		invitation = &Invitation{
+			CompanyId: companyId,
+			Email:     request.Email,
+			Role:      request.Role,
+			Hash:      hash,
+			ExpiresAt: now.Add(INVITATION_DURATION),
+			CreatedAt: now,
+		}
+
+		if inviterId != 0 {
+			invitation.InvitedBy = &inviterId
+		}
+
+		invitation.Id, err = s.repository.SaveInvitation(ctx, invitation)
+		if err != nil {
+			return err
+		}
+
+		// The token is only ever mailed, so the invitation is useless when
+		// it can't be
+		return s.mailer.Send(ctx, &mail.Message{
+			To:      request.Email,
+			Subject: "Join " + company.Name,
+			Body: fmt.Sprintf(
+				"You were invited to join %s as %s.\n\nAccept the invitation with this token, it expires in %.0f days:\n\n%s\n",
+				company.Name, request.Role, INVITATION_DURATION.Hours()/24, token,
+			),
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// Returns the invitations of the company that weren't accepted yet
+func (s *service) GetInvitations(ctx context.Context, companyId uint64) ([]*Invitation, error) {
+	return s.repository.GetInvitations(ctx, companyId)
+}
+
+func (s *service) RevokeInvitation(ctx context.Context, companyId, invitationId uint64) error {
+	deleted, err := s.repository.DeleteInvitation(ctx, companyId, invitationId)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return server.NewBusinessRuleError("invitation not found")
+	}
+
+	return nil
+}
+
+// Adds the user the invitation was mailed to as member of the company,
+// signing the user up first when there's no user with the email yet
+func (s *service) AcceptInvitation(ctx context.Context, acceptance *InvitationAcceptance) (*Member, error) {
+	var member *Member
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		invitation, err := s.repository.GetInvitation(ctx, auth.HashSecret(acceptance.Token))
+		if err != nil {
+			return err
+		}
+
+		if invitation == nil || invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+			return ErrInvalidInvitation
+		}
+
+		company, err := s.repository.GetById(ctx, invitation.CompanyId)
+		if err != nil {
+			return err
+		}
+
+		if company == nil {
+			return ErrInvalidInvitation
+		}
+
+		user, err := s.getInvitedUser(ctx, invitation.Email, acceptance)
+		if err != nil {
+			return err
+		}
+
+		existing, err := s.repository.GetMember(ctx, company.Id, user.Id)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			return server.NewBusinessRuleError("user is already a member of the company")
+		}
+
+		accepted, err := s.repository.AcceptInvitation(ctx, invitation.Id)
+		if err != nil {
+			return err
+		}
+
+		if !accepted {
+			return ErrInvalidInvitation
+		}
+
+		err = s.repository.AddMember(ctx, &Member{
+			CompanyId: company.Id,
+			UserId:    user.Id,
+			Role:      invitation.Role,
+			InvitedBy: invitation.InvitedBy,
+			JoinedAt:  time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+
+		member, err = s.repository.GetMember(ctx, company.Id, user.Id)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// Returns the user with the email, checking the password, or signs a new
+// one up with it
+func (s *service) getInvitedUser(ctx context.Context, email string, acceptance *InvitationAcceptance) (*User, error) {
+	user, err := s.repository.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		if err := auth.ComparePassword(user.Pass, acceptance.Password); err != nil {
+			return nil, server.NewBusinessRuleError("password is wrong")
+		}
+		return user, nil
+	}
+
+	name := strings.TrimSpace(acceptance.Name)
+	if name == "" {
+		return nil, server.NewBusinessRuleError("name is required to sign up")
+	}
+
+	hashedPassword, err := auth.HashPassword(acceptance.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user = &User{
+		Name:      name,
+		Email:     email,
+		Pass:      hashedPassword,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	user.Id, err = s.repository.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *service) GetMembers(ctx context.Context, companyId uint64) ([]*Member, error) {
+	return s.repository.GetMembers(ctx, companyId)
+}
+
+// Changes the role of the member and revokes its sessions, so the old role
+// doesn't outlive the change in tokens already issued
+func (s *service) UpdateMemberRole(ctx context.Context, companyId, userId uint64, change *RoleChange) (*Member, error) {
+	var member *Member
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		updated, err := s.repository.UpdateMemberRole(ctx, companyId, userId, change.Role)
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return server.NewBusinessRuleError("member not found")
+		}
+
+		if err := s.repository.RevokeMemberSessions(ctx, companyId, userId); err != nil {
+			return err
+		}
+
+		member, err = s.repository.GetMember(ctx, companyId, userId)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// Removes the member from the company and revokes its sessions
+func (s *service) RemoveMember(ctx context.Context, companyId, userId uint64) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		removed, err := s.repository.RemoveMember(ctx, companyId, userId)
+		if err != nil {
+			return err
+		}
+
+		if !removed {
+			return server.NewBusinessRuleError("member not found")
+		}
+
+		return s.repository.RevokeMemberSessions(ctx, companyId, userId)
+	})
+}
+
+// Records the change made with the claims in the audit log of the company.
+// It's recorded after the change succeeded, so failing to record it is
+// only logged.
+func (s *service) RecordAction(ctx context.Context, claims *auth.Claims, method, path string) {
+	companyId, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || companyId == 0 {
+		return
+	}
+
+	entry := &AuditEntry{
+		CompanyId: companyId,
+		Role:      claims.Member,
+		Method:    method,
+		Path:      truncate(path, 255),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if claims.UserId != 0 {
+		entry.UserId = &claims.UserId
+	}
+
+	if claims.ApiKeyId != 0 {
+		entry.ApiKeyId = &claims.ApiKeyId
+	}
+
+	if err := s.repository.SaveAuditEntry(ctx, entry); err != nil {
+		s.logger.Printf("could not record %s %s for company %d: %s", method, path, companyId, err)
+	}
+}
+
+// Returns the changes made to the company, newest first
+func (s *service) GetAuditLog(ctx context.Context, companyId uint64, filter AuditFilter) ([]*AuditEntry, error) {
+	if filter.Limit == 0 {
+		filter.Limit = AUDIT_LOG_PAGE_SIZE
+	}
+
+	filter.Limit = min(filter.Limit, MAX_AUDIT_LOG_PAGE_SIZE)
+
+	return s.repository.GetAuditLog(ctx, companyId, filter)
+}
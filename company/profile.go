@@ -0,0 +1,75 @@
+package company
+
+import (
+	"api/server"
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	DIRECTORY_PAGE_SIZE     = 50
+	MAX_DIRECTORY_PAGE_SIZE = 100
+)
+
+type (
+	// Profile is what every player can see of a company, unlike the account
+	// only its owner sees
+	Profile struct {
+		Id          uint64    `db:"id" json:"id"`
+		Name        string    `db:"name" json:"name"`
+		LogoUrl     string    `db:"logo_url" json:"logo_url"`
+		Description string    `db:"description" json:"description"`
+		FoundedAt   time.Time `db:"created_at" json:"founded_at"`
+		Terrains    int8      `db:"available_terrains" json:"terrains"`
+		Buildings   int       `db:"buildings" json:"buildings"`
+		OpenOrders  int       `db:"open_orders" json:"open_orders"`
+	}
+
+	ProfileUpdate struct {
+		Name        string `json:"name" validate:"required,max=255"`
+		LogoUrl     string `json:"logo_url" validate:"omitempty,url,max=255"`
+		Description string `json:"description" validate:"max=1000"`
+	}
+
+	DirectoryFilter struct {
+		// Matches anywhere in the name, whatever the case
+		Search string
+
+		// Pages start at 0
+		Page  uint
+		Limit uint
+	}
+)
+
+func (s *service) GetProfile(ctx context.Context, companyId uint64) (*Profile, error) {
+	return s.repository.GetProfile(ctx, companyId)
+}
+
+func (s *service) UpdateProfile(ctx context.Context, companyId uint64, update *ProfileUpdate) (*Profile, error) {
+	update.Name = strings.TrimSpace(update.Name)
+	if update.Name == "" {
+		return nil, server.NewBusinessRuleError("name can't be blank")
+	}
+
+	updated, err := s.repository.UpdateProfile(ctx, companyId, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return nil, server.NewBusinessRuleError("company not found")
+	}
+
+	return s.repository.GetProfile(ctx, companyId)
+}
+
+func (s *service) GetDirectory(ctx context.Context, filter DirectoryFilter) ([]*Profile, error) {
+	if filter.Limit == 0 {
+		filter.Limit = DIRECTORY_PAGE_SIZE
+	}
+	filter.Limit = min(filter.Limit, MAX_DIRECTORY_PAGE_SIZE)
+	filter.Search = strings.TrimSpace(filter.Search)
+
+	return s.repository.GetProfiles(ctx, filter)
+}
@@ -2,8 +2,11 @@ package company
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/database"
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
@@ -13,25 +16,102 @@ type (
 	Repository interface {
 		Register(ctx context.Context, registration *Registration) (*Company, error)
 		GetById(ctx context.Context, id uint64) (*Company, error)
+
+		// Reports whether the company has at least amount in cash, locking
+		// its balance until the unit of work running in ctx ends
+		HoldCash(ctx context.Context, companyId uint64, amount int) (bool, error)
 		GetByEmail(ctx context.Context, email string) (*Company, error)
-		PurchaseTerrain(ctx context.Context, total int, companyId uint64) error
+		GetProfile(ctx context.Context, companyId uint64) (*Profile, error)
+		GetProfiles(ctx context.Context, filter DirectoryFilter) ([]*Profile, error)
+
+		// Returns false when the company doesn't exist
+		UpdateProfile(ctx context.Context, companyId uint64, update *ProfileUpdate) (bool, error)
+		GetTerrains(ctx context.Context, companyId uint64) ([]*Terrain, error)
+		GetTerrain(ctx context.Context, companyId uint64, position uint8) (*Terrain, error)
+		PurchaseTerrain(ctx context.Context, companyId uint64, position uint8, total int) error
+
+		// Return false when the company doesn't own the terrain
+		SellTerrain(ctx context.Context, companyId uint64, position uint8, refund int) (bool, error)
+		TransferTerrain(ctx context.Context, companyId uint64, position uint8, toCompanyId uint64, toPosition uint8) (bool, error)
+
+		UpdatePassword(ctx context.Context, companyId uint64, password string) error
+
+		// Reports whether any company registered with the email, even
+		// blocked or deleted ones
+		EmailExists(ctx context.Context, email string) (bool, error)
+		VerifyEmail(ctx context.Context, companyId uint64) error
+		SaveEmailToken(ctx context.Context, token *EmailToken) error
+		GetEmailToken(ctx context.Context, hash string) (*EmailToken, error)
+
+		// Marks the email token as used, returning false when it already was
+		UseEmailToken(ctx context.Context, id uint64) (bool, error)
+
+		SaveLoginAttempt(ctx context.Context, attempt *LoginAttempt) error
+		UpdateLastLogin(ctx context.Context, companyId uint64, at time.Time) error
+		GetLoginAttempts(ctx context.Context, companyId uint64, limit uint) ([]*LoginAttempt, error)
+
+		// Return when logins failed since the given time, newest first. Only
+		// failures after the last successful login for the email count.
+		GetFailedLoginsByEmail(ctx context.Context, email string, since time.Time) ([]time.Time, error)
+		GetFailedLoginsByIP(ctx context.Context, ip string, since time.Time) ([]time.Time, error)
+
+		CreateSession(ctx context.Context, companyId uint64) (uint64, error)
+		RevokeSession(ctx context.Context, sessionId uint64) error
+		RevokeSessions(ctx context.Context, companyId uint64) error
+		IsSessionRevoked(ctx context.Context, sessionId uint64) (bool, error)
+		SaveRefreshToken(ctx context.Context, sessionId uint64, hash string, expiresAt time.Time) error
+		GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error)
+
+		// Marks the refresh token as used, returning false when it already was
+		UseRefreshToken(ctx context.Context, id uint64) (bool, error)
+
+		GetUserByEmail(ctx context.Context, email string) (*User, error)
+		CreateUser(ctx context.Context, user *User) (uint64, error)
+		GetMembers(ctx context.Context, companyId uint64) ([]*Member, error)
+		GetMember(ctx context.Context, companyId, userId uint64) (*Member, error)
+
+		// Returns the memberships of the user in companies that aren't
+		// blocked or deleted
+		GetMemberships(ctx context.Context, userId uint64) ([]*Member, error)
+		AddMember(ctx context.Context, member *Member) error
+
+		// Return false when the user isn't a member of the company
+		UpdateMemberRole(ctx context.Context, companyId, userId uint64, role string) (bool, error)
+		RemoveMember(ctx context.Context, companyId, userId uint64) (bool, error)
+
+		SaveInvitation(ctx context.Context, invitation *Invitation) (uint64, error)
+		GetInvitation(ctx context.Context, hash string) (*Invitation, error)
+		GetInvitations(ctx context.Context, companyId uint64) ([]*Invitation, error)
+
+		// Marks the invitation as accepted, returning false when it already was
+		AcceptInvitation(ctx context.Context, id uint64) (bool, error)
+
+		// Returns false when the company has no such pending invitation
+		DeleteInvitation(ctx context.Context, companyId, id uint64) (bool, error)
+
+		CreateMemberSession(ctx context.Context, companyId, userId uint64) (uint64, error)
+		RevokeMemberSessions(ctx context.Context, companyId, userId uint64) error
+
+		SaveAuditEntry(ctx context.Context, entry *AuditEntry) error
+		GetAuditLog(ctx context.Context, companyId uint64, filter AuditFilter) ([]*AuditEntry, error)
 	}
 
 	goquRepository struct {
 		builder        *goqu.Database
 		accountingRepo accounting.Repository
+		clock          clock.Clock
 	}
 )
 
-func NewRepository(conn *database.Connection, accountingRepo accounting.Repository) Repository {
+func NewRepository(conn *database.Connection, accountingRepo accounting.Repository, clock clock.Clock) Repository {
 	builder := goqu.New(conn.Driver, conn.DB)
-	return &goquRepository{builder, accountingRepo}
+	return &goquRepository{builder, accountingRepo, clock}
 }
 
 func (r *goquRepository) GetById(ctx context.Context, id uint64) (*Company, error) {
 	company := new(Company)
 
-	found, err := r.getSelect().
+	found, err := r.getSelect(ctx).
 		Where(r.getCondition().Append(goqu.I("c.id").Eq(id))).
 		ScanStructContext(ctx, company)
 
@@ -42,10 +122,26 @@ func (r *goquRepository) GetById(ctx context.Context, id uint64) (*Company, erro
 	return company, err
 }
 
+func (r *goquRepository) HoldCash(ctx context.Context, companyId uint64, amount int) (bool, error) {
+	tx, err := database.BeginTx(ctx, r.builder)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	held, err := r.accountingRepo.HoldCash(tx.DB, companyId, int64(amount))
+	if err != nil {
+		return false, err
+	}
+
+	return held, tx.Commit()
+}
+
 func (r *goquRepository) GetByEmail(ctx context.Context, email string) (*Company, error) {
 	company := new(Company)
 
-	found, err := r.getSelect().
+	found, err := r.getSelect(ctx).
 		Where(r.getCondition().Append(goqu.I("c.email").Eq(email))).
 		ScanStructContext(ctx, company)
 
@@ -56,28 +152,119 @@ func (r *goquRepository) GetByEmail(ctx context.Context, email string) (*Company
 	return company, nil
 }
 
-func (r *goquRepository) PurchaseTerrain(ctx context.Context, total int, companyId uint64) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+func (r *goquRepository) GetProfile(ctx context.Context, companyId uint64) (*Profile, error) {
+	profile := new(Profile)
+
+	found, err := r.getProfileSelect(ctx).
+		Where(r.getCondition().Append(goqu.I("c.id").Eq(companyId))).
+		ScanStructContext(ctx, profile)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (r *goquRepository) GetProfiles(ctx context.Context, filter DirectoryFilter) ([]*Profile, error) {
+	profiles := make([]*Profile, 0)
+
+	condition := r.getCondition()
+	if filter.Search != "" {
+		condition = condition.Append(goqu.I("c.name").ILike("%" + filter.Search + "%"))
+	}
+
+	err := r.getProfileSelect(ctx).
+		Where(condition).
+		Order(goqu.I("c.name").Asc(), goqu.I("c.id").Asc()).
+		Limit(filter.Limit).
+		Offset(filter.Page*filter.Limit).
+		ScanStructsContext(ctx, &profiles)
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if _, err = tx.
+	return profiles, nil
+}
+
+func (r *goquRepository) UpdateProfile(ctx context.Context, companyId uint64, update *ProfileUpdate) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
 		Update(goqu.T("companies")).
 		Set(goqu.Record{
-			"available_terrains": goqu.L("? + 1", goqu.I("available_terrains")),
+			"name":        update.Name,
+			"logo_url":    update.LogoUrl,
+			"description": update.Description,
 		}).
-		Where(goqu.I("id").Eq(companyId)).
+		Where(
+			goqu.I("id").Eq(companyId),
+			goqu.I("blocked_at").IsNull(),
+			goqu.I("deleted_at").IsNull(),
+		).
 		Executor().
-		Exec(); err != nil {
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *goquRepository) GetTerrains(ctx context.Context, companyId uint64) ([]*Terrain, error) {
+	terrains := make([]*Terrain, 0)
+
+	err := r.getTerrainSelect(ctx).
+		Where(goqu.I("t.company_id").Eq(companyId)).
+		Order(goqu.I("t.position").Asc()).
+		ScanStructsContext(ctx, &terrains)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return terrains, nil
+}
+
+func (r *goquRepository) GetTerrain(ctx context.Context, companyId uint64, position uint8) (*Terrain, error) {
+	terrain := new(Terrain)
+
+	found, err := r.getTerrainSelect(ctx).
+		Where(
+			goqu.I("t.company_id").Eq(companyId),
+			goqu.I("t.position").Eq(position),
+		).
+		ScanStructContext(ctx, terrain)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return terrain, nil
+}
+
+func (r *goquRepository) PurchaseTerrain(ctx context.Context, companyId uint64, position uint8, total int) error {
+	tx, err := database.BeginTx(ctx, r.builder)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := r.insertTerrains(tx.DB, companyId, total, position); err != nil {
+		return err
+	}
+
+	if err := r.countTerrains(tx.DB, companyId, 1); err != nil {
 		return err
 	}
 
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          -total,
-			Description:    "Purchase of terrain",
+			Description:    fmt.Sprintf("Purchase of terrain at position %d", position),
 			Classification: accounting.TERRAIN_PURCHASE,
 		},
 		companyId,
@@ -88,33 +275,162 @@ func (r *goquRepository) PurchaseTerrain(ctx context.Context, total int, company
 	return tx.Commit()
 }
 
-func (r *goquRepository) Register(ctx context.Context, registration *Registration) (*Company, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+func (r *goquRepository) SellTerrain(ctx context.Context, companyId uint64, position uint8, refund int) (bool, error) {
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
+	defer tx.Rollback()
+
 	result, err := tx.
-		Insert(goqu.T("companies")).
-		Rows(goqu.Record{
-			"name":     registration.Name,
-			"email":    registration.Email,
-			"password": registration.Password,
+		Delete(goqu.T("terrains")).
+		Where(
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("position").Eq(position),
+		).
+		Executor().
+		Exec()
+
+	if err != nil {
+		return false, err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	if err := r.countTerrains(tx.DB, companyId, -1); err != nil {
+		return false, err
+	}
+
+	if refund > 0 {
+		if _, err := r.accountingRepo.RegisterTransaction(
+			tx.DB,
+			accounting.Transaction{
+				Value:          refund,
+				Description:    fmt.Sprintf("Sale of terrain at position %d", position),
+				Classification: accounting.TERRAIN_SALE,
+			},
+			companyId,
+		); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *goquRepository) TransferTerrain(ctx context.Context, companyId uint64, position uint8, toCompanyId uint64, toPosition uint8) (bool, error) {
+	tx, err := database.BeginTx(ctx, r.builder)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.
+		Update(goqu.T("terrains")).
+		Set(goqu.Record{
+			"company_id": toCompanyId,
+			"position":   toPosition,
 		}).
+		Where(
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("position").Eq(position),
+		).
 		Executor().
 		Exec()
 
+	if err != nil {
+		return false, err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	if err := r.countTerrains(tx.DB, companyId, -1); err != nil {
+		return false, err
+	}
+
+	if err := r.countTerrains(tx.DB, toCompanyId, 1); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *goquRepository) insertTerrains(tx *database.DB, companyId uint64, price int, positions ...uint8) error {
+	now := r.clock.Now().UTC()
+
+	rows := make([]any, 0, len(positions))
+	for _, position := range positions {
+		rows = append(rows, goqu.Record{
+			"company_id":     companyId,
+			"position":       position,
+			"purchase_price": price,
+			"purchased_at":   now,
+		})
+	}
+
+	_, err := tx.
+		Insert(goqu.T("terrains")).
+		Rows(rows...).
+		Executor().
+		Exec()
+
+	return err
+}
+
+// Keeps the number of terrains of the company in step with its plots
+func (r *goquRepository) countTerrains(tx *database.DB, companyId uint64, delta int) error {
+	_, err := tx.
+		Update(goqu.T("companies")).
+		Set(goqu.Record{
+			"available_terrains": goqu.L("? + ?", goqu.I("available_terrains"), delta),
+		}).
+		Where(goqu.I("id").Eq(companyId)).
+		Executor().
+		Exec()
+
+	return err
+}
+
+func (r *goquRepository) Register(ctx context.Context, registration *Registration) (*Company, error) {
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
+	record := goqu.Record{
+		"name":               registration.Name,
+		"email":              registration.Email,
+		"password":           registration.Password,
+		"available_terrains": STARTING_TERRAINS,
+	}
+
+	if registration.Admin {
+		record["is_admin"] = 1
+	}
+
+	id, err := database.InsertId(ctx, tx.Insert(goqu.T("companies")).Rows(record))
+
 	if err != nil {
 		return nil, err
 	}
 
+	positions := make([]uint8, 0, STARTING_TERRAINS)
+	for position := uint8(1); position <= STARTING_TERRAINS; position++ {
+		positions = append(positions, position)
+	}
+
+	if err := r.insertTerrains(tx.DB, uint64(id), 0, positions...); err != nil {
+		return nil, err
+	}
+
 	if _, err = r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Classification: accounting.SOCIAL_CAPITAL,
 			Value:          1_000_000 * 100,
@@ -132,25 +448,626 @@ func (r *goquRepository) Register(ctx context.Context, registration *Registratio
 	return r.GetById(ctx, uint64(id))
 }
 
-func (r *goquRepository) getSelect() *goqu.SelectDataset {
-	return r.builder.
+func (r *goquRepository) UpdatePassword(ctx context.Context, companyId uint64, password string) error {
+	_, err := database.Query(ctx, r.builder).
+		Update(goqu.T("companies")).
+		Set(goqu.Record{"password": password}).
+		Where(goqu.I("id").Eq(companyId)).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	var id uint64
+
+	return database.Query(ctx, r.builder).
+		From(goqu.T("companies")).
+		Select(goqu.I("id")).
+		Where(goqu.I("email").Eq(email)).
+		ScanValContext(ctx, &id)
+}
+
+func (r *goquRepository) VerifyEmail(ctx context.Context, companyId uint64) error {
+	_, err := database.Query(ctx, r.builder).
+		Update(goqu.T("companies")).
+		Set(goqu.Record{"email_verified_at": time.Now().UTC()}).
+		Where(goqu.I("id").Eq(companyId), goqu.I("email_verified_at").IsNull()).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) SaveEmailToken(ctx context.Context, token *EmailToken) error {
+	_, err := database.Query(ctx, r.builder).
+		Insert(goqu.T("email_tokens")).
+		Rows(token).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) GetEmailToken(ctx context.Context, hash string) (*EmailToken, error) {
+	token := new(EmailToken)
+
+	found, err := database.Query(ctx, r.builder).
+		From(goqu.T("email_tokens")).
+		Where(goqu.I("token_hash").Eq(hash)).
+		ScanStructContext(ctx, token)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (r *goquRepository) UseEmailToken(ctx context.Context, id uint64) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
+		Update(goqu.T("email_tokens")).
+		Set(goqu.Record{"used_at": time.Now().UTC()}).
+		Where(goqu.I("id").Eq(id), goqu.I("used_at").IsNull()).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *goquRepository) SaveLoginAttempt(ctx context.Context, attempt *LoginAttempt) error {
+	_, err := database.Query(ctx, r.builder).
+		Insert(goqu.T("login_attempts")).
+		Rows(attempt).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) UpdateLastLogin(ctx context.Context, companyId uint64, at time.Time) error {
+	_, err := database.Query(ctx, r.builder).
+		Update(goqu.T("companies")).
+		Set(goqu.Record{"last_login": at}).
+		Where(goqu.I("id").Eq(companyId)).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) GetLoginAttempts(ctx context.Context, companyId uint64, limit uint) ([]*LoginAttempt, error) {
+	attempts := make([]*LoginAttempt, 0)
+
+	err := database.Query(ctx, r.builder).
+		From(goqu.T("login_attempts")).
+		Where(goqu.I("company_id").Eq(companyId)).
+		Order(goqu.I("created_at").Desc(), goqu.I("id").Desc()).
+		Limit(limit).
+		ScanStructsContext(ctx, &attempts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+func (r *goquRepository) GetFailedLoginsByEmail(ctx context.Context, email string, since time.Time) ([]time.Time, error) {
+	var lastSuccess time.Time
+
+	found, err := database.Query(ctx, r.builder).
+		From(goqu.T("login_attempts")).
+		Select(goqu.I("created_at")).
+		Where(goqu.I("email").Eq(email), goqu.I("success").IsTrue()).
+		Order(goqu.I("created_at").Desc()).
+		Limit(1).
+		ScanValContext(ctx, &lastSuccess)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if found && lastSuccess.After(since) {
+		since = lastSuccess
+	}
+
+	return r.getFailedLogins(ctx, goqu.I("email").Eq(email), since)
+}
+
+func (r *goquRepository) GetFailedLoginsByIP(ctx context.Context, ip string, since time.Time) ([]time.Time, error) {
+	return r.getFailedLogins(ctx, goqu.I("ip").Eq(ip), since)
+}
+
+// Logins refused while locked aren't failures, or they would keep the
+// logins locked for as long as someone keeps trying
+func (r *goquRepository) getFailedLogins(ctx context.Context, condition exp.Expression, since time.Time) ([]time.Time, error) {
+	failures := make([]time.Time, 0)
+
+	err := database.Query(ctx, r.builder).
+		From(goqu.T("login_attempts")).
+		Select(goqu.I("created_at")).
+		Where(
+			condition,
+			goqu.I("success").IsFalse(),
+			goqu.I("locked").IsFalse(),
+			goqu.I("created_at").Gt(since),
+		).
+		Order(goqu.I("created_at").Desc()).
+		ScanValsContext(ctx, &failures)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return failures, nil
+}
+
+func (r *goquRepository) CreateSession(ctx context.Context, companyId uint64) (uint64, error) {
+	id, err := database.InsertId(ctx, database.Query(ctx, r.builder).
+		Insert(goqu.T("sessions")).
+		Rows(goqu.Record{
+			"company_id": companyId,
+			"created_at": time.Now().UTC(),
+		}))
+
+	return uint64(id), err
+}
+
+func (r *goquRepository) RevokeSession(ctx context.Context, sessionId uint64) error {
+	return r.revokeSessions(ctx, goqu.I("id").Eq(sessionId))
+}
+
+func (r *goquRepository) RevokeSessions(ctx context.Context, companyId uint64) error {
+	return r.revokeSessions(ctx, goqu.I("company_id").Eq(companyId))
+}
+
+func (r *goquRepository) revokeSessions(ctx context.Context, condition exp.Expression) error {
+	_, err := database.Query(ctx, r.builder).
+		Update(goqu.T("sessions")).
+		Set(goqu.Record{"revoked_at": time.Now().UTC()}).
+		Where(condition, goqu.I("revoked_at").IsNull()).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+// Sessions that don't exist are reported as revoked, so tokens outliving
+// their session are rejected
+func (r *goquRepository) IsSessionRevoked(ctx context.Context, sessionId uint64) (bool, error) {
+	var revokedAt *time.Time
+
+	found, err := database.Query(ctx, r.builder).
+		From(goqu.T("sessions")).
+		Select(goqu.I("revoked_at")).
+		Where(goqu.I("id").Eq(sessionId)).
+		ScanValContext(ctx, &revokedAt)
+
+	if err != nil {
+		return true, err
+	}
+
+	return !found || revokedAt != nil, nil
+}
+
+func (r *goquRepository) SaveRefreshToken(ctx context.Context, sessionId uint64, hash string, expiresAt time.Time) error {
+	_, err := database.Query(ctx, r.builder).
+		Insert(goqu.T("refresh_tokens")).
+		Rows(goqu.Record{
+			"session_id": sessionId,
+			"token_hash": hash,
+			"expires_at": expiresAt,
+			"created_at": time.Now().UTC(),
+		}).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	token := new(RefreshToken)
+
+	found, err := database.Query(ctx, r.builder).
+		From(goqu.T("refresh_tokens").As("t")).
+		Select(
+			goqu.I("t.id"),
+			goqu.I("t.session_id"),
+			goqu.I("s.company_id"),
+			goqu.I("s.user_id"),
+			goqu.I("t.expires_at"),
+			goqu.I("t.used_at"),
+			goqu.I("s.revoked_at"),
+		).
+		InnerJoin(
+			goqu.T("sessions").As("s"),
+			goqu.On(goqu.I("s.id").Eq(goqu.I("t.session_id"))),
+		).
+		Where(goqu.I("t.token_hash").Eq(hash)).
+		ScanStructContext(ctx, token)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (r *goquRepository) UseRefreshToken(ctx context.Context, id uint64) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
+		Update(goqu.T("refresh_tokens")).
+		Set(goqu.Record{"used_at": time.Now().UTC()}).
+		Where(goqu.I("id").Eq(id), goqu.I("used_at").IsNull()).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *goquRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	user := new(User)
+
+	found, err := database.Query(ctx, r.builder).
+		From(goqu.T("users")).
+		Where(goqu.I("email").Eq(email)).
+		ScanStructContext(ctx, user)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *goquRepository) CreateUser(ctx context.Context, user *User) (uint64, error) {
+	id, err := database.InsertId(ctx, database.Query(ctx, r.builder).
+		Insert(goqu.T("users")).
+		Rows(user))
+
+	return uint64(id), err
+}
+
+func (r *goquRepository) GetMembers(ctx context.Context, companyId uint64) ([]*Member, error) {
+	members := make([]*Member, 0)
+
+	err := r.getMemberSelect(ctx).
+		Where(goqu.I("m.company_id").Eq(companyId)).
+		Order(goqu.I("m.created_at").Asc(), goqu.I("m.id").Asc()).
+		ScanStructsContext(ctx, &members)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (r *goquRepository) GetMember(ctx context.Context, companyId, userId uint64) (*Member, error) {
+	member := new(Member)
+
+	found, err := r.getMemberSelect(ctx).
+		Where(
+			goqu.I("m.company_id").Eq(companyId),
+			goqu.I("m.user_id").Eq(userId),
+		).
+		ScanStructContext(ctx, member)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (r *goquRepository) GetMemberships(ctx context.Context, userId uint64) ([]*Member, error) {
+	members := make([]*Member, 0)
+
+	err := r.getMemberSelect(ctx).
+		InnerJoin(
+			goqu.T("companies").As("c"),
+			goqu.On(goqu.I("c.id").Eq(goqu.I("m.company_id"))),
+		).
+		Where(r.getCondition().Append(goqu.I("m.user_id").Eq(userId))).
+		Order(goqu.I("m.company_id").Asc()).
+		ScanStructsContext(ctx, &members)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (r *goquRepository) AddMember(ctx context.Context, member *Member) error {
+	_, err := database.Query(ctx, r.builder).
+		Insert(goqu.T("company_members")).
+		Rows(goqu.Record{
+			"company_id": member.CompanyId,
+			"user_id":    member.UserId,
+			"role":       member.Role,
+			"invited_by": member.InvitedBy,
+			"created_at": member.JoinedAt,
+		}).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) UpdateMemberRole(ctx context.Context, companyId, userId uint64, role string) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
+		Update(goqu.T("company_members")).
+		Set(goqu.Record{"role": role}).
+		Where(
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("user_id").Eq(userId),
+		).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *goquRepository) RemoveMember(ctx context.Context, companyId, userId uint64) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
+		Delete(goqu.T("company_members")).
+		Where(
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("user_id").Eq(userId),
+		).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *goquRepository) SaveInvitation(ctx context.Context, invitation *Invitation) (uint64, error) {
+	id, err := database.InsertId(ctx, database.Query(ctx, r.builder).
+		Insert(goqu.T("member_invitations")).
+		Rows(invitation))
+
+	return uint64(id), err
+}
+
+func (r *goquRepository) GetInvitation(ctx context.Context, hash string) (*Invitation, error) {
+	invitation := new(Invitation)
+
+	found, err := database.Query(ctx, r.builder).
+		From(goqu.T("member_invitations")).
+		Where(goqu.I("token_hash").Eq(hash)).
+		ScanStructContext(ctx, invitation)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (r *goquRepository) GetInvitations(ctx context.Context, companyId uint64) ([]*Invitation, error) {
+	invitations := make([]*Invitation, 0)
+
+	err := database.Query(ctx, r.builder).
+		From(goqu.T("member_invitations")).
+		Where(
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("accepted_at").IsNull(),
+		).
+		Order(goqu.I("created_at").Desc(), goqu.I("id").Desc()).
+		ScanStructsContext(ctx, &invitations)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (r *goquRepository) AcceptInvitation(ctx context.Context, id uint64) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
+		Update(goqu.T("member_invitations")).
+		Set(goqu.Record{"accepted_at": time.Now().UTC()}).
+		Where(goqu.I("id").Eq(id), goqu.I("accepted_at").IsNull()).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *goquRepository) DeleteInvitation(ctx context.Context, companyId, id uint64) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
+		Delete(goqu.T("member_invitations")).
+		Where(
+			goqu.I("id").Eq(id),
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("accepted_at").IsNull(),
+		).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *goquRepository) CreateMemberSession(ctx context.Context, companyId, userId uint64) (uint64, error) {
+	id, err := database.InsertId(ctx, database.Query(ctx, r.builder).
+		Insert(goqu.T("sessions")).
+		Rows(goqu.Record{
+			"company_id": companyId,
+			"user_id":    userId,
+			"created_at": time.Now().UTC(),
+		}))
+
+	return uint64(id), err
+}
+
+func (r *goquRepository) RevokeMemberSessions(ctx context.Context, companyId, userId uint64) error {
+	return r.revokeSessions(ctx, goqu.And(
+		goqu.I("company_id").Eq(companyId),
+		goqu.I("user_id").Eq(userId),
+	))
+}
+
+func (r *goquRepository) SaveAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	_, err := database.Query(ctx, r.builder).
+		Insert(goqu.T("audit_log")).
+		Rows(entry).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) GetAuditLog(ctx context.Context, companyId uint64, filter AuditFilter) ([]*AuditEntry, error) {
+	entries := make([]*AuditEntry, 0)
+
+	condition := goqu.And(goqu.I("company_id").Eq(companyId))
+	if filter.UserId != 0 {
+		condition = condition.Append(goqu.I("user_id").Eq(filter.UserId))
+	}
+
+	err := database.Query(ctx, r.builder).
+		From(goqu.T("audit_log")).
+		Where(condition).
+		Order(goqu.I("created_at").Desc(), goqu.I("id").Desc()).
+		Limit(filter.Limit).
+		Offset(filter.Page*filter.Limit).
+		ScanStructsContext(ctx, &entries)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *goquRepository) getSelect(ctx context.Context) *goqu.SelectDataset {
+	return database.Query(ctx, r.builder).
 		Select(
 			goqu.I("c.id"),
 			goqu.I("c.name"),
 			goqu.I("c.email"),
 			goqu.I("c.password"),
+			goqu.I("c.email_verified_at"),
 			goqu.I("c.last_login"),
 			goqu.I("c.created_at"),
 			goqu.I("c.is_admin"),
+			goqu.I("c.logo_url"),
+			goqu.I("c.description"),
 			goqu.I("c.available_terrains"),
-			goqu.COALESCE(goqu.SUM("t.value"), 0).As("cash"),
+			goqu.COALESCE(goqu.I("b.cash"), 0).As("cash"),
 		).
 		From(goqu.T("companies").As("c")).
 		LeftJoin(
-			goqu.T("transactions").As("t"),
-			goqu.On(goqu.I("t.company_id").Eq(goqu.I("c.id"))),
+			goqu.T("company_balances").As("b"),
+			goqu.On(goqu.I("b.company_id").Eq(goqu.I("c.id"))),
+		)
+}
+
+// Selects the public side of companies, with how many buildings and open
+// orders they have
+func (r *goquRepository) getProfileSelect(ctx context.Context) *goqu.SelectDataset {
+	buildings := r.builder.
+		From(goqu.T("companies_buildings").As("cb")).
+		Select(goqu.COUNT("*")).
+		Where(
+			goqu.I("cb.company_id").Eq(goqu.I("c.id")),
+			goqu.I("cb.demolished_at").IsNull(),
+		)
+
+	openOrders := r.builder.
+		From(goqu.T("orders").As("o")).
+		Select(goqu.COUNT("*")).
+		Where(
+			goqu.I("o.company_id").Eq(goqu.I("c.id")),
+			goqu.I("o.canceled_at").IsNull(),
+			goqu.I("o.quantity").Gt(0),
+		)
+
+	return database.Query(ctx, r.builder).
+		Select(
+			goqu.I("c.id"),
+			goqu.I("c.name"),
+			goqu.I("c.logo_url"),
+			goqu.I("c.description"),
+			goqu.I("c.created_at"),
+			goqu.I("c.available_terrains"),
+			buildings.As("buildings"),
+			openOrders.As("open_orders"),
+		).
+		From(goqu.T("companies").As("c"))
+}
+
+// Selects the plots with the building standing on them
+func (r *goquRepository) getTerrainSelect(ctx context.Context) *goqu.SelectDataset {
+	return database.Query(ctx, r.builder).
+		Select(
+			goqu.I("t.id"),
+			goqu.I("t.company_id"),
+			goqu.I("t.position"),
+			goqu.I("t.purchase_price"),
+			goqu.I("t.purchased_at"),
+			goqu.I("cb.id").As("building_id"),
+		).
+		From(goqu.T("terrains").As("t")).
+		LeftJoin(
+			goqu.T("companies_buildings").As("cb"),
+			goqu.On(
+				goqu.I("cb.company_id").Eq(goqu.I("t.company_id")),
+				goqu.I("cb.position").Eq(goqu.I("t.position")),
+				goqu.I("cb.demolished_at").IsNull(),
+			),
+		)
+}
+
+// Selects the members with the name and email of their user
+func (r *goquRepository) getMemberSelect(ctx context.Context) *goqu.SelectDataset {
+	return database.Query(ctx, r.builder).
+		Select(
+			goqu.I("m.company_id"),
+			goqu.I("m.user_id"),
+			goqu.I("u.name"),
+			goqu.I("u.email"),
+			goqu.I("m.role"),
+			goqu.I("m.invited_by"),
+			goqu.I("m.created_at"),
 		).
-		GroupBy(goqu.I("c.id"))
+		From(goqu.T("company_members").As("m")).
+		InnerJoin(
+			goqu.T("users").As("u"),
+			goqu.On(goqu.I("u.id").Eq(goqu.I("m.user_id"))),
+		)
 }
 
 func (r *goquRepository) getCondition() exp.ExpressionList {
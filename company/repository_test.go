@@ -2,8 +2,10 @@ package company_test
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/company"
 	"api/database"
+	"api/mail"
 	"context"
 	"log"
 	"os"
@@ -12,7 +14,7 @@ import (
 )
 
 func TestMain(t *testing.M) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		log.Fatalf("could not connect to database: %s", err)
 	}
@@ -26,9 +28,9 @@ func TestMain(t *testing.M) {
 
 	_, err = tx.Exec(`
         INSERT INTO companies (id, name, email, password, created_at, blocked_at, deleted_at) VALUES
-        (1, "Coca-Cola", "coke@email.com", "aoeu", "2023-10-22T01:11:53Z", NULL, NULL),
-        (2, "Blocked", "blocked@email.com", "aoeu", "2023-10-22T01:11:53Z", "2023-10-22T01:11:53Z", NULL),
-        (3, "Deleted", "deleted@email.com", "aoeu", "2023-10-22T01:11:53Z", NULL, "2023-10-22T01:11:53Z");
+        (1, 'Coca-Cola', 'coke@email.com', 'aoeu', '2023-10-22T01:11:53Z', NULL, NULL),
+        (2, 'Blocked', 'blocked@email.com', 'aoeu', '2023-10-22T01:11:53Z', '2023-10-22T01:11:53Z', NULL),
+        (3, 'Deleted', 'deleted@email.com', 'aoeu', '2023-10-22T01:11:53Z', NULL, '2023-10-22T01:11:53Z');
     `)
 
 	if err != nil {
@@ -40,6 +42,13 @@ func TestMain(t *testing.M) {
 		log.Fatalf("could not seed database: %s", err)
 	}
 
+	if _, err := tx.Exec(`
+        INSERT INTO company_balances (company_id, cash)
+        SELECT company_id, SUM(value) FROM transactions GROUP BY company_id
+    `); err != nil {
+		log.Fatalf("could not seed database: %s", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		log.Fatalf("could not commit transaction: %s", err)
 	}
@@ -50,7 +59,7 @@ func TestMain(t *testing.M) {
 }
 
 func TestRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -60,6 +69,39 @@ func TestRepository(t *testing.T) {
 	t.Cleanup(func() {
 		cancel()
 
+		if _, err := conn.DB.Exec("DELETE FROM login_attempts"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec("DELETE FROM email_tokens"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec("DELETE FROM refresh_tokens"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec("DELETE FROM sessions"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+
+		if _, err := conn.DB.Exec("DELETE FROM audit_log"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec("DELETE FROM member_invitations"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec("DELETE FROM company_members"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec("DELETE FROM users"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+
+		if _, err := conn.DB.Exec("DELETE FROM terrains"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec("DELETE FROM companies_buildings"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+
 		if _, err := conn.DB.Exec("DELETE FROM companies"); err != nil {
 			log.Fatalf("could not cleanup database: %s", err)
 		}
@@ -67,10 +109,13 @@ func TestRepository(t *testing.T) {
 		if _, err := conn.DB.Exec("DELETE FROM transactions"); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec("DELETE FROM company_balances"); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
 	})
 
-	accountingRepo := accounting.NewRepository(conn)
-	repository := company.NewRepository(conn, accountingRepo)
+	accountingRepo := accounting.NewRepository(conn, clock.New())
+	repository := company.NewRepository(conn, accountingRepo, clock.New())
 
 	t.Run("should return with cash", func(t *testing.T) {
 		company, err := repository.GetById(ctx, 1)
@@ -99,6 +144,38 @@ func TestRepository(t *testing.T) {
 		if company.Id == 0 {
 			t.Errorf("expected an id, got %d", company.Id)
 		}
+
+		if company.IsAdmin() {
+			t.Error("expected company not to be an admin")
+		}
+
+		terrains, err := repository.GetTerrains(ctx, company.Id)
+		if err != nil {
+			t.Fatalf("could not get terrains: %s", err)
+		}
+
+		if len(terrains) != int(company.AvailableTerrains) || terrains[0].Position != 1 {
+			t.Errorf("expected %d terrains from position 1, got %+v", company.AvailableTerrains, terrains)
+		}
+	})
+
+	t.Run("should register admins", func(t *testing.T) {
+		registration := &company.Registration{
+			Name:     "Operator",
+			Password: "password",
+			Email:    "operator@entrepreneur.com",
+			Confirm:  "password",
+			Admin:    true,
+		}
+
+		company, err := repository.Register(ctx, registration)
+		if err != nil {
+			t.Fatalf("could not save company: %s", err)
+		}
+
+		if !company.IsAdmin() {
+			t.Error("expected company to be an admin")
+		}
 	})
 
 	t.Run("should return nil when not found by id", func(t *testing.T) {
@@ -163,7 +240,7 @@ func TestRepository(t *testing.T) {
 
 	t.Run("PurchaseTerrain", func(t *testing.T) {
 		t.Run("should increment available terrains", func(t *testing.T) {
-			if err := repository.PurchaseTerrain(ctx, 0, 1); err != nil {
+			if err := repository.PurchaseTerrain(ctx, 1, 4, 0); err != nil {
 				t.Fatalf("could not purchase terrain: %s", err)
 			}
 
@@ -178,7 +255,7 @@ func TestRepository(t *testing.T) {
 		})
 
 		t.Run("should reduce cash", func(t *testing.T) {
-			if err := repository.PurchaseTerrain(ctx, 500_000_00, 1); err != nil {
+			if err := repository.PurchaseTerrain(ctx, 1, 5, 500_000_00); err != nil {
 				t.Fatalf("could not purchase terrain: %s", err)
 			}
 
@@ -192,5 +269,502 @@ func TestRepository(t *testing.T) {
 				t.Errorf("expected cash %d, got %d", expectedCash, company.AvailableCash)
 			}
 		})
+
+		t.Run("should not purchase owned terrain twice", func(t *testing.T) {
+			if err := repository.PurchaseTerrain(ctx, 1, 5, 0); err == nil {
+				t.Error("expected terrain to be owned already")
+			}
+		})
+	})
+
+	t.Run("Terrains", func(t *testing.T) {
+		if _, err := conn.DB.Exec(`
+            INSERT INTO companies_buildings (id, name, company_id, building_id, position) VALUES (1, 'Mill', 1, 1, 4)
+        `); err != nil {
+			t.Fatalf("could not seed database: %s", err)
+		}
+
+		t.Run("should return plots with their buildings", func(t *testing.T) {
+			terrains, err := repository.GetTerrains(ctx, 1)
+			if err != nil {
+				t.Fatalf("could not get terrains: %s", err)
+			}
+
+			if len(terrains) != 2 || terrains[0].Position != 4 || terrains[1].Position != 5 {
+				t.Fatalf("expected terrains at 4 and 5, got %+v", terrains)
+			}
+
+			if terrains[0].BuildingId == nil || *terrains[0].BuildingId != 1 || !terrains[1].IsEmpty() {
+				t.Errorf("expected building on terrain 4 only, got %+v", terrains)
+			}
+
+			if terrains[1].PurchasePrice != 500_000_00 {
+				t.Errorf("expected purchase price %d, got %d", 500_000_00, terrains[1].PurchasePrice)
+			}
+		})
+
+		t.Run("should transfer terrain", func(t *testing.T) {
+			transferred, err := repository.TransferTerrain(ctx, 1, 5, 2, 1)
+			if err != nil || !transferred {
+				t.Fatalf("expected terrain to be transferred, got %t: %v", transferred, err)
+			}
+
+			if terrain, _ := repository.GetTerrain(ctx, 1, 5); terrain != nil {
+				t.Errorf("expected terrain to be given away, got %+v", terrain)
+			}
+
+			terrain, _ := repository.GetTerrain(ctx, 2, 1)
+			if terrain == nil || terrain.PurchasePrice != 500_000_00 {
+				t.Errorf("expected terrain with its purchase price, got %+v", terrain)
+			}
+
+			if transferred, _ := repository.TransferTerrain(ctx, 1, 5, 2, 2); transferred {
+				t.Error("expected terrain not to be owned anymore")
+			}
+		})
+
+		t.Run("should sell terrain", func(t *testing.T) {
+			sold, err := repository.SellTerrain(ctx, 1, 4, 100_000_00)
+			if err != nil || !sold {
+				t.Fatalf("expected terrain to be sold, got %t: %v", sold, err)
+			}
+
+			company, _ := repository.GetById(ctx, 1)
+			if company.AvailableTerrains != 3 || company.AvailableCash != 600_000_00 {
+				t.Errorf("expected 3 terrains and refunded cash, got %d and %d", company.AvailableTerrains, company.AvailableCash)
+			}
+
+			if sold, _ := repository.SellTerrain(ctx, 1, 4, 100_000_00); sold {
+				t.Error("expected terrain to be sold only once")
+			}
+		})
+	})
+
+	t.Run("should not spend the same cash twice on terrains", func(t *testing.T) {
+		pricing := company.TerrainPricing{BaseValue: 400_000_00}
+		service := company.NewService(repository, "secret", pricing, mail.NewOutbox(), log.Default(), database.NewUnitOfWork(conn), clock.New(), clock.New())
+
+		// Cash left is 600_000_00, enough for only one of them
+		purchased := make(chan error, 2)
+		for _, position := range []uint8{10, 11} {
+			go func(position uint8) {
+				purchased <- service.PurchaseTerrain(ctx, 1, position)
+			}(position)
+		}
+
+		succeeded := 0
+		for i := 0; i < 2; i++ {
+			if err := <-purchased; err == nil {
+				succeeded++
+			}
+		}
+
+		if succeeded != 1 {
+			t.Errorf("expected a single purchase, got %d", succeeded)
+		}
+
+		company, _ := repository.GetById(ctx, 1)
+		if company.AvailableCash != 200_000_00 {
+			t.Errorf("expected %d cash, got %d", 200_000_00, company.AvailableCash)
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		sessionId, err := repository.CreateSession(ctx, 1)
+		if err != nil {
+			t.Fatalf("could not create session: %s", err)
+		}
+
+		expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+		if err := repository.SaveRefreshToken(ctx, sessionId, "hash", expiresAt); err != nil {
+			t.Fatalf("could not save refresh token: %s", err)
+		}
+
+		t.Run("should get refresh token by hash", func(t *testing.T) {
+			token, err := repository.GetRefreshToken(ctx, "hash")
+			if err != nil {
+				t.Fatalf("could not get refresh token: %s", err)
+			}
+
+			if token == nil || token.SessionId != sessionId || token.CompanyId != 1 {
+				t.Fatalf("expected token of session %d, got %+v", sessionId, token)
+			}
+
+			if !token.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("expected expiration %s, got %s", expiresAt, token.ExpiresAt)
+			}
+
+			if token.UsedAt != nil || token.RevokedAt != nil {
+				t.Errorf("expected unused token of active session, got %+v", token)
+			}
+		})
+
+		t.Run("should use refresh token once", func(t *testing.T) {
+			token, _ := repository.GetRefreshToken(ctx, "hash")
+
+			if used, err := repository.UseRefreshToken(ctx, token.Id); err != nil || !used {
+				t.Fatalf("expected token to be used, got %t: %v", used, err)
+			}
+
+			if used, err := repository.UseRefreshToken(ctx, token.Id); err != nil || used {
+				t.Errorf("expected token to be used only once, got %t: %v", used, err)
+			}
+		})
+
+		t.Run("should revoke sessions of company", func(t *testing.T) {
+			other, _ := repository.CreateSession(ctx, 1)
+
+			if revoked, err := repository.IsSessionRevoked(ctx, other); err != nil || revoked {
+				t.Fatalf("expected session to be active, got %t: %v", revoked, err)
+			}
+
+			if err := repository.RevokeSessions(ctx, 1); err != nil {
+				t.Fatalf("could not revoke sessions: %s", err)
+			}
+
+			for _, id := range []uint64{sessionId, other} {
+				if revoked, err := repository.IsSessionRevoked(ctx, id); err != nil || !revoked {
+					t.Errorf("expected session %d to be revoked, got %t: %v", id, revoked, err)
+				}
+			}
+
+			token, _ := repository.GetRefreshToken(ctx, "hash")
+			if token.RevokedAt == nil {
+				t.Error("expected refresh token of revoked session")
+			}
+		})
+
+		t.Run("should report unknown sessions as revoked", func(t *testing.T) {
+			if revoked, err := repository.IsSessionRevoked(ctx, 9999); err != nil || !revoked {
+				t.Errorf("expected unknown session to be revoked, got %t: %v", revoked, err)
+			}
+		})
+	})
+
+	t.Run("Members", func(t *testing.T) {
+		userId, err := repository.CreateUser(ctx, &company.User{Name: "Member", Email: "member@email.com", Pass: "aoeu", CreatedAt: time.Now().UTC()})
+		if err != nil {
+			t.Fatalf("could not create user: %s", err)
+		}
+
+		for _, companyId := range []uint64{1, 2} {
+			if err := repository.AddMember(ctx, &company.Member{CompanyId: companyId, UserId: userId, Role: "trader", JoinedAt: time.Now().UTC()}); err != nil {
+				t.Fatalf("could not add member: %s", err)
+			}
+		}
+
+		t.Run("should return members with their user", func(t *testing.T) {
+			members, err := repository.GetMembers(ctx, 1)
+			if err != nil {
+				t.Fatalf("could not get members: %s", err)
+			}
+
+			if len(members) != 1 || members[0].UserId != userId || members[0].Email != "member@email.com" || members[0].Role != "trader" {
+				t.Errorf("expected trader member@email.com, got %+v", members)
+			}
+		})
+
+		t.Run("should not return memberships of blocked companies", func(t *testing.T) {
+			memberships, err := repository.GetMemberships(ctx, userId)
+			if err != nil {
+				t.Fatalf("could not get memberships: %s", err)
+			}
+
+			if len(memberships) != 1 || memberships[0].CompanyId != 1 {
+				t.Errorf("expected membership of company 1 only, got %+v", memberships)
+			}
+		})
+
+		t.Run("should change role", func(t *testing.T) {
+			if updated, err := repository.UpdateMemberRole(ctx, 1, userId, "viewer"); err != nil || !updated {
+				t.Fatalf("expected role to change, got %t: %v", updated, err)
+			}
+
+			if updated, err := repository.UpdateMemberRole(ctx, 1, 9999, "viewer"); err != nil || updated {
+				t.Errorf("expected unknown member not to change, got %t: %v", updated, err)
+			}
+
+			member, _ := repository.GetMember(ctx, 1, userId)
+			if member == nil || member.Role != "viewer" {
+				t.Errorf("expected role %s, got %+v", "viewer", member)
+			}
+		})
+
+		t.Run("should revoke sessions of the member only", func(t *testing.T) {
+			memberSession, _ := repository.CreateMemberSession(ctx, 1, userId)
+			companySession, _ := repository.CreateSession(ctx, 1)
+
+			if err := repository.SaveRefreshToken(ctx, memberSession, "member-hash", time.Now().UTC().Add(time.Hour)); err != nil {
+				t.Fatalf("could not save refresh token: %s", err)
+			}
+
+			token, _ := repository.GetRefreshToken(ctx, "member-hash")
+			if token == nil || token.UserId == nil || *token.UserId != userId {
+				t.Fatalf("expected refresh token of user %d, got %+v", userId, token)
+			}
+
+			if err := repository.RevokeMemberSessions(ctx, 1, userId); err != nil {
+				t.Fatalf("could not revoke sessions: %s", err)
+			}
+
+			if revoked, _ := repository.IsSessionRevoked(ctx, memberSession); !revoked {
+				t.Error("expected session of the member to be revoked")
+			}
+
+			if revoked, _ := repository.IsSessionRevoked(ctx, companySession); revoked {
+				t.Error("expected session of the company to be active")
+			}
+		})
+
+		t.Run("should accept invitations once", func(t *testing.T) {
+			id, err := repository.SaveInvitation(ctx, &company.Invitation{
+				CompanyId: 1,
+				Email:     "invited@email.com",
+				Role:      "accountant",
+				Hash:      "invitation-hash",
+				ExpiresAt: time.Now().UTC().Add(time.Hour),
+				CreatedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				t.Fatalf("could not save invitation: %s", err)
+			}
+
+			invitation, _ := repository.GetInvitation(ctx, "invitation-hash")
+			if invitation == nil || invitation.Id != id || invitation.Role != "accountant" {
+				t.Fatalf("expected invitation %d, got %+v", id, invitation)
+			}
+
+			if accepted, err := repository.AcceptInvitation(ctx, id); err != nil || !accepted {
+				t.Fatalf("expected invitation to be accepted, got %t: %v", accepted, err)
+			}
+
+			if accepted, err := repository.AcceptInvitation(ctx, id); err != nil || accepted {
+				t.Errorf("expected invitation to be accepted once, got %t: %v", accepted, err)
+			}
+
+			if pending, _ := repository.GetInvitations(ctx, 1); len(pending) != 0 {
+				t.Errorf("expected no pending invitations, got %+v", pending)
+			}
+
+			if deleted, err := repository.DeleteInvitation(ctx, 1, id); err != nil || deleted {
+				t.Errorf("expected accepted invitation not to be deleted, got %t: %v", deleted, err)
+			}
+		})
+
+		t.Run("should record audit entries", func(t *testing.T) {
+			for _, path := range []string{"/companies/1", "/market/orders"} {
+				if err := repository.SaveAuditEntry(ctx, &company.AuditEntry{CompanyId: 1, UserId: &userId, Role: "viewer", Method: "POST", Path: path, CreatedAt: time.Now().UTC()}); err != nil {
+					t.Fatalf("could not save audit entry: %s", err)
+				}
+			}
+
+			if err := repository.SaveAuditEntry(ctx, &company.AuditEntry{CompanyId: 1, Method: "PUT", Path: "/companies/1", CreatedAt: time.Now().UTC()}); err != nil {
+				t.Fatalf("could not save audit entry: %s", err)
+			}
+
+			entries, err := repository.GetAuditLog(ctx, 1, company.AuditFilter{UserId: userId, Limit: 10})
+			if err != nil {
+				t.Fatalf("could not get audit log: %s", err)
+			}
+
+			if len(entries) != 2 || entries[0].Path != "/market/orders" || entries[0].Role != "viewer" {
+				t.Errorf("expected changes by the member, newest first, got %+v", entries)
+			}
+
+			if page, _ := repository.GetAuditLog(ctx, 1, company.AuditFilter{Page: 1, Limit: 2}); len(page) != 1 {
+				t.Errorf("expected %d entry on the second page, got %d", 1, len(page))
+			}
+		})
+
+		t.Run("should remove member", func(t *testing.T) {
+			if removed, err := repository.RemoveMember(ctx, 1, userId); err != nil || !removed {
+				t.Fatalf("expected member to be removed, got %t: %v", removed, err)
+			}
+
+			if member, _ := repository.GetMember(ctx, 1, userId); member != nil {
+				t.Errorf("expected no member, got %+v", member)
+			}
+		})
+	})
+
+	t.Run("should update password", func(t *testing.T) {
+		if err := repository.UpdatePassword(ctx, 1, "changed"); err != nil {
+			t.Fatalf("could not update password: %s", err)
+		}
+
+		company, _ := repository.GetById(ctx, 1)
+		if company.Pass != "changed" {
+			t.Errorf("expected password %s, got %s", "changed", company.Pass)
+		}
+	})
+
+	t.Run("should find emails of blocked and deleted companies", func(t *testing.T) {
+		for _, email := range []string{"coke@email.com", "blocked@email.com", "deleted@email.com"} {
+			if exists, err := repository.EmailExists(ctx, email); err != nil || !exists {
+				t.Errorf("expected %s to exist, got %t: %v", email, exists, err)
+			}
+		}
+
+		if exists, err := repository.EmailExists(ctx, "pepsi@email.com"); err != nil || exists {
+			t.Errorf("expected email not to exist, got %t: %v", exists, err)
+		}
+	})
+
+	t.Run("EmailTokens", func(t *testing.T) {
+		expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+		if err := repository.SaveEmailToken(ctx, &company.EmailToken{
+			CompanyId: 1,
+			Purpose:   company.PURPOSE_VERIFY,
+			Hash:      "email-hash",
+			ExpiresAt: expiresAt,
+			CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatalf("could not save email token: %s", err)
+		}
+
+		token, err := repository.GetEmailToken(ctx, "email-hash")
+		if err != nil || token == nil {
+			t.Fatalf("could not get email token: %v", err)
+		}
+
+		if token.CompanyId != 1 || token.Purpose != company.PURPOSE_VERIFY || !token.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("expected saved token, got %+v", token)
+		}
+
+		if used, err := repository.UseEmailToken(ctx, token.Id); err != nil || !used {
+			t.Fatalf("expected token to be used, got %t: %v", used, err)
+		}
+
+		if used, err := repository.UseEmailToken(ctx, token.Id); err != nil || used {
+			t.Errorf("expected token to be used only once, got %t: %v", used, err)
+		}
+
+		if err := repository.VerifyEmail(ctx, 1); err != nil {
+			t.Fatalf("could not verify email: %s", err)
+		}
+
+		verified, _ := repository.GetById(ctx, 1)
+		if verified.EmailVerifiedAt == nil {
+			t.Error("expected email to be verified")
+		}
+	})
+	t.Run("LoginAttempts", func(t *testing.T) {
+		companyId := uint64(1)
+		now := time.Now().UTC()
+		attempts := []company.LoginAttempt{
+			{Email: "coke@email.com", IP: "10.0.0.1", Success: false, CreatedAt: now.Add(-3 * time.Hour)},
+			{CompanyId: &companyId, Email: "coke@email.com", IP: "10.0.0.1", Success: true, CreatedAt: now.Add(-2 * time.Hour)},
+			{CompanyId: &companyId, Email: "coke@email.com", IP: "10.0.0.2", UserAgent: "curl", CreatedAt: now.Add(-time.Hour)},
+			{Email: "coke@email.com", IP: "10.0.0.2", Locked: true, CreatedAt: now.Add(-time.Minute)},
+			{Email: "pepsi@email.com", IP: "10.0.0.1", CreatedAt: now.Add(-time.Minute)},
+		}
+
+		for i := range attempts {
+			if err := repository.SaveLoginAttempt(ctx, &attempts[i]); err != nil {
+				t.Fatalf("could not save login attempt: %s", err)
+			}
+		}
+
+		t.Run("should count failures after the last success by email", func(t *testing.T) {
+			failures, err := repository.GetFailedLoginsByEmail(ctx, "coke@email.com", now.Add(-24*time.Hour))
+			if err != nil {
+				t.Fatalf("could not get failures: %s", err)
+			}
+
+			if len(failures) != 1 || !failures[0].Equal(attempts[2].CreatedAt) {
+				t.Errorf("expected the failure after the success, got %v", failures)
+			}
+		})
+
+		t.Run("should count failures since the given time by address", func(t *testing.T) {
+			failures, err := repository.GetFailedLoginsByIP(ctx, "10.0.0.1", now.Add(-24*time.Hour))
+			if err != nil {
+				t.Fatalf("could not get failures: %s", err)
+			}
+
+			if len(failures) != 2 || !failures[0].After(failures[1]) {
+				t.Errorf("expected both failures newest first, got %v", failures)
+			}
+
+			failures, _ = repository.GetFailedLoginsByIP(ctx, "10.0.0.1", now.Add(-time.Hour))
+			if len(failures) != 1 {
+				t.Errorf("expected only the latest failure, got %v", failures)
+			}
+		})
+
+		t.Run("should return the attempts of the company", func(t *testing.T) {
+			history, err := repository.GetLoginAttempts(ctx, 1, 10)
+			if err != nil {
+				t.Fatalf("could not get login attempts: %s", err)
+			}
+
+			if len(history) != 2 || history[0].UserAgent != "curl" || history[1].Success != true {
+				t.Errorf("expected both attempts of the company newest first, got %+v", history)
+			}
+		})
+
+		t.Run("should update last login", func(t *testing.T) {
+			at := now.Truncate(time.Second)
+			if err := repository.UpdateLastLogin(ctx, 1, at); err != nil {
+				t.Fatalf("could not update last login: %s", err)
+			}
+
+			company, _ := repository.GetById(ctx, 1)
+			if company.LastLogin == nil || !company.LastLogin.Equal(at) {
+				t.Errorf("expected last login %s, got %v", at, company.LastLogin)
+			}
+		})
+	})
+
+	t.Run("Profiles", func(t *testing.T) {
+		t.Run("should update profile", func(t *testing.T) {
+			updated, err := repository.UpdateProfile(ctx, 1, &company.ProfileUpdate{
+				Name:        "Coca-Cola",
+				LogoUrl:     "https://example.com/coke.png",
+				Description: "Soft drinks",
+			})
+			if err != nil || !updated {
+				t.Fatalf("expected profile to be updated, got %t %v", updated, err)
+			}
+
+			if updated, _ := repository.UpdateProfile(ctx, 2, &company.ProfileUpdate{Name: "Unblocked"}); updated {
+				t.Error("expected blocked company not to be updated")
+			}
+		})
+
+		t.Run("should return profile without account data", func(t *testing.T) {
+			profile, err := repository.GetProfile(ctx, 1)
+			if err != nil {
+				t.Fatalf("could not get profile: %s", err)
+			}
+
+			if profile == nil || profile.LogoUrl != "https://example.com/coke.png" || profile.Description != "Soft drinks" {
+				t.Fatalf("expected updated profile, got %+v", profile)
+			}
+
+			if profile.FoundedAt.IsZero() || profile.OpenOrders != 0 {
+				t.Errorf("expected founding date and no open orders, got %+v", profile)
+			}
+
+			if profile, _ := repository.GetProfile(ctx, 3); profile != nil {
+				t.Errorf("expected no profile for deleted company, got %+v", profile)
+			}
+		})
+
+		t.Run("should search directory by name", func(t *testing.T) {
+			profiles, err := repository.GetProfiles(ctx, company.DirectoryFilter{Search: "coca", Limit: 10})
+			if err != nil {
+				t.Fatalf("could not get profiles: %s", err)
+			}
+
+			if len(profiles) != 1 || profiles[0].Id != 1 {
+				t.Errorf("expected only Coca-Cola, got %+v", profiles)
+			}
+
+			profiles, _ = repository.GetProfiles(ctx, company.DirectoryFilter{Search: "blocked", Limit: 10})
+			if len(profiles) != 0 {
+				t.Errorf("expected blocked company not to be listed, got %+v", profiles)
+			}
+		})
 	})
 }
@@ -3,6 +3,8 @@ package company
 import (
 	"api/auth"
 	"api/server"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -12,24 +14,97 @@ import (
 func CreateEndpoints(e *echo.Echo, service Service) *echo.Group {
 	group := e.Group("/companies")
 
-	group.GET("/:id", func(c echo.Context) error {
+	directory := group.GET("", func(c echo.Context) error {
+		page, err := strconv.ParseUint(c.QueryParam("page"), 10, 64)
+		if err != nil || page == 0 {
+			page = 1
+		}
+
+		limit, err := strconv.ParseUint(c.QueryParam("limit"), 10, 64)
+		if err != nil {
+			limit = DIRECTORY_PAGE_SIZE
+		}
+
+		profiles, err := service.GetDirectory(c.Request().Context(), DirectoryFilter{
+			Search: c.QueryParam("search"),
+			Page:   uint(page - 1),
+			Limit:  uint(limit),
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, profiles)
+	})
+
+	// Owners get their account, everyone else the public profile
+	get := group.GET("/:id", func(c echo.Context) error {
 		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
 
-		company, err := service.GetById(c.Request().Context(), companyId)
+		if authenticated, err := auth.ParseToken(c.Get("user")); err == nil && authenticated == companyId {
+			company, err := service.GetById(c.Request().Context(), companyId)
+			if err != nil {
+				return err
+			}
+
+			if company == nil {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+
+			return c.JSON(http.StatusOK, company)
+		}
+
+		profile, err := service.GetProfile(c.Request().Context(), companyId)
 		if err != nil {
 			return err
 		}
 
-		if company == nil {
+		if profile == nil {
 			return echo.NewHTTPError(http.StatusNotFound)
 		}
 
-		return c.JSON(http.StatusOK, company)
+		return c.JSON(http.StatusOK, profile)
 	})
 
+	update := group.PUT("/:id", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		profileUpdate := new(ProfileUpdate)
+		if err := c.Bind(profileUpdate); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(profileUpdate); err != nil {
+			return err
+		}
+
+		profile, err := service.UpdateProfile(c.Request().Context(), companyId, profileUpdate)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, profile)
+	}, server.RequireOwner(":id"))
+
+	sessions := group.GET("/:id/sessions", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		attempts, err := service.GetLoginAttempts(c.Request().Context(), companyId)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, attempts)
+	}, server.RequireOwner(":id"))
+
 	group.POST("/register", func(c echo.Context) error {
 		registration := new(Registration)
 		if err := c.Bind(registration); err != nil {
@@ -58,18 +133,162 @@ func CreateEndpoints(e *echo.Echo, service Service) *echo.Group {
 			return err
 		}
 
-		token, err := service.Login(c.Request().Context(), credentials)
-		if err != nil {
+		credentials.IP = c.RealIP()
+		credentials.UserAgent = c.Request().UserAgent()
+
+		tokens, err := service.Login(c.Request().Context(), credentials)
+
+		var locked LockedError
+		if errors.As(err, &locked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			return echo.NewHTTPError(http.StatusTooManyRequests, locked.Error())
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
 			return echo.NewHTTPError(http.StatusBadRequest, server.ValidationErrors{
 				Errors: map[string]string{"email": err.Error()},
 			})
 		}
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, tokens)
+	})
+
+	group.POST("/token/refresh", func(c echo.Context) error {
+		var refresh Refresh
+
+		if err := c.Bind(&refresh); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if err := c.Validate(&refresh); err != nil {
+			return err
+		}
+
+		tokens, err := service.Refresh(c.Request().Context(), refresh.RefreshToken)
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		if err != nil {
+			return err
+		}
 
-		return c.JSON(http.StatusOK, map[string]string{"token": token})
+		return c.JSON(http.StatusOK, tokens)
 	})
 
-	group.POST("/terrains/:position", func(c echo.Context) error {
-		position, err := strconv.ParseInt(c.Param("position"), 10, 64)
+	logout := group.POST("/logout", func(c echo.Context) error {
+		sessionId, err := auth.ParseSessionId(c.Get("user"))
+		if err != nil {
+			return err
+		}
+
+		if err := service.Logout(c.Request().Context(), sessionId); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	group.PUT("/password", func(c echo.Context) error {
+		change := new(PasswordChange)
+		if err := c.Bind(change); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(change); err != nil {
+			return err
+		}
+
+		companyId, err := auth.ParseToken(c.Get("user"))
+		if err != nil {
+			return err
+		}
+
+		tokens, err := service.ChangePassword(c.Request().Context(), companyId, change)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, tokens)
+	})
+
+	group.POST("/email/verify", func(c echo.Context) error {
+		verification := new(EmailVerification)
+		if err := c.Bind(verification); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(verification); err != nil {
+			return err
+		}
+
+		if err := service.VerifyEmail(c.Request().Context(), verification.Token); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	group.POST("/email/verification", func(c echo.Context) error {
+		companyId, err := auth.ParseToken(c.Get("user"))
+		if err != nil {
+			return err
+		}
+
+		if err := service.ResendVerification(c.Request().Context(), companyId); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	group.POST("/password/forgot", func(c echo.Context) error {
+		forgot := new(ForgotPassword)
+		if err := c.Bind(forgot); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(forgot); err != nil {
+			return err
+		}
+
+		if err := service.ForgotPassword(c.Request().Context(), forgot.Email); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	group.POST("/password/reset", func(c echo.Context) error {
+		reset := new(PasswordReset)
+		if err := c.Bind(reset); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(reset); err != nil {
+			return err
+		}
+
+		if err := service.ResetPassword(c.Request().Context(), reset); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	terrains := group.GET("/:id/terrains", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		plots, err := service.GetTerrains(c.Request().Context(), companyId)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, plots)
+	}, server.RequireOwner(":id"))
+
+	purchase := group.POST("/terrains/:position", func(c echo.Context) error {
+		position, err := strconv.ParseUint(c.Param("position"), 10, 8)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
 		}
@@ -79,7 +298,7 @@ func CreateEndpoints(e *echo.Echo, service Service) *echo.Group {
 			return err
 		}
 
-		err = service.PurchaseTerrain(c.Request().Context(), companyId, int(position))
+		err = service.PurchaseTerrain(c.Request().Context(), companyId, uint8(position))
 		if err != nil {
 			return err
 		}
@@ -87,5 +306,256 @@ func CreateEndpoints(e *echo.Echo, service Service) *echo.Group {
 		return c.JSON(http.StatusNoContent, nil)
 	})
 
+	sell := group.DELETE("/terrains/:position", func(c echo.Context) error {
+		position, err := strconv.ParseUint(c.Param("position"), 10, 8)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest)
+		}
+
+		companyId, err := auth.ParseToken(c.Get("user"))
+		if err != nil {
+			return err
+		}
+
+		if err := service.SellTerrain(c.Request().Context(), companyId, uint8(position)); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	transfer := group.POST("/terrains/:position/transfer", func(c echo.Context) error {
+		position, err := strconv.ParseUint(c.Param("position"), 10, 8)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest)
+		}
+
+		terrainTransfer := new(TerrainTransfer)
+		if err := c.Bind(terrainTransfer); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(terrainTransfer); err != nil {
+			return err
+		}
+
+		companyId, err := auth.ParseToken(c.Get("user"))
+		if err != nil {
+			return err
+		}
+
+		terrain, err := service.TransferTerrain(c.Request().Context(), companyId, uint8(position), terrainTransfer)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, terrain)
+	})
+
+	members := group.GET("/:id/members", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		list, err := service.GetMembers(c.Request().Context(), companyId)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, list)
+	}, server.RequireOwner(":id"))
+
+	changeRole := group.PUT("/:id/members/:userId", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		userId, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		change := new(RoleChange)
+		if err := c.Bind(change); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(change); err != nil {
+			return err
+		}
+
+		member, err := service.UpdateMemberRole(c.Request().Context(), companyId, userId, change)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, member)
+	}, server.RequireOwner(":id"))
+
+	remove := group.DELETE("/:id/members/:userId", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		userId, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if err := service.RemoveMember(c.Request().Context(), companyId, userId); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}, server.RequireOwner(":id"))
+
+	invite := group.POST("/:id/invitations", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		request := new(InvitationRequest)
+		if err := c.Bind(request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(request); err != nil {
+			return err
+		}
+
+		claims, err := auth.ParseClaims(c.Get("user"))
+		if err != nil {
+			return err
+		}
+
+		invitation, err := service.InviteMember(c.Request().Context(), companyId, claims.UserId, request)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, invitation)
+	}, server.RequireOwner(":id"))
+
+	invitations := group.GET("/:id/invitations", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		pending, err := service.GetInvitations(c.Request().Context(), companyId)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, pending)
+	}, server.RequireOwner(":id"))
+
+	revoke := group.DELETE("/:id/invitations/:invitationId", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		invitationId, err := strconv.ParseUint(c.Param("invitationId"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if err := service.RevokeInvitation(c.Request().Context(), companyId, invitationId); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}, server.RequireOwner(":id"))
+
+	audit := group.GET("/:id/audit", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		page, err := strconv.ParseUint(c.QueryParam("page"), 10, 64)
+		if err != nil || page == 0 {
+			page = 1
+		}
+
+		limit, err := strconv.ParseUint(c.QueryParam("limit"), 10, 64)
+		if err != nil {
+			limit = AUDIT_LOG_PAGE_SIZE
+		}
+
+		// Every member when it's left out
+		userId, _ := strconv.ParseUint(c.QueryParam("user_id"), 10, 64)
+
+		entries, err := service.GetAuditLog(c.Request().Context(), companyId, AuditFilter{
+			UserId: userId,
+			Page:   uint(page - 1),
+			Limit:  uint(limit),
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, entries)
+	}, server.RequireOwner(":id"))
+
+	users := e.Group("/users")
+
+	users.POST("/login", func(c echo.Context) error {
+		var credentials MemberCredentials
+
+		if err := c.Bind(&credentials); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if err := c.Validate(&credentials); err != nil {
+			return err
+		}
+
+		credentials.IP = c.RealIP()
+		credentials.UserAgent = c.Request().UserAgent()
+
+		tokens, err := service.LoginMember(c.Request().Context(), credentials)
+
+		var locked LockedError
+		if errors.As(err, &locked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			return echo.NewHTTPError(http.StatusTooManyRequests, locked.Error())
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
+			return echo.NewHTTPError(http.StatusBadRequest, server.ValidationErrors{
+				Errors: map[string]string{"email": err.Error()},
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, tokens)
+	})
+
+	users.POST("/invitations/accept", func(c echo.Context) error {
+		acceptance := new(InvitationAcceptance)
+		if err := c.Bind(acceptance); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := c.Validate(acceptance); err != nil {
+			return err
+		}
+
+		member, err := service.AcceptInvitation(c.Request().Context(), acceptance)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, member)
+	})
+
+	server.AllowMembers("", directory, get, logout)
+	server.AllowMembers(auth.SCOPE_COMPANY_READ, terrains, members)
+	server.AllowMembers(auth.SCOPE_COMPANY_WRITE, update, purchase, sell, transfer)
+	server.AllowMembers(auth.SCOPE_MEMBERS_MANAGE, sessions, changeRole, remove, invite, invitations, revoke, audit)
+
 	return group
 }
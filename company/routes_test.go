@@ -2,25 +2,32 @@ package company_test
 
 import (
 	"api/auth"
+	"api/clock"
 	"api/company"
+	"api/database"
+	"api/mail"
 	"api/server"
+	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/labstack/echo/v4"
 )
 
 func TestCompanyRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
 	token, err := auth.GenerateToken(1, "secret")
 	if err != nil {
 		t.Fatalf("could not generate jwt token: %s", err)
 	}
 
-	svr := server.NewServer()
-	svc := company.NewService(company.NewFakeRepository())
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
+	svc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 
 	company.CreateEndpoints(svr, svc)
 
@@ -208,6 +215,10 @@ func TestCompanyRoutes(t *testing.T) {
 		if _, ok := response["token"]; !ok {
 			t.Error("expected token")
 		}
+
+		if _, ok := response["refresh_token"]; !ok {
+			t.Error("expected refresh token")
+		}
 	})
 
 	t.Run("PurchaseTerrain", func(t *testing.T) {
@@ -242,7 +253,12 @@ func TestCompanyRoutes(t *testing.T) {
 				t.Fatalf("could not generate jwt token: %s", err)
 			}
 
-			req := httptest.NewRequest("POST", "/companies/terrains/1", nil)
+			// Companies start with the first plots, so one is sold first
+			req := httptest.NewRequest("DELETE", "/companies/terrains/1", nil)
+			req.Header.Set("Authorization", "Bearer "+newToken)
+			svr.ServeHTTP(httptest.NewRecorder(), req)
+
+			req = httptest.NewRequest("POST", "/companies/terrains/1", nil)
 			req.Header.Set("Authorization", "Bearer "+newToken)
 			req.Header.Set("Accept", "application/json")
 
@@ -255,3 +271,660 @@ func TestCompanyRoutes(t *testing.T) {
 		})
 	})
 }
+
+func TestTerrainRoutes(t *testing.T) {
+	svc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
+
+	company.CreateEndpoints(svr, svc)
+
+	token, err := auth.GenerateToken(3, "secret")
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
+	}
+
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should return the map to owners only", func(t *testing.T) {
+		rec := request("GET", "/companies/3/terrains", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var terrains []company.Terrain
+		if err := json.Unmarshal(rec.Body.Bytes(), &terrains); err != nil {
+			t.Fatalf("could not parse response: %s", err)
+		}
+
+		if len(terrains) != company.STARTING_TERRAINS {
+			t.Errorf("expected %d terrains, got %+v", company.STARTING_TERRAINS, terrains)
+		}
+
+		if rec := request("GET", "/companies/1/terrains", ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("should sell terrain", func(t *testing.T) {
+		if rec := request("DELETE", "/companies/terrains/3", ""); rec.Code != http.StatusNoContent {
+			t.Errorf("expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+		}
+
+		if rec := request("DELETE", "/companies/terrains/3", ""); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("should transfer terrain", func(t *testing.T) {
+		if rec := request("POST", "/companies/terrains/2/transfer", `{}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+		}
+
+		rec := request("POST", "/companies/terrains/2/transfer", `{"company_id":1}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var terrain company.Terrain
+		if err := json.Unmarshal(rec.Body.Bytes(), &terrain); err != nil {
+			t.Fatalf("could not parse response: %s", err)
+		}
+
+		if terrain.Position != company.STARTING_TERRAINS+1 {
+			t.Errorf("expected terrain at position %d, got %d", company.STARTING_TERRAINS+1, terrain.Position)
+		}
+	})
+}
+
+func TestSessionRoutes(t *testing.T) {
+	svc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	svr := server.NewServer(server.Config{JwtSecret: "secret", IsRevoked: svc.IsRevoked})
+
+	company.CreateEndpoints(svr, svc)
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	login := func(t *testing.T, password string) *company.Tokens {
+		rec := send("POST", "/companies/login", "", `{"email":"admin@test.com","password":"`+password+`"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("could not login: %d %s", rec.Code, rec.Body.String())
+		}
+
+		tokens := new(company.Tokens)
+		if err := json.Unmarshal(rec.Body.Bytes(), tokens); err != nil {
+			t.Fatalf("could not parse tokens: %s", err)
+		}
+		return tokens
+	}
+
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		return send("POST", "/companies/token/refresh", "", `{"refresh_token":"`+refreshToken+`"}`)
+	}
+
+	t.Run("should rotate refresh tokens", func(t *testing.T) {
+		tokens := login(t, "password")
+
+		rec := refresh(tokens.RefreshToken)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		rotated := new(company.Tokens)
+		if err := json.Unmarshal(rec.Body.Bytes(), rotated); err != nil {
+			t.Fatalf("could not parse tokens: %s", err)
+		}
+
+		if rotated.RefreshToken == tokens.RefreshToken {
+			t.Error("expected a new refresh token")
+		}
+
+		if rec := send("GET", "/companies/1", rotated.Token, ""); rec.Code != http.StatusOK {
+			t.Errorf("expected refreshed token to be accepted, got %d", rec.Code)
+		}
+	})
+
+	t.Run("should revoke the session when a refresh token is reused", func(t *testing.T) {
+		tokens := login(t, "password")
+
+		rec := refresh(tokens.RefreshToken)
+		rotated := new(company.Tokens)
+		json.Unmarshal(rec.Body.Bytes(), rotated)
+
+		if rec := refresh(tokens.RefreshToken); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d reusing refresh token, got %d", http.StatusUnauthorized, rec.Code)
+		}
+
+		if rec := refresh(rotated.RefreshToken); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d after session was revoked, got %d", http.StatusUnauthorized, rec.Code)
+		}
+
+		if rec := send("GET", "/companies/1", rotated.Token, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected access token to be revoked, got %d", rec.Code)
+		}
+	})
+
+	t.Run("should reject unknown refresh tokens", func(t *testing.T) {
+		if rec := refresh("unknown"); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("should revoke tokens on logout", func(t *testing.T) {
+		tokens := login(t, "password")
+
+		if rec := send("POST", "/companies/logout", tokens.Token, ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		if rec := send("GET", "/companies/1", tokens.Token, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected access token to be revoked, got %d", rec.Code)
+		}
+
+		if rec := refresh(tokens.RefreshToken); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected refresh token to be revoked, got %d", rec.Code)
+		}
+	})
+
+	t.Run("should revoke every session when the password changes", func(t *testing.T) {
+		other := login(t, "password")
+		current := login(t, "password")
+
+		body := `{"current_password":"wrong","password":"changed","confirm_password":"changed"}`
+		if rec := send("PUT", "/companies/password", current.Token, body); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d with wrong password, got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+
+		body = `{"current_password":"password","password":"changed","confirm_password":"changed"}`
+		rec := send("PUT", "/companies/password", current.Token, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		changed := new(company.Tokens)
+		if err := json.Unmarshal(rec.Body.Bytes(), changed); err != nil {
+			t.Fatalf("could not parse tokens: %s", err)
+		}
+
+		for _, revoked := range []string{other.Token, current.Token} {
+			if rec := send("GET", "/companies/1", revoked, ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected previous session to be revoked, got %d", rec.Code)
+			}
+		}
+
+		if rec := send("GET", "/companies/1", changed.Token, ""); rec.Code != http.StatusOK {
+			t.Errorf("expected new session to be accepted, got %d", rec.Code)
+		}
+
+		login(t, "changed")
+	})
+}
+
+func TestEmailRoutes(t *testing.T) {
+	outbox := mail.NewOutbox()
+	svc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, outbox, log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	svr := server.NewServer(server.Config{JwtSecret: "secret", IsRevoked: svc.IsRevoked})
+
+	company.CreateEndpoints(svr, svc)
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Tokens are mailed on a line of their own
+	tokenPattern := regexp.MustCompile(`(?m)^[A-Za-z0-9_-]{43}$`)
+	mailedToken := func(t *testing.T, to string) string {
+		message := outbox.Last(to)
+		if message == nil {
+			t.Fatalf("expected an email to %s", to)
+		}
+
+		token := tokenPattern.FindString(message.Body)
+		if token == "" {
+			t.Fatalf("expected a token in %q", message.Body)
+		}
+		return token
+	}
+
+	t.Run("should verify the email of new companies", func(t *testing.T) {
+		body := `{"name":"Pepsi","email":"pepsi@test.com","password":"password","confirm_password":"password"}`
+		rec := send("POST", "/companies/register", "", body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d", http.StatusCreated, rec.Code)
+		}
+
+		registered := new(company.Company)
+		json.Unmarshal(rec.Body.Bytes(), registered)
+		if registered.EmailVerifiedAt != nil {
+			t.Error("expected email not to be verified yet")
+		}
+
+		token := mailedToken(t, "pepsi@test.com")
+
+		if rec := send("POST", "/companies/email/verify", "", `{"token":"`+token+`"}`); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		if rec := send("POST", "/companies/email/verify", "", `{"token":"`+token+`"}`); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected token to be used once, got %d", rec.Code)
+		}
+
+		verified, _ := svc.GetById(context.Background(), registered.Id)
+		if verified.EmailVerifiedAt == nil {
+			t.Error("expected email to be verified")
+		}
+	})
+
+	t.Run("should not register an email twice", func(t *testing.T) {
+		body := `{"name":"Other","email":"admin@test.com","password":"password","confirm_password":"password"}`
+		if rec := send("POST", "/companies/register", "", body); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("should resend verification", func(t *testing.T) {
+		token, err := auth.GenerateToken(2, "secret")
+		if err != nil {
+			t.Fatalf("could not generate jwt token: %s", err)
+		}
+
+		if rec := send("POST", "/companies/email/verification", token, ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		verification := mailedToken(t, "admin@test2.com")
+		if rec := send("POST", "/companies/email/verify", "", `{"token":"`+verification+`"}`); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		if rec := send("POST", "/companies/email/verification", token, ""); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected verified email not to be resent, got %d", rec.Code)
+		}
+	})
+
+	t.Run("should not tell whether an email is registered", func(t *testing.T) {
+		sent := len(outbox.Messages())
+
+		if rec := send("POST", "/companies/password/forgot", "", `{"email":"nobody@test.com"}`); rec.Code != http.StatusNoContent {
+			t.Errorf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		if len(outbox.Messages()) != sent {
+			t.Error("expected no email to be sent")
+		}
+	})
+
+	t.Run("should reset forgotten passwords", func(t *testing.T) {
+		rec := send("POST", "/companies/login", "", `{"email":"admin@test.com","password":"password"}`)
+		tokens := new(company.Tokens)
+		json.Unmarshal(rec.Body.Bytes(), tokens)
+
+		if rec := send("POST", "/companies/password/forgot", "", `{"email":"admin@test.com"}`); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		reset := mailedToken(t, "admin@test.com")
+
+		if rec := send("POST", "/companies/email/verify", "", `{"token":"`+reset+`"}`); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected reset token not to verify emails, got %d", rec.Code)
+		}
+
+		body := `{"token":"` + reset + `","password":"reset","confirm_password":"reset"}`
+		if rec := send("POST", "/companies/password/reset", "", body); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		if rec := send("POST", "/companies/password/reset", "", body); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected reset token to be used once, got %d", rec.Code)
+		}
+
+		if rec := send("GET", "/companies/1", tokens.Token, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected sessions to be revoked, got %d", rec.Code)
+		}
+
+		if rec := send("POST", "/companies/login", "", `{"email":"admin@test.com","password":"reset"}`); rec.Code != http.StatusOK {
+			t.Errorf("expected login with the new password, got %d", rec.Code)
+		}
+	})
+}
+
+func TestLoginRoutes(t *testing.T) {
+	svc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	svr := server.NewServer(server.Config{JwtSecret: "secret", IsRevoked: svc.IsRevoked})
+
+	company.CreateEndpoints(svr, svc)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body := strings.NewReader(`{"email":"admin@test.com","password":"` + password + `"}`)
+		req := httptest.NewRequest("POST", "/companies/login", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "routes-test")
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := login("password")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("could not login: %d", rec.Code)
+	}
+
+	tokens := new(company.Tokens)
+	json.Unmarshal(rec.Body.Bytes(), tokens)
+
+	t.Run("should lock logins after repeated failures", func(t *testing.T) {
+		for i := 0; i < company.MAX_EMAIL_FAILURES; i++ {
+			if rec := login("wrong"); rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+			}
+		}
+
+		rec := login("password")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+		}
+
+		if rec.Header().Get("Retry-After") == "" {
+			t.Error("expected Retry-After header")
+		}
+	})
+
+	t.Run("should show login activity to owners", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/companies/1/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.Token)
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var attempts []company.LoginAttempt
+		if err := json.Unmarshal(rec.Body.Bytes(), &attempts); err != nil {
+			t.Fatalf("could not parse attempts: %s", err)
+		}
+
+		if len(attempts) != company.MAX_EMAIL_FAILURES+2 {
+			t.Fatalf("expected %d attempts, got %d", company.MAX_EMAIL_FAILURES+2, len(attempts))
+		}
+
+		if first := attempts[len(attempts)-1]; !first.Success || first.UserAgent != "routes-test" || first.IP == "" {
+			t.Errorf("expected first attempt to succeed from routes-test, got %+v", first)
+		}
+
+		req = httptest.NewRequest("GET", "/companies/2/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.Token)
+
+		rec = httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d for other companies, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+}
+
+func TestProfileRoutes(t *testing.T) {
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
+	svc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+
+	company.CreateEndpoints(svr, svc)
+
+	token, err := auth.GenerateToken(1, "secret")
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
+	}
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should not show the email of other companies", func(t *testing.T) {
+		rec := send("GET", "/companies/2", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		if body := rec.Body.String(); strings.Contains(body, "admin@test2.com") || strings.Contains(body, "available_cash") {
+			t.Errorf("expected public profile only, got %s", body)
+		}
+
+		if rec := send("GET", "/companies/1", ""); !strings.Contains(rec.Body.String(), "admin@test.com") {
+			t.Errorf("expected owner to get their account, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("should update own profile only", func(t *testing.T) {
+		body := `{"name":"Acme","logo_url":"https://example.com/acme.png","description":"Anvils"}`
+
+		if rec := send("PUT", "/companies/2", body); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+
+		if rec := send("PUT", "/companies/1", `{"name":"Acme","logo_url":"not a url"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+
+		rec := send("PUT", "/companies/1", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		profile := new(company.Profile)
+		if err := json.Unmarshal(rec.Body.Bytes(), profile); err != nil {
+			t.Fatalf("could not parse profile: %s", err)
+		}
+
+		if profile.Name != "Acme" || profile.Description != "Anvils" {
+			t.Errorf("expected updated profile, got %+v", profile)
+		}
+	})
+
+	t.Run("should search and paginate the directory", func(t *testing.T) {
+		var profiles []*company.Profile
+
+		rec := send("GET", "/companies?search=test", "")
+		if err := json.Unmarshal(rec.Body.Bytes(), &profiles); err != nil {
+			t.Fatalf("could not parse directory: %s", err)
+		}
+
+		if len(profiles) != 2 || profiles[0].Name != "Test 2" {
+			t.Errorf("expected both test companies by name, got %+v", profiles)
+		}
+
+		rec = send("GET", "/companies?limit=1&page=2", "")
+		if err := json.Unmarshal(rec.Body.Bytes(), &profiles); err != nil {
+			t.Fatalf("could not parse directory: %s", err)
+		}
+
+		if len(profiles) != 1 || profiles[0].Name != "Test 2" {
+			t.Errorf("expected second company by name, got %+v", profiles)
+		}
+	})
+}
+
+func TestMemberRoutes(t *testing.T) {
+	outbox := mail.NewOutbox()
+	svc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, outbox, log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	svr := server.NewServer(server.Config{JwtSecret: "secret", IsRevoked: svc.IsRevoked, RecordAction: svc.RecordAction})
+
+	company.CreateEndpoints(svr, svc)
+
+	ok := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+
+	server.AllowMembers(auth.SCOPE_MARKET_TRADE, svr.POST("/test/market", ok))
+	server.AllowMembers(auth.SCOPE_FINANCE_WRITE, svr.POST("/test/loans", ok))
+	server.AllowMembers(auth.SCOPE_FINANCE_READ, svr.GET("/test/ledger", ok))
+	svr.POST("/test/closed", ok)
+
+	owner, err := auth.GenerateToken(1, "secret")
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
+	}
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tokenPattern := regexp.MustCompile(`(?m)^[A-Za-z0-9_-]{43}$`)
+	join := func(t *testing.T, email, role string) (*company.Member, *company.Tokens) {
+		if rec := send("POST", "/companies/1/invitations", owner, `{"email":"`+email+`","role":"`+role+`"}`); rec.Code != http.StatusCreated {
+			t.Fatalf("could not invite member: %d %s", rec.Code, rec.Body.String())
+		}
+
+		token := tokenPattern.FindString(outbox.Last(email).Body)
+		rec := send("POST", "/users/invitations/accept", "", `{"token":"`+token+`","name":"Member","password":"password"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("could not accept invitation: %d %s", rec.Code, rec.Body.String())
+		}
+
+		member := new(company.Member)
+		json.Unmarshal(rec.Body.Bytes(), member)
+
+		rec = send("POST", "/users/login", "", `{"email":"`+email+`","password":"password"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("could not login: %d %s", rec.Code, rec.Body.String())
+		}
+
+		tokens := new(company.Tokens)
+		json.Unmarshal(rec.Body.Bytes(), tokens)
+		return member, tokens
+	}
+
+	trader, traderTokens := join(t, "trader@test.com", auth.MEMBER_TRADER)
+	_, accountantTokens := join(t, "accountant@test.com", auth.MEMBER_ACCOUNTANT)
+
+	t.Run("should validate invitations", func(t *testing.T) {
+		if rec := send("POST", "/companies/1/invitations", owner, `{"email":"someone@test.com","role":"boss"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should let traders trade but not borrow", func(t *testing.T) {
+		if rec := send("POST", "/test/market", traderTokens.Token, ""); rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		if rec := send("POST", "/test/loans", traderTokens.Token, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("should let accountants read the ledger only", func(t *testing.T) {
+		if rec := send("GET", "/test/ledger", accountantTokens.Token, ""); rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		for _, path := range []string{"/test/market", "/test/loans"} {
+			if rec := send("POST", path, accountantTokens.Token, ""); rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d on %s, got %d", http.StatusForbidden, path, rec.Code)
+			}
+		}
+	})
+
+	t.Run("should refuse members endpoints not open to them", func(t *testing.T) {
+		if rec := send("POST", "/test/closed", traderTokens.Token, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+
+		if rec := send("POST", "/companies/1/invitations", traderTokens.Token, `{"email":"friend@test.com","role":"owner"}`); rec.Code != http.StatusForbidden {
+			t.Errorf("expected traders not to invite members, got %d", rec.Code)
+		}
+
+		if rec := send("POST", "/test/closed", owner, ""); rec.Code != http.StatusOK {
+			t.Errorf("expected owners to call every endpoint, got %d", rec.Code)
+		}
+	})
+
+	t.Run("should record which member made each change", func(t *testing.T) {
+		rec := send("GET", "/companies/1/audit", owner, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var entries []company.AuditEntry
+		if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("could not parse audit log: %s", err)
+		}
+
+		var traded bool
+		for _, entry := range entries {
+			if entry.Path == "/test/loans" || entry.Path == "/test/ledger" {
+				t.Errorf("expected only successful changes to be recorded, got %+v", entry)
+			}
+			if entry.Path == "/test/market" && entry.UserId != nil && *entry.UserId == trader.UserId {
+				traded = entry.Role == auth.MEMBER_TRADER
+			}
+		}
+
+		if !traded {
+			t.Errorf("expected trade by the trader to be recorded, got %+v", entries)
+		}
+	})
+
+	t.Run("should revoke tokens of removed members", func(t *testing.T) {
+		if rec := send("DELETE", "/companies/1/members/"+strconv.FormatUint(trader.UserId, 10), owner, ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		if rec := send("POST", "/test/market", traderTokens.Token, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+
+		rec := send("GET", "/companies/1/members", owner, "")
+
+		var members []company.Member
+		json.Unmarshal(rec.Body.Bytes(), &members)
+		if len(members) != 1 || members[0].Role != auth.MEMBER_ACCOUNTANT {
+			t.Errorf("expected the accountant to be left, got %+v", members)
+		}
+	})
+}
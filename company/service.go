@@ -2,16 +2,26 @@ package company
 
 import (
 	"api/auth"
+	"api/clock"
+	"api/database"
+	"api/mail"
 	"api/server"
 	"context"
 	"errors"
+	"log"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type (
 	Credentials struct {
 		Email string `form:"email" json:"email" validate:"required,email"`
 		Pass  string `form:"password" json:"password" validate:"required"`
+
+		// Where the login comes from, set from the request
+		IP        string `form:"-" json:"-"`
+		UserAgent string `form:"-" json:"-"`
 	}
 
 	Registration struct {
@@ -19,6 +29,9 @@ type (
 		Email    string `json:"email" validate:"required,email"`
 		Password string `json:"password" validate:"required"`
 		Confirm  string `json:"confirm_password" validate:"required,eqfield=Password"`
+
+		// Only operators can create admins, so it's never bound from requests
+		Admin bool `json:"-"`
 	}
 
 	Company struct {
@@ -27,6 +40,9 @@ type (
 		Email             string     `db:"email" json:"email"`
 		Pass              string     `db:"password" json:"-"`
 		Admin             bool       `db:"is_admin" json:"-"`
+		LogoUrl           string     `db:"logo_url" json:"logo_url"`
+		Description       string     `db:"description" json:"description"`
+		EmailVerifiedAt   *time.Time `db:"email_verified_at" json:"email_verified_at"`
 		LastLogin         *time.Time `db:"last_login" json:"last_login"`
 		CreatedAt         time.Time  `db:"created_at" json:"created_at"`
 		AvailableCash     int        `db:"cash" json:"available_cash"`
@@ -35,83 +51,149 @@ type (
 
 	Service interface {
 		GetById(ctx context.Context, id uint64) (*Company, error)
+
+		// Reports whether the company has at least amount in cash. Debits
+		// must be checked with it in the same unit of work: it locks the
+		// balance so concurrent ones can't spend the same cash twice.
+		HoldCash(ctx context.Context, companyId uint64, amount int) (bool, error)
 		GetByEmail(ctx context.Context, email string) (*Company, error)
-		Login(ctx context.Context, credentials Credentials) (string, error)
+		GetProfile(ctx context.Context, companyId uint64) (*Profile, error)
+		UpdateProfile(ctx context.Context, companyId uint64, update *ProfileUpdate) (*Profile, error)
+		GetDirectory(ctx context.Context, filter DirectoryFilter) ([]*Profile, error)
+		Login(ctx context.Context, credentials Credentials) (*Tokens, error)
+		GetLoginAttempts(ctx context.Context, companyId uint64) ([]*LoginAttempt, error)
+		Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+		Logout(ctx context.Context, sessionId uint64) error
+		ChangePassword(ctx context.Context, companyId uint64, change *PasswordChange) (*Tokens, error)
+		RevokeSessions(ctx context.Context, companyId uint64) error
+		IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims) (bool, error)
 		Register(ctx context.Context, registration *Registration) (*Company, error)
-		PurchaseTerrain(ctx context.Context, companyId uint64, position int) error
+		ResendVerification(ctx context.Context, companyId uint64) error
+		VerifyEmail(ctx context.Context, token string) error
+		ForgotPassword(ctx context.Context, email string) error
+		ResetPassword(ctx context.Context, reset *PasswordReset) error
+		GetTerrains(ctx context.Context, companyId uint64) ([]*Terrain, error)
+		GetTerrain(ctx context.Context, companyId uint64, position uint8) (*Terrain, error)
+		PurchaseTerrain(ctx context.Context, companyId uint64, position uint8) error
+		SellTerrain(ctx context.Context, companyId uint64, position uint8) error
+		TransferTerrain(ctx context.Context, companyId uint64, position uint8, transfer *TerrainTransfer) (*Terrain, error)
+		LoginMember(ctx context.Context, credentials MemberCredentials) (*Tokens, error)
+		InviteMember(ctx context.Context, companyId, inviterId uint64, request *InvitationRequest) (*Invitation, error)
+		GetInvitations(ctx context.Context, companyId uint64) ([]*Invitation, error)
+		RevokeInvitation(ctx context.Context, companyId, invitationId uint64) error
+		AcceptInvitation(ctx context.Context, acceptance *InvitationAcceptance) (*Member, error)
+		GetMembers(ctx context.Context, companyId uint64) ([]*Member, error)
+		UpdateMemberRole(ctx context.Context, companyId, userId uint64, change *RoleChange) (*Member, error)
+		RemoveMember(ctx context.Context, companyId, userId uint64) error
+		RecordAction(ctx context.Context, claims *auth.Claims, method, path string)
+		GetAuditLog(ctx context.Context, companyId uint64, filter AuditFilter) ([]*AuditEntry, error)
+		GetCreditScore(company *Company) int64
+		TerrainValue(position int8) int64
+	}
+
+	// TerrainPricing is how much terrains are worth. Every terrain costs
+	// the base value plus the position value for each position, and every
+	// five terrains the unit value is added on top.
+	TerrainPricing struct {
+		BaseValue     int64 `json:"base_value"`
+		UnitValue     int64 `json:"unit_value"`
+		PositionValue int64 `json:"position_value"`
 	}
 
 	service struct {
 		repository Repository
+		jwtSecret  string
+		terrains   TerrainPricing
+		mailer     mail.Mailer
+		logger     *log.Logger
+		uow        database.UnitOfWork
+		clock      clock.Clock
+		gameClock  clock.Clock
 	}
 )
 
-const (
-	TERRAIN_BASE_VALUE     = 1_000_000_00
-	TERRAIN_UNIT_VALUE     = 500_000_00
-	TERRAIN_POSITION_VALUE = 100_000_00
-)
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+var DEFAULT_TERRAIN_PRICING = TerrainPricing{
+	BaseValue:     1_000_000_00,
+	UnitValue:     500_000_00,
+	PositionValue: 100_000_00,
+}
 
 func (c *Company) IsAdmin() bool {
 	return c.Admin
 }
 
-func (c *Company) GetCreditScore() int64 {
-	return c.TerrainValue(c.AvailableTerrains)
+// Returns the roles tokens issued for the company carry
+func (c *Company) Roles() []string {
+	if c.IsAdmin() {
+		return []string{auth.ROLE_ADMIN}
+	}
+	return nil
+}
+
+// Creates the service. Sessions and lockouts run on clock, which should
+// follow real time, while terrains age on gameClock.
+func NewService(repository Repository, jwtSecret string, terrains TerrainPricing, mailer mail.Mailer, logger *log.Logger, uow database.UnitOfWork, clock clock.Clock, gameClock clock.Clock) Service {
+	return &service{repository, jwtSecret, terrains, mailer, logger, uow, clock, gameClock}
 }
 
-func (c *Company) TerrainValue(position int8) int64 {
-	return int64(TERRAIN_BASE_VALUE + TERRAIN_UNIT_VALUE*((int(position)-1)/5) + (TERRAIN_POSITION_VALUE * int(position)))
+func (s *service) GetCreditScore(company *Company) int64 {
+	return s.TerrainValue(company.AvailableTerrains)
 }
 
-func NewService(repository Repository) Service {
-	return &service{repository}
+func (s *service) TerrainValue(position int8) int64 {
+	return s.terrains.BaseValue + s.terrains.UnitValue*int64((int(position)-1)/5) + s.terrains.PositionValue*int64(position)
 }
 
 func (s *service) GetById(ctx context.Context, id uint64) (*Company, error) {
 	return s.repository.GetById(ctx, id)
 }
 
+func (s *service) HoldCash(ctx context.Context, companyId uint64, amount int) (bool, error) {
+	return s.repository.HoldCash(ctx, companyId, amount)
+}
+
 func (s *service) GetByEmail(ctx context.Context, email string) (*Company, error) {
 	return s.repository.GetByEmail(ctx, email)
 }
 
-func (s *service) PurchaseTerrain(ctx context.Context, companyId uint64, position int) error {
-	company, err := s.repository.GetById(ctx, companyId)
+// Logs the company in, recording the attempt. Logins are refused with a
+// LockedError after too many failures for the email or from the address.
+func (s *service) Login(ctx context.Context, credentials Credentials) (*Tokens, error) {
+	company, err := s.GetByEmail(ctx, credentials.Email)
 	if err != nil {
-		return err
-	}
-
-	if company == nil {
-		return server.NewBusinessRuleError("company not found")
+		return nil, err
 	}
 
-	total := (TERRAIN_BASE_VALUE + (TERRAIN_UNIT_VALUE * (int(company.AvailableTerrains) / 5)) + (TERRAIN_POSITION_VALUE * position))
-	if company.AvailableCash < total {
-		return server.NewBusinessRuleError("not enough cash")
+	retryAfter, err := s.checkLockout(ctx, credentials.Email, credentials.IP)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.repository.PurchaseTerrain(ctx, total, companyId)
-}
-
-func (s *service) Login(ctx context.Context, credentials Credentials) (string, error) {
-	company, err := s.GetByEmail(ctx, credentials.Email)
-	if err != nil || company == nil {
-		return "", errors.New("invalid credentials")
+	if retryAfter > 0 {
+		if err := s.recordLogin(ctx, credentials, company, false, true); err != nil {
+			return nil, err
+		}
+		return nil, LockedError{retryAfter}
 	}
 
-	if err := auth.ComparePassword(company.Pass, credentials.Pass); err != nil {
-		return "", errors.New("invalid credentials")
+	if company == nil || auth.ComparePassword(company.Pass, credentials.Pass) != nil {
+		if err := s.recordLogin(ctx, credentials, company, false, false); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	}
 
-	token, err := auth.GenerateToken(company.Id, server.GetJwtSecret())
-	if err != nil {
-		return "", err
+	if err := s.recordLogin(ctx, credentials, company, true, false); err != nil {
+		return nil, err
 	}
 
-	return token, nil
+	return s.startSession(ctx, company)
 }
 
+// Registers the company and mails it a verification token. The company is
+// registered even if the email can't be sent, it can ask for another one.
 func (s *service) Register(ctx context.Context, registration *Registration) (*Company, error) {
 	hashedPassword, err := auth.HashPassword(registration.Password)
 	if err != nil {
@@ -120,5 +202,28 @@ func (s *service) Register(ctx context.Context, registration *Registration) (*Co
 
 	registration.Password = hashedPassword
 
-	return s.repository.Register(ctx, registration)
+	var company *Company
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		taken, err := s.repository.EmailExists(ctx, registration.Email)
+		if err != nil {
+			return err
+		}
+
+		if taken {
+			return server.NewBusinessRuleError("email is already registered")
+		}
+
+		company, err = s.repository.Register(ctx, registration)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sendVerification(ctx, company); err != nil {
+		s.logger.Printf("could not send verification to company %d: %s", company.Id, err)
+	}
+
+	return company, nil
 }
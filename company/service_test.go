@@ -1,14 +1,24 @@
 package company_test
 
 import (
+	"api/auth"
+	"api/clock"
 	"api/company"
+	"api/database"
+	"api/mail"
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
 	"testing"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestCompanyService(t *testing.T) {
-	service := company.NewService(company.NewFakeRepository())
+	service := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -31,5 +41,413 @@ func TestCompanyService(t *testing.T) {
 				t.Errorf("expected error \"%s\", got \"%s\"", expectedError, err)
 			}
 		})
+
+		t.Run("should validate position", func(t *testing.T) {
+			for _, position := range []uint8{0, company.MAX_TERRAINS + 1} {
+				if err := service.PurchaseTerrain(ctx, 3, position); err == nil || err.Error() != "invalid position" {
+					t.Errorf("expected position %d to be invalid, got %v", position, err)
+				}
+			}
+		})
+
+		t.Run("should not purchase owned terrain", func(t *testing.T) {
+			if err := service.PurchaseTerrain(ctx, 3, 1); err == nil || err.Error() != "terrain already owned" {
+				t.Errorf("expected terrain to be owned, got %v", err)
+			}
+		})
+
+	})
+
+	t.Run("SellTerrain", func(t *testing.T) {
+		t.Run("should sell terrain", func(t *testing.T) {
+			if err := service.SellTerrain(ctx, 3, 1); err != nil {
+				t.Fatalf("could not sell terrain: %s", err)
+			}
+
+			if terrain, _ := service.GetTerrain(ctx, 3, 1); terrain != nil {
+				t.Errorf("expected terrain to be sold, got %+v", terrain)
+			}
+		})
+
+		t.Run("should not sell terrains it doesn't own", func(t *testing.T) {
+			if err := service.SellTerrain(ctx, 3, 1); err == nil || err.Error() != "terrain not owned" {
+				t.Errorf("expected terrain not to be owned, got %v", err)
+			}
+		})
+
+		t.Run("should refund part of the purchase price", func(t *testing.T) {
+			if err := service.PurchaseTerrain(ctx, 3, 1); err != nil {
+				t.Fatalf("could not purchase terrain: %s", err)
+			}
+
+			company3, _ := service.GetById(ctx, 3)
+			before := company3.AvailableCash
+			terrain, _ := service.GetTerrain(ctx, 3, 1)
+
+			if err := service.SellTerrain(ctx, 3, 1); err != nil {
+				t.Fatalf("could not sell terrain: %s", err)
+			}
+
+			company3, _ = service.GetById(ctx, 3)
+			refund := int(float64(terrain.PurchasePrice) * company.TERRAIN_RESALE_RATE)
+			if refund == 0 || company3.AvailableCash-before != refund {
+				t.Errorf("expected refund of %d, got %d", refund, company3.AvailableCash-before)
+			}
+		})
+
+		t.Run("should depreciate the refund with age", func(t *testing.T) {
+			company1, _ := service.GetById(ctx, 1)
+			before := company1.AvailableCash
+
+			// Pretend the plot was bought ten weeks ago
+			terrain, _ := service.GetTerrain(ctx, 1, 3)
+			weeks := 10.0
+			terrain.PurchasePrice = 1_000_000_00
+			terrain.PurchasedAt = time.Now().Add(-time.Duration(weeks) * 7 * 24 * time.Hour)
+
+			if err := service.SellTerrain(ctx, 1, 3); err != nil {
+				t.Fatalf("could not sell terrain: %s", err)
+			}
+
+			company1, _ = service.GetById(ctx, 1)
+			refund := int(float64(terrain.PurchasePrice) * (company.TERRAIN_RESALE_RATE - company.TERRAIN_WEEKLY_DEPRECIATION*weeks))
+			if company1.AvailableCash-before != refund {
+				t.Errorf("expected refund of %d, got %d", refund, company1.AvailableCash-before)
+			}
+		})
+	})
+
+	t.Run("TransferTerrain", func(t *testing.T) {
+		t.Run("should not transfer to the same company", func(t *testing.T) {
+			if _, err := service.TransferTerrain(ctx, 3, 2, &company.TerrainTransfer{CompanyId: 3}); err == nil {
+				t.Error("expected transfer to be refused")
+			}
+		})
+
+		t.Run("should validate receiver", func(t *testing.T) {
+			if _, err := service.TransferTerrain(ctx, 3, 2, &company.TerrainTransfer{CompanyId: 10}); err == nil || err.Error() != "company not found" {
+				t.Errorf("expected receiver not to be found, got %v", err)
+			}
+		})
+
+		t.Run("should give the first free position", func(t *testing.T) {
+			terrain, err := service.TransferTerrain(ctx, 3, 2, &company.TerrainTransfer{CompanyId: 2})
+			if err != nil {
+				t.Fatalf("could not transfer terrain: %s", err)
+			}
+
+			if terrain.Position != company.STARTING_TERRAINS+1 {
+				t.Errorf("expected position %d, got %d", company.STARTING_TERRAINS+1, terrain.Position)
+			}
+
+			sender, _ := service.GetById(ctx, 3)
+			receiver, _ := service.GetById(ctx, 2)
+			if sender.AvailableTerrains != company.STARTING_TERRAINS-2 || receiver.AvailableTerrains != company.STARTING_TERRAINS+1 {
+				t.Errorf("expected terrains to be counted, got %d and %d", sender.AvailableTerrains, receiver.AvailableTerrains)
+			}
+		})
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		login := func(t *testing.T, email string) *auth.Claims {
+			tokens, err := service.Login(ctx, company.Credentials{Email: email, Pass: "password"})
+			if err != nil {
+				t.Fatalf("could not login: %s", err)
+			}
+
+			token, err := jwt.ParseWithClaims(tokens.Token, new(auth.Claims), func(t *jwt.Token) (any, error) {
+				return []byte("secret"), nil
+			})
+			if err != nil {
+				t.Fatalf("could not parse token: %s", err)
+			}
+
+			return token.Claims.(*auth.Claims)
+		}
+
+		t.Run("should issue admin role to admins", func(t *testing.T) {
+			if claims := login(t, "admin@test3.com"); !claims.HasRole(auth.ROLE_ADMIN) {
+				t.Errorf("expected role %s, got %v", auth.ROLE_ADMIN, claims.Roles)
+			}
+		})
+
+		t.Run("should not issue admin role to others", func(t *testing.T) {
+			if claims := login(t, "admin@test.com"); claims.HasRole(auth.ROLE_ADMIN) {
+				t.Errorf("expected no roles, got %v", claims.Roles)
+			}
+		})
+
+		t.Run("should update last login", func(t *testing.T) {
+			login(t, "admin@test2.com")
+
+			company, _ := service.GetById(ctx, 2)
+			if company.LastLogin == nil {
+				t.Error("expected last login to be set")
+			}
+		})
+	})
+
+	t.Run("Lockout", func(t *testing.T) {
+		service := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+
+		attempt := func(email, password, ip string) error {
+			_, err := service.Login(ctx, company.Credentials{Email: email, Pass: password, IP: ip})
+			return err
+		}
+
+		t.Run("should lock the email after repeated failures", func(t *testing.T) {
+			for i := 0; i < company.MAX_EMAIL_FAILURES; i++ {
+				if err := attempt("admin@test.com", "wrong", "10.0.0.1"); !errors.Is(err, company.ErrInvalidCredentials) {
+					t.Fatalf("expected invalid credentials, got %v", err)
+				}
+			}
+
+			err := attempt("admin@test.com", "password", "10.0.0.2")
+
+			var locked company.LockedError
+			if !errors.As(err, &locked) {
+				t.Fatalf("expected logins to be locked, got %v", err)
+			}
+
+			if locked.RetryAfter <= 0 || locked.RetryAfter > company.LOCKOUT_DURATION {
+				t.Errorf("expected to retry within %s, got %s", company.LOCKOUT_DURATION, locked.RetryAfter)
+			}
+
+			if err := attempt("admin@test2.com", "password", "10.0.0.2"); err != nil {
+				t.Errorf("expected other emails not to be locked, got %s", err)
+			}
+		})
+
+		t.Run("should lock the address after repeated failures", func(t *testing.T) {
+			for i := 0; i < company.MAX_IP_FAILURES; i++ {
+				attempt(fmt.Sprintf("nobody%d@test.com", i), "wrong", "10.0.0.3")
+			}
+
+			var locked company.LockedError
+			if err := attempt("admin@test3.com", "password", "10.0.0.3"); !errors.As(err, &locked) {
+				t.Errorf("expected address to be locked, got %v", err)
+			}
+
+			if err := attempt("admin@test3.com", "password", "10.0.0.4"); err != nil {
+				t.Errorf("expected other addresses not to be locked, got %s", err)
+			}
+		})
+
+		t.Run("should record every attempt", func(t *testing.T) {
+			attempts, err := service.GetLoginAttempts(ctx, 1)
+			if err != nil {
+				t.Fatalf("could not get login attempts: %s", err)
+			}
+
+			if len(attempts) != company.MAX_EMAIL_FAILURES+1 {
+				t.Fatalf("expected %d attempts, got %d", company.MAX_EMAIL_FAILURES+1, len(attempts))
+			}
+
+			if !attempts[0].Locked || attempts[0].IP != "10.0.0.2" {
+				t.Errorf("expected latest attempt to be locked, got %+v", attempts[0])
+			}
+
+			for _, attempt := range attempts[1:] {
+				if attempt.Success || attempt.Locked || attempt.IP != "10.0.0.1" {
+					t.Errorf("expected failure from 10.0.0.1, got %+v", attempt)
+				}
+			}
+		})
+
+		t.Run("should unlock once the lockout is over", func(t *testing.T) {
+			now := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			service := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), now, clock.New())
+
+			for i := 0; i < company.MAX_EMAIL_FAILURES; i++ {
+				service.Login(ctx, company.Credentials{Email: "admin@test.com", Pass: "wrong", IP: "10.0.0.5"})
+			}
+
+			now.Advance(company.LOCKOUT_DURATION / 2)
+
+			var locked company.LockedError
+			_, err := service.Login(ctx, company.Credentials{Email: "admin@test.com", Pass: "password", IP: "10.0.0.5"})
+			if !errors.As(err, &locked) || locked.RetryAfter != company.LOCKOUT_DURATION/2 {
+				t.Fatalf("expected to retry in %s, got %v", company.LOCKOUT_DURATION/2, err)
+			}
+
+			now.Advance(company.LOCKOUT_DURATION / 2)
+
+			if _, err := service.Login(ctx, company.Credentials{Email: "admin@test.com", Pass: "password", IP: "10.0.0.5"}); err != nil {
+				t.Errorf("expected login to be unlocked, got %s", err)
+			}
+		})
+	})
+
+	t.Run("Members", func(t *testing.T) {
+		outbox := mail.NewOutbox()
+		service := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, outbox, log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+
+		tokenPattern := regexp.MustCompile(`(?m)^[A-Za-z0-9_-]{43}$`)
+		invite := func(t *testing.T, companyId uint64, email, role string) string {
+			if _, err := service.InviteMember(ctx, companyId, 0, &company.InvitationRequest{Email: email, Role: role}); err != nil {
+				t.Fatalf("could not invite member: %s", err)
+			}
+			return tokenPattern.FindString(outbox.Last(email).Body)
+		}
+
+		parse := func(t *testing.T, tokens *company.Tokens) *auth.Claims {
+			token, err := jwt.ParseWithClaims(tokens.Token, new(auth.Claims), func(t *jwt.Token) (any, error) {
+				return []byte("secret"), nil
+			})
+			if err != nil {
+				t.Fatalf("could not parse token: %s", err)
+			}
+			return token.Claims.(*auth.Claims)
+		}
+
+		login := func(t *testing.T, companyId uint64) *company.Tokens {
+			tokens, err := service.LoginMember(ctx, company.MemberCredentials{
+				Credentials: company.Credentials{Email: "member@test.com", Pass: "password"},
+				CompanyId:   companyId,
+			})
+			if err != nil {
+				t.Fatalf("could not login: %s", err)
+			}
+			return tokens
+		}
+
+		var userId uint64
+
+		t.Run("should sign invited users up", func(t *testing.T) {
+			token := invite(t, 1, "member@test.com", auth.MEMBER_TRADER)
+
+			if _, err := service.AcceptInvitation(ctx, &company.InvitationAcceptance{Token: token, Password: "password"}); err == nil {
+				t.Fatal("expected a name to be required to sign up")
+			}
+
+			member, err := service.AcceptInvitation(ctx, &company.InvitationAcceptance{Token: token, Name: "Member", Password: "password"})
+			if err != nil {
+				t.Fatalf("could not accept invitation: %s", err)
+			}
+
+			if member.CompanyId != 1 || member.Role != auth.MEMBER_TRADER || member.Name != "Member" {
+				t.Errorf("expected trader of company 1, got %+v", member)
+			}
+			userId = member.UserId
+
+			if _, err := service.AcceptInvitation(ctx, &company.InvitationAcceptance{Token: token, Password: "password"}); !errors.Is(err, company.ErrInvalidInvitation) {
+				t.Errorf("expected invitation to be accepted once, got %v", err)
+			}
+		})
+
+		t.Run("should not invite members again", func(t *testing.T) {
+			_, err := service.InviteMember(ctx, 1, 0, &company.InvitationRequest{Email: "member@test.com", Role: auth.MEMBER_VIEWER})
+			if err == nil || err.Error() != "user is already a member of the company" {
+				t.Errorf("expected member not to be invited again, got %v", err)
+			}
+		})
+
+		t.Run("should issue tokens with the role of the member", func(t *testing.T) {
+			claims := parse(t, login(t, 0))
+
+			if claims.Subject != "1" || claims.UserId != userId || claims.Member != auth.MEMBER_TRADER {
+				t.Errorf("expected trader token for company 1, got %+v", claims)
+			}
+
+			if claims.HasScope(auth.SCOPE_FINANCE_WRITE) {
+				t.Errorf("should not hold scope %s", auth.SCOPE_FINANCE_WRITE)
+			}
+		})
+
+		t.Run("should check the password of existing users", func(t *testing.T) {
+			token := invite(t, 2, "member@test.com", auth.MEMBER_ACCOUNTANT)
+
+			if _, err := service.AcceptInvitation(ctx, &company.InvitationAcceptance{Token: token, Password: "wrong"}); err == nil {
+				t.Fatal("expected wrong password to be refused")
+			}
+
+			if _, err := service.AcceptInvitation(ctx, &company.InvitationAcceptance{Token: token, Password: "password"}); err != nil {
+				t.Fatalf("could not accept invitation: %s", err)
+			}
+		})
+
+		t.Run("should ask members of several companies which one to act for", func(t *testing.T) {
+			_, err := service.LoginMember(ctx, company.MemberCredentials{
+				Credentials: company.Credentials{Email: "member@test.com", Pass: "password"},
+			})
+			if err == nil {
+				t.Fatal("expected company to be required")
+			}
+
+			if claims := parse(t, login(t, 2)); claims.Subject != "2" || claims.Member != auth.MEMBER_ACCOUNTANT {
+				t.Errorf("expected accountant token for company 2, got %+v", claims)
+			}
+
+			if _, err := service.LoginMember(ctx, company.MemberCredentials{
+				Credentials: company.Credentials{Email: "member@test.com", Pass: "password"},
+				CompanyId:   3,
+			}); err == nil {
+				t.Error("expected companies the user isn't a member of to be refused")
+			}
+		})
+
+		t.Run("should revoke the sessions of members whose role changes", func(t *testing.T) {
+			tokens := login(t, 1)
+
+			member, err := service.UpdateMemberRole(ctx, 1, userId, &company.RoleChange{Role: auth.MEMBER_VIEWER})
+			if err != nil {
+				t.Fatalf("could not change role: %s", err)
+			}
+
+			if member.Role != auth.MEMBER_VIEWER {
+				t.Errorf("expected role %s, got %s", auth.MEMBER_VIEWER, member.Role)
+			}
+
+			if _, err := service.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, company.ErrInvalidRefreshToken) {
+				t.Errorf("expected session to be revoked, got %v", err)
+			}
+
+			if claims := parse(t, login(t, 1)); claims.Member != auth.MEMBER_VIEWER {
+				t.Errorf("expected role %s, got %s", auth.MEMBER_VIEWER, claims.Member)
+			}
+		})
+
+		t.Run("should not refresh tokens of removed members", func(t *testing.T) {
+			tokens := login(t, 1)
+
+			if err := service.RemoveMember(ctx, 1, userId); err != nil {
+				t.Fatalf("could not remove member: %s", err)
+			}
+
+			if _, err := service.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, company.ErrInvalidRefreshToken) {
+				t.Errorf("expected refresh to be refused, got %v", err)
+			}
+
+			if err := service.RemoveMember(ctx, 1, userId); err == nil {
+				t.Error("expected member to be removed once")
+			}
+		})
+
+		t.Run("should record who made each change", func(t *testing.T) {
+			service.RecordAction(ctx, &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "2"}, UserId: userId, Member: auth.MEMBER_ACCOUNTANT}, "POST", "/financing/loans")
+			service.RecordAction(ctx, &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "2"}}, "PUT", "/companies/2")
+
+			entries, err := service.GetAuditLog(ctx, 2, company.AuditFilter{})
+			if err != nil {
+				t.Fatalf("could not get audit log: %s", err)
+			}
+
+			if len(entries) != 2 {
+				t.Fatalf("expected %d entries, got %d", 2, len(entries))
+			}
+
+			if entries[0].UserId != nil || entries[0].Path != "/companies/2" {
+				t.Errorf("expected latest change to be the company's, got %+v", entries[0])
+			}
+
+			if entries[1].UserId == nil || *entries[1].UserId != userId || entries[1].Role != auth.MEMBER_ACCOUNTANT {
+				t.Errorf("expected change by the accountant, got %+v", entries[1])
+			}
+
+			filtered, _ := service.GetAuditLog(ctx, 2, company.AuditFilter{UserId: userId})
+			if len(filtered) != 1 {
+				t.Errorf("expected %d entry by the member, got %d", 1, len(filtered))
+			}
+		})
 	})
 }
@@ -0,0 +1,249 @@
+package company
+
+import (
+	"api/auth"
+	"api/server"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type (
+	// Tokens are what clients authenticate with. The access token expires
+	// within minutes and the refresh token exchanges it for a new pair, so
+	// logging in again is only needed once the session is revoked or the
+	// refresh token expires.
+	Tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// RefreshToken is the stored side of a refresh token, only its hash
+	// is kept. Every refresh uses the token up and issues a new one for
+	// the same session.
+	RefreshToken struct {
+		Id        uint64     `db:"id"`
+		SessionId uint64     `db:"session_id"`
+		CompanyId uint64     `db:"company_id"`
+		UserId    *uint64    `db:"user_id"`
+		ExpiresAt time.Time  `db:"expires_at"`
+		UsedAt    *time.Time `db:"used_at"`
+		RevokedAt *time.Time `db:"revoked_at"`
+	}
+
+	Refresh struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+
+	PasswordChange struct {
+		Current  string `json:"current_password" validate:"required"`
+		Password string `json:"password" validate:"required"`
+		Confirm  string `json:"confirm_password" validate:"required,eqfield=Password"`
+	}
+)
+
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// Starts a session for the company, returning its first pair of tokens
+func (s *service) startSession(ctx context.Context, company *Company) (*Tokens, error) {
+	var tokens *Tokens
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		sessionId, err := s.repository.CreateSession(ctx, company.Id)
+		if err != nil {
+			return err
+		}
+
+		tokens, err = s.issueTokens(ctx, company, nil, sessionId)
+		return err
+	})
+
+	return tokens, err
+}
+
+// Starts a session for the member, whose tokens only hold the scopes of
+// the role
+func (s *service) startMemberSession(ctx context.Context, company *Company, member *Member) (*Tokens, error) {
+	var tokens *Tokens
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		sessionId, err := s.repository.CreateMemberSession(ctx, company.Id, member.UserId)
+		if err != nil {
+			return err
+		}
+
+		tokens, err = s.issueTokens(ctx, company, member, sessionId)
+		return err
+	})
+
+	return tokens, err
+}
+
+// Roles are read from the company every time, so refreshed tokens pick up
+// the roles it was granted or lost since. Tokens of members carry their
+// role instead.
+func (s *service) issueTokens(ctx context.Context, company *Company, member *Member, sessionId uint64) (*Tokens, error) {
+	refreshToken, hash, err := auth.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().UTC().Add(auth.REFRESH_TOKEN_DURATION)
+	if err := s.repository.SaveRefreshToken(ctx, sessionId, hash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	var token string
+	if member != nil {
+		token, err = auth.GenerateMemberToken(company.Id, sessionId, member.UserId, member.Role, s.jwtSecret)
+	} else {
+		token, err = auth.GenerateSessionToken(company.Id, sessionId, company.Roles(), s.jwtSecret)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{token, refreshToken}, nil
+}
+
+// Exchanges the refresh token for a new pair. A refresh token that was
+// already used means it leaked, so the whole session is revoked.
+func (s *service) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	var tokens *Tokens
+	var reused *RefreshToken
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		stored, err := s.repository.GetRefreshToken(ctx, auth.HashSecret(refreshToken))
+		if err != nil {
+			return err
+		}
+
+		if stored == nil || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		if stored.UsedAt != nil {
+			reused = stored
+			return ErrInvalidRefreshToken
+		}
+
+		used, err := s.repository.UseRefreshToken(ctx, stored.Id)
+		if err != nil {
+			return err
+		}
+
+		// Another refresh used it up in the meantime
+		if !used {
+			reused = stored
+			return ErrInvalidRefreshToken
+		}
+
+		company, err := s.repository.GetById(ctx, stored.CompanyId)
+		if err != nil {
+			return err
+		}
+
+		if company == nil {
+			return ErrInvalidRefreshToken
+		}
+
+		// Members removed from the company can't refresh its tokens
+		var member *Member
+		if stored.UserId != nil {
+			member, err = s.repository.GetMember(ctx, company.Id, *stored.UserId)
+			if err != nil {
+				return err
+			}
+
+			if member == nil {
+				return ErrInvalidRefreshToken
+			}
+		}
+
+		tokens, err = s.issueTokens(ctx, company, member, stored.SessionId)
+		return err
+	})
+
+	// Revoked outside the unit of work, which is rolled back by the error
+	if reused != nil {
+		if err := s.repository.RevokeSession(ctx, reused.SessionId); err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, err
+}
+
+func (s *service) Logout(ctx context.Context, sessionId uint64) error {
+	if sessionId == 0 {
+		return server.NewBusinessRuleError("token was not issued for a session")
+	}
+
+	return s.repository.RevokeSession(ctx, sessionId)
+}
+
+// Revokes every session of the company, so its tokens are rejected and
+// can't be refreshed
+func (s *service) RevokeSessions(ctx context.Context, companyId uint64) error {
+	return s.repository.RevokeSessions(ctx, companyId)
+}
+
+// Changes the password and revokes every session, returning the tokens of
+// a new one for the client that changed it
+func (s *service) ChangePassword(ctx context.Context, companyId uint64, change *PasswordChange) (*Tokens, error) {
+	company, err := s.repository.GetById(ctx, companyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if company == nil {
+		return nil, server.NewBusinessRuleError("company not found")
+	}
+
+	if err := auth.ComparePassword(company.Pass, change.Current); err != nil {
+		return nil, server.NewBusinessRuleError("current password is wrong")
+	}
+
+	hashedPassword, err := auth.HashPassword(change.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens *Tokens
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repository.UpdatePassword(ctx, companyId, hashedPassword); err != nil {
+			return err
+		}
+
+		if err := s.repository.RevokeSessions(ctx, companyId); err != nil {
+			return err
+		}
+
+		sessionId, err := s.repository.CreateSession(ctx, companyId)
+		if err != nil {
+			return err
+		}
+
+		tokens, err = s.issueTokens(ctx, company, nil, sessionId)
+		return err
+	})
+
+	return tokens, err
+}
+
+// Tokens issued for a session are revoked with it. Tokens issued without
+// one, like cron tokens, are only ever revoked by expiring.
+func (s *service) IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims) (bool, error) {
+	sessionId, err := auth.GetSessionId(claims)
+	if err != nil {
+		return true, err
+	}
+
+	if sessionId == 0 {
+		return false, nil
+	}
+
+	return s.repository.IsSessionRevoked(ctx, sessionId)
+}
@@ -0,0 +1,217 @@
+package company
+
+import (
+	"api/server"
+	"context"
+	"time"
+)
+
+const (
+	// Plots are positioned from 1 to MAX_TERRAINS on every company's map
+	MAX_TERRAINS = 100
+
+	// Plots companies are registered with, at the first positions
+	STARTING_TERRAINS = 3
+
+	// Share of the purchase price refunded when new plots are sold back
+	TERRAIN_RESALE_RATE = 0.75
+
+	// Share of the purchase price plots lose every game week they're held,
+	// down to TERRAIN_MIN_RESALE_RATE
+	TERRAIN_WEEKLY_DEPRECIATION = 0.01
+	TERRAIN_MIN_RESALE_RATE     = 0.25
+)
+
+type (
+	// Terrain is a plot owned by the company. Buildings can only be built on
+	// plots the company owns, one per plot.
+	Terrain struct {
+		Id            uint64    `db:"id" json:"id"`
+		CompanyId     uint64    `db:"company_id" json:"-"`
+		Position      uint8     `db:"position" json:"position"`
+		PurchasePrice int64     `db:"purchase_price" json:"purchase_price"`
+		PurchasedAt   time.Time `db:"purchased_at" json:"purchased_at"`
+
+		// Building standing on the plot, if any
+		BuildingId *uint64 `db:"building_id" json:"building_id"`
+	}
+
+	TerrainTransfer struct {
+		CompanyId uint64 `json:"company_id" validate:"required"`
+	}
+)
+
+func (t *Terrain) IsEmpty() bool {
+	return t.BuildingId == nil
+}
+
+// Returns what the plot is refunded for when sold back at now. It starts at
+// TERRAIN_RESALE_RATE of the purchase price and depreciates every week held.
+func (t *Terrain) ResaleValue(now time.Time) int {
+	weeks := int(now.Sub(t.PurchasedAt) / (7 * 24 * time.Hour))
+	if weeks < 0 {
+		weeks = 0
+	}
+
+	rate := max(TERRAIN_RESALE_RATE-TERRAIN_WEEKLY_DEPRECIATION*float64(weeks), TERRAIN_MIN_RESALE_RATE)
+	return int(float64(t.PurchasePrice) * rate)
+}
+
+func (s *service) GetTerrains(ctx context.Context, companyId uint64) ([]*Terrain, error) {
+	return s.repository.GetTerrains(ctx, companyId)
+}
+
+func (s *service) GetTerrain(ctx context.Context, companyId uint64, position uint8) (*Terrain, error) {
+	return s.repository.GetTerrain(ctx, companyId, position)
+}
+
+func (s *service) PurchaseTerrain(ctx context.Context, companyId uint64, position uint8) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		company, err := s.repository.GetById(ctx, companyId)
+		if err != nil {
+			return err
+		}
+
+		if company == nil {
+			return server.NewBusinessRuleError("company not found")
+		}
+
+		if position < 1 || position > MAX_TERRAINS {
+			return server.NewBusinessRuleError("invalid position")
+		}
+
+		terrain, err := s.repository.GetTerrain(ctx, companyId, position)
+		if err != nil {
+			return err
+		}
+
+		if terrain != nil {
+			return server.NewBusinessRuleError("terrain already owned")
+		}
+
+		total := int(s.terrains.BaseValue + (s.terrains.UnitValue * int64(company.AvailableTerrains/5)) + (s.terrains.PositionValue * int64(position)))
+		held, err := s.repository.HoldCash(ctx, companyId, total)
+		if err != nil {
+			return err
+		}
+
+		if !held {
+			return server.NewBusinessRuleError("not enough cash")
+		}
+
+		return s.repository.PurchaseTerrain(ctx, companyId, position, total)
+	})
+}
+
+// Sells the empty plot back for its resale value, which depreciates with the
+// game weeks it was held. Plots companies were registered with weren't paid
+// for, so they're worth nothing.
+func (s *service) SellTerrain(ctx context.Context, companyId uint64, position uint8) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		terrain, err := s.getEmptyTerrain(ctx, companyId, position)
+		if err != nil {
+			return err
+		}
+
+		refund := terrain.ResaleValue(s.gameClock.Now())
+
+		sold, err := s.repository.SellTerrain(ctx, companyId, position, refund)
+		if err != nil {
+			return err
+		}
+
+		if !sold {
+			return server.NewBusinessRuleError("terrain not owned")
+		}
+
+		return nil
+	})
+}
+
+// Gives the empty plot to another company, which gets it at the first
+// position free on its map
+func (s *service) TransferTerrain(ctx context.Context, companyId uint64, position uint8, transfer *TerrainTransfer) (*Terrain, error) {
+	if transfer.CompanyId == companyId {
+		return nil, server.NewBusinessRuleError("cannot transfer terrain to the same company")
+	}
+
+	var transferred *Terrain
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		receiver, err := s.repository.GetById(ctx, transfer.CompanyId)
+		if err != nil {
+			return err
+		}
+
+		if receiver == nil {
+			return server.NewBusinessRuleError("company not found")
+		}
+
+		if _, err := s.getEmptyTerrain(ctx, companyId, position); err != nil {
+			return err
+		}
+
+		terrains, err := s.repository.GetTerrains(ctx, receiver.Id)
+		if err != nil {
+			return err
+		}
+
+		free := freePosition(terrains)
+		if free == 0 {
+			return server.NewBusinessRuleError("company has no room for more terrains")
+		}
+
+		moved, err := s.repository.TransferTerrain(ctx, companyId, position, receiver.Id, free)
+		if err != nil {
+			return err
+		}
+
+		if !moved {
+			return server.NewBusinessRuleError("terrain not owned")
+		}
+
+		transferred, err = s.repository.GetTerrain(ctx, receiver.Id, free)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transferred, nil
+}
+
+func (s *service) getEmptyTerrain(ctx context.Context, companyId uint64, position uint8) (*Terrain, error) {
+	terrain, err := s.repository.GetTerrain(ctx, companyId, position)
+	if err != nil {
+		return nil, err
+	}
+
+	if terrain == nil {
+		return nil, server.NewBusinessRuleError("terrain not owned")
+	}
+
+	if !terrain.IsEmpty() {
+		return nil, server.NewBusinessRuleError("terrain is occupied")
+	}
+
+	return terrain, nil
+}
+
+// Returns the first position not taken by the plots, sorted by position, or
+// zero when the map is full
+func freePosition(terrains []*Terrain) uint8 {
+	position := uint8(1)
+	for _, terrain := range terrains {
+		if terrain.Position != position {
+			break
+		}
+		position++
+	}
+
+	if position > MAX_TERRAINS {
+		return 0
+	}
+
+	return position
+}
@@ -0,0 +1,333 @@
+// Package config loads the settings the game runs with. Every setting has
+// a default, which can be overridden by a JSON file, then by environment
+// variables and finally by command line flags.
+package config
+
+import (
+	"api/accounting"
+	"api/company"
+	"api/database"
+	"api/financing/loans"
+	"api/mail"
+	"api/market"
+	"api/research/staff"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// Path of the JSON file to read, when not given with -config
+const CONFIG_FILE_KEY = "CONFIG_FILE"
+
+// Shortest secret tokens may be signed with. HS256 keys shorter than its
+// 32 byte hash are easier to brute force.
+const MIN_JWT_SECRET_LENGTH = 32
+
+type (
+	Config struct {
+		Database       Database `json:"database"`
+		Server         Server   `json:"server"`
+		Log            Log      `json:"log"`
+		Mail           Mail     `json:"mail"`
+		Clock          Clock    `json:"clock"`
+		Game           Game     `json:"game"`
+		MigrateOnStart bool     `json:"migrate_on_start"`
+	}
+
+	Database struct {
+		Driver string `json:"driver"`
+		Url    string `json:"url"`
+	}
+
+	Server struct {
+		Address      string `json:"address"`
+		JwtSecret    string `json:"jwt_secret"`
+		ClientOrigin string `json:"client_origin"`
+
+		// How long in-flight requests, running jobs and pending
+		// notifications get to finish when the server is stopped
+		ShutdownTimeout Duration `json:"shutdown_timeout"`
+	}
+
+	// Without a file, logs are written to stderr
+	Log struct {
+		File   string `json:"file"`
+		Prefix string `json:"prefix"`
+	}
+
+	// Emails are sent through the SMTP server, or appended to the outbox
+	// file with the file driver
+	Mail struct {
+		Driver string          `json:"driver"`
+		From   string          `json:"from"`
+		Outbox string          `json:"outbox"`
+		SMTP   mail.SMTPServer `json:"smtp"`
+	}
+
+	// Game time runs speed times faster than real time, counting from
+	// epoch. The epoch must be kept across restarts when speed isn't 1.
+	Clock struct {
+		Speed float64   `json:"speed"`
+		Epoch time.Time `json:"epoch"`
+	}
+
+	// Game holds the balance parameters of the economy
+	Game struct {
+		TaxRate             float64                `json:"tax_rate"`
+		TransportFee        float64                `json:"transport_fee"`
+		Terrains            company.TerrainPricing `json:"terrains"`
+		StaffSearchDuration Duration               `json:"staff_search_duration"`
+		MaxDelayedPayments  int                    `json:"max_delayed_payments"`
+	}
+
+	// Duration is a time.Duration written like "12h" in files, environment
+	// variables and flags
+	Duration time.Duration
+
+	setting struct {
+		flag  string
+		env   string
+		usage string
+		bind  func(flags *flag.FlagSet, name, usage string)
+	}
+)
+
+func Default() *Config {
+	return &Config{
+		Database: Database{
+			Driver: database.SQLITE,
+			Url:    "development.db?_loc=UTC",
+		},
+		Server: Server{
+			Address:         ":1323",
+			ShutdownTimeout: Duration(30 * time.Second),
+		},
+		Log: Log{
+			File:   "dev.log",
+			Prefix: "[DEV]",
+		},
+		Mail: Mail{
+			Driver: mail.FILE,
+			From:   "no-reply@localhost",
+			Outbox: "mail.log",
+			SMTP:   mail.SMTPServer{Port: 587},
+		},
+		Clock: Clock{
+			Speed: 1,
+		},
+		Game: Game{
+			TaxRate:             accounting.DEFAULT_TAX_RATE,
+			TransportFee:        market.DEFAULT_TRANSPORT_FEE,
+			Terrains:            company.DEFAULT_TERRAIN_PRICING,
+			StaffSearchDuration: Duration(staff.DEFAULT_SEARCH_DURATION),
+			MaxDelayedPayments:  loans.DEFAULT_MAX_DELAYED_PAYMENTS,
+		},
+	}
+}
+
+// Loads the config from its sources, returning the arguments left after
+// the flags, like the command to run
+func Load(args []string) (*Config, []string, error) {
+	config := Default()
+	flags := flag.NewFlagSet("etp", flag.ContinueOnError)
+	path := flags.String("config", os.Getenv(CONFIG_FILE_KEY), "path of a JSON config file")
+
+	settings := config.settings()
+	for _, s := range settings {
+		s.bind(flags, s.flag, fmt.Sprintf("%s (%s)", s.usage, s.env))
+	}
+
+	// Flags are parsed once to find the file and again after the file and
+	// the environment are read, so they take precedence over both
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *path != "" {
+		if err := config.readFile(*path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			if err := flags.Set(s.flag, value); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %w", s.env, err)
+			}
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return config, flags.Args(), nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Database.Driver {
+	case database.SQLITE, database.MYSQL, database.POSTGRES:
+	default:
+		errs = append(errs, fmt.Errorf("unsupported database driver %q", c.Database.Driver))
+	}
+
+	if c.Database.Url == "" {
+		errs = append(errs, errors.New("database url is required"))
+	}
+
+	if c.Server.Address == "" {
+		errs = append(errs, errors.New("server address is required"))
+	}
+
+	if len(c.Server.JwtSecret) < MIN_JWT_SECRET_LENGTH {
+		errs = append(errs, fmt.Errorf("jwt secret must be at least %d bytes", MIN_JWT_SECRET_LENGTH))
+	}
+
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown timeout must be greater than zero"))
+	}
+
+	switch c.Mail.Driver {
+	case mail.SMTP:
+		if c.Mail.SMTP.Host == "" {
+			errs = append(errs, errors.New("smtp host is required"))
+		}
+	case mail.FILE:
+		if c.Mail.Outbox == "" {
+			errs = append(errs, errors.New("mail outbox is required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unsupported mail driver %q", c.Mail.Driver))
+	}
+
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("mail sender is required"))
+	}
+
+	if c.Clock.Speed <= 0 {
+		errs = append(errs, errors.New("game speed must be greater than zero"))
+	}
+
+	// Without a fixed epoch game time would jump back on every restart
+	if c.Clock.Speed != 1 && c.Clock.Epoch.IsZero() {
+		errs = append(errs, errors.New("game epoch is required when game speed isn't 1"))
+	}
+
+	if c.Game.TaxRate < 0 || c.Game.TaxRate > 1 {
+		errs = append(errs, errors.New("tax rate must be between 0 and 1"))
+	}
+
+	if c.Game.TransportFee < 0 || c.Game.TransportFee > 1 {
+		errs = append(errs, errors.New("transport fee must be between 0 and 1"))
+	}
+
+	terrains := c.Game.Terrains
+	if terrains.BaseValue <= 0 || terrains.UnitValue < 0 || terrains.PositionValue < 0 {
+		errs = append(errs, errors.New("terrain base value must be positive and the others not negative"))
+	}
+
+	if c.Game.StaffSearchDuration <= 0 {
+		errs = append(errs, errors.New("staff search duration must be greater than zero"))
+	}
+
+	if c.Game.MaxDelayedPayments <= 0 || c.Game.MaxDelayedPayments > math.MaxInt8 {
+		errs = append(errs, fmt.Errorf("max delayed payments must be between 1 and %d", math.MaxInt8))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Every setting that can be overridden, with the flag and environment
+// variable that override it
+func (c *Config) settings() []setting {
+	str := func(p *string) func(*flag.FlagSet, string, string) {
+		return func(flags *flag.FlagSet, name, usage string) { flags.StringVar(p, name, *p, usage) }
+	}
+	float := func(p *float64) func(*flag.FlagSet, string, string) {
+		return func(flags *flag.FlagSet, name, usage string) { flags.Float64Var(p, name, *p, usage) }
+	}
+	int64Value := func(p *int64) func(*flag.FlagSet, string, string) {
+		return func(flags *flag.FlagSet, name, usage string) { flags.Int64Var(p, name, *p, usage) }
+	}
+
+	return []setting{
+		{"database-driver", "DATABASE_DRIVER", "database driver: sqlite3, mysql or postgres", str(&c.Database.Driver)},
+		{"database-url", "DATABASE_URL", "database connection url", str(&c.Database.Url)},
+		{"address", "SERVER_ADDRESS", "address the server listens on", str(&c.Server.Address)},
+		{"jwt-secret", "JWT_SECRET", "secret tokens are signed with", str(&c.Server.JwtSecret)},
+		{"client-origin", "CLIENT_ORIGIN", "origin of the web client allowed by CORS", str(&c.Server.ClientOrigin)},
+		{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long the server waits for work in flight when stopped", func(flags *flag.FlagSet, name, usage string) {
+			flags.TextVar(&c.Server.ShutdownTimeout, name, c.Server.ShutdownTimeout, usage)
+		}},
+		{"log-file", "LOG_FILE", "file logs are appended to, stderr when empty", str(&c.Log.File)},
+		{"log-prefix", "LOG_PREFIX", "prefix of every log line", str(&c.Log.Prefix)},
+		{"mail-driver", "MAIL_DRIVER", "how emails are sent: smtp, or file to append them to the outbox", str(&c.Mail.Driver)},
+		{"mail-from", "MAIL_FROM", "address emails are sent from", str(&c.Mail.From)},
+		{"mail-outbox", "MAIL_OUTBOX", "file emails are appended to with the file driver", str(&c.Mail.Outbox)},
+		{"smtp-host", "SMTP_HOST", "host of the SMTP server", str(&c.Mail.SMTP.Host)},
+		{"smtp-port", "SMTP_PORT", "port of the SMTP server", func(flags *flag.FlagSet, name, usage string) {
+			flags.IntVar(&c.Mail.SMTP.Port, name, c.Mail.SMTP.Port, usage)
+		}},
+		{"smtp-username", "SMTP_USERNAME", "username to authenticate with the SMTP server", str(&c.Mail.SMTP.Username)},
+		{"smtp-password", "SMTP_PASSWORD", "password to authenticate with the SMTP server", str(&c.Mail.SMTP.Password)},
+		{"game-speed", "GAME_SPEED", "how many times faster than real time the game runs", float(&c.Clock.Speed)},
+		{"game-epoch", "GAME_EPOCH", "RFC 3339 time game time counts from", func(flags *flag.FlagSet, name, usage string) {
+			flags.TextVar(&c.Clock.Epoch, name, c.Clock.Epoch, usage)
+		}},
+		{"migrate-on-start", "MIGRATE_ON_START", "apply pending migrations when the server starts", func(flags *flag.FlagSet, name, usage string) {
+			flags.BoolVar(&c.MigrateOnStart, name, c.MigrateOnStart, usage)
+		}},
+		{"tax-rate", "TAX_RATE", "share of the taxable income paid in taxes", float(&c.Game.TaxRate)},
+		{"transport-fee", "TRANSPORT_FEE", "share of the sourcing cost charged to ship purchases", float(&c.Game.TransportFee)},
+		{"terrain-base-value", "TERRAIN_BASE_VALUE", "value of every terrain, in cents", int64Value(&c.Game.Terrains.BaseValue)},
+		{"terrain-unit-value", "TERRAIN_UNIT_VALUE", "value added every five terrains, in cents", int64Value(&c.Game.Terrains.UnitValue)},
+		{"terrain-position-value", "TERRAIN_POSITION_VALUE", "value added for each terrain position, in cents", int64Value(&c.Game.Terrains.PositionValue)},
+		{"staff-search-duration", "STAFF_SEARCH_DURATION", "how long searching for staff takes", func(flags *flag.FlagSet, name, usage string) {
+			flags.TextVar(&c.Game.StaffSearchDuration, name, c.Game.StaffSearchDuration, usage)
+		}},
+		{"max-delayed-payments", "MAX_DELAYED_PAYMENTS", "loan payments missed before terrains are seized", func(flags *flag.FlagSet, name, usage string) {
+			flags.IntVar(&c.Game.MaxDelayedPayments, name, c.Game.MaxDelayedPayments, usage)
+		}},
+	}
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+	return nil
+}
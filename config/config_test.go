@@ -0,0 +1,152 @@
+package config_test
+
+import (
+	"api/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+func TestConfig(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		t.Run("should be valid with a secret", func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Server.JwtSecret = secret
+
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("expected default config to be valid, got %s", err)
+			}
+		})
+
+		t.Run("should require a secret", func(t *testing.T) {
+			cfg := config.Default()
+			if err := cfg.Validate(); err == nil {
+				t.Error("expected error without jwt secret")
+			}
+
+			cfg.Server.JwtSecret = "secret"
+			if err := cfg.Validate(); err == nil {
+				t.Error("expected error with a short jwt secret")
+			}
+		})
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", secret)
+
+		t.Run("should return the args after the flags", func(t *testing.T) {
+			cfg, args, err := config.Load([]string{"-address", ":8080", "migrate", "up"})
+			if err != nil {
+				t.Fatalf("could not load config: %s", err)
+			}
+
+			if cfg.Server.Address != ":8080" {
+				t.Errorf("expected address %s, got %s", ":8080", cfg.Server.Address)
+			}
+
+			if len(args) != 2 || args[0] != "migrate" || args[1] != "up" {
+				t.Errorf("expected args [migrate up], got %v", args)
+			}
+		})
+
+		t.Run("flags should override env and env the file", func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			file := `{
+				"server": {"address": ":1000", "jwt_secret": "file0123456789abcdef0123456789abcdef"},
+				"game": {"tax_rate": 0.2, "staff_search_duration": "6h", "terrains": {"base_value": 100}}
+			}`
+			if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+				t.Fatalf("could not write config file: %s", err)
+			}
+
+			t.Setenv(config.CONFIG_FILE_KEY, path)
+			t.Setenv("JWT_SECRET", "")
+			t.Setenv("SERVER_ADDRESS", ":2000")
+			t.Setenv("TAX_RATE", "0.3")
+
+			cfg, _, err := config.Load([]string{"-tax-rate", "0.4"})
+			if err != nil {
+				t.Fatalf("could not load config: %s", err)
+			}
+
+			if cfg.Server.JwtSecret != "file0123456789abcdef0123456789abcdef" {
+				t.Errorf("expected secret from file, got %s", cfg.Server.JwtSecret)
+			}
+
+			if cfg.Server.Address != ":2000" {
+				t.Errorf("expected address from env, got %s", cfg.Server.Address)
+			}
+
+			if cfg.Game.TaxRate != 0.4 {
+				t.Errorf("expected tax rate from flags, got %f", cfg.Game.TaxRate)
+			}
+
+			if time.Duration(cfg.Game.StaffSearchDuration) != 6*time.Hour {
+				t.Errorf("expected search duration %s, got %s", 6*time.Hour, time.Duration(cfg.Game.StaffSearchDuration))
+			}
+
+			terrains := cfg.Game.Terrains
+			if terrains.BaseValue != 100 || terrains.UnitValue != config.Default().Game.Terrains.UnitValue {
+				t.Errorf("expected only the base value overridden, got %+v", terrains)
+			}
+		})
+
+		t.Run("should reject invalid env values", func(t *testing.T) {
+			t.Setenv("GAME_SPEED", "fast")
+
+			if _, _, err := config.Load(nil); err == nil {
+				t.Error("expected error parsing speed")
+			}
+		})
+
+		t.Run("should parse the epoch", func(t *testing.T) {
+			cfg, _, err := config.Load([]string{"-game-speed", "60", "-game-epoch", "2024-01-01T00:00:00Z"})
+			if err != nil {
+				t.Fatalf("could not load config: %s", err)
+			}
+
+			if !cfg.Clock.Epoch.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("expected epoch 2024-01-01, got %s", cfg.Clock.Epoch)
+			}
+		})
+
+		t.Run("should validate", func(t *testing.T) {
+			if _, _, err := config.Load([]string{"-tax-rate", "2"}); err == nil {
+				t.Error("expected error with tax rate above 1")
+			}
+
+			if _, _, err := config.Load([]string{"-database-driver", "oracle"}); err == nil {
+				t.Error("expected error with unsupported driver")
+			}
+
+			if _, _, err := config.Load([]string{"-max-delayed-payments", "0"}); err == nil {
+				t.Error("expected error without delayed payments")
+			}
+
+			if _, _, err := config.Load([]string{"-shutdown-timeout", "0s"}); err == nil {
+				t.Error("expected error without shutdown timeout")
+			}
+
+			if _, _, err := config.Load([]string{"-game-speed", "60"}); err == nil {
+				t.Error("expected error with game speed but no epoch")
+			}
+
+			if _, _, err := config.Load([]string{"-mail-driver", "smtp"}); err == nil {
+				t.Error("expected error without smtp host")
+			}
+
+			if _, _, err := config.Load([]string{"-mail-driver", "smtp", "-smtp-host", "localhost"}); err != nil {
+				t.Errorf("expected smtp with a host to be valid, got %s", err)
+			}
+		})
+
+		t.Run("should fail on a missing file", func(t *testing.T) {
+			if _, _, err := config.Load([]string{"-config", filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+				t.Error("expected error reading file")
+			}
+		})
+	})
+}
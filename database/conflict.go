@@ -0,0 +1,56 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Number of times a unit of work is run before giving up on a conflict
+const MAX_CONFLICT_ATTEMPTS = 3
+
+// ConflictError is returned when a conditional update finds that the row
+// changed since it was read, i.e. its version no longer matches
+type ConflictError struct {
+	Table string
+}
+
+func NewConflictError(table string) ConflictError {
+	return ConflictError{table}
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("%s changed while being updated, try again", e.Table)
+}
+
+// Reports whether err is, or wraps, a ConflictError
+func IsConflict(err error) bool {
+	var conflict ConflictError
+	return errors.As(err, &conflict)
+}
+
+// Turns a conditional update that affected no rows into a ConflictError
+func CheckVersion(result sql.Result, table string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return NewConflictError(table)
+	}
+
+	return nil
+}
+
+// Runs attempt until it succeeds, fails with anything but a conflict or
+// MAX_CONFLICT_ATTEMPTS is reached
+func retryOnConflict(attempt func() error) error {
+	var err error
+	for i := 0; i < MAX_CONFLICT_ATTEMPTS; i++ {
+		if err = attempt(); !IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -2,17 +2,31 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
+	"os"
 
 	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	_ "github.com/doug-martin/goqu/v9/dialect/sqlite3"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Driver names, which are also the names of the goqu dialects and of the
+// migrations directories
 const (
 	SQLITE   = "sqlite3"
 	MYSQL    = "mysql"
 	POSTGRES = "postgres"
 )
 
+const (
+	TEST_DATABASE_DRIVER_KEY = "TEST_DATABASE_DRIVER"
+	TEST_DATABASE_URL_KEY    = "TEST_DATABASE_URL"
+)
+
 type Connection struct {
 	Driver string
 	DB     *sql.DB
@@ -28,7 +42,12 @@ var connection *Connection
 // is already established, it is reused.
 func GetConnection(driver, connectionUrl string) (*Connection, error) {
 	if connection == nil {
-		conn, err := sql.Open(driver, connectionUrl)
+		dsn, err := prepareDSN(driver, connectionUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := sql.Open(driver, dsn)
 		if err != nil {
 			return nil, err
 		}
@@ -36,3 +55,52 @@ func GetConnection(driver, connectionUrl string) (*Connection, error) {
 	}
 	return connection, nil
 }
+
+// Closes the connection once every query in flight is done. The next call
+// to GetConnection establishes a new one.
+func (c *Connection) Close() error {
+	if connection == c {
+		connection = nil
+	}
+	return c.DB.Close()
+}
+
+// Returns the connection repository tests run against: the database in
+// TEST_DATABASE_DRIVER and TEST_DATABASE_URL when set, e.g. a local
+// Postgres or MySQL container migrated with `migrate up`, or the SQLite
+// file at path otherwise
+func GetTestConnection(path string) (*Connection, error) {
+	driver, url := os.Getenv(TEST_DATABASE_DRIVER_KEY), os.Getenv(TEST_DATABASE_URL_KEY)
+
+	switch driver {
+	case "":
+		return GetConnection(SQLITE, path)
+	case SQLITE, MYSQL, POSTGRES:
+		if url == "" {
+			return nil, fmt.Errorf("%s is required for driver %s", TEST_DATABASE_URL_KEY, driver)
+		}
+		return GetConnection(driver, url)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// MySQL needs parseTime to scan timestamps into time.Time, multiStatements
+// to run migrations with more than one statement and clientFoundRows so
+// updates that match a row without changing it still count it as affected
+func prepareDSN(driver, connectionUrl string) (string, error) {
+	if driver != MYSQL {
+		return connectionUrl, nil
+	}
+
+	config, err := mysql.ParseDSN(connectionUrl)
+	if err != nil {
+		return "", err
+	}
+
+	config.ParseTime = true
+	config.MultiStatements = true
+	config.ClientFoundRows = true
+
+	return config.FormatDSN(), nil
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// Runs the insert and returns the id of the created row. Postgres can't
+// report it through LastInsertId, so there it's read back with RETURNING.
+func InsertId(ctx context.Context, insert *goqu.InsertDataset) (int64, error) {
+	if insert.Dialect().Dialect() == POSTGRES {
+		var id int64
+		_, err := insert.Returning(goqu.C("id")).Executor().ScanValContext(ctx, &id)
+		return id, err
+	}
+
+	result, err := insert.Executor().ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Divides two integer expressions discarding the remainder, the way SQLite
+// does with "/". MySQL's "/" and Postgres' over aggregates yield decimals,
+// which can't be scanned into integers.
+func IntDiv(dialect string, dividend, divisor any) exp.LiteralExpression {
+	switch dialect {
+	case MYSQL:
+		return goqu.L("? DIV ?", dividend, divisor)
+	case POSTGRES:
+		return goqu.L("DIV(?, ?)", dividend, divisor)
+	default:
+		return goqu.L("? / ?", dividend, divisor)
+	}
+}
@@ -0,0 +1,71 @@
+package database_test
+
+import (
+	"api/database"
+	"context"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+func TestInsertId(t *testing.T) {
+	ctx := context.Background()
+
+	conn := newMemoryConnection(t)
+	if _, err := conn.DB.Exec("CREATE TABLE `items` (`id` INTEGER PRIMARY KEY AUTOINCREMENT, `name` VARCHAR(255))"); err != nil {
+		t.Fatalf("could not create table: %s", err)
+	}
+
+	builder := goqu.New(conn.Driver, conn.DB)
+
+	for _, expected := range []int64{1, 2} {
+		id, err := database.InsertId(ctx, builder.Insert("items").Rows(goqu.Record{"name": "foo"}))
+		if err != nil {
+			t.Fatalf("could not insert item: %s", err)
+		}
+
+		if id != expected {
+			t.Errorf("expected id %d, got %d", expected, id)
+		}
+	}
+}
+
+func TestIntDiv(t *testing.T) {
+	t.Run("should truncate on sqlite", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+
+		var result int64
+		if _, err := goqu.New(conn.Driver, conn.DB).
+			Select(database.IntDiv(database.SQLITE, 7, 2)).
+			ScanValContext(context.Background(), &result); err != nil {
+			t.Fatalf("could not divide: %s", err)
+		}
+
+		if result != 3 {
+			t.Errorf("expected %d, got %d", 3, result)
+		}
+	})
+
+	t.Run("should use each dialect's integer division", func(t *testing.T) {
+		expected := map[string]string{
+			database.SQLITE:   "SELECT `a` / `b` FROM `t`",
+			database.MYSQL:    "SELECT `a` DIV `b` FROM `t`",
+			database.POSTGRES: `SELECT DIV("a", "b") FROM "t"`,
+		}
+
+		for dialect, sql := range expected {
+			query, _, err := goqu.Dialect(dialect).
+				From("t").
+				Select(database.IntDiv(dialect, goqu.I("a"), goqu.I("b"))).
+				ToSQL()
+
+			if err != nil {
+				t.Fatalf("could not build %s query: %s", dialect, err)
+			}
+
+			if query != sql {
+				t.Errorf("expected %s query %q, got %q", dialect, sql, query)
+			}
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package database
+
+import "context"
+
+type fakeUnitOfWork struct{}
+
+// Creates a unit of work that just runs the work, retrying it on conflicts
+// like the real one, for services tested against fake repositories
+func NewFakeUnitOfWork() UnitOfWork {
+	return &fakeUnitOfWork{}
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, work func(ctx context.Context) error) error {
+	return retryOnConflict(func() error {
+		return work(ctx)
+	})
+}
@@ -0,0 +1,395 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// Table that keeps track of the applied migrations
+const MIGRATIONS_TABLE = "schema_migrations"
+
+var (
+	ErrSchemaAhead       = errors.New("database schema is ahead of the available migrations")
+	ErrMigrationNotFound = errors.New("migration not found")
+
+	migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+type (
+	// Migration is a numbered pair of up and down SQL scripts, read from
+	// files named like 000001_create_resources_table.up.sql
+	Migration struct {
+		Version uint64
+		Name    string
+		Up      string
+		Down    string
+	}
+
+	// MigrationStatus tells whether a migration was applied and when
+	MigrationStatus struct {
+		Version   uint64     `db:"version" json:"version"`
+		Name      string     `db:"name" json:"name"`
+		AppliedAt *time.Time `db:"applied_at" json:"applied_at"`
+	}
+
+	Migrator struct {
+		builder    *goqu.Database
+		migrations []*Migration
+	}
+)
+
+// Creates a migrator that applies the migrations found at the root of
+// source, usually an embed.FS, to the given connection
+func NewMigrator(conn *Connection, source fs.FS) (*Migrator, error) {
+	migrations, err := readMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		builder:    goqu.New(conn.Driver, conn.DB),
+		migrations: migrations,
+	}, nil
+}
+
+func readMigrations(source fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+
+	for _, entry := range entries {
+		matches := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		migration, found := byVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, matches[2])
+		}
+
+		contents, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Returns the version of the latest migration available
+func (m *Migrator) Latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Returns the version of the latest migration applied to the database,
+// zero if none was
+func (m *Migrator) Version(ctx context.Context) (uint64, error) {
+	if err := m.createTable(ctx); err != nil {
+		return 0, err
+	}
+
+	var version uint64
+	found, err := m.builder.
+		From(MIGRATIONS_TABLE).
+		Select(goqu.COALESCE(goqu.MAX("version"), 0)).
+		ScanValContext(ctx, &version)
+
+	if err != nil || !found {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Returns an error if the database was migrated further than the
+// migrations known to this binary, since it would not understand the schema
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return fmt.Errorf("%w: database is at %d, latest migration is %d", ErrSchemaAhead, version, m.Latest())
+	}
+
+	return nil
+}
+
+// Applies every pending migration, returning how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Rolls back the latest applied migration, returning how many were rolled
+// back, either one or zero if nothing was applied
+func (m *Migrator) Down(ctx context.Context) (int, error) {
+	version, err := m.Version(ctx)
+	if err != nil || version == 0 {
+		return 0, err
+	}
+
+	previous := uint64(0)
+	for _, migration := range m.migrations {
+		if migration.Version < version {
+			previous = migration.Version
+		}
+	}
+
+	return m.To(ctx, previous)
+}
+
+// Applies or rolls back migrations until the database is at the given
+// version, returning how many migrations ran. Each migration runs in its
+// own transaction, so a failure leaves the database at the last one that
+// succeeded.
+func (m *Migrator) To(ctx context.Context, target uint64) (int, error) {
+	if err := m.Check(ctx); err != nil {
+		return 0, err
+	}
+
+	if target != 0 && m.find(target) == nil {
+		return 0, fmt.Errorf("%w: %d", ErrMigrationNotFound, target)
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, migration := range m.migrations {
+		if migration.Version > target || applied[migration.Version] {
+			continue
+		}
+
+		if err := m.up(ctx, migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= target || !applied[migration.Version] {
+			continue
+		}
+
+		if err := m.down(ctx, migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Records every migration up to the given version as applied without
+// running it, returning how many were recorded. It's meant for databases
+// whose schema was created by hand or before migrations were tracked.
+func (m *Migrator) Baseline(ctx context.Context, version uint64) (int, error) {
+	if err := m.Check(ctx); err != nil {
+		return 0, err
+	}
+
+	if m.find(version) == nil {
+		return 0, fmt.Errorf("%w: %d", ErrMigrationNotFound, version)
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := m.builder.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	count := 0
+
+	for _, migration := range m.migrations {
+		if migration.Version > version || applied[migration.Version] {
+			continue
+		}
+
+		if err := m.record(ctx, tx, migration); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	return count, tx.Commit()
+}
+
+// Returns every known migration and when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	if err := m.createTable(ctx); err != nil {
+		return nil, err
+	}
+
+	records := make([]*MigrationStatus, 0)
+	if err := m.builder.
+		From(MIGRATIONS_TABLE).
+		Select("version", "name", "applied_at").
+		ScanStructsContext(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	appliedAt := make(map[uint64]*time.Time)
+	for _, record := range records {
+		appliedAt[record.Version] = record.AppliedAt
+	}
+
+	status := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status = append(status, &MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: appliedAt[migration.Version],
+		})
+	}
+
+	return status, nil
+}
+
+func (m *Migrator) up(ctx context.Context, migration *Migration) error {
+	tx, err := m.builder.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return fmt.Errorf("could not apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := m.record(ctx, tx, migration); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) record(ctx context.Context, tx *goqu.TxDatabase, migration *Migration) error {
+	_, err := tx.
+		Insert(MIGRATIONS_TABLE).
+		Rows(goqu.Record{
+			"version":    migration.Version,
+			"name":       migration.Name,
+			"applied_at": time.Now().UTC(),
+		}).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (m *Migrator) down(ctx context.Context, migration *Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+	}
+
+	tx, err := m.builder.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("could not roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.
+		Delete(MIGRATIONS_TABLE).
+		Where(goqu.I("version").Eq(migration.Version)).
+		Executor().
+		ExecContext(ctx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[uint64]bool, error) {
+	versions := make([]uint64, 0)
+	if err := m.builder.
+		From(MIGRATIONS_TABLE).
+		Select("version").
+		ScanValsContext(ctx, &versions); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint64]bool)
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) find(version uint64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) createTable(ctx context.Context) error {
+	// Left unquoted since backticks are MySQL and SQLite only
+	_, err := m.builder.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+MIGRATIONS_TABLE+" ("+
+		"version BIGINT NOT NULL PRIMARY KEY, "+
+		"name VARCHAR(255) NOT NULL, "+
+		"applied_at TIMESTAMP NOT NULL)")
+	return err
+}
@@ -0,0 +1,283 @@
+package database_test
+
+import (
+	"api/database"
+	"api/migrations"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func newMemoryConnection(t *testing.T) *database.Connection {
+	db, err := sql.Open(database.SQLITE, ":memory:")
+	if err != nil {
+		t.Fatalf("could not open database: %s", err)
+	}
+
+	// Every connection to :memory: is a different database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	return &database.Connection{Driver: database.SQLITE, DB: db}
+}
+
+func tableExists(t *testing.T, conn *database.Connection, table string) bool {
+	var name string
+	err := conn.DB.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("could not check table %s: %s", table, err)
+	}
+	return name == table
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	source := fstest.MapFS{
+		"000001_create_users.up.sql":      {Data: []byte("CREATE TABLE `users` (`id` INTEGER PRIMARY KEY);")},
+		"000001_create_users.down.sql":    {Data: []byte("DROP TABLE IF EXISTS `users`;")},
+		"000002_create_posts.up.sql":      {Data: []byte("CREATE TABLE `posts` (`id` INTEGER PRIMARY KEY);")},
+		"000002_create_posts.down.sql":    {Data: []byte("DROP TABLE IF EXISTS `posts`;")},
+		"000003_create_comments.up.sql":   {Data: []byte("CREATE TABLE `comments` (`id` INTEGER PRIMARY KEY);")},
+		"000003_create_comments.down.sql": {Data: []byte("DROP TABLE IF EXISTS `comments`;")},
+		"README.md":                       {Data: []byte("not a migration")},
+	}
+
+	t.Run("Up", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+
+		migrator, err := database.NewMigrator(conn, source)
+		if err != nil {
+			t.Fatalf("could not create migrator: %s", err)
+		}
+
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			t.Fatalf("could not migrate: %s", err)
+		}
+
+		if count != 3 {
+			t.Errorf("expected %d migrations, got %d", 3, count)
+		}
+
+		for _, table := range []string{"users", "posts", "comments"} {
+			if !tableExists(t, conn, table) {
+				t.Errorf("expected table %s to exist", table)
+			}
+		}
+
+		count, err = migrator.Up(ctx)
+		if err != nil {
+			t.Fatalf("could not migrate: %s", err)
+		}
+
+		if count != 0 {
+			t.Errorf("expected nothing to run again, got %d", count)
+		}
+	})
+
+	t.Run("Down", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+		migrator, _ := database.NewMigrator(conn, source)
+
+		if _, err := migrator.Up(ctx); err != nil {
+			t.Fatalf("could not migrate: %s", err)
+		}
+
+		if _, err := migrator.Down(ctx); err != nil {
+			t.Fatalf("could not roll back: %s", err)
+		}
+
+		if tableExists(t, conn, "comments") {
+			t.Error("expected comments table to be dropped")
+		}
+
+		version, _ := migrator.Version(ctx)
+		if version != 2 {
+			t.Errorf("expected version %d, got %d", 2, version)
+		}
+	})
+
+	t.Run("To", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+		migrator, _ := database.NewMigrator(conn, source)
+
+		if _, err := migrator.To(ctx, 2); err != nil {
+			t.Fatalf("could not migrate: %s", err)
+		}
+
+		if tableExists(t, conn, "comments") {
+			t.Error("expected comments table not to exist")
+		}
+
+		if _, err := migrator.To(ctx, 0); err != nil {
+			t.Fatalf("could not roll back: %s", err)
+		}
+
+		if tableExists(t, conn, "users") {
+			t.Error("expected users table to be dropped")
+		}
+
+		if _, err := migrator.To(ctx, 5); !errors.Is(err, database.ErrMigrationNotFound) {
+			t.Errorf("expected error %s, got %s", database.ErrMigrationNotFound, err)
+		}
+	})
+
+	t.Run("Baseline", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+		migrator, _ := database.NewMigrator(conn, source)
+
+		// Schema created by hand, without recording migrations
+		if _, err := conn.DB.Exec("CREATE TABLE `users` (`id` INTEGER PRIMARY KEY); CREATE TABLE `posts` (`id` INTEGER PRIMARY KEY);"); err != nil {
+			t.Fatalf("could not create tables: %s", err)
+		}
+
+		count, err := migrator.Baseline(ctx, 2)
+		if err != nil {
+			t.Fatalf("could not baseline: %s", err)
+		}
+
+		if count != 2 {
+			t.Errorf("expected %d migrations recorded, got %d", 2, count)
+		}
+
+		version, _ := migrator.Version(ctx)
+		if version != 2 {
+			t.Errorf("expected version %d, got %d", 2, version)
+		}
+
+		count, err = migrator.Up(ctx)
+		if err != nil {
+			t.Fatalf("could not migrate after baseline: %s", err)
+		}
+
+		if count != 1 || !tableExists(t, conn, "comments") {
+			t.Errorf("expected only the comments migration to run, got %d", count)
+		}
+
+		if _, err := migrator.Baseline(ctx, 5); !errors.Is(err, database.ErrMigrationNotFound) {
+			t.Errorf("expected error %s, got %s", database.ErrMigrationNotFound, err)
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+		migrator, _ := database.NewMigrator(conn, source)
+
+		if _, err := migrator.To(ctx, 1); err != nil {
+			t.Fatalf("could not migrate: %s", err)
+		}
+
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("could not get status: %s", err)
+		}
+
+		if len(status) != 3 {
+			t.Fatalf("expected %d migrations, got %d", 3, len(status))
+		}
+
+		if status[0].Name != "create_users" || status[0].AppliedAt == nil {
+			t.Errorf("expected create_users to be applied, got %+v", status[0])
+		}
+
+		if status[1].AppliedAt != nil {
+			t.Errorf("expected %s to be pending", status[1].Name)
+		}
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+		migrator, _ := database.NewMigrator(conn, source)
+
+		if _, err := migrator.Up(ctx); err != nil {
+			t.Fatalf("could not migrate: %s", err)
+		}
+
+		older, _ := database.NewMigrator(conn, fstest.MapFS{
+			"000001_create_users.up.sql": source["000001_create_users.up.sql"],
+		})
+
+		if err := older.Check(ctx); !errors.Is(err, database.ErrSchemaAhead) {
+			t.Errorf("expected error %s, got %s", database.ErrSchemaAhead, err)
+		}
+
+		if _, err := older.Up(ctx); !errors.Is(err, database.ErrSchemaAhead) {
+			t.Errorf("expected error %s, got %s", database.ErrSchemaAhead, err)
+		}
+	})
+
+	t.Run("failed migration", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+		migrator, _ := database.NewMigrator(conn, fstest.MapFS{
+			"000001_create_users.up.sql": source["000001_create_users.up.sql"],
+			"000002_broken.up.sql":       {Data: []byte("CREATE TABLE `posts` (`id` INTEGER PRIMARY KEY); CREATE TABLE oops")},
+		})
+
+		count, err := migrator.Up(ctx)
+		if err == nil {
+			t.Fatal("expected broken migration to fail")
+		}
+
+		if count != 1 {
+			t.Errorf("expected %d migrations to run, got %d", 1, count)
+		}
+
+		if tableExists(t, conn, "posts") {
+			t.Error("expected broken migration to be rolled back")
+		}
+
+		version, _ := migrator.Version(ctx)
+		if version != 1 {
+			t.Errorf("expected version %d, got %d", 1, version)
+		}
+	})
+
+	t.Run("embedded migrations", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+
+		source, err := migrations.For(database.SQLITE)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		migrator, err := database.NewMigrator(conn, source)
+		if err != nil {
+			t.Fatalf("could not load migrations: %s", err)
+		}
+
+		if _, err := migrator.Up(ctx); err != nil {
+			t.Fatalf("could not apply migrations: %s", err)
+		}
+
+		if _, err := migrator.To(ctx, 0); err != nil {
+			t.Fatalf("could not roll back migrations: %s", err)
+		}
+	})
+	t.Run("every driver at the same version", func(t *testing.T) {
+		conn := newMemoryConnection(t)
+
+		var latest uint64
+		for _, driver := range []string{database.SQLITE, database.MYSQL, database.POSTGRES} {
+			source, err := migrations.For(driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			migrator, err := database.NewMigrator(conn, source)
+			if err != nil {
+				t.Fatalf("could not load %s migrations: %s", driver, err)
+			}
+
+			if latest == 0 {
+				latest = migrator.Latest()
+			}
+
+			if migrator.Latest() != latest {
+				t.Errorf("expected %s migrations at version %d, got %d", driver, latest, migrator.Latest())
+			}
+		}
+	})
+}
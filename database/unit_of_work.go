@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type (
+	// UnitOfWork runs a business operation in a single transaction. The
+	// context handed to the work carries the transaction, so repositories
+	// called with it read and write through it instead of on their own.
+	UnitOfWork interface {
+		Do(ctx context.Context, work func(ctx context.Context) error) error
+	}
+
+	// Builder is what the database and its transactions have in common
+	Builder interface {
+		From(from ...any) *goqu.SelectDataset
+		Select(cols ...any) *goqu.SelectDataset
+		Insert(table any) *goqu.InsertDataset
+		Update(table any) *goqu.UpdateDataset
+		Delete(table any) *goqu.DeleteDataset
+	}
+
+	// Tx is a transaction a repository either started or joined from a
+	// unit of work. Joined transactions are left for the unit of work to
+	// commit or roll back.
+	Tx struct {
+		*DB
+		joined bool
+	}
+
+	goquUnitOfWork struct {
+		builder *goqu.Database
+	}
+
+	txKey struct{}
+)
+
+func NewUnitOfWork(conn *Connection) UnitOfWork {
+	return &goquUnitOfWork{goqu.New(conn.Driver, conn.DB)}
+}
+
+// Runs work in a transaction that is committed if it returns no error and
+// rolled back otherwise. Nested units of work join the outer one. Work that
+// fails with a ConflictError is run again in a fresh transaction, so it
+// reads the rows' latest versions, up to MAX_CONFLICT_ATTEMPTS times.
+func (u *goquUnitOfWork) Do(ctx context.Context, work func(ctx context.Context) error) error {
+	if FromContext(ctx) != nil {
+		return work(ctx)
+	}
+
+	return retryOnConflict(func() error {
+		return u.run(ctx, work)
+	})
+}
+
+func (u *goquUnitOfWork) run(ctx context.Context, work func(ctx context.Context) error) error {
+	tx, err := u.builder.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := work(WithTx(ctx, &DB{TxDatabase: tx})); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Returns a copy of ctx carrying the given transaction
+func WithTx(ctx context.Context, tx *DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// Returns the transaction of the unit of work running in ctx, if any
+func FromContext(ctx context.Context) *DB {
+	tx, _ := ctx.Value(txKey{}).(*DB)
+	return tx
+}
+
+// Joins the unit of work running in ctx or starts a new transaction
+func BeginTx(ctx context.Context, builder *goqu.Database) (*Tx, error) {
+	if tx := FromContext(ctx); tx != nil {
+		return &Tx{tx, true}, nil
+	}
+
+	tx, err := builder.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{&DB{TxDatabase: tx}, false}, nil
+}
+
+// Returns the unit of work's transaction running in ctx, so reads see its
+// writes and take part in it, or the builder when there is none
+func Query(ctx context.Context, builder *goqu.Database) Builder {
+	if tx := FromContext(ctx); tx != nil {
+		return tx
+	}
+	return builder
+}
+
+func (t *Tx) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.TxDatabase.Commit()
+}
+
+func (t *Tx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.TxDatabase.Rollback()
+}
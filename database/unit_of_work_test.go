@@ -0,0 +1,196 @@
+package database_test
+
+import (
+	"api/database"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+
+	conn := newMemoryConnection(t)
+	if _, err := conn.DB.Exec("CREATE TABLE `items` (`id` INTEGER PRIMARY KEY, `name` VARCHAR(255))"); err != nil {
+		t.Fatalf("could not create table: %s", err)
+	}
+
+	builder := goqu.New(conn.Driver, conn.DB)
+	uow := database.NewUnitOfWork(conn)
+
+	count := func(t *testing.T) int {
+		var total int
+		if _, err := builder.From("items").Select(goqu.COUNT("*")).ScanValContext(ctx, &total); err != nil {
+			t.Fatalf("could not count items: %s", err)
+		}
+		return total
+	}
+
+	// Saves an item the way repositories do, joining the unit of work if any
+	insert := func(ctx context.Context, name string) error {
+		tx, err := database.BeginTx(ctx, builder)
+		if err != nil {
+			return err
+		}
+
+		defer tx.Rollback()
+
+		if _, err := tx.Insert("items").Rows(goqu.Record{"name": name}).Executor().ExecContext(ctx); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	t.Cleanup(func() {
+		conn.DB.Exec("DELETE FROM `items`")
+	})
+
+	t.Run("should commit", func(t *testing.T) {
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "foo"); err != nil {
+				return err
+			}
+			return insert(ctx, "bar")
+		})
+
+		if err != nil {
+			t.Fatalf("could not run unit of work: %s", err)
+		}
+
+		if total := count(t); total != 2 {
+			t.Errorf("expected %d items, got %d", 2, total)
+		}
+	})
+
+	t.Run("should roll back every repository", func(t *testing.T) {
+		expected := count(t)
+		errFailed := errors.New("not enough cash")
+
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "bazz"); err != nil {
+				return err
+			}
+			return errFailed
+		})
+
+		if !errors.Is(err, errFailed) {
+			t.Errorf("expected error %s, got %s", errFailed, err)
+		}
+
+		if total := count(t); total != expected {
+			t.Errorf("expected %d items, got %d", expected, total)
+		}
+	})
+
+	t.Run("should read its own writes", func(t *testing.T) {
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "qux"); err != nil {
+				return err
+			}
+
+			var id int64
+
+			found, err := database.Query(ctx, builder).
+				From("items").
+				Select("id").
+				Where(goqu.I("name").Eq("qux")).
+				ScanValContext(ctx, &id)
+
+			if err != nil {
+				return err
+			}
+
+			if !found {
+				t.Error("expected item to be found inside the unit of work")
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("could not run unit of work: %s", err)
+		}
+	})
+
+	t.Run("should join outer unit of work", func(t *testing.T) {
+		expected := count(t)
+
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			if err := uow.Do(ctx, func(ctx context.Context) error {
+				return insert(ctx, "inner")
+			}); err != nil {
+				return err
+			}
+			return errors.New("outer failed")
+		})
+
+		if err == nil {
+			t.Fatal("expected outer unit of work to fail")
+		}
+
+		if total := count(t); total != expected {
+			t.Errorf("expected inner work to roll back, got %d items", total)
+		}
+	})
+	t.Run("should retry on conflict", func(t *testing.T) {
+		expected := count(t)
+		attempts := 0
+
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			attempts++
+			if err := insert(ctx, "retried"); err != nil {
+				return err
+			}
+			if attempts < database.MAX_CONFLICT_ATTEMPTS {
+				return database.NewConflictError("items")
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("could not run unit of work: %s", err)
+		}
+
+		if attempts != database.MAX_CONFLICT_ATTEMPTS {
+			t.Errorf("expected %d attempts, got %d", database.MAX_CONFLICT_ATTEMPTS, attempts)
+		}
+
+		if total := count(t); total != expected+1 {
+			t.Errorf("expected failed attempts to roll back, got %d items", total-expected)
+		}
+	})
+
+	t.Run("should give up after too many conflicts", func(t *testing.T) {
+		attempts := 0
+
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			attempts++
+			return fmt.Errorf("saving item: %w", database.NewConflictError("items"))
+		})
+
+		if !database.IsConflict(err) {
+			t.Errorf("expected conflict, got %v", err)
+		}
+
+		if attempts != database.MAX_CONFLICT_ATTEMPTS {
+			t.Errorf("expected %d attempts, got %d", database.MAX_CONFLICT_ATTEMPTS, attempts)
+		}
+	})
+
+	t.Run("should not retry other errors", func(t *testing.T) {
+		attempts := 0
+
+		uow.Do(ctx, func(ctx context.Context) error {
+			attempts++
+			return errors.New("not enough cash")
+		})
+
+		if attempts != 1 {
+			t.Errorf("expected %d attempt, got %d", 1, attempts)
+		}
+	})
+}
@@ -112,7 +112,7 @@ func (r *goquRepository) GetCompanyBonds(ctx context.Context, companyId int64) (
 func (r *goquRepository) GetBond(ctx context.Context, bondId int64) (*Bond, error) {
 	bond := new(Bond)
 
-	found, err := r.builder.
+	found, err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("b.id"),
 			goqu.I("b.amount"),
@@ -132,7 +132,7 @@ func (r *goquRepository) GetBond(ctx context.Context, bondId int64) (*Bond, erro
 			goqu.On(goqu.I("b.company_id").Eq(goqu.I("c.id"))),
 		).
 		Where(goqu.I("b.id").Eq(bondId)).
-		GroupBy(goqu.I("b.id")).
+		GroupBy(goqu.I("b.id"), goqu.I("c.id")).
 		ScanStructContext(ctx, bond)
 
 	if err != nil {
@@ -155,7 +155,7 @@ func (r *goquRepository) GetBond(ctx context.Context, bondId int64) (*Bond, erro
 func (r *goquRepository) getCreditors(ctx context.Context, bondId int64) ([]*Creditor, error) {
 	creditors := make([]*Creditor, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("bc.interest_rate"),
 			goqu.I("bc.interest_paid"),
@@ -163,6 +163,7 @@ func (r *goquRepository) getCreditors(ctx context.Context, bondId int64) ([]*Cre
 			goqu.I("bc.principal_paid"),
 			goqu.I("bc.payable_from"),
 			goqu.I("bc.delayed_payments"),
+			goqu.I("bc.version"),
 			goqu.I("c.id").As(goqu.C("company.id")),
 			goqu.I("c.name").As(goqu.C("company.name")),
 		).
@@ -182,7 +183,7 @@ func (r *goquRepository) getCreditors(ctx context.Context, bondId int64) ([]*Cre
 }
 
 func (r *goquRepository) SaveBond(ctx context.Context, bond *Bond) (*Bond, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -190,7 +191,7 @@ func (r *goquRepository) SaveBond(ctx context.Context, bond *Bond) (*Bond, error
 	defer tx.Rollback()
 
 	_, err = r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          int(bond.Amount),
 			Classification: accounting.BOND_EMISSION,
@@ -207,12 +208,7 @@ func (r *goquRepository) SaveBond(ctx context.Context, bond *Bond) (*Bond, error
 		return nil, err
 	}
 
-	result, err := tx.Insert(goqu.T("bonds")).Rows(bond).Executor().Exec()
-	if err != nil {
-		return nil, err
-	}
-
-	id, err := result.LastInsertId()
+	id, err := database.InsertId(ctx, tx.Insert(goqu.T("bonds")).Rows(bond))
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +222,7 @@ func (r *goquRepository) SaveBond(ctx context.Context, bond *Bond) (*Bond, error
 }
 
 func (r *goquRepository) PayBondInterest(ctx context.Context, bond *Bond, creditor *Creditor) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
@@ -234,7 +230,7 @@ func (r *goquRepository) PayBondInterest(ctx context.Context, bond *Bond, credit
 	defer tx.Rollback()
 
 	_, err = r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          -int(creditor.GetInterest()),
 			Classification: accounting.BOND_INTEREST_EXPENSE,
@@ -251,7 +247,7 @@ func (r *goquRepository) PayBondInterest(ctx context.Context, bond *Bond, credit
 	}
 
 	_, err = r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          int(creditor.GetInterest()),
 			Classification: accounting.BOND_INTEREST_INCOME,
@@ -263,20 +259,10 @@ func (r *goquRepository) PayBondInterest(ctx context.Context, bond *Bond, credit
 		uint64(creditor.Id),
 	)
 
-	_, err = tx.
-		Update(goqu.T("bonds_creditors")).
-		Set(goqu.Record{
-			"delayed_payments": 0,
-			"interest_paid":    goqu.L("? + ?", goqu.I("interest_paid"), creditor.GetInterest()),
-		}).
-		Where(goqu.And(
-			goqu.I("bond_id").Eq(bond.Id),
-			goqu.I("company_id").Eq(creditor.Id),
-		)).
-		Executor().
-		Exec()
-
-	if err != nil {
+	if err := r.updateCreditor(tx.DB, bond, creditor, goqu.Record{
+		"delayed_payments": 0,
+		"interest_paid":    goqu.L("? + ?", goqu.I("interest_paid"), creditor.GetInterest()),
+	}); err != nil {
 		return err
 	}
 
@@ -284,7 +270,7 @@ func (r *goquRepository) PayBondInterest(ctx context.Context, bond *Bond, credit
 }
 
 func (r *goquRepository) SaveCreditor(ctx context.Context, bond *Bond, creditor *Creditor) (*Creditor, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -293,7 +279,7 @@ func (r *goquRepository) SaveCreditor(ctx context.Context, bond *Bond, creditor
 
 	// Transfer to issuer
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          int(creditor.Principal),
 			Classification: accounting.BOND_PURCHASED,
@@ -310,7 +296,7 @@ func (r *goquRepository) SaveCreditor(ctx context.Context, bond *Bond, creditor
 
 	// Remove from creditor
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          -int(creditor.Principal),
 			Classification: accounting.BOND_PURCHASE,
@@ -349,7 +335,7 @@ func (r *goquRepository) SaveCreditor(ctx context.Context, bond *Bond, creditor
 }
 
 func (r *goquRepository) BuyBackBond(ctx context.Context, amount int64, creditor *Creditor, bond *Bond) (*Creditor, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -357,7 +343,7 @@ func (r *goquRepository) BuyBackBond(ctx context.Context, amount int64, creditor
 	defer tx.Rollback()
 
 	_, err = r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          -int(amount),
 			Description:    "Bond buy back",
@@ -371,7 +357,7 @@ func (r *goquRepository) BuyBackBond(ctx context.Context, amount int64, creditor
 	}
 
 	_, err = r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          int(amount),
 			Description:    "Bond buy back",
@@ -384,19 +370,9 @@ func (r *goquRepository) BuyBackBond(ctx context.Context, amount int64, creditor
 		return nil, err
 	}
 
-	_, err = tx.
-		Update(goqu.T("bonds_creditors")).
-		Set(goqu.Record{
-			"principal_paid": goqu.L("? + ?", goqu.I("principal_paid"), amount),
-		}).
-		Where(goqu.And(
-			goqu.I("bond_id").Eq(bond.Id),
-			goqu.I("company_id").Eq(creditor.Id),
-		)).
-		Executor().
-		Exec()
-
-	if err != nil {
+	if err := r.updateCreditor(tx.DB, bond, creditor, goqu.Record{
+		"principal_paid": goqu.L("? + ?", goqu.I("principal_paid"), amount),
+	}); err != nil {
 		return nil, err
 	}
 
@@ -411,3 +387,31 @@ func (r *goquRepository) BuyBackBond(ctx context.Context, amount int64, creditor
 
 	return bond.GetCreditor(int64(creditor.Id))
 }
+
+// Updates the creditor only if it wasn't changed since it was read, bumping
+// its version
+func (r *goquRepository) updateCreditor(tx *database.DB, bond *Bond, creditor *Creditor, record goqu.Record) error {
+	record["version"] = creditor.Version + 1
+
+	result, err := tx.
+		Update(goqu.T("bonds_creditors")).
+		Set(record).
+		Where(goqu.And(
+			goqu.I("bond_id").Eq(bond.Id),
+			goqu.I("company_id").Eq(creditor.Id),
+			goqu.I("version").Eq(creditor.Version),
+		)).
+		Executor().
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	if err := database.CheckVersion(result, "bond creditor"); err != nil {
+		return err
+	}
+
+	creditor.Version++
+	return nil
+}
@@ -2,6 +2,7 @@ package bonds_test
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/company"
 	"api/database"
 	"api/financing/bonds"
@@ -11,7 +12,7 @@ import (
 )
 
 func TestBondRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../../test.db")
+	conn, err := database.GetTestConnection("../../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -25,10 +26,10 @@ func TestBondRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password) VALUES
-        (1, "Coca-Cola", "coke@email.com", "aoeu"),
-        (2, "Pepsi", "coke@email.com", "aoeu"),
-        (3, "Tesla", "coke@email.com", "aoeu"),
-        (4, "Amazon", "coke@email.com", "aoeu")
+        (1, 'Coca-Cola', 'coke@email.com', 'aoeu'),
+        (2, 'Pepsi', 'coke@email.com', 'aoeu'),
+        (3, 'Tesla', 'coke@email.com', 'aoeu'),
+        (4, 'Amazon', 'coke@email.com', 'aoeu')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -42,9 +43,9 @@ func TestBondRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO bonds_creditors (bond_id, company_id, principal, interest_rate, payable_from, delayed_payments) VALUES
-        (1, 2, 100000000, 0.15, "2024-12-12 00:00:00", 1),
-        (1, 3, 100000000, 0.15, "2024-12-12 00:00:00", 2),
-        (3, 3, 50000000, 0.5, "2024-12-12 00:00:00", 0)
+        (1, 2, 100000000, 0.15, '2024-12-12 00:00:00', 1),
+        (1, 3, 100000000, 0.15, '2024-12-12 00:00:00', 2),
+        (3, 3, 50000000, 0.5, '2024-12-12 00:00:00', 0)
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -57,6 +58,9 @@ func TestBondRepository(t *testing.T) {
 		if _, err := conn.DB.Exec(`DELETE FROM transactions`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec(`DELETE FROM company_balances`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
 		if _, err := conn.DB.Exec(`DELETE FROM bonds_creditors`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
@@ -71,8 +75,8 @@ func TestBondRepository(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	accountingRepo := accounting.NewRepository(conn)
-	companyRepo := company.NewRepository(conn, accountingRepo)
+	accountingRepo := accounting.NewRepository(conn, clock.New())
+	companyRepo := company.NewRepository(conn, accountingRepo, clock.New())
 	repository := bonds.NewRepository(conn, accountingRepo)
 
 	t.Run("GetBonds", func(t *testing.T) {
@@ -313,6 +317,7 @@ func TestBondRepository(t *testing.T) {
 			InterestPaid:    0,
 			PrincipalPaid:   0,
 			DelayedPayments: 2,
+			Version:         1,
 		})
 
 		if err != nil {
@@ -411,6 +416,7 @@ func TestBondRepository(t *testing.T) {
 
 		creditor := &bonds.Creditor{
 			Company: &company.Company{Id: 3},
+			Version: 1,
 		}
 
 		creditor, err := repository.BuyBackBond(ctx, 250_000_00, creditor, bond)
@@ -448,5 +454,8 @@ func TestBondRepository(t *testing.T) {
 		if creditor.GetPrincipal() != 250_000_00 {
 			t.Errorf("expected principal %d, got %d", 250_000_00, creditor.GetPrincipal())
 		}
+		if creditor.Version != 2 {
+			t.Errorf("expected version %d, got %d", 2, creditor.Version)
+		}
 	})
 }
@@ -2,6 +2,7 @@ package bonds
 
 import (
 	"api/auth"
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -9,7 +10,7 @@ import (
 )
 
 func CreateEndpoints(group *echo.Group, service Service) {
-	group.GET("/bonds", func(c echo.Context) error {
+	list := group.GET("/bonds", func(c echo.Context) error {
 		companyId, err := strconv.ParseInt(c.QueryParam("company"), 10, 64)
 		if err == nil {
 			bonds, err := service.GetCompanyBonds(c.Request().Context(), companyId)
@@ -37,7 +38,7 @@ func CreateEndpoints(group *echo.Group, service Service) {
 		return c.JSON(http.StatusOK, bonds)
 	})
 
-	group.POST("/bonds", func(c echo.Context) error {
+	issue := group.POST("/bonds", func(c echo.Context) error {
 		request := struct {
 			Rate   float64 `json:"rate" validate:"required"`
 			Amount int64   `json:"amount" validate:"required"`
@@ -64,7 +65,7 @@ func CreateEndpoints(group *echo.Group, service Service) {
 		return c.JSON(http.StatusOK, bond)
 	})
 
-	group.POST("/bonds/:bondId", func(c echo.Context) error {
+	buyBack := group.POST("/bonds/:bondId", func(c echo.Context) error {
 		bondId, err := strconv.ParseInt(c.Param("bondId"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -95,4 +96,8 @@ func CreateEndpoints(group *echo.Group, service Service) {
 
 		return c.JSON(http.StatusOK, creditor)
 	})
+
+	server.AllowApiKeys(auth.SCOPE_FINANCE_READ, list)
+	server.AllowMembers(auth.SCOPE_FINANCE_READ, list)
+	server.AllowMembers(auth.SCOPE_FINANCE_WRITE, issue, buyBack)
 }
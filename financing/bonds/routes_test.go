@@ -2,8 +2,11 @@ package bonds_test
 
 import (
 	"api/auth"
+	"api/clock"
 	"api/company"
+	"api/database"
 	"api/financing/bonds"
+	"api/mail"
 	"api/notification"
 	"api/server"
 	"encoding/json"
@@ -15,18 +18,16 @@ import (
 )
 
 func TestBondRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
 	token, err := auth.GenerateToken(1, "secret")
 	if err != nil {
 		t.Fatalf("could not generate jwt token: %s", err)
 	}
 
 	companyRepo := company.NewFakeRepository()
-	companySvc := company.NewService(companyRepo)
-	svc := bonds.NewService(bonds.NewFakeRepository(companyRepo), companySvc, notification.NoOpNotifier(), log.Default())
+	companySvc := company.NewService(companyRepo, "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	svc := bonds.NewService(bonds.NewFakeRepository(companyRepo), companySvc, notification.NoOpNotifier(), log.Default(), database.NewFakeUnitOfWork(), clock.New())
 
-	svr := server.NewServer()
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
 	group := svr.Group("/financing")
 	bonds.CreateEndpoints(group, svc)
 
@@ -7,19 +7,34 @@ import (
 	"time"
 )
 
-type scheduledService struct {
-	service   Service
-	scheduler *scheduler.Scheduler
-}
+const BOND_INTEREST_JOB = "bonds.interest"
+
+type (
+	scheduledService struct {
+		service   Service
+		scheduler *scheduler.Scheduler
+	}
+
+	interestJob struct {
+		BondId     int64 `json:"bond_id"`
+		CreditorId int64 `json:"creditor_id"`
+	}
+)
 
 func NewScheduledService(service Service, scheduler *scheduler.Scheduler) Service {
-	return &scheduledService{service, scheduler}
+	scheduled := &scheduledService{service, scheduler}
+	scheduler.Register(BOND_INTEREST_JOB, scheduled.payInterest)
+	return scheduled
 }
 
 func (s *scheduledService) GetBonds(ctx context.Context, page, limit uint) ([]*Bond, error) {
 	return s.service.GetBonds(ctx, page, limit)
 }
 
+func (s *scheduledService) GetBond(ctx context.Context, bondId int64) (*Bond, error) {
+	return s.service.GetBond(ctx, bondId)
+}
+
 func (s *scheduledService) GetCompanyBonds(ctx context.Context, companyId int64) ([]*Bond, error) {
 	return s.service.GetCompanyBonds(ctx, companyId)
 }
@@ -34,12 +49,22 @@ func (s *scheduledService) BuyBond(ctx context.Context, amount, bondId, companyI
 		return nil, nil, err
 	}
 
-	s.scheduler.Repeat(fmt.Sprintf("BOND_%d_CREDITOR_%d", bond.Id, creditor.Id), Week, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
+	job, err := scheduler.NewJob(
+		fmt.Sprintf("BOND_%d_CREDITOR_%d", bond.Id, creditor.Id),
+		BOND_INTEREST_JOB,
+		bond.CompanyId,
+		interestJob{bond.Id, int64(creditor.Id)},
+		s.scheduler.Now().Add(Week),
+	)
 
-		return s.PayBondInterest(ctx, creditor, bond)
-	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	job.RepeatsEvery = Week
+	if err := s.scheduler.Schedule(ctx, job); err != nil {
+		return nil, nil, err
+	}
 
 	return bond, creditor, nil
 }
@@ -51,3 +76,25 @@ func (s *scheduledService) PayBondInterest(ctx context.Context, creditor *Credit
 func (s *scheduledService) BuyBackBond(ctx context.Context, amount, bondId, creditorId, companyId int64) (*Creditor, error) {
 	return s.service.BuyBackBond(ctx, amount, bondId, creditorId, companyId)
 }
+
+func (s *scheduledService) payInterest(job *scheduler.Job) error {
+	var payload interestJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	bond, err := s.service.GetBond(ctx, payload.BondId)
+	if err != nil {
+		return err
+	}
+
+	creditor, err := bond.GetCreditor(payload.CreditorId)
+	if err != nil {
+		return err
+	}
+
+	return s.PayBondInterest(ctx, creditor, bond)
+}
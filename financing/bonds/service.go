@@ -1,7 +1,9 @@
 package bonds
 
 import (
+	"api/clock"
 	"api/company"
+	"api/database"
 	"api/notification"
 	"api/server"
 	"context"
@@ -39,10 +41,12 @@ type (
 		Principal        int64     `db:"principal" json:"principal"`
 		PrincipalPaid    int64     `db:"principal_paid" json:"principal_paid"`
 		DelayedPayments  int8      `db:"delayed_payments" json:"delayed_payments"`
+		Version          uint64    `db:"version" json:"-"`
 	}
 
 	Service interface {
 		GetBonds(ctx context.Context, page, limit uint) ([]*Bond, error)
+		GetBond(ctx context.Context, bondId int64) (*Bond, error)
 		GetCompanyBonds(ctx context.Context, companyId int64) ([]*Bond, error)
 		EmitBond(ctx context.Context, rate float64, amount, companyId int64) (*Bond, error)
 		BuyBond(ctx context.Context, amount, bondId, companyId int64) (*Bond, *Creditor, error)
@@ -56,6 +60,8 @@ type (
 
 		notifier notification.Notifier
 		logger   *log.Logger
+		uow      database.UnitOfWork
+		clock    clock.Clock
 	}
 )
 
@@ -81,14 +87,20 @@ func NewService(
 	companySvc company.Service,
 	notifier notification.Notifier,
 	logger *log.Logger,
+	uow database.UnitOfWork,
+	clock clock.Clock,
 ) Service {
-	return &service{repository, companySvc, notifier, logger}
+	return &service{repository, companySvc, notifier, logger, uow, clock}
 }
 
 func (s *service) GetBonds(ctx context.Context, page, limit uint) ([]*Bond, error) {
 	return s.repository.GetBonds(ctx, page, limit)
 }
 
+func (s *service) GetBond(ctx context.Context, bondId int64) (*Bond, error) {
+	return s.repository.GetBond(ctx, bondId)
+}
+
 func (s *service) GetCompanyBonds(ctx context.Context, companyId int64) ([]*Bond, error) {
 	return s.repository.GetCompanyBonds(ctx, companyId)
 }
@@ -99,8 +111,8 @@ func (s *service) EmitBond(ctx context.Context, rate float64, amount, companyId
 		return nil, err
 	}
 
-	if amount > company.GetCreditScore() {
-		return nil, fmt.Errorf("amount must not be higher than %.2f", float64(company.GetCreditScore())/100)
+	if amount > s.companySvc.GetCreditScore(company) {
+		return nil, fmt.Errorf("amount must not be higher than %.2f", float64(s.companySvc.GetCreditScore(company))/100)
 	}
 
 	bond, err := s.repository.SaveBond(ctx, &Bond{
@@ -117,12 +129,28 @@ func (s *service) EmitBond(ctx context.Context, rate float64, amount, companyId
 }
 
 func (s *service) PayBondInterest(ctx context.Context, creditor *Creditor, bond *Bond) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		return s.payBondInterest(ctx, creditor, bond)
+	})
+}
+
+func (s *service) payBondInterest(ctx context.Context, creditor *Creditor, bond *Bond) error {
 	emissor, err := s.companySvc.GetById(ctx, uint64(bond.CompanyId))
 	if err != nil {
 		return err
 	}
 
-	if emissor.AvailableCash < int(creditor.GetInterest()) {
+	// Blocked issuers skip payments until they're unblocked
+	if emissor == nil {
+		return nil
+	}
+
+	held, err := s.companySvc.HoldCash(ctx, uint64(bond.CompanyId), int(creditor.GetInterest()))
+	if err != nil {
+		return err
+	}
+
+	if !held {
 		issuerMessage := fmt.Sprintf("Bond interest payment for %s missed due to insufficient cash", creditor.Name)
 		if err := s.notifier.Notify(ctx, issuerMessage, int64(emissor.Id)); err != nil {
 			s.logger.Printf("Error notifying issuer of bond interest payment missed: %s\n", err)
@@ -143,6 +171,19 @@ func (s *service) PayBondInterest(ctx context.Context, creditor *Creditor, bond
 }
 
 func (s *service) BuyBond(ctx context.Context, amount, bondId, companyId int64) (*Bond, *Creditor, error) {
+	var bond *Bond
+	var creditor *Creditor
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		bond, creditor, err = s.buyBond(ctx, amount, bondId, companyId)
+		return err
+	})
+
+	return bond, creditor, err
+}
+
+func (s *service) buyBond(ctx context.Context, amount, bondId, companyId int64) (*Bond, *Creditor, error) {
 	bond, err := s.repository.GetBond(ctx, bondId)
 	if err != nil {
 		return nil, nil, err
@@ -157,7 +198,12 @@ func (s *service) BuyBond(ctx context.Context, amount, bondId, companyId int64)
 		return nil, nil, err
 	}
 
-	if company.AvailableCash < int(amount) {
+	held, err := s.companySvc.HoldCash(ctx, uint64(companyId), int(amount))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !held {
 		return nil, nil, ErrNotEnoughCash
 	}
 
@@ -165,7 +211,7 @@ func (s *service) BuyBond(ctx context.Context, amount, bondId, companyId int64)
 		Company:      company,
 		Principal:    amount,
 		InterestRate: bond.InterestRate,
-		PayableFrom:  time.Now().Add(2 * Week),
+		PayableFrom:  s.clock.Now().Add(2 * Week),
 	}
 
 	creditor, err = s.repository.SaveCreditor(ctx, bond, creditor)
@@ -177,6 +223,27 @@ func (s *service) BuyBond(ctx context.Context, amount, bondId, companyId int64)
 }
 
 func (s *service) BuyBackBond(ctx context.Context, amount, bondId, creditorId, companyId int64) (*Creditor, error) {
+	var creditor *Creditor
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		creditor, err = s.buyBackBond(ctx, amount, bondId, creditorId, companyId)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("%s bought back %.2f in bonds", creditor.Name, float64(amount)/100)
+	if err := s.notifier.Notify(ctx, message, companyId); err != nil {
+		s.logger.Printf("Error notifying bond buy back: %s\n", err)
+	}
+
+	return creditor, nil
+}
+
+func (s *service) buyBackBond(ctx context.Context, amount, bondId, creditorId, companyId int64) (*Creditor, error) {
 	bond, err := s.repository.GetBond(ctx, bondId)
 	if err != nil {
 		return nil, err
@@ -195,24 +262,14 @@ func (s *service) BuyBackBond(ctx context.Context, amount, bondId, creditorId, c
 		return nil, ErrAmountHigherThanPrincipal
 	}
 
-	company, err := s.companySvc.GetById(ctx, uint64(companyId))
+	held, err := s.companySvc.HoldCash(ctx, uint64(companyId), int(amount))
 	if err != nil {
 		return nil, err
 	}
 
-	if company.AvailableCash < int(amount) {
+	if !held {
 		return nil, ErrNotEnoughCash
 	}
 
-	creditor, err = s.repository.BuyBackBond(ctx, amount, creditor, bond)
-	if err != nil {
-		return nil, err
-	}
-
-	message := fmt.Sprintf("%s bought back %.2f in bonds", creditor.Name, float64(amount)/100)
-	if err := s.notifier.Notify(ctx, message, companyId); err != nil {
-		s.logger.Printf("Error notifying bond buy back: %s\n", err)
-	}
-
-	return creditor, nil
+	return s.repository.BuyBackBond(ctx, amount, creditor, bond)
 }
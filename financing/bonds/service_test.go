@@ -1,8 +1,11 @@
 package bonds_test
 
 import (
+	"api/clock"
 	"api/company"
+	"api/database"
 	"api/financing/bonds"
+	"api/mail"
 	"api/notification"
 	"context"
 	"log"
@@ -12,8 +15,8 @@ import (
 
 func TestBondService(t *testing.T) {
 	companyRepo := company.NewFakeRepository()
-	companySvc := company.NewService(companyRepo)
-	service := bonds.NewService(bonds.NewFakeRepository(companyRepo), companySvc, notification.NoOpNotifier(), log.Default())
+	companySvc := company.NewService(companyRepo, "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	service := bonds.NewService(bonds.NewFakeRepository(companyRepo), companySvc, notification.NoOpNotifier(), log.Default(), database.NewFakeUnitOfWork(), clock.New())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -54,6 +57,17 @@ func TestBondService(t *testing.T) {
 			}
 		})
 
+		t.Run("should skip blocked issuers", func(t *testing.T) {
+			err := service.PayBondInterest(ctx, &bonds.Creditor{
+				Principal:    1_500_000_00,
+				InterestRate: 0.1,
+			}, &bonds.Bond{CompanyId: 10})
+
+			if err != nil {
+				t.Fatalf("could not skip interest: %s", err)
+			}
+		})
+
 		t.Run("should pay interest", func(t *testing.T) {
 			company, err := companyRepo.GetById(ctx, 3)
 			if err != nil {
@@ -96,8 +96,9 @@ func (r *fakeRepository) ForcePrincipalPayment(ctx context.Context, terrains []i
 	loan.PrincipalPaid = loan.GetPrincipal()
 	r.loans[loan.Id] = loan
 
-	company, _ := r.companyRepo.GetById(ctx, uint64(loan.CompanyId))
-	company.AvailableTerrains -= int8(len(terrains))
+	for _, position := range terrains {
+		r.companyRepo.SellTerrain(ctx, uint64(loan.CompanyId), uint8(position), 0)
+	}
 
 	return nil
 }
@@ -2,11 +2,11 @@ package loans
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/database"
 	"api/server"
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/doug-martin/goqu/v9"
 )
@@ -27,12 +27,13 @@ type (
 	goquRepository struct {
 		builder        *goqu.Database
 		accountingRepo accounting.Repository
+		clock          clock.Clock
 	}
 )
 
-func NewRepository(conn *database.Connection, accountingRepo accounting.Repository) Repository {
+func NewRepository(conn *database.Connection, accountingRepo accounting.Repository, clock clock.Clock) Repository {
 	builder := goqu.New(conn.Driver, conn.DB)
-	return &goquRepository{builder, accountingRepo}
+	return &goquRepository{builder, accountingRepo, clock}
 }
 
 func (r *goquRepository) GetLoans(ctx context.Context, companyId int64) ([]*Loan, error) {
@@ -48,6 +49,7 @@ func (r *goquRepository) GetLoans(ctx context.Context, companyId int64) ([]*Loan
 			goqu.I("principal_paid"),
 			goqu.I("delayed_payments"),
 			goqu.I("company_id"),
+			goqu.I("version"),
 		).
 		From(goqu.T("loans")).
 		Where(goqu.I("company_id").Eq(companyId)).
@@ -61,7 +63,7 @@ func (r *goquRepository) GetLoans(ctx context.Context, companyId int64) ([]*Loan
 }
 
 func (r *goquRepository) BuyBackLoan(ctx context.Context, amount int64, loan *Loan) (*Loan, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +71,7 @@ func (r *goquRepository) BuyBackLoan(ctx context.Context, amount int64, loan *Lo
 	defer tx.Rollback()
 
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          -int(amount),
 			Description:    "Loan buy back",
@@ -80,19 +82,9 @@ func (r *goquRepository) BuyBackLoan(ctx context.Context, amount int64, loan *Lo
 		return nil, err
 	}
 
-	_, err = tx.
-		Update(goqu.T("loans")).
-		Set(goqu.Record{
-			"principal_paid": goqu.L("? + ?", goqu.I("principal_paid"), amount),
-		}).
-		Where(goqu.And(
-			goqu.I("id").Eq(loan.Id),
-			goqu.I("company_id").Eq(loan.CompanyId),
-		)).
-		Executor().
-		Exec()
-
-	if err != nil {
+	if err := r.updateLoan(tx.DB, loan, goqu.Record{
+		"principal_paid": goqu.L("? + ?", goqu.I("principal_paid"), amount),
+	}); err != nil {
 		return nil, err
 	}
 
@@ -104,7 +96,7 @@ func (r *goquRepository) BuyBackLoan(ctx context.Context, amount int64, loan *Lo
 }
 
 func (r *goquRepository) SaveLoan(ctx context.Context, loan *Loan) (*Loan, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +104,7 @@ func (r *goquRepository) SaveLoan(ctx context.Context, loan *Loan) (*Loan, error
 	defer tx.Rollback()
 
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Classification: accounting.LOAN,
 			Value:          int(loan.Principal),
@@ -127,7 +119,7 @@ func (r *goquRepository) SaveLoan(ctx context.Context, loan *Loan) (*Loan, error
 		return nil, err
 	}
 
-	result, err := tx.Insert(goqu.T("loans")).Rows(loan).Executor().Exec()
+	id, err := database.InsertId(ctx, tx.Insert(goqu.T("loans")).Rows(loan))
 	if err != nil {
 		return nil, err
 	}
@@ -136,27 +128,37 @@ func (r *goquRepository) SaveLoan(ctx context.Context, loan *Loan) (*Loan, error
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
 	loan.Id = id
 	return loan, nil
 }
 
 func (r *goquRepository) UpdateLoan(ctx context.Context, loan *Loan) (*Loan, error) {
-	_, err := r.builder.Update(goqu.T("loans")).Set(loan).Executor().ExecContext(ctx)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
+
+	defer tx.Rollback()
+
+	if err := r.updateLoan(tx.DB, loan, goqu.Record{
+		"interest_paid":    loan.InterestPaid,
+		"principal_paid":   loan.PrincipalPaid,
+		"delayed_payments": loan.DelayedPayments,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return loan, nil
 }
 
 func (r *goquRepository) GetLoan(ctx context.Context, loanId, companyId int64) (*Loan, error) {
 	loan := new(Loan)
 
-	found, err := r.builder.
+	found, err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("id"),
 			goqu.I("interest_rate"),
@@ -166,6 +168,7 @@ func (r *goquRepository) GetLoan(ctx context.Context, loanId, companyId int64) (
 			goqu.I("principal_paid"),
 			goqu.I("delayed_payments"),
 			goqu.I("company_id"),
+			goqu.I("version"),
 		).
 		From(goqu.T("loans")).
 		Where(goqu.And(
@@ -186,7 +189,7 @@ func (r *goquRepository) GetLoan(ctx context.Context, loanId, companyId int64) (
 }
 
 func (r *goquRepository) PayLoanInterest(ctx context.Context, loan *Loan) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
@@ -197,7 +200,7 @@ func (r *goquRepository) PayLoanInterest(ctx context.Context, loan *Loan) error
 	principal := loan.GetPrincipal()
 
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          -int(interest),
 			Classification: accounting.LOAN_INTEREST_PAYMENT,
@@ -211,41 +214,65 @@ func (r *goquRepository) PayLoanInterest(ctx context.Context, loan *Loan) error
 		return err
 	}
 
-	tx.
-		Update(goqu.T("loans")).
-		Set(goqu.Record{
-			"delayed_payments": 0,
-			"interest_paid": goqu.L(
-				"? + ?",
-				goqu.I("interest_paid"),
-				interest,
-			),
-		}).
-		Where(goqu.And(
-			goqu.I("id").Eq(loan.Id),
-			goqu.I("company_id").Eq(loan.CompanyId),
-		)).
-		Executor().
-		Exec()
+	if err := r.updateLoan(tx.DB, loan, goqu.Record{
+		"delayed_payments": 0,
+		"interest_paid": goqu.L(
+			"? + ?",
+			goqu.I("interest_paid"),
+			interest,
+		),
+	}); err != nil {
+		return err
+	}
 
 	return tx.Commit()
 }
 
 func (r *goquRepository) ForcePrincipalPayment(ctx context.Context, terrains []int8, loan *Loan) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
 
 	defer tx.Rollback()
 
-	// Demolish buildings in terrains
-	_, err = tx.
+	if len(terrains) > 0 {
+		if err := r.seizeTerrains(tx.DB, loan.CompanyId, terrains); err != nil {
+			return err
+		}
+	}
+
+	if err := r.updateLoan(tx.DB, loan, goqu.Record{
+		"principal_paid": loan.GetPrincipal(),
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Demolishes the buildings on the terrains and takes them from the company
+func (r *goquRepository) seizeTerrains(tx *database.DB, companyId int64, terrains []int8) error {
+	_, err := tx.
 		Update(goqu.T("companies_buildings")).
-		Set(goqu.Record{"demolished_at": time.Now()}).
+		Set(goqu.Record{"demolished_at": r.clock.Now()}).
 		Where(goqu.And(
 			goqu.I("position").In(terrains),
-			goqu.I("company_id").Eq(loan.CompanyId),
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("demolished_at").IsNull(),
+		)).
+		Executor().
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.
+		Delete(goqu.T("terrains")).
+		Where(goqu.And(
+			goqu.I("position").In(terrains),
+			goqu.I("company_id").Eq(companyId),
 		)).
 		Executor().
 		Exec()
@@ -254,7 +281,6 @@ func (r *goquRepository) ForcePrincipalPayment(ctx context.Context, terrains []i
 		return err
 	}
 
-	// Reduce the number of available terrains
 	_, err = tx.Update(goqu.T("companies")).
 		Set(goqu.Record{
 			"available_terrains": goqu.L(
@@ -263,25 +289,37 @@ func (r *goquRepository) ForcePrincipalPayment(ctx context.Context, terrains []i
 				len(terrains),
 			),
 		}).
-		Where(goqu.I("id").Eq(loan.CompanyId)).
+		Where(goqu.I("id").Eq(companyId)).
 		Executor().
 		Exec()
 
-	if err != nil {
-		return err
-	}
+	return err
+}
 
-	tx.
+// Updates the loan only if it wasn't changed since it was read, bumping
+// its version
+func (r *goquRepository) updateLoan(tx *database.DB, loan *Loan, record goqu.Record) error {
+	record["version"] = loan.Version + 1
+
+	result, err := tx.
 		Update(goqu.T("loans")).
-		Set(goqu.Record{
-			"principal_paid": loan.GetPrincipal(),
-		}).
+		Set(record).
 		Where(goqu.And(
 			goqu.I("id").Eq(loan.Id),
 			goqu.I("company_id").Eq(loan.CompanyId),
+			goqu.I("version").Eq(loan.Version),
 		)).
 		Executor().
 		Exec()
 
-	return tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	if err := database.CheckVersion(result, "loan"); err != nil {
+		return err
+	}
+
+	loan.Version++
+	return nil
 }
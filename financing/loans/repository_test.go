@@ -2,6 +2,7 @@ package loans_test
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/company"
 	"api/company/building"
 	"api/database"
@@ -14,7 +15,7 @@ import (
 )
 
 func TestLoansRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../../test.db")
+	conn, err := database.GetTestConnection("../../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -28,23 +29,30 @@ func TestLoansRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password) VALUES
-        (1, "Coca-Cola", "coke@email.com", "aoeu"),
-        (2, "Pepsi", "coke@email.com", "aoeu"),
-        (3, "Tesla", "coke@email.com", "aoeu"),
-        (4, "Amazon", "coke@email.com", "aoeu")
+        (1, 'Coca-Cola', 'coke@email.com', 'aoeu'),
+        (2, 'Pepsi', 'coke@email.com', 'aoeu'),
+        (3, 'Tesla', 'coke@email.com', 'aoeu'),
+        (4, 'Amazon', 'coke@email.com', 'aoeu')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
-        INSERT INTO buildings (id, name) VALUES (1, "Mill"), (2, "Plantation")
+        INSERT INTO buildings (id, name) VALUES (1, 'Mill'), (2, 'Plantation')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO companies_buildings (id, name, company_id, building_id, position)
-        VALUES (1, "Mill", 2, 1, 0), (2, "Plantation", 2, 2, 2), (3, "Store", 2, 2, 3)
+        VALUES (1, 'Mill', 2, 1, 1), (2, 'Plantation', 2, 2, 2), (3, 'Store', 2, 2, 3)
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
+	if _, err := tx.Exec(`
+        INSERT INTO terrains (company_id, position, purchase_price, purchased_at) VALUES
+        (2, 1, 0, '2024-12-12 00:00:00'), (2, 2, 0, '2024-12-12 00:00:00'), (2, 3, 0, '2024-12-12 00:00:00')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -66,6 +74,12 @@ func TestLoansRepository(t *testing.T) {
 		if _, err := conn.DB.Exec(`DELETE FROM transactions`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec(`DELETE FROM company_balances`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec(`DELETE FROM terrains`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
 		if _, err := conn.DB.Exec(`DELETE FROM companies_buildings`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
@@ -83,9 +97,9 @@ func TestLoansRepository(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	accountingRepo := accounting.NewRepository(conn)
-	companyRepo := company.NewRepository(conn, accountingRepo)
-	repository := loans.NewRepository(conn, accountingRepo)
+	accountingRepo := accounting.NewRepository(conn, clock.New())
+	companyRepo := company.NewRepository(conn, accountingRepo, clock.New())
+	repository := loans.NewRepository(conn, accountingRepo, clock.New())
 
 	t.Run("GetLoans", func(t *testing.T) {
 		t.Run("should return empty list when not found", func(t *testing.T) {
@@ -144,6 +158,7 @@ func TestLoansRepository(t *testing.T) {
 			CompanyId:       2,
 			Principal:       1_000_000_00,
 			DelayedPayments: 2,
+			Version:         1,
 		}
 
 		if err := repository.PayLoanInterest(ctx, loan); err != nil {
@@ -174,10 +189,11 @@ func TestLoansRepository(t *testing.T) {
 	})
 
 	t.Run("ForcePrincipalPayment", func(t *testing.T) {
-		err := repository.ForcePrincipalPayment(ctx, []int8{0, 1, 2}, &loans.Loan{
+		err := repository.ForcePrincipalPayment(ctx, []int8{3, 2}, &loans.Loan{
 			Id:        1,
 			CompanyId: 2,
 			Principal: 1_000_000_00,
+			Version:   2,
 		})
 
 		if err != nil {
@@ -189,8 +205,17 @@ func TestLoansRepository(t *testing.T) {
 			t.Fatalf("could not get company: %s", err)
 		}
 
-		if company.AvailableTerrains != 0 {
-			t.Errorf("expected 0 terrains left, got %d", company.AvailableTerrains)
+		if company.AvailableTerrains != 1 {
+			t.Errorf("expected 1 terrain left, got %d", company.AvailableTerrains)
+		}
+
+		terrains, err := companyRepo.GetTerrains(ctx, 2)
+		if err != nil {
+			t.Fatalf("could not get terrains: %s", err)
+		}
+
+		if len(terrains) != 1 || terrains[0].Position != 1 {
+			t.Errorf("expected only the first terrain left, got %+v", terrains)
 		}
 
 		loan, err := repository.GetLoan(ctx, 1, 2)
@@ -206,6 +231,7 @@ func TestLoansRepository(t *testing.T) {
 			conn,
 			resource.NewRepository(conn),
 			warehouse.NewRepository(conn),
+			clock.New(),
 		)
 
 		buildings, err := buildingsRepo.GetAll(ctx, 2)
@@ -224,6 +250,7 @@ func TestLoansRepository(t *testing.T) {
 			CompanyId:   2,
 			Principal:   1_000_000_00,
 			PayableFrom: time.Now().Add(time.Second),
+			Version:     1,
 		})
 
 		if err != nil {
@@ -247,4 +274,34 @@ func TestLoansRepository(t *testing.T) {
 			t.Errorf("expected principal %d, got %d", 500_000_00, loan.GetPrincipal())
 		}
 	})
+	t.Run("BuyBackLoan stale version", func(t *testing.T) {
+		_, err := repository.BuyBackLoan(ctx, 100_000_00, &loans.Loan{
+			Id:        2,
+			CompanyId: 2,
+			Principal: 1_000_000_00,
+			Version:   1,
+		})
+
+		if !database.IsConflict(err) {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+
+		loan, err := repository.GetLoan(ctx, 2, 2)
+		if err != nil {
+			t.Fatalf("could not get loan: %s", err)
+		}
+
+		if loan.PrincipalPaid != 500_000_00 {
+			t.Errorf("expected principal paid %d, got %d", 500_000_00, loan.PrincipalPaid)
+		}
+
+		company, err := companyRepo.GetById(ctx, 2)
+		if err != nil {
+			t.Fatalf("could not get company: %s", err)
+		}
+
+		if company.AvailableCash != -650_000_00 {
+			t.Errorf("should have rolled back the payment, got cash %d", company.AvailableCash)
+		}
+	})
 }
@@ -2,6 +2,7 @@ package loans
 
 import (
 	"api/auth"
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -9,7 +10,7 @@ import (
 )
 
 func CreateEndpoints(group *echo.Group, service Service) {
-	group.GET("/loans", func(c echo.Context) error {
+	list := group.GET("/loans", func(c echo.Context) error {
 		companyId, err := auth.ParseToken(c.Get("user"))
 		if err != nil {
 			return err
@@ -23,7 +24,7 @@ func CreateEndpoints(group *echo.Group, service Service) {
 		return c.JSON(http.StatusOK, loans)
 	})
 
-	group.POST("/loans", func(c echo.Context) error {
+	take := group.POST("/loans", func(c echo.Context) error {
 		request := struct {
 			Amount int64 `json:"amount" validate:"required"`
 		}{}
@@ -49,7 +50,7 @@ func CreateEndpoints(group *echo.Group, service Service) {
 		return c.JSON(http.StatusCreated, loan)
 	})
 
-	group.POST("/loans/:loanId", func(c echo.Context) error {
+	buyBack := group.POST("/loans/:loanId", func(c echo.Context) error {
 		loanId, err := strconv.ParseInt(c.Param("loanId"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -79,4 +80,8 @@ func CreateEndpoints(group *echo.Group, service Service) {
 
 		return c.JSON(http.StatusOK, loan)
 	})
+
+	server.AllowApiKeys(auth.SCOPE_FINANCE_READ, list)
+	server.AllowMembers(auth.SCOPE_FINANCE_READ, list)
+	server.AllowMembers(auth.SCOPE_FINANCE_WRITE, take, buyBack)
 }
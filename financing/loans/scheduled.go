@@ -7,13 +7,24 @@ import (
 	"time"
 )
 
-type scheduledService struct {
-	service   Service
-	scheduler *scheduler.Scheduler
-}
+const LOAN_INTEREST_JOB = "loans.interest"
+
+type (
+	scheduledService struct {
+		service   Service
+		scheduler *scheduler.Scheduler
+	}
+
+	interestJob struct {
+		LoanId    int64 `json:"loan_id"`
+		CompanyId int64 `json:"company_id"`
+	}
+)
 
 func NewScheduledService(service Service, scheduler *scheduler.Scheduler) Service {
-	return &scheduledService{service, scheduler}
+	scheduled := &scheduledService{service, scheduler}
+	scheduler.Register(LOAN_INTEREST_JOB, scheduled.payInterest)
+	return scheduled
 }
 
 func (s *scheduledService) GetLoans(ctx context.Context, companyId int64) ([]*Loan, error) {
@@ -30,13 +41,22 @@ func (s *scheduledService) TakeLoan(ctx context.Context, amount, companyId int64
 		return nil, err
 	}
 
-	s.scheduler.Repeat(fmt.Sprintf("LOAN_%d", loan.Id), Week, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
+	job, err := scheduler.NewJob(
+		fmt.Sprintf("LOAN_%d", loan.Id),
+		LOAN_INTEREST_JOB,
+		loan.CompanyId,
+		interestJob{loan.Id, loan.CompanyId},
+		s.scheduler.Now().Add(Week),
+	)
 
-		_, err := s.PayLoanInterest(ctx, loan.Id, loan.CompanyId)
-		return err
-	})
+	if err != nil {
+		return nil, err
+	}
+
+	job.RepeatsEvery = Week
+	if err := s.scheduler.Schedule(ctx, job); err != nil {
+		return nil, err
+	}
 
 	return loan, nil
 }
@@ -51,3 +71,16 @@ func (s *scheduledService) PayLoanInterest(ctx context.Context, loanId, companyI
 	}
 	return true, nil
 }
+
+func (s *scheduledService) payInterest(job *scheduler.Job) error {
+	var payload interestJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.PayLoanInterest(ctx, payload.LoanId, payload.CompanyId)
+	return err
+}
@@ -1,7 +1,9 @@
 package loans
 
 import (
+	"api/clock"
 	"api/company"
+	"api/database"
 	"api/financing"
 	"api/notification"
 	"api/server"
@@ -11,10 +13,11 @@ import (
 	"time"
 )
 
-const (
-	Week                 = 7 * 24 * time.Hour
-	MAX_DELAYED_PAYMENTS = 4
-)
+const Week = 7 * 24 * time.Hour
+
+// Consecutive interest payments a company can miss before its terrains
+// are seized to cover the principal
+const DEFAULT_MAX_DELAYED_PAYMENTS = 4
 
 var (
 	ErrNotEnoughCash             = server.NewBusinessRuleError("not enough cash")
@@ -32,6 +35,7 @@ type (
 		PrincipalPaid   int64     `db:"principal_paid" json:"principal_paid"`
 		CompanyId       int64     `db:"company_id" json:"-"`
 		DelayedPayments int8      `db:"delayed_payments" json:"delayed_payments"`
+		Version         uint64    `db:"version" json:"-"`
 	}
 
 	Service interface {
@@ -46,8 +50,12 @@ type (
 		companySvc   company.Service
 		financingSvc financing.Service
 
+		maxDelayedPayments int8
+
 		notifier notification.Notifier
 		logger   *log.Logger
+		uow      database.UnitOfWork
+		clock    clock.Clock
 	}
 )
 
@@ -65,13 +73,19 @@ func NewService(
 	financingSvc financing.Service,
 	notifier notification.Notifier,
 	logger *log.Logger,
+	maxDelayedPayments int8,
+	uow database.UnitOfWork,
+	clock clock.Clock,
 ) Service {
 	return &service{
-		repository:   repository,
-		companySvc:   companySvc,
-		financingSvc: financingSvc,
-		notifier:     notifier,
-		logger:       logger,
+		repository:         repository,
+		companySvc:         companySvc,
+		financingSvc:       financingSvc,
+		maxDelayedPayments: maxDelayedPayments,
+		notifier:           notifier,
+		logger:             logger,
+		uow:                uow,
+		clock:              clock,
 	}
 }
 
@@ -80,6 +94,18 @@ func (s *service) GetLoans(ctx context.Context, companyId int64) ([]*Loan, error
 }
 
 func (s *service) BuyBackLoan(ctx context.Context, amount, loanId, companyId int64) (*Loan, error) {
+	var loan *Loan
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		loan, err = s.buyBackLoan(ctx, amount, loanId, companyId)
+		return err
+	})
+
+	return loan, err
+}
+
+func (s *service) buyBackLoan(ctx context.Context, amount, loanId, companyId int64) (*Loan, error) {
 	loan, err := s.repository.GetLoan(ctx, loanId, companyId)
 	if err != nil {
 		return nil, err
@@ -89,12 +115,12 @@ func (s *service) BuyBackLoan(ctx context.Context, amount, loanId, companyId int
 		return nil, ErrAmountHigherThanPrincipal
 	}
 
-	company, err := s.companySvc.GetById(ctx, uint64(companyId))
+	held, err := s.companySvc.HoldCash(ctx, uint64(companyId), int(amount))
 	if err != nil {
 		return nil, err
 	}
 
-	if company.AvailableCash < int(amount) {
+	if !held {
 		return nil, ErrNotEnoughCash
 	}
 
@@ -107,8 +133,8 @@ func (s *service) TakeLoan(ctx context.Context, amount int64, companyId int64) (
 		return nil, err
 	}
 
-	if amount > company.GetCreditScore() {
-		return nil, fmt.Errorf("amount must not be higher than %.2f", float64(company.GetCreditScore()/100))
+	if amount > s.companySvc.GetCreditScore(company) {
+		return nil, fmt.Errorf("amount must not be higher than %.2f", float64(s.companySvc.GetCreditScore(company)/100))
 	}
 
 	rates, err := s.financingSvc.GetEffectiveRates(ctx)
@@ -119,7 +145,7 @@ func (s *service) TakeLoan(ctx context.Context, amount int64, companyId int64) (
 	loan, err := s.repository.SaveLoan(ctx, &Loan{
 		Principal:    amount,
 		CompanyId:    companyId,
-		PayableFrom:  time.Now().Add(4 * Week),
+		PayableFrom:  s.clock.Now().Add(4 * Week),
 		InterestRate: rates.Interest,
 	})
 
@@ -131,6 +157,18 @@ func (s *service) TakeLoan(ctx context.Context, amount int64, companyId int64) (
 }
 
 func (s *service) PayLoanInterest(ctx context.Context, loanId, companyId int64) (bool, error) {
+	keep := true
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		keep, err = s.payLoanInterest(ctx, loanId, companyId)
+		return err
+	})
+
+	return keep, err
+}
+
+func (s *service) payLoanInterest(ctx context.Context, loanId, companyId int64) (bool, error) {
 	loan, err := s.repository.GetLoan(ctx, loanId, companyId)
 	if err != nil {
 		return true, err
@@ -141,13 +179,23 @@ func (s *service) PayLoanInterest(ctx context.Context, loanId, companyId int64)
 		return true, err
 	}
 
+	// Blocked companies skip installments until they're unblocked
+	if company == nil {
+		return true, nil
+	}
+
 	interest := loan.GetInterest()
 
+	held, err := s.companySvc.HoldCash(ctx, uint64(companyId), int(interest))
+	if err != nil {
+		return true, err
+	}
+
 	// If can't pay 4 consecutive installments lose terrains to cover the debt
-	if company.AvailableCash < int(interest) {
+	if !held {
 		loan.DelayedPayments++
 
-		if loan.DelayedPayments >= MAX_DELAYED_PAYMENTS {
+		if loan.DelayedPayments >= s.maxDelayedPayments {
 			return false, s.forcePayment(ctx, loan, company)
 		}
 
@@ -156,7 +204,7 @@ func (s *service) PayLoanInterest(ctx context.Context, loanId, companyId int64)
 		}
 
 		message := "Loan interest payment missed due to insuffient cash."
-		if loan.DelayedPayments-MAX_DELAYED_PAYMENTS == 1 {
+		if loan.DelayedPayments-s.maxDelayedPayments == 1 {
 			message += " Missing the next payment will incur seizing of terrains to cover principal."
 		}
 
@@ -171,17 +219,19 @@ func (s *service) PayLoanInterest(ctx context.Context, loanId, companyId int64)
 }
 
 func (s *service) forcePayment(ctx context.Context, loan *Loan, company *company.Company) error {
+	plots, err := s.companySvc.GetTerrains(ctx, company.Id)
+	if err != nil {
+		return err
+	}
+
 	var total int64
 	terrains := []int8{}
 	principal := loan.GetPrincipal()
 
-	for i := company.AvailableTerrains; i > 0; i++ {
-		terrains = append(terrains, i)
-		total += company.TerrainValue(i)
-
-		if total >= principal {
-			break
-		}
+	// Plots furthest away are seized first
+	for i := len(plots) - 1; i >= 0 && total < principal; i-- {
+		terrains = append(terrains, int8(plots[i].Position))
+		total += s.companySvc.TerrainValue(int8(plots[i].Position))
 	}
 
 	if err := s.repository.ForcePrincipalPayment(ctx, terrains, loan); err != nil {
@@ -1,9 +1,12 @@
 package loans_test
 
 import (
+	"api/clock"
 	"api/company"
+	"api/database"
 	"api/financing"
 	"api/financing/loans"
+	"api/mail"
 	"api/notification"
 	"api/scheduler"
 	"context"
@@ -15,13 +18,13 @@ import (
 
 func TestLoansService(t *testing.T) {
 	companyRepo := company.NewFakeRepository()
-	companySvc := company.NewService(companyRepo)
+	companySvc := company.NewService(companyRepo, "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 
 	logger := log.Default()
 	notifier := notification.NoOpNotifier()
 
-	financingSvc := financing.NewService(financing.NewFakeRepository(), notifier, logger)
-	service := loans.NewService(loans.NewFakeRepository(companyRepo), companySvc, financingSvc, notifier, logger)
+	financingSvc := financing.NewService(financing.NewFakeRepository(), notifier, logger, clock.New())
+	service := loans.NewService(loans.NewFakeRepository(companyRepo), companySvc, financingSvc, notifier, logger, loans.DEFAULT_MAX_DELAYED_PAYMENTS, database.NewFakeUnitOfWork(), clock.New())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -74,6 +77,34 @@ func TestLoansService(t *testing.T) {
 			if ok {
 				t.Error("should force payment")
 			}
+
+			terrains, err := companySvc.GetTerrains(ctx, 1)
+			if err != nil {
+				t.Fatalf("could not get terrains: %s", err)
+			}
+
+			if len(terrains) != company.STARTING_TERRAINS-1 || terrains[len(terrains)-1].Position != company.STARTING_TERRAINS-1 {
+				t.Errorf("should seize the last terrain, got %+v", terrains)
+			}
+		})
+
+		t.Run("should skip blocked companies", func(t *testing.T) {
+			ok, err := service.PayLoanInterest(ctx, 2, 10)
+			if err != nil {
+				t.Fatalf("could not skip interest: %s", err)
+			}
+			if !ok {
+				t.Error("should keep the loan's job")
+			}
+
+			loans, err := service.GetLoans(ctx, 3)
+			if err != nil {
+				t.Fatalf("could not get loans: %s", err)
+			}
+
+			if len(loans) != 1 || loans[0].DelayedPayments != 3 {
+				t.Errorf("should not count a delayed payment, got %+v", loans)
+			}
 		})
 
 		t.Run("should clear timer", func(t *testing.T) {
@@ -39,7 +39,7 @@ func (r *goquRepository) GetEffectiveRates(ctx context.Context) (*Rates, error)
 			goqu.COALESCE(goqu.SUM(goqu.I("interest")), 0).As("interest"),
 		).
 		From(
-			goqu.
+			r.builder.
 				Select(goqu.I("inflation"), goqu.I("interest")).
 				From(goqu.T("rates_history")).
 				Order(goqu.I("period").Desc()).
@@ -96,7 +96,8 @@ func (r *goquRepository) GetAveragePrices(ctx context.Context, start, end time.T
 	err := r.builder.
 		Select(
 			goqu.I("r.category_id"),
-			goqu.L("? / ?",
+			database.IntDiv(
+				r.builder.Dialect(),
 				goqu.SUM(goqu.I("t.value")),
 				goqu.SUM(goqu.I("ot.quantity")),
 			).As("average_price"),
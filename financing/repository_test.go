@@ -9,7 +9,7 @@ import (
 )
 
 func TestFinancingRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		t.Fatalf("could not get connection: %s", err)
 	}
@@ -21,14 +21,14 @@ func TestFinancingRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password)
-        VALUES (1, "Test", "", ""), (2, "Test", "", "")
+        VALUES (1, 'Test', '', ''), (2, 'Test', '', '')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO classifications (id, name)
-        VALUES (6, "Market sale")
+        VALUES (6, 'Market sale')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -44,16 +44,23 @@ func TestFinancingRepository(t *testing.T) {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
+	if _, err := tx.Exec(`
+        INSERT INTO company_balances (company_id, cash)
+        SELECT company_id, SUM(value) FROM transactions GROUP BY company_id
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
 	if _, err := tx.Exec(`
         INSERT INTO categories (id, name) VALUES
-        (1, "Food"), (2, "Construction")
+        (1, 'Food'), (2, 'Construction')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO resources (id, name, category_id) VALUES
-        (1, "Rice", 1), (2, "Iron", 2)
+        (1, 'Rice', 1), (2, 'Iron', 2)
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -121,6 +128,9 @@ func TestFinancingRepository(t *testing.T) {
 		if _, err := conn.DB.Exec(`DELETE FROM transactions`); err != nil {
 			t.Errorf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec(`DELETE FROM company_balances`); err != nil {
+			t.Errorf("could not cleanup database: %s", err)
+		}
 		if _, err := conn.DB.Exec(`DELETE FROM classifications`); err != nil {
 			t.Errorf("could not cleanup database: %s", err)
 		}
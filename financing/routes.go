@@ -3,6 +3,7 @@ package financing
 import (
 	"api/auth"
 	"api/company"
+	"api/server"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -11,7 +12,7 @@ import (
 func CreateEndpoints(e *echo.Echo, financingSvc Service, companySvc company.Service) *echo.Group {
 	group := e.Group("/financing")
 
-	group.GET("/rates", func(c echo.Context) error {
+	rates := group.GET("/rates", func(c echo.Context) error {
 		rates, err := financingSvc.GetEffectiveRates(c.Request().Context())
 		if err != nil {
 			return err
@@ -21,15 +22,6 @@ func CreateEndpoints(e *echo.Echo, financingSvc Service, companySvc company.Serv
 	})
 
 	group.POST("/rates", func(c echo.Context) error {
-		id, err := auth.ParseToken(c.Get("user"))
-		if err != nil {
-			return err
-		}
-
-		if id != 0 {
-			return echo.NewHTTPError(http.StatusUnauthorized)
-		}
-
 		ctx := c.Request().Context()
 		rates, err := financingSvc.CalculateRates(ctx)
 		if err != nil {
@@ -37,7 +29,10 @@ func CreateEndpoints(e *echo.Echo, financingSvc Service, companySvc company.Serv
 		}
 
 		return c.JSON(http.StatusOK, rates)
-	})
+	}, server.RequireRole(auth.ROLE_ADMIN))
+
+	server.AllowApiKeys(auth.SCOPE_FINANCE_READ, rates)
+	server.AllowMembers(auth.SCOPE_FINANCE_READ, rates)
 
 	return group
 }
@@ -2,8 +2,11 @@ package financing_test
 
 import (
 	"api/auth"
+	"api/clock"
 	"api/company"
+	"api/database"
 	"api/financing"
+	"api/mail"
 	"api/notification"
 	"api/server"
 	"encoding/json"
@@ -14,19 +17,17 @@ import (
 )
 
 func TestFinancingRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
 	token, err := auth.GenerateToken(1, "secret")
 	if err != nil {
 		t.Fatalf("could not generate jwt token: %s", err)
 	}
 
-	svc := financing.NewService(financing.NewFakeRepository(), notification.NoOpNotifier(), log.Default())
+	svc := financing.NewService(financing.NewFakeRepository(), notification.NoOpNotifier(), log.Default(), clock.New())
 
 	companyRepo := company.NewFakeRepository()
-	companySvc := company.NewService(companyRepo)
+	companySvc := company.NewService(companyRepo, "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 
-	svr := server.NewServer()
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
 	financing.CreateEndpoints(svr, svc, companySvc)
 
 	t.Run("GetRates", func(t *testing.T) {
@@ -89,8 +90,8 @@ func TestFinancingRoutes(t *testing.T) {
 			rec := httptest.NewRecorder()
 			svr.ServeHTTP(rec, req)
 
-			if rec.Code != http.StatusUnauthorized {
-				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
 			}
 		})
 
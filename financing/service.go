@@ -2,6 +2,7 @@ package financing
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/notification"
 	"context"
 	"log"
@@ -17,6 +18,7 @@ type (
 
 	Service interface {
 		CalculateRates(ctx context.Context) (*Rates, error)
+		CalculatePeriodRates(ctx context.Context, start, end time.Time) (*Rates, error)
 		GetEffectiveRates(ctx context.Context) (*Rates, error)
 		GetInflationPeriod(ctx context.Context, start, end time.Time) (float64, error)
 		GetInterestPeriod(ctx context.Context, start, end time.Time, inflation float64) (float64, error)
@@ -26,13 +28,14 @@ type (
 		repository Repository
 		notifier   notification.Notifier
 		logger     *log.Logger
+		clock      clock.Clock
 	}
 )
 
 const Day = 24 * time.Hour
 
-func NewService(repository Repository, notifier notification.Notifier, logger *log.Logger) Service {
-	return &service{repository, notifier, logger}
+func NewService(repository Repository, notifier notification.Notifier, logger *log.Logger, clock clock.Clock) Service {
+	return &service{repository, notifier, logger, clock}
 }
 
 func (s *service) GetEffectiveRates(ctx context.Context) (*Rates, error) {
@@ -40,8 +43,11 @@ func (s *service) GetEffectiveRates(ctx context.Context) (*Rates, error) {
 }
 
 func (s *service) CalculateRates(ctx context.Context) (*Rates, error) {
-	start, end := accounting.GetCurrentPeriod()
+	start, end := accounting.GetPeriod(s.clock.Now())
+	return s.CalculatePeriodRates(ctx, start, end)
+}
 
+func (s *service) CalculatePeriodRates(ctx context.Context, start, end time.Time) (*Rates, error) {
 	inflation, err := s.GetInflationPeriod(ctx, start, end)
 	if err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package financing_test
 
 import (
+	"api/clock"
 	"api/financing"
 	"api/notification"
 	"context"
@@ -11,7 +12,7 @@ import (
 )
 
 func TestFinancingService(t *testing.T) {
-	service := financing.NewService(financing.NewFakeRepository(), notification.NoOpNotifier(), log.Default())
+	service := financing.NewService(financing.NewFakeRepository(), notification.NoOpNotifier(), log.Default(), clock.New())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
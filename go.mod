@@ -4,9 +4,12 @@ go 1.21.2
 
 require (
 	github.com/doug-martin/goqu/v9 v9.18.0
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/gorilla/websocket v1.5.0
 	github.com/labstack/echo/v4 v4.11.1
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.17
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
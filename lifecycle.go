@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+type (
+	// lifecycle starts the parts of the app in the order they were
+	// appended and stops them in reverse, so nothing is stopped while
+	// something started after it may still use it
+	lifecycle struct {
+		logger  *log.Logger
+		hooks   []hook
+		started int
+	}
+
+	// hook starts and stops one part of the app. Either can be nil.
+	hook struct {
+		name  string
+		start func(ctx context.Context) error
+		stop  func(ctx context.Context) error
+	}
+)
+
+func newLifecycle(logger *log.Logger) *lifecycle {
+	return &lifecycle{logger: logger}
+}
+
+func (l *lifecycle) Append(h hook) {
+	l.hooks = append(l.hooks, h)
+}
+
+// Runs every start hook in order. When one fails, the ones already
+// started are stopped before returning.
+func (l *lifecycle) Start(ctx context.Context) error {
+	for _, h := range l.hooks[l.started:] {
+		if h.start != nil {
+			if err := h.start(ctx); err != nil {
+				err = fmt.Errorf("could not start %s: %w", h.name, err)
+				return errors.Join(err, l.Stop(ctx))
+			}
+		}
+		l.started++
+	}
+
+	return nil
+}
+
+// Runs the stop hook of everything started, last started first. Every
+// hook runs even when one fails, and all the errors are returned.
+func (l *lifecycle) Stop(ctx context.Context) error {
+	var errs []error
+
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.stop == nil {
+			continue
+		}
+
+		l.logger.Printf("stopping %s", h.name)
+		if err := h.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not stop %s: %w", h.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+// Package mail sends emails to players. Production sends them through an
+// SMTP server, while development writes them to a file and tests keep them
+// in memory to read them back.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	SMTP = "smtp"
+	FILE = "file"
+)
+
+type (
+	Message struct {
+		To      string
+		Subject string
+		Body    string
+	}
+
+	Mailer interface {
+		Send(ctx context.Context, message *Message) error
+	}
+)
+
+// Formats the message as a plain text email, headers included
+func (m *Message) format(from string, date time.Time) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	return []byte(b.String())
+}
+
+// Header values can't span lines, or they could inject headers of their own
+func (m *Message) validate() error {
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return errors.New("mail headers can't contain line breaks")
+	}
+	if m.To == "" {
+		return errors.New("mail has no recipient")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package mail_test
+
+import (
+	"api/mail"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMailer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Outbox", func(t *testing.T) {
+		outbox := mail.NewOutbox()
+
+		for _, to := range []string{"foo@test.com", "bar@test.com", "foo@test.com"} {
+			if err := outbox.Send(ctx, &mail.Message{To: to, Subject: "Hi", Body: to}); err != nil {
+				t.Fatalf("could not send mail: %s", err)
+			}
+		}
+
+		if messages := outbox.Messages(); len(messages) != 3 {
+			t.Errorf("expected 3 messages, got %d", len(messages))
+		}
+
+		if last := outbox.Last("bar@test.com"); last == nil || last.To != "bar@test.com" {
+			t.Errorf("expected last message to bar, got %v", last)
+		}
+
+		if last := outbox.Last("baz@test.com"); last != nil {
+			t.Errorf("expected no message to baz, got %v", last)
+		}
+	})
+
+	t.Run("should refuse headers with line breaks", func(t *testing.T) {
+		outbox := mail.NewOutbox()
+
+		message := &mail.Message{To: "foo@test.com", Subject: "Hi\r\nBcc: bar@test.com"}
+		if err := outbox.Send(ctx, message); err == nil {
+			t.Error("expected error with line breaks in subject")
+		}
+
+		if err := outbox.Send(ctx, &mail.Message{Subject: "Hi"}); err == nil {
+			t.Error("expected error without recipient")
+		}
+	})
+
+	t.Run("should append messages to the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mail.log")
+		mailer := mail.NewFileMailer(path, "game@test.com")
+
+		for _, subject := range []string{"First", "Second"} {
+			if err := mailer.Send(ctx, &mail.Message{To: "foo@test.com", Subject: subject, Body: "hello"}); err != nil {
+				t.Fatalf("could not send mail: %s", err)
+			}
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("could not read outbox: %s", err)
+		}
+
+		content := string(data)
+		for _, expected := range []string{"From: game@test.com", "To: foo@test.com", "Subject: First", "Subject: Second", "hello"} {
+			if !strings.Contains(content, expected) {
+				t.Errorf("expected outbox to contain %q", expected)
+			}
+		}
+	})
+}
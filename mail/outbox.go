@@ -0,0 +1,86 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// Outbox keeps the emails it's sent in memory, so tests can read them
+	Outbox struct {
+		mutex    sync.Mutex
+		messages []Message
+	}
+
+	// Appends the emails to a file instead of sending them, for development
+	fileMailer struct {
+		mutex sync.Mutex
+		path  string
+		from  string
+	}
+)
+
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+func (o *Outbox) Send(ctx context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.messages = append(o.messages, *message)
+	return nil
+}
+
+// Returns every email sent so far, oldest first
+func (o *Outbox) Messages() []Message {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return append([]Message(nil), o.messages...)
+}
+
+// Returns the latest email sent to the address, or nil when there is none
+func (o *Outbox) Last(to string) *Message {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		if o.messages[i].To == to {
+			message := o.messages[i]
+			return &message
+		}
+	}
+	return nil
+}
+
+func NewFileMailer(path, from string) Mailer {
+	return &fileMailer{path: path, from: from}
+}
+
+func (m *fileMailer) Send(ctx context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0664)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(message.format(m.from, time.Now()), "\r\n"...)); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
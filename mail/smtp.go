@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type (
+	// SMTP server emails are sent through. Without a username no
+	// authentication is attempted.
+	SMTPServer struct {
+		Host     string `json:"host"`
+		Port     int    `json:"port"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	smtpMailer struct {
+		server SMTPServer
+		from   string
+	}
+)
+
+func NewSMTPMailer(server SMTPServer, from string) Mailer {
+	return &smtpMailer{server, from}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.server.Username != "" {
+		auth = smtp.PlainAuth("", m.server.Username, m.server.Password, m.server.Host)
+	}
+
+	address := net.JoinHostPort(m.server.Host, strconv.Itoa(m.server.Port))
+	return smtp.SendMail(address, auth, m.from, []string{message.To}, message.format(m.from, time.Now()))
+}
@@ -1,92 +1,250 @@
+// Command etp runs the game server and the tasks operators need around it.
+// Flags and environment variables configure it, see the config package.
+//
+//	etp [flags] [serve]
+//	etp [flags] migrate up | down | to <version> | baseline <version> | status
+//	etp [flags] seed <fixture.sql | catalog.json | catalog.yaml>...
+//	etp [flags] catalog export [-format json | yaml]
+//	etp [flags] admin create-company -name <name> -email <email> -password <password> [-admin]
+//	etp [flags] run taxes | rates [-period <date>]
+//	etp [flags] reconcile
 package main
 
 import (
 	"api/accounting"
+	"api/apikey"
 	"api/building"
 	"api/company"
-	companyBuilding "api/company/building"
 	"api/company/building/production"
-	"api/database"
+	"api/config"
 	"api/financing"
 	"api/financing/bonds"
 	"api/financing/loans"
 	"api/market"
+	"api/moderation"
 	"api/notification"
-	"api/research"
 	"api/research/staff"
 	"api/resource"
 	"api/scheduler"
 	"api/server"
+	"api/valuation"
 	"api/warehouse"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const usage = "usage: etp [flags] serve | migrate | seed | catalog | admin | run | reconcile"
+
 func main() {
-	conn, err := database.GetConnection(database.SQLITE, "development.db?_loc=UTC")
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("could not connect to database: %s", err)
+		log.Fatal(err)
 	}
 
-	svr := server.NewServer()
-	timer := scheduler.NewScheduler()
+	if err := run(context.Background(), cfg, args, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	logFile, err := os.OpenFile("dev.log", os.O_CREATE|os.O_APPEND, 664)
-	logger := log.New(logFile, "[DEV]", log.Flags())
+// Runs the command in args, serving the game when there's none
+func run(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
 
-	notificationRepo := notification.NewRepository(conn)
-	notifier := notification.NewNotifier(notificationRepo)
+	conn, migrator, err := connect(cfg)
+	if err != nil {
+		return fmt.Errorf("could not connect to database: %w", err)
+	}
 
-	resourceRepo := resource.NewRepository(conn)
-	resourceSvc := resource.NewService(resourceRepo)
-	resource.CreateEndpoints(svr, resourceSvc)
+	if command == "migrate" {
+		defer conn.Close()
+		return runMigrate(ctx, migrator, args, out)
+	}
 
-	warehouseRepo := warehouse.NewRepository(conn)
-	warehouseSvc := warehouse.NewService(warehouseRepo)
-	warehouse.CreateEndpoints(svr, warehouseSvc)
+	if command == "serve" && cfg.MigrateOnStart {
+		if _, err := migrator.Up(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("could not migrate database: %w", err)
+		}
+	}
 
-	buildingSvc := building.NewService(building.NewRepository(conn, resourceRepo))
-	building.CreateEndpoints(svr, buildingSvc)
+	app, err := newApp(ctx, cfg, conn, migrator)
+	if err != nil {
+		conn.Close()
+		return err
+	}
 
-	accountingRepo := accounting.NewRepository(conn)
-	companyRepo := company.NewRepository(conn, accountingRepo)
-	companySvc := company.NewService(companyRepo)
+	lc := app.lifecycle()
+	if command == "serve" {
+		return serve(ctx, cfg, app, lc)
+	}
 
-	companyBuildingRepo := companyBuilding.NewBuildingRepository(conn, resourceRepo, warehouseRepo)
-	companyBuildingSvc := companyBuilding.NewBuildingService(companyBuildingRepo, warehouseSvc, buildingSvc)
-	scheduledBuildingSvc := companyBuilding.NewScheduledBuildingService(companyBuildingSvc, timer)
+	if err := lc.Start(ctx); err != nil {
+		return err
+	}
 
-	researchSvc := research.NewService(research.NewRepository(conn, accountingRepo), companySvc)
-	productionRepo := production.NewProductionRepository(conn, accountingRepo, companyBuildingRepo, warehouseRepo)
-	productionSvc := production.NewProductionService(productionRepo, companySvc, companyBuildingSvc, warehouseSvc, researchSvc)
-	scheduledProductionSvc := production.NewScheduledProductionService(productionSvc, timer)
+	err = runCommand(ctx, app, command, args, out)
 
-	company.CreateEndpoints(svr, companySvc)
-	production.CreateEndpoints(svr, scheduledProductionSvc, scheduledBuildingSvc, companySvc)
+	stopCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
 
-	staffRepo := staff.NewRepository(conn, accountingRepo)
-	staffSvc := staff.NewService(staffRepo, timer, notifier, logger)
-	staff.CreateEndpoints(svr, staffSvc)
+	return errors.Join(err, lc.Stop(stopCtx))
+}
 
-	marketRepo := market.NewRepository(conn, companyRepo, warehouseRepo, accountingRepo)
-	marketSvc := market.NewService(marketRepo, companySvc, warehouseSvc, notifier, logger)
-	market.CreateEndpoints(svr, marketSvc)
+func runCommand(ctx context.Context, app *app, command string, args []string, out io.Writer) error {
+	switch command {
+	case "seed":
+		return runSeed(ctx, app.uow, app.catalogSvc, args, out)
+	case "catalog":
+		return runCatalog(ctx, app.catalogSvc, args, out)
+	case "admin":
+		return runAdmin(ctx, app.companySvc, args, out)
+	case "run":
+		return runJob(ctx, app, args, out)
+	case "reconcile":
+		return runReconcile(ctx, app.accountingSvc, out)
+	default:
+		return errors.New(usage)
+	}
+}
 
-	financingSvc := financing.NewService(financing.NewRepository(conn), notifier, logger)
-	financingGroup := financing.CreateEndpoints(svr, financingSvc, companySvc)
+// Exposes every service through the HTTP API and starts the world, until
+// the process is interrupted or terminated. Requests in flight, running jobs
+// and pending notifications then get until the shutdown timeout to finish.
+func serve(ctx context.Context, cfg *config.Config, app *app, lc *lifecycle) error {
+	svr := server.NewServer(server.Config{
+		JwtSecret:          cfg.Server.JwtSecret,
+		ClientOrigin:       cfg.Server.ClientOrigin,
+		IsRevoked:          app.companySvc.IsRevoked,
+		AuthenticateApiKey: app.apiKeySvc.Authenticate,
+		RecordAction:       app.companySvc.RecordAction,
+	})
+
+	scheduler.CreateEndpoints(svr, app.timer)
+	resource.CreateEndpoints(svr, app.resourceSvc)
+	warehouse.CreateEndpoints(svr, app.warehouseSvc)
+	building.CreateEndpoints(svr, app.buildingSvc)
+	accounting.CreateEndpoints(svr, app.accountingSvc)
+	company.CreateEndpoints(svr, app.companySvc)
+	apikey.CreateEndpoints(svr, app.apiKeySvc)
+	production.CreateEndpoints(svr, app.scheduledProductionSvc, app.scheduledBuildingSvc, app.companySvc)
+	staff.CreateEndpoints(svr, app.staffSvc)
+	market.CreateEndpoints(svr, app.marketSvc)
+	moderation.CreateEndpoints(svr, app.moderationSvc)
+	valuation.CreateEndpoints(svr, app.valuationSvc)
+
+	financingGroup := financing.CreateEndpoints(svr, app.financingSvc, app.companySvc)
+	loans.CreateEndpoints(financingGroup, app.scheduledLoansSvc)
+	bonds.CreateEndpoints(financingGroup, app.scheduledBondsSvc)
+
+	notification.CreateEndpoints(svr, app.notificationSvc, app.notifier)
+
+	lc.Append(hook{
+		name: "world",
+		start: func(ctx context.Context) error {
+			// Every job handler is registered by now, so pending jobs can be
+			// rehydrated. Jobs that were due while the server was down run right away.
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+
+			if err := app.timer.Restore(ctx); err != nil {
+				return fmt.Errorf("could not restore scheduled jobs: %w", err)
+			}
+
+			if err := registerWorldJobs(app.timer, app.accountingSvc, app.financingSvc, app.staffSvc, app.valuationSvc); err != nil {
+				return fmt.Errorf("could not register world jobs: %w", err)
+			}
+
+			if err := app.timer.StartWorld(ctx); err != nil {
+				return fmt.Errorf("could not start world jobs: %w", err)
+			}
+
+			return nil
+		},
+	})
+
+	failed := make(chan error, 1)
+	lc.Append(hook{
+		name: "http",
+		start: func(ctx context.Context) error {
+			go func() {
+				if err := svr.Start(cfg.Server.Address); !errors.Is(err, http.ErrServerClosed) {
+					failed <- err
+				}
+			}()
+			return nil
+		},
+		stop: svr.Shutdown,
+	})
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := lc.Start(ctx); err != nil {
+		return err
+	}
 
-	loansRepo := loans.NewRepository(conn, accountingRepo)
-	loansSvc := loans.NewService(loansRepo, companySvc, financingSvc, notifier, logger)
-	scheduledLoansSvc := loans.NewScheduledService(loansSvc, timer)
-	loans.CreateEndpoints(financingGroup, scheduledLoansSvc)
+	var err error
+	select {
+	case <-ctx.Done():
+		app.logger.Print("shutting down")
+	case err = <-failed:
+	}
 
-	bondsRepo := bonds.NewRepository(conn, accountingRepo)
-	bondsSvc := bonds.NewService(bondsRepo, companySvc, notifier, logger)
-	scheduledBondsSvc := bonds.NewScheduledService(bondsSvc, timer)
-	bonds.CreateEndpoints(financingGroup, scheduledBondsSvc)
+	stopCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
 
-	notificationSvc := notification.NewService(notificationRepo)
-	notification.CreateEndpoints(svr, notificationSvc, notifier)
+	return errors.Join(err, lc.Stop(stopCtx))
+}
+
+// Registers the jobs that run for every company at once, like collecting
+// taxes at the start of each game week for the week before
+func registerWorldJobs(timer *scheduler.Scheduler, accountingSvc accounting.Service, financingSvc financing.Service, staffSvc staff.Service, valuationSvc valuation.Service) error {
+	if err := timer.RegisterWorld("taxes", scheduler.WEEKLY, func(ctx context.Context, runAt time.Time) error {
+		start, end := accounting.GetPeriod(runAt)
+		return accountingSvc.PayTaxes(ctx, start, end)
+	}); err != nil {
+		return err
+	}
+
+	if err := timer.RegisterWorld("rates", scheduler.WEEKLY, func(ctx context.Context, runAt time.Time) error {
+		start, end := accounting.GetPeriod(runAt)
+		_, err := financingSvc.CalculatePeriodRates(ctx, start, end)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Snapshots of every balance, so the ledger can be audited later
+	if err := timer.RegisterWorld("balances", "@daily", func(ctx context.Context, runAt time.Time) error {
+		return accountingSvc.CheckpointBalances(ctx)
+	}); err != nil {
+		return err
+	}
+
+	// Valuations leaderboards and charts are drawn from
+	if err := timer.RegisterWorld("valuations", "@daily", func(ctx context.Context, runAt time.Time) error {
+		return valuationSvc.Snapshot(ctx)
+	}); err != nil {
+		return err
+	}
 
-	svr.Start(":1323")
+	return timer.RegisterWorld("payroll", scheduler.WEEKLY, func(ctx context.Context, runAt time.Time) error {
+		start, _ := accounting.GetPeriod(runAt)
+		return staffSvc.PaySalaries(ctx, start)
+	})
 }
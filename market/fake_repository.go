@@ -65,6 +65,18 @@ func (r *fakeRepository) GetByResource(ctx context.Context, resourceId uint64, q
 	return orders, nil
 }
 
+func (r *fakeRepository) GetByCompany(ctx context.Context, companyId uint64) ([]*Order, error) {
+	orders := make([]*Order, 0)
+
+	for id := uint64(1); id <= r.lastId; id++ {
+		if order, ok := r.orders[id]; ok && order.CompanyId == companyId {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders, nil
+}
+
 func (r *fakeRepository) PlaceOrder(ctx context.Context, order *Order, inventory *warehouse.Inventory) (*Order, error) {
 	r.lastId++
 
@@ -75,6 +87,7 @@ func (r *fakeRepository) PlaceOrder(ctx context.Context, order *Order, inventory
 }
 
 func (r *fakeRepository) CancelOrder(ctx context.Context, order *Order, inventory *warehouse.Inventory) error {
+	delete(r.orders, order.Id)
 	return nil
 }
 
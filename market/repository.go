@@ -2,6 +2,7 @@ package market
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/company"
 	"api/database"
 	"api/resource"
@@ -9,7 +10,6 @@ import (
 	"api/warehouse"
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/doug-martin/goqu/v9"
 )
@@ -18,6 +18,7 @@ type (
 	Repository interface {
 		GetById(ctx context.Context, orderId uint64) (*Order, error)
 		GetByResource(ctx context.Context, resourceId uint64, quality uint8) ([]*Order, error)
+		GetByCompany(ctx context.Context, companyId uint64) ([]*Order, error)
 		PlaceOrder(ctx context.Context, order *Order, inventory *warehouse.Inventory) (*Order, error)
 		CancelOrder(ctx context.Context, order *Order, inventory *warehouse.Inventory) error
 		Purchase(ctx context.Context, purchase *Purchase, companyId uint64) ([]*warehouse.StockItem, []*Order, error)
@@ -28,42 +29,19 @@ type (
 		companyRepo    company.Repository
 		warehouseRepo  warehouse.Repository
 		accountingRepo accounting.Repository
+		clock          clock.Clock
 	}
 )
 
-func NewRepository(conn *database.Connection, companyRepo company.Repository, warehouseRepo warehouse.Repository, accountingRepo accounting.Repository) Repository {
+func NewRepository(conn *database.Connection, companyRepo company.Repository, warehouseRepo warehouse.Repository, accountingRepo accounting.Repository, clock clock.Clock) Repository {
 	builder := goqu.New(conn.Driver, conn.DB)
-	return &goquRepository{builder, companyRepo, warehouseRepo, accountingRepo}
+	return &goquRepository{builder, companyRepo, warehouseRepo, accountingRepo, clock}
 }
 
 func (r *goquRepository) GetById(ctx context.Context, orderId uint64) (*Order, error) {
 	order := new(Order)
 
-	found, err := r.builder.
-		Select(
-			goqu.I("o.id"),
-			goqu.I("o.price"),
-			goqu.I("o.quality"),
-			goqu.I("o.quantity"),
-			goqu.I("o.market_fee"),
-			goqu.I("o.sourcing_cost"),
-			goqu.I("o.purchased_at").As("last_purchase"),
-			goqu.I("c.id").As(goqu.C("company.id")),
-			goqu.I("c.name").As(goqu.C("company.name")),
-			goqu.I("r.id").As(goqu.C("resource.id")),
-			goqu.I("r.id").As(goqu.C("resource.id")),
-			goqu.I("r.name").As(goqu.C("resource.name")),
-			goqu.I("r.image").As(goqu.C("resource.image")),
-		).
-		From(goqu.T("orders").As("o")).
-		InnerJoin(
-			goqu.T("companies").As("c"),
-			goqu.On(goqu.I("o.company_id").Eq(goqu.I("c.id"))),
-		).
-		InnerJoin(
-			goqu.T("resources").As("r"),
-			goqu.On(goqu.I("o.resource_id").Eq(goqu.I("r.id"))),
-		).
+	found, err := r.getSelect(ctx).
 		Where(goqu.And(
 			goqu.I("o.id").Eq(orderId),
 			goqu.I("o.quantity").Gt(0),
@@ -81,19 +59,59 @@ func (r *goquRepository) GetById(ctx context.Context, orderId uint64) (*Order, e
 func (r *goquRepository) GetByResource(ctx context.Context, resourceId uint64, quality uint8) ([]*Order, error) {
 	orders := make([]*Order, 0)
 
-	err := r.builder.
+	err := r.getSelect(ctx).
+		Where(goqu.And(
+			goqu.I("o.canceled_at").IsNull(),
+			goqu.I("o.quantity").Gt(0),
+			goqu.I("o.quality").Gte(quality),
+			goqu.I("o.resource_id").Eq(resourceId),
+		)).
+		Order(goqu.I("o.price").Asc()).
+		ScanStructsContext(ctx, &orders)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (r *goquRepository) GetByCompany(ctx context.Context, companyId uint64) ([]*Order, error) {
+	orders := make([]*Order, 0)
+
+	err := r.getSelect(ctx).
+		Where(goqu.And(
+			goqu.I("o.canceled_at").IsNull(),
+			goqu.I("o.quantity").Gt(0),
+			goqu.I("o.company_id").Eq(companyId),
+		)).
+		Order(goqu.I("o.id").Asc()).
+		ScanStructsContext(ctx, &orders)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (r *goquRepository) getSelect(ctx context.Context) *goqu.SelectDataset {
+	return database.Query(ctx, r.builder).
 		Select(
 			goqu.I("o.id"),
 			goqu.I("o.price"),
 			goqu.I("o.quality"),
 			goqu.I("o.quantity"),
 			goqu.I("o.market_fee"),
+			goqu.I("o.transport_fee"),
 			goqu.I("o.sourcing_cost"),
+			goqu.I("o.company_id"),
+			goqu.I("o.resource_id"),
 			goqu.I("o.purchased_at").As("last_purchase"),
+			goqu.I("o.version"),
 			goqu.I("c.id").As(goqu.C("company.id")),
 			goqu.I("c.name").As(goqu.C("company.name")),
 			goqu.I("r.id").As(goqu.C("resource.id")),
-			goqu.I("r.id").As(goqu.C("resource.id")),
 			goqu.I("r.name").As(goqu.C("resource.name")),
 			goqu.I("r.image").As(goqu.C("resource.image")),
 		).
@@ -105,32 +123,18 @@ func (r *goquRepository) GetByResource(ctx context.Context, resourceId uint64, q
 		InnerJoin(
 			goqu.T("resources").As("r"),
 			goqu.On(goqu.I("o.resource_id").Eq(goqu.I("r.id"))),
-		).
-		Where(goqu.And(
-			goqu.I("o.canceled_at").IsNull(),
-			goqu.I("o.quantity").Gt(0),
-			goqu.I("o.quality").Gte(quality),
-			goqu.I("o.resource_id").Eq(resourceId),
-		)).
-		Order(goqu.I("o.price").Asc()).
-		ScanStructsContext(ctx, &orders)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return orders, nil
+		)
 }
 
 func (r *goquRepository) PlaceOrder(ctx context.Context, order *Order, inventory *warehouse.Inventory) (*Order, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
 
 	defer tx.Rollback()
 
-	dbTx := &database.DB{TxDatabase: tx}
+	dbTx := tx.DB
 	if err := r.warehouseRepo.UpdateInventory(dbTx, inventory); err != nil {
 		return nil, err
 	}
@@ -147,7 +151,7 @@ func (r *goquRepository) PlaceOrder(ctx context.Context, order *Order, inventory
 		return nil, err
 	}
 
-	result, err := tx.
+	id, err := database.InsertId(ctx, tx.
 		Insert(goqu.T("orders")).
 		Rows(goqu.Record{
 			"price":         order.Price,
@@ -158,19 +162,12 @@ func (r *goquRepository) PlaceOrder(ctx context.Context, order *Order, inventory
 			"market_fee":    order.MarketFee,
 			"transport_fee": order.TransportFee,
 			"sourcing_cost": order.SourcingCost,
-		}).
-		Executor().
-		Exec()
+		}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -180,14 +177,14 @@ func (r *goquRepository) PlaceOrder(ctx context.Context, order *Order, inventory
 }
 
 func (r *goquRepository) CancelOrder(ctx context.Context, order *Order, inventory *warehouse.Inventory) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
 
 	defer tx.Rollback()
 
-	dbTx := &database.DB{TxDatabase: tx}
+	dbTx := tx.DB
 	if err := r.warehouseRepo.UpdateInventory(dbTx, inventory); err != nil {
 		return err
 	}
@@ -204,14 +201,16 @@ func (r *goquRepository) CancelOrder(ctx context.Context, order *Order, inventor
 		return err
 	}
 
-	_, err = tx.
+	result, err := tx.
 		Update(goqu.T("orders")).
 		Set(goqu.Record{
-			"canceled_at": time.Now(),
+			"canceled_at": r.clock.Now(),
+			"version":     order.Version + 1,
 		}).
 		Where(goqu.And(
 			goqu.I("id").Eq(order.Id),
 			goqu.I("company_id").Eq(order.Company.Id),
+			goqu.I("version").Eq(order.Version),
 		)).
 		Executor().
 		Exec()
@@ -220,6 +219,10 @@ func (r *goquRepository) CancelOrder(ctx context.Context, order *Order, inventor
 		return err
 	}
 
+	if err := database.CheckVersion(result, "order"); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -229,7 +232,7 @@ func (r *goquRepository) Purchase(ctx context.Context, purchase *Purchase, compa
 		return nil, nil, err
 	}
 
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -238,52 +241,61 @@ func (r *goquRepository) Purchase(ctx context.Context, purchase *Purchase, compa
 
 	total := 0
 	remaining := purchase.Quantity
+	filled := make([]*Order, 0)
+
+	for _, order := range orders {
+		if remaining == 0 {
+			break
+		}
+
+		quantity := min(order.Quantity, remaining)
+		total += int(order.Price) * int(quantity)
+		remaining -= quantity
+
+		filled = append(filled, order)
+	}
+
+	if remaining > 0 {
+		return nil, nil, server.NewBusinessRuleError("not enough market orders")
+	}
+
+	enough, err := r.accountingRepo.HoldCash(tx.DB, companyId, int64(total))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !enough {
+		return nil, nil, server.NewBusinessRuleError("not enough cash")
+	}
+
+	remaining = purchase.Quantity
 
 	purchasedOrders := make([]*Order, 0)
 	purchasedItems := make([]*warehouse.StockItem, 0)
 
-	for _, order := range orders {
+	for _, order := range filled {
 		if order.Quantity >= remaining {
-			item, err := r.partialPurchase(tx, order, remaining, companyId)
+			item, err := r.partialPurchase(tx.DB, order, remaining, companyId)
 			if err != nil {
 				return nil, nil, err
 			}
 
 			order.Quantity -= remaining
 			purchasedOrders = append(purchasedOrders, order)
-
-			remaining = 0
-			total += int(order.Price) * int(remaining)
 			purchasedItems = append(purchasedItems, item)
 
 			break
-		} else {
-			remaining -= order.Quantity
-			total += int(order.Price) * int(order.Quantity)
-
-			item, err := r.fullPurchase(tx, order, companyId)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			purchasedItems = append(purchasedItems, item)
-
-			order.Quantity = 0
-			purchasedOrders = append(purchasedOrders, order)
 		}
-	}
 
-	if remaining > 0 {
-		return nil, nil, server.NewBusinessRuleError("not enough market orders")
-	}
+		remaining -= order.Quantity
 
-	company, err := r.companyRepo.GetById(ctx, companyId)
-	if err != nil {
-		return nil, nil, err
-	}
+		item, err := r.fullPurchase(tx.DB, order, companyId)
+		if err != nil {
+			return nil, nil, err
+		}
 
-	if company.AvailableCash < total {
-		return nil, nil, server.NewBusinessRuleError("not enough cash")
+		purchasedOrders = append(purchasedOrders, order)
+		purchasedItems = append(purchasedItems, item)
 	}
 
 	inventory, err := r.warehouseRepo.FetchInventory(ctx, companyId)
@@ -293,7 +305,7 @@ func (r *goquRepository) Purchase(ctx context.Context, purchase *Purchase, compa
 
 	inventory.IncrementStock(purchasedItems)
 
-	dbTx := &database.DB{TxDatabase: tx}
+	dbTx := tx.DB
 	if err := r.warehouseRepo.UpdateInventory(dbTx, inventory); err != nil {
 		return nil, nil, err
 	}
@@ -305,7 +317,7 @@ func (r *goquRepository) Purchase(ctx context.Context, purchase *Purchase, compa
 	return purchasedItems, purchasedOrders, nil
 }
 
-func (r *goquRepository) fullPurchase(tx *goqu.TxDatabase, order *Order, companyId uint64) (*warehouse.StockItem, error) {
+func (r *goquRepository) fullPurchase(tx *database.DB, order *Order, companyId uint64) (*warehouse.StockItem, error) {
 	item := &warehouse.StockItem{
 		Cost: order.SourcingCost,
 		Item: &resource.Item{
@@ -327,7 +339,7 @@ func (r *goquRepository) fullPurchase(tx *goqu.TxDatabase, order *Order, company
 	return item, nil
 }
 
-func (r *goquRepository) partialPurchase(tx *goqu.TxDatabase, order *Order, quantity, companyId uint64) (*warehouse.StockItem, error) {
+func (r *goquRepository) partialPurchase(tx *database.DB, order *Order, quantity, companyId uint64) (*warehouse.StockItem, error) {
 	if err := r.registerPurchaseTransactions(tx, order, quantity, companyId); err != nil {
 		return nil, err
 	}
@@ -347,7 +359,7 @@ func (r *goquRepository) partialPurchase(tx *goqu.TxDatabase, order *Order, quan
 	}, nil
 }
 
-func (r *goquRepository) registerPurchaseTransactions(tx *goqu.TxDatabase, order *Order, quantity, companyId uint64) error {
+func (r *goquRepository) registerPurchaseTransactions(tx *database.DB, order *Order, quantity, companyId uint64) error {
 	total := int(order.Price) * int(quantity)
 
 	if order.LastPurchase == nil {
@@ -355,7 +367,7 @@ func (r *goquRepository) registerPurchaseTransactions(tx *goqu.TxDatabase, order
 	}
 
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx,
 		accounting.Transaction{
 			Classification: accounting.MARKET_PURCHASE,
 			Value:          total * -1,
@@ -367,7 +379,7 @@ func (r *goquRepository) registerPurchaseTransactions(tx *goqu.TxDatabase, order
 	}
 
 	transactionId, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx,
 		accounting.Transaction{
 			Classification: accounting.MARKET_SALE,
 			Value:          total,
@@ -393,16 +405,29 @@ func (r *goquRepository) registerPurchaseTransactions(tx *goqu.TxDatabase, order
 	return err
 }
 
-func (r *goquRepository) updateOrder(tx *goqu.TxDatabase, order *Order) error {
-	_, err := tx.
+func (r *goquRepository) updateOrder(tx *database.DB, order *Order) error {
+	result, err := tx.
 		Update(goqu.T("orders")).
 		Set(goqu.Record{
 			"quantity":     order.Quantity,
-			"purchased_at": time.Now(),
+			"purchased_at": r.clock.Now(),
+			"version":      order.Version + 1,
 		}).
-		Where(goqu.I("id").Eq(order.Id)).
+		Where(goqu.And(
+			goqu.I("id").Eq(order.Id),
+			goqu.I("version").Eq(order.Version),
+		)).
 		Executor().
 		Exec()
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	if err := database.CheckVersion(result, "order"); err != nil {
+		return err
+	}
+
+	order.Version++
+	return nil
 }
@@ -2,6 +2,7 @@ package market_test
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/company"
 	"api/database"
 	"api/market"
@@ -9,10 +10,11 @@ import (
 	"api/warehouse"
 	"context"
 	"testing"
+	"time"
 )
 
 func TestMarketRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -26,21 +28,21 @@ func TestMarketRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password) VALUES
-        (1, "Coca-Cola", "coke@email.com", "aoeu"),
-        (2, "McDonalds", "mcdonalds@email.com", "aoeu"),
-        (3, "McDonalds", "mcdonalds@email.com", "aoeu"),
-        (4, "McDonalds", "mcdonalds@email.com", "aoeu")
+        (1, 'Coca-Cola', 'coke@email.com', 'aoeu'),
+        (2, 'McDonalds', 'mcdonalds@email.com', 'aoeu'),
+        (3, 'McDonalds', 'mcdonalds@email.com', 'aoeu'),
+        (4, 'McDonalds', 'mcdonalds@email.com', 'aoeu')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
-	if _, err := tx.Exec(`INSERT INTO categories (id, name) VALUES (1, "Construction")`); err != nil {
+	if _, err := tx.Exec(`INSERT INTO categories (id, name) VALUES (1, 'Construction')`); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO resources (id, name, category_id)
-        VALUES (1, "Metal", 1), (2, "Concrete", 1), (3, "Glass", 1)
+        VALUES (1, 'Metal', 1), (2, 'Concrete', 1), (3, 'Glass', 1)
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -74,6 +76,13 @@ func TestMarketRepository(t *testing.T) {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
+	if _, err := tx.Exec(`
+        INSERT INTO company_balances (company_id, cash)
+        SELECT company_id, SUM(value) FROM transactions GROUP BY company_id
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		t.Fatalf("could not commit transaction: %s", err)
 	}
@@ -82,6 +91,9 @@ func TestMarketRepository(t *testing.T) {
 		if _, err := conn.DB.Exec(`DELETE FROM transactions`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec(`DELETE FROM company_balances`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
 		if _, err := conn.DB.Exec(`DELETE FROM orders`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
@@ -99,10 +111,11 @@ func TestMarketRepository(t *testing.T) {
 		}
 	})
 
-	accountingRepo := accounting.NewRepository(conn)
-	companyRepo := company.NewRepository(conn, accountingRepo)
+	accountingRepo := accounting.NewRepository(conn, clock.New())
+	companyRepo := company.NewRepository(conn, accountingRepo, clock.New())
 	warehouseRepo := warehouse.NewRepository(conn)
-	repository := market.NewRepository(conn, companyRepo, warehouseRepo, accountingRepo)
+	gameTime := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	repository := market.NewRepository(conn, companyRepo, warehouseRepo, accountingRepo, clock.NewFakeClock(gameTime))
 
 	ctx := context.Background()
 
@@ -157,6 +170,23 @@ func TestMarketRepository(t *testing.T) {
 		})
 	})
 
+	t.Run("GetByCompany", func(t *testing.T) {
+		t.Run("should only list open orders", func(t *testing.T) {
+			orders, err := repository.GetByCompany(ctx, 4)
+			if err != nil {
+				t.Fatalf("could not list orders: %s", err)
+			}
+
+			if len(orders) != 1 || orders[0].Id != 5 {
+				t.Fatalf("expected order 5, got %v", orders)
+			}
+
+			if orders[0].CompanyId != 4 || orders[0].ResourceId != 3 {
+				t.Errorf("expected order of company 4 for resource 3, got %+v", orders[0])
+			}
+		})
+	})
+
 	t.Run("PlaceOrder", func(t *testing.T) {
 		inventory, err := warehouseRepo.FetchInventory(ctx, 1)
 		if err != nil {
@@ -235,6 +265,7 @@ func TestMarketRepository(t *testing.T) {
 			TransportFee: 1137,
 			SourcingCost: 1553,
 			Company:      &company.Company{Id: 1},
+			Version:      1,
 		}
 
 		if err := repository.CancelOrder(ctx, order, inventory); err != nil {
@@ -262,6 +293,15 @@ func TestMarketRepository(t *testing.T) {
 		if stock != 200 {
 			t.Errorf("expected stock %d, got %d", 200, stock)
 		}
+
+		var canceledAt time.Time
+		if err := conn.DB.QueryRow(`SELECT canceled_at FROM orders WHERE id = 1`).Scan(&canceledAt); err != nil {
+			t.Fatalf("could not get order: %s", err)
+		}
+
+		if !canceledAt.Equal(gameTime) {
+			t.Errorf("expected order canceled at game time %s, got %s", gameTime, canceledAt)
+		}
 	})
 
 	t.Run("Purchase", func(t *testing.T) {
@@ -493,5 +533,36 @@ func TestMarketRepository(t *testing.T) {
 				}
 			}
 		})
+
+		t.Run("cash enough for the whole purchase", func(t *testing.T) {
+			purchase := &market.Purchase{
+				ResourceId: 3,
+				Quantity:   489,
+				Quality:    0,
+			}
+
+			_, _, err := repository.Purchase(ctx, purchase, 3)
+			expectedError := "not enough cash"
+
+			if err == nil || err.Error() != expectedError {
+				t.Errorf("expected error \"%s\", got \"%v\"", expectedError, err)
+			}
+
+			purchase.Quantity = 488
+
+			if _, _, err := repository.Purchase(ctx, purchase, 3); err != nil {
+				t.Fatalf("could not purchase order: %s", err)
+			}
+
+			buyer, err := companyRepo.GetById(ctx, 3)
+			if err != nil {
+				t.Fatalf("could not get company: %s", err)
+			}
+
+			expectedCash := (500 * 4435) - (488 * 4535)
+			if buyer.AvailableCash != expectedCash {
+				t.Errorf("expected cash %d, got %d", expectedCash, buyer.AvailableCash)
+			}
+		})
 	})
 }
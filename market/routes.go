@@ -2,6 +2,7 @@ package market
 
 import (
 	"api/auth"
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -11,7 +12,7 @@ import (
 func CreateEndpoints(e *echo.Echo, service Service) {
 	group := e.Group("/market")
 
-	group.GET("/orders", func(c echo.Context) error {
+	list := group.GET("/orders", func(c echo.Context) error {
 		resourceId, err := strconv.ParseUint(c.QueryParam("resource"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -30,7 +31,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusOK, orders)
 	})
 
-	group.POST("/orders", func(c echo.Context) error {
+	place := group.POST("/orders", func(c echo.Context) error {
 		order := new(Order)
 
 		if err := c.Bind(order); err != nil {
@@ -56,7 +57,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusCreated, order)
 	})
 
-	group.DELETE("/orders/:id", func(c echo.Context) error {
+	cancel := group.DELETE("/orders/:id", func(c echo.Context) error {
 		orderId, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -85,7 +86,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return service.CancelOrder(ctx, order)
 	})
 
-	group.POST("/orders/purchase", func(c echo.Context) error {
+	buy := group.POST("/orders/purchase", func(c echo.Context) error {
 		purchase := new(Purchase)
 
 		if err := c.Bind(purchase); err != nil {
@@ -108,4 +109,9 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 
 		return c.JSON(http.StatusOK, items)
 	})
+
+	server.AllowApiKeys(auth.SCOPE_MARKET_READ, list)
+	server.AllowApiKeys(auth.SCOPE_MARKET_TRADE, place, cancel, buy)
+	server.AllowMembers(auth.SCOPE_MARKET_READ, list)
+	server.AllowMembers(auth.SCOPE_MARKET_TRADE, place, cancel, buy)
 }
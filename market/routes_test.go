@@ -2,7 +2,10 @@ package market_test
 
 import (
 	"api/auth"
+	"api/clock"
 	"api/company"
+	"api/database"
+	"api/mail"
 	"api/market"
 	"api/notification"
 	"api/server"
@@ -16,19 +19,17 @@ import (
 )
 
 func TestMarketRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
 	token, err := auth.GenerateToken(1, "secret")
 	if err != nil {
 		t.Fatalf("could not generate jwt token: %s", err)
 	}
 
-	svr := server.NewServer()
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
 
-	companySvc := company.NewService(company.NewFakeRepository())
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 	warehouseSvc := warehouse.NewService(warehouse.NewFakeRepository())
 
-	service := market.NewService(market.NewFakeRepository(), companySvc, warehouseSvc, notification.NoOpNotifier(), log.Default())
+	service := market.NewService(market.NewFakeRepository(), companySvc, warehouseSvc, notification.NoOpNotifier(), log.Default(), market.DEFAULT_TRANSPORT_FEE, database.NewFakeUnitOfWork())
 
 	market.CreateEndpoints(svr, service)
 
@@ -2,6 +2,7 @@ package market
 
 import (
 	"api/company"
+	"api/database"
 	"api/notification"
 	"api/resource"
 	"api/server"
@@ -11,7 +12,9 @@ import (
 	"time"
 )
 
-const TRANSPORT_FEE_PERCENTAGE = 0.005
+// Share of the sourcing cost charged to ship what was purchased, unless
+// configured otherwise
+const DEFAULT_TRANSPORT_FEE = 0.005
 
 type (
 	Order struct {
@@ -25,6 +28,7 @@ type (
 		ResourceId   uint64     `db:"resource_id" json:"resource_id,omitempty" validate:"required"`
 		SourcingCost uint64     `db:"sourcing_cost" json:"-" validate:"-"`
 		LastPurchase *time.Time `db:"last_purchase" json:"-" validate:"-"`
+		Version      uint64     `db:"version" json:"-" validate:"-"`
 
 		Resource *resource.Resource `db:"resource" json:"resource" validate:"-"`
 		Company  *company.Company   `db:"company" json:"company" validate:"-"`
@@ -41,6 +45,9 @@ type (
 		GetByResource(ctx context.Context, resourceId, quality uint64) ([]*Order, error)
 		PlaceOrder(ctx context.Context, order *Order) (*Order, error)
 		CancelOrder(ctx context.Context, order *Order) error
+
+		// Cancels every open order of the company, returning how many were
+		CancelCompanyOrders(ctx context.Context, companyId uint64) (int, error)
 		Purchase(ctx context.Context, purchase *Purchase, companyId uint64) ([]*warehouse.StockItem, error)
 	}
 
@@ -50,11 +57,13 @@ type (
 		warehouseSvc warehouse.Service
 		notifier     notification.Notifier
 		logger       *log.Logger
+		transportFee float64
+		uow          database.UnitOfWork
 	}
 )
 
-func NewService(repository Repository, companySvc company.Service, warehouseSvc warehouse.Service, notifier notification.Notifier, logger *log.Logger) Service {
-	return &service{repository, companySvc, warehouseSvc, notifier, logger}
+func NewService(repository Repository, companySvc company.Service, warehouseSvc warehouse.Service, notifier notification.Notifier, logger *log.Logger, transportFee float64, uow database.UnitOfWork) Service {
+	return &service{repository, companySvc, warehouseSvc, notifier, logger, transportFee, uow}
 }
 
 func (s *service) GetById(ctx context.Context, orderId uint64) (*Order, error) {
@@ -66,7 +75,15 @@ func (s *service) GetByResource(ctx context.Context, resourceId, quality uint64)
 }
 
 func (s *service) Purchase(ctx context.Context, purchase *Purchase, companyId uint64) ([]*warehouse.StockItem, error) {
-	stockItem, orders, err := s.repository.Purchase(ctx, purchase, companyId)
+	var stockItem []*warehouse.StockItem
+	var orders []*Order
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		stockItem, orders, err = s.repository.Purchase(ctx, purchase, companyId)
+		return err
+	})
+
 	if err != nil {
 		return nil, err
 	}
@@ -84,6 +101,33 @@ func (s *service) Purchase(ctx context.Context, purchase *Purchase, companyId ui
 }
 
 func (s *service) PlaceOrder(ctx context.Context, order *Order) (*Order, error) {
+	var newOrder *Order
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		newOrder, err = s.placeOrder(ctx, order)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	event := notification.Event{
+		Type:    notification.OrderPlaced,
+		Payload: newOrder,
+	}
+
+	if err := s.notifier.Broadcast(ctx, event); err != nil {
+		s.logger.Printf("error broadcasting order placed event: %s", err)
+	}
+
+	return newOrder, err
+}
+
+// Checks the company has the resources and cash to place the order and
+// places it, all in the caller's unit of work
+func (s *service) placeOrder(ctx context.Context, order *Order) (*Order, error) {
 	inventory, err := s.warehouseSvc.GetInventory(ctx, order.CompanyId)
 	if err != nil {
 		return nil, err
@@ -99,55 +143,60 @@ func (s *service) PlaceOrder(ctx context.Context, order *Order) (*Order, error)
 		return nil, server.NewBusinessRuleError("not enough resources")
 	}
 
-	orderCompany, err := s.companySvc.GetById(ctx, order.CompanyId)
-	if err != nil {
-		return nil, err
-	}
-
 	sourcingCost := inventory.ReduceStock(orderItem)
 
 	order.SourcingCost = sourcingCost
-	order.TransportFee = uint64(float64(sourcingCost*order.Quantity) * TRANSPORT_FEE_PERCENTAGE)
+	order.TransportFee = uint64(float64(sourcingCost*order.Quantity) * s.transportFee)
 
-	if orderCompany.AvailableCash < int(order.TransportFee) {
-		return nil, server.NewBusinessRuleError("not enough cash to pay transport fee")
-	}
-
-	newOrder, err := s.repository.PlaceOrder(ctx, order, inventory)
+	held, err := s.companySvc.HoldCash(ctx, order.CompanyId, int(order.TransportFee))
 	if err != nil {
 		return nil, err
 	}
 
-	event := notification.Event{
-		Type:    notification.OrderPlaced,
-		Payload: newOrder,
-	}
-
-	if err := s.notifier.Broadcast(ctx, event); err != nil {
-		s.logger.Printf("error broadcasting order placed event: %s", err)
+	if !held {
+		return nil, server.NewBusinessRuleError("not enough cash to pay transport fee")
 	}
 
-	return newOrder, err
+	return s.repository.PlaceOrder(ctx, order, inventory)
 }
 
-func (s *service) CancelOrder(ctx context.Context, order *Order) error {
-	inventory, err := s.warehouseSvc.GetInventory(ctx, order.CompanyId)
+func (s *service) CancelCompanyOrders(ctx context.Context, companyId uint64) (int, error) {
+	orders, err := s.repository.GetByCompany(ctx, companyId)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	for i, order := range orders {
+		if err := s.CancelOrder(ctx, order); err != nil {
+			return i, err
+		}
 	}
 
-	inventory.IncrementStock([]*warehouse.StockItem{
-		{
-			Item: &resource.Item{
-				Qty:      order.Quantity,
-				Quality:  order.Quality,
-				Resource: &resource.Resource{Id: order.Resource.Id},
+	return len(orders), nil
+}
+
+func (s *service) CancelOrder(ctx context.Context, order *Order) error {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		inventory, err := s.warehouseSvc.GetInventory(ctx, order.CompanyId)
+		if err != nil {
+			return err
+		}
+
+		inventory.IncrementStock([]*warehouse.StockItem{
+			{
+				Item: &resource.Item{
+					Qty:      order.Quantity,
+					Quality:  order.Quality,
+					Resource: &resource.Resource{Id: order.Resource.Id},
+				},
+				Cost: order.SourcingCost,
 			},
-			Cost: order.SourcingCost,
-		},
+		})
+
+		return s.repository.CancelOrder(ctx, order, inventory)
 	})
 
-	if err := s.repository.CancelOrder(ctx, order, inventory); err != nil {
+	if err != nil {
 		return err
 	}
 
@@ -1,7 +1,10 @@
 package market_test
 
 import (
+	"api/clock"
 	"api/company"
+	"api/database"
+	"api/mail"
 	"api/market"
 	"api/notification"
 	"api/warehouse"
@@ -11,10 +14,10 @@ import (
 )
 
 func TestMarketService(t *testing.T) {
-	companySvc := company.NewService(company.NewFakeRepository())
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
 	warehouseSvc := warehouse.NewService(warehouse.NewFakeRepository())
 
-	service := market.NewService(market.NewFakeRepository(), companySvc, warehouseSvc, notification.NoOpNotifier(), log.Default())
+	service := market.NewService(market.NewFakeRepository(), companySvc, warehouseSvc, notification.NoOpNotifier(), log.Default(), market.DEFAULT_TRANSPORT_FEE, database.NewFakeUnitOfWork())
 
 	ctx := context.Background()
 
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"api/database"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate up | down | to <version> | baseline <version> | status"
+
+// Runs the migrate subcommand, writing what was done to out
+func runMigrate(ctx context.Context, migrator *database.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	var count int
+	var err error
+
+	switch args[0] {
+	case "up":
+		count, err = migrator.Up(ctx)
+	case "down":
+		count, err = migrator.Down(ctx)
+	case "to":
+		version, parseErr := parseVersion(args)
+		if parseErr != nil {
+			return parseErr
+		}
+
+		count, err = migrator.To(ctx, version)
+	case "baseline":
+		version, parseErr := parseVersion(args)
+		if parseErr != nil {
+			return parseErr
+		}
+
+		var recorded int
+		recorded, err = migrator.Baseline(ctx, version)
+		if recorded > 0 {
+			fmt.Fprintf(out, "recorded %d migrations as applied\n", recorded)
+		}
+	case "status":
+		return printMigrationStatus(ctx, migrator, out)
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	if count > 0 {
+		fmt.Fprintf(out, "ran %d migrations\n", count)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "database at version %d\n", version)
+	return nil
+}
+
+// Parses the version given after the subcommand
+func parseVersion(args []string) (uint64, error) {
+	if len(args) < 2 {
+		return 0, errors.New(migrateUsage)
+	}
+
+	version, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q", args[1])
+	}
+
+	return version, nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *database.Migrator, out io.Writer) error {
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range status {
+		appliedAt := "pending"
+		if migration.AppliedAt != nil {
+			appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(out, "%06d %-50s %s\n", migration.Version, migration.Name, appliedAt)
+	}
+
+	return nil
+}
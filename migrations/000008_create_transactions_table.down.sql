@@ -1,2 +0,0 @@
-DROP TABLE IF EXITS `classifications`;
-DROP TABLE IF EXITS `transactions`;
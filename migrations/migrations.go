@@ -0,0 +1,23 @@
+// Package migrations embeds the SQL migrations so the binary can apply
+// them without the files being around. Each driver has its own directory,
+// named after it, since DDL differs between databases. MySQL and Postgres
+// start from a single baseline matching the SQLite schema at that version;
+// new migrations are added to every directory under the same number.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed sqlite3/*.sql mysql/*.sql postgres/*.sql
+var FS embed.FS
+
+// Returns the migrations written for the given database driver
+func For(driver string) (fs.FS, error) {
+	if _, err := fs.Stat(FS, driver); err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+	return fs.Sub(FS, driver)
+}
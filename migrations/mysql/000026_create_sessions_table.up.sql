@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `sessions` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT NOT NULL,
+    `revoked_at` DATETIME DEFAULT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `session_id` BIGINT NOT NULL,
+    `token_hash` CHAR(64) NOT NULL UNIQUE,
+    `expires_at` DATETIME NOT NULL,
+    `used_at` DATETIME DEFAULT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`session_id`) REFERENCES `sessions`(`id`)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT NOT NULL,
+    `name` VARCHAR(255) NOT NULL,
+    `prefix` VARCHAR(16) NOT NULL,
+    `key_hash` CHAR(64) NOT NULL UNIQUE,
+    `scopes` VARCHAR(255) NOT NULL,
+    `expires_at` DATETIME DEFAULT NULL,
+    `last_used_at` DATETIME DEFAULT NULL,
+    `revoked_at` DATETIME DEFAULT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `login_attempts` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT DEFAULT NULL,
+    `email` VARCHAR(255) NOT NULL,
+    `ip` VARCHAR(45) NOT NULL,
+    `user_agent` VARCHAR(255) NOT NULL,
+    `success` TINYINT NOT NULL DEFAULT 0,
+    `locked` TINYINT NOT NULL DEFAULT 0,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`),
+    INDEX `login_attempts_email` (`email`, `created_at`),
+    INDEX `login_attempts_ip` (`ip`, `created_at`)
+);
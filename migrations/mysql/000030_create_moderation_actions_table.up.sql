@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `moderation_actions` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT NOT NULL,
+    `admin_id` BIGINT NOT NULL,
+    `action` VARCHAR(16) NOT NULL,
+    `reason` VARCHAR(255) NOT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`),
+    FOREIGN KEY (`admin_id`) REFERENCES `companies`(`id`),
+    INDEX `moderation_actions_company` (`company_id`, `created_at`)
+);
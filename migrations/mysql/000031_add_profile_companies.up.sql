@@ -0,0 +1,2 @@
+ALTER TABLE `companies` ADD COLUMN `logo_url` VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE `companies` ADD COLUMN `description` VARCHAR(1000) NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `valuations` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT NOT NULL,
+    `cash` BIGINT NOT NULL,
+    `inventory` BIGINT NOT NULL,
+    `terrains` BIGINT NOT NULL,
+    `buildings` BIGINT NOT NULL,
+    `receivables` BIGINT NOT NULL,
+    `debt` BIGINT NOT NULL,
+    `net_worth` BIGINT NOT NULL,
+    `revenue` BIGINT NOT NULL,
+    `growth` DOUBLE NOT NULL DEFAULT 0,
+    `created_at` DATETIME NOT NULL,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`),
+    INDEX `valuations_company` (`company_id`, `created_at`),
+    INDEX `valuations_created_at` (`created_at`)
+);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `terrains` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT NOT NULL,
+    `position` TINYINT UNSIGNED NOT NULL,
+    `purchase_price` BIGINT NOT NULL DEFAULT 0,
+    `purchased_at` DATETIME NOT NULL,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`),
+    UNIQUE INDEX `terrains_company_position` (`company_id`, `position`)
+);
+
+-- Maps hold 100 plots, companies counting more keep the first 100
+UPDATE `companies` SET `available_terrains` = 100 WHERE `available_terrains` > 100;
+
+INSERT INTO `terrains` (`company_id`, `position`, `purchase_price`, `purchased_at`)
+WITH RECURSIVE `positions` (`position`) AS (
+    SELECT 1
+    UNION ALL
+    SELECT `position` + 1 FROM `positions` WHERE `position` < 100
+)
+SELECT `c`.`id`, `p`.`position`, 0, COALESCE(`c`.`created_at`, CURRENT_TIMESTAMP)
+FROM `companies` `c`
+INNER JOIN `positions` `p` ON `p`.`position` <= `c`.`available_terrains`;
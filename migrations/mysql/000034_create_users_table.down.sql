@@ -0,0 +1,4 @@
+ALTER TABLE `sessions` DROP COLUMN `user_id`;
+DROP TABLE IF EXISTS `member_invitations`;
+DROP TABLE IF EXISTS `company_members`;
+DROP TABLE IF EXISTS `users`;
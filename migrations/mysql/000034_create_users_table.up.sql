@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `name` VARCHAR(255) NOT NULL,
+    `email` VARCHAR(255) NOT NULL UNIQUE,
+    `password` VARCHAR(255) NOT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS `company_members` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT NOT NULL,
+    `user_id` BIGINT NOT NULL,
+    `role` VARCHAR(16) NOT NULL,
+    `invited_by` BIGINT DEFAULT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`),
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+    FOREIGN KEY (`invited_by`) REFERENCES `users`(`id`),
+    UNIQUE INDEX `company_members_user` (`company_id`, `user_id`),
+    INDEX `company_members_user_id` (`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `member_invitations` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT NOT NULL,
+    `email` VARCHAR(255) NOT NULL,
+    `role` VARCHAR(16) NOT NULL,
+    `token_hash` CHAR(64) NOT NULL UNIQUE,
+    `invited_by` BIGINT DEFAULT NULL,
+    `expires_at` DATETIME NOT NULL,
+    `accepted_at` DATETIME DEFAULT NULL,
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`),
+    FOREIGN KEY (`invited_by`) REFERENCES `users`(`id`),
+    INDEX `member_invitations_company` (`company_id`)
+);
+
+ALTER TABLE `sessions` ADD COLUMN `user_id` BIGINT DEFAULT NULL;
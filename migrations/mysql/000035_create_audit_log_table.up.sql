@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `audit_log` (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `company_id` BIGINT NOT NULL,
+    `user_id` BIGINT DEFAULT NULL,
+    `api_key_id` BIGINT DEFAULT NULL,
+    `role` VARCHAR(16) NOT NULL DEFAULT '',
+    `method` VARCHAR(8) NOT NULL,
+    `path` VARCHAR(255) NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`),
+    INDEX `audit_log_company` (`company_id`, `created_at`)
+);
@@ -0,0 +1 @@
+ALTER TABLE `scheduled_jobs` ADD COLUMN `paused` TINYINT DEFAULT 0;
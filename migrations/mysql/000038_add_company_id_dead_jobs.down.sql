@@ -0,0 +1 @@
+ALTER TABLE `dead_jobs` DROP COLUMN `company_id`;
@@ -0,0 +1 @@
+ALTER TABLE `dead_jobs` ADD COLUMN `company_id` BIGINT DEFAULT 0;
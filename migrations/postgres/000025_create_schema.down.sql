@@ -0,0 +1,30 @@
+DROP TABLE IF EXISTS "balance_checkpoints";
+DROP TABLE IF EXISTS "company_balances";
+DROP TABLE IF EXISTS "job_runs";
+DROP TABLE IF EXISTS "dead_jobs";
+DROP TABLE IF EXISTS "scheduled_jobs";
+DROP TABLE IF EXISTS "notifications";
+DROP TABLE IF EXISTS "rates_history";
+DROP TABLE IF EXISTS "bonds_creditors";
+DROP TABLE IF EXISTS "bonds";
+DROP TABLE IF EXISTS "loans";
+DROP TABLE IF EXISTS "trainings";
+DROP TABLE IF EXISTS "staff_searches";
+DROP TABLE IF EXISTS "research_staff";
+DROP TABLE IF EXISTS "resources_qualities";
+DROP TABLE IF EXISTS "assigned_staff";
+DROP TABLE IF EXISTS "researches";
+DROP TABLE IF EXISTS "productions";
+DROP TABLE IF EXISTS "orders_transactions";
+DROP TABLE IF EXISTS "transactions";
+DROP TABLE IF EXISTS "classifications";
+DROP TABLE IF EXISTS "companies_buildings";
+DROP TABLE IF EXISTS "buildings_resources";
+DROP TABLE IF EXISTS "buildings_requirements";
+DROP TABLE IF EXISTS "buildings";
+DROP TABLE IF EXISTS "orders";
+DROP TABLE IF EXISTS "inventories";
+DROP TABLE IF EXISTS "companies";
+DROP TABLE IF EXISTS "resources_requirements";
+DROP TABLE IF EXISTS "resources";
+DROP TABLE IF EXISTS "categories";
@@ -0,0 +1,310 @@
+CREATE TABLE IF NOT EXISTS "categories" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "resources" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "image" VARCHAR(255),
+    "category_id" BIGINT REFERENCES "categories"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "resources_requirements" (
+    "resource_id" BIGINT NOT NULL,
+    "requirement_id" BIGINT NOT NULL,
+    "qty" BIGINT NOT NULL,
+    PRIMARY KEY ("resource_id", "requirement_id"),
+    FOREIGN KEY ("resource_id") REFERENCES "resources"("id"),
+    FOREIGN KEY ("requirement_id") REFERENCES "resources"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "companies" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "email" VARCHAR(255) NOT NULL,
+    "password" VARCHAR(255) NOT NULL,
+    "available_terrains" SMALLINT DEFAULT 3,
+    "is_admin" SMALLINT DEFAULT 0,
+    "last_login" TIMESTAMP DEFAULT NULL,
+    "blocked_at" TIMESTAMP DEFAULT NULL,
+    "deleted_at" TIMESTAMP DEFAULT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS "inventories" (
+    "resource_id" BIGINT,
+    "company_id" BIGINT,
+    "quantity" BIGINT,
+    "quality" SMALLINT,
+    "sourcing_cost" BIGINT,
+    "version" BIGINT NOT NULL DEFAULT 1,
+    PRIMARY KEY ("resource_id", "company_id", "quality"),
+    FOREIGN KEY ("resource_id") REFERENCES "resources"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "orders" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "quantity" BIGINT NOT NULL,
+    "quality" SMALLINT NOT NULL,
+    "price" BIGINT NOT NULL,
+    "sourcing_cost" BIGINT DEFAULT 0,
+    "market_fee" BIGINT DEFAULT 0,
+    "transport_fee" BIGINT DEFAULT 0,
+    "company_id" BIGINT NOT NULL,
+    "resource_id" BIGINT NOT NULL,
+    "purchased_at" TIMESTAMP DEFAULT NULL,
+    "canceled_at" TIMESTAMP DEFAULT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "version" BIGINT NOT NULL DEFAULT 1,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id"),
+    FOREIGN KEY ("resource_id") REFERENCES "resources"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "buildings" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "downtime" BIGINT DEFAULT NULL,
+    "wages_per_hour" BIGINT DEFAULT 0,
+    "admin_per_hour" BIGINT DEFAULT 0,
+    "maintenance_per_hour" BIGINT DEFAULT 0,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "buildings_requirements" (
+    "building_id" BIGINT NOT NULL,
+    "resource_id" BIGINT NOT NULL,
+    "qty" BIGINT NOT NULL,
+    PRIMARY KEY ("building_id", "resource_id"),
+    FOREIGN KEY ("building_id") REFERENCES "buildings"("id"),
+    FOREIGN KEY ("resource_id") REFERENCES "resources"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "buildings_resources" (
+    "building_id" BIGINT NOT NULL,
+    "resource_id" BIGINT NOT NULL,
+    "qty_per_hour" BIGINT NOT NULL,
+    PRIMARY KEY ("building_id", "resource_id"),
+    FOREIGN KEY ("building_id") REFERENCES "buildings"("id"),
+    FOREIGN KEY ("resource_id") REFERENCES "resources"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "companies_buildings" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "company_id" BIGINT NOT NULL,
+    "building_id" BIGINT NOT NULL,
+    "level" SMALLINT DEFAULT 1,
+    "position" SMALLINT DEFAULT NULL,
+    "built_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "completes_at" TIMESTAMP DEFAULT NULL,
+    "demolished_at" TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id"),
+    FOREIGN KEY ("building_id") REFERENCES "buildings"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "classifications" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "parent_id" BIGINT DEFAULT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY ("parent_id") REFERENCES "classifications" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "transactions" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "value" BIGINT,
+    "company_id" BIGINT,
+    "description" VARCHAR(255),
+    "classification_id" BIGINT,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("company_id") REFERENCES "companies" ("id"),
+    FOREIGN KEY ("classification_id") REFERENCES "classifications" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "orders_transactions" (
+    "order_id" BIGINT,
+    "quantity" BIGINT,
+    "transaction_id" BIGINT,
+    PRIMARY KEY ("order_id", "transaction_id"),
+    FOREIGN KEY ("order_id") REFERENCES "orders" ("id"),
+    FOREIGN KEY ("transaction_id") REFERENCES "transactions" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "productions" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "resource_id" BIGINT NOT NULL,
+    "building_id" BIGINT NOT NULL,
+    "qty" BIGINT NOT NULL,
+    "quality" SMALLINT NOT NULL,
+    "sourcing_cost" BIGINT,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "finishes_at" TIMESTAMP,
+    "canceled_at" TIMESTAMP,
+    "collected_at" TIMESTAMP,
+    FOREIGN KEY ("resource_id") REFERENCES "resources" ("id"),
+    FOREIGN KEY ("building_id") REFERENCES "companies_buildings" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "researches" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "patents" SMALLINT DEFAULT 0,
+    "investment" BIGINT DEFAULT 0,
+    "finishes_at" TIMESTAMP,
+    "completed_at" TIMESTAMP,
+    "company_id" BIGINT NOT NULL,
+    "resource_id" BIGINT NOT NULL,
+    FOREIGN KEY ("resource_id") REFERENCES "resources" ("id"),
+    FOREIGN KEY ("company_id") REFERENCES "companies" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "assigned_staff" (
+    "staff_id" BIGINT NOT NULL,
+    "research_id" BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "resources_qualities" (
+    "resource_id" BIGINT NOT NULL,
+    "company_id" BIGINT NOT NULL,
+    "quality" SMALLINT NOT NULL,
+    "patents" SMALLINT DEFAULT 0,
+    PRIMARY KEY ("resource_id", "company_id"),
+    FOREIGN KEY ("resource_id") REFERENCES "resources"("id"),
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "research_staff" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "salary" BIGINT DEFAULT 0,
+    "skill" SMALLINT DEFAULT 0,
+    "talent" SMALLINT DEFAULT 0,
+    "status" SMALLINT DEFAULT 0,
+    "offer" BIGINT DEFAULT 0,
+    "company_id" BIGINT NOT NULL,
+    "poacher_id" BIGINT DEFAULT NULL,
+    FOREIGN KEY ("company_id") REFERENCES "companies" ("id"),
+    FOREIGN KEY ("poacher_id") REFERENCES "companies" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "staff_searches" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "company_id" BIGINT NOT NULL,
+    "finishes_at" TIMESTAMP,
+    "started_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("company_id") REFERENCES "companies" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "trainings" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "result" SMALLINT,
+    "staff_id" BIGINT,
+    "company_id" BIGINT,
+    "investment" BIGINT,
+    "finishes_at" TIMESTAMP,
+    "completed_at" TIMESTAMP,
+    FOREIGN KEY ("staff_id") REFERENCES "research_staff" ("id"),
+    FOREIGN KEY ("company_id") REFERENCES "companies" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "loans" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "company_id" BIGINT NOT NULL,
+    "principal" BIGINT NOT NULL,
+    "interest_rate" DECIMAL(4, 2) NOT NULL,
+    "payable_from" TIMESTAMP NOT NULL,
+    "interest_paid" BIGINT DEFAULT 0,
+    "principal_paid" BIGINT DEFAULT 0,
+    "delayed_payments" SMALLINT DEFAULT 0,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "version" BIGINT NOT NULL DEFAULT 1,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "bonds" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "company_id" BIGINT NOT NULL,
+    "amount" BIGINT NOT NULL,
+    "interest_rate" DECIMAL(4, 2) NOT NULL,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "bonds_creditors" (
+    "company_id" BIGINT NOT NULL,
+    "bond_id" BIGINT NOT NULL,
+    "interest_rate" DECIMAL(4, 2) NOT NULL,
+    "interest_paid" BIGINT DEFAULT 0,
+    "payable_from" TIMESTAMP NOT NULL,
+    "principal" BIGINT NOT NULL,
+    "principal_paid" BIGINT DEFAULT 0,
+    "delayed_payments" SMALLINT DEFAULT 0,
+    "version" BIGINT NOT NULL DEFAULT 1,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id"),
+    FOREIGN KEY ("bond_id") REFERENCES "bonds"("id"),
+    PRIMARY KEY ("company_id", "bond_id")
+);
+
+CREATE TABLE IF NOT EXISTS "rates_history" (
+    "period" DATE PRIMARY KEY,
+    "inflation" DECIMAL(5, 4),
+    "interest" DECIMAL(5, 4)
+);
+
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "message" TEXT,
+    "company_id" BIGINT,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("company_id") REFERENCES "companies" ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "scheduled_jobs" (
+    "id" VARCHAR(255) PRIMARY KEY,
+    "type" VARCHAR(255) NOT NULL,
+    "payload" TEXT,
+    "runs_at" TIMESTAMP NOT NULL,
+    "repeats_every" BIGINT DEFAULT 0,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    "attempts" BIGINT DEFAULT 0,
+    "retry_at" TIMESTAMP,
+    "company_id" BIGINT DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "dead_jobs" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "job_id" VARCHAR(255) NOT NULL,
+    "type" VARCHAR(255) NOT NULL,
+    "payload" TEXT,
+    "attempts" BIGINT NOT NULL,
+    "error" TEXT NOT NULL,
+    "failed_at" TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "job_runs" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "scheduled_at" TIMESTAMP NOT NULL,
+    "started_at" TIMESTAMP NOT NULL,
+    "finished_at" TIMESTAMP NOT NULL,
+    "error" TEXT DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "company_balances" (
+    "company_id" BIGINT PRIMARY KEY,
+    "cash" BIGINT NOT NULL DEFAULT 0,
+    "updated_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "balance_checkpoints" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "company_id" BIGINT NOT NULL,
+    "cash" BIGINT NOT NULL,
+    "transaction_id" BIGINT NOT NULL DEFAULT 0,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id")
+);
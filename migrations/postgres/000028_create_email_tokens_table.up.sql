@@ -0,0 +1,12 @@
+ALTER TABLE "companies" ADD COLUMN "email_verified_at" TIMESTAMP DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS "email_tokens" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "company_id" BIGINT NOT NULL,
+    "purpose" VARCHAR(16) NOT NULL,
+    "token_hash" CHAR(64) NOT NULL UNIQUE,
+    "expires_at" TIMESTAMP NOT NULL,
+    "used_at" TIMESTAMP DEFAULT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id")
+);
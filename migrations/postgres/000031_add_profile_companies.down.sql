@@ -0,0 +1,2 @@
+ALTER TABLE "companies" DROP COLUMN "description";
+ALTER TABLE "companies" DROP COLUMN "logo_url";
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "email" VARCHAR(255) NOT NULL UNIQUE,
+    "password" VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS "company_members" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "company_id" BIGINT NOT NULL,
+    "user_id" BIGINT NOT NULL,
+    "role" VARCHAR(16) NOT NULL,
+    "invited_by" BIGINT DEFAULT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id"),
+    FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    FOREIGN KEY ("invited_by") REFERENCES "users"("id")
+);
+
+CREATE UNIQUE INDEX "company_members_user" ON "company_members" ("company_id", "user_id");
+CREATE INDEX "company_members_user_id" ON "company_members" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "member_invitations" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "company_id" BIGINT NOT NULL,
+    "email" VARCHAR(255) NOT NULL,
+    "role" VARCHAR(16) NOT NULL,
+    "token_hash" CHAR(64) NOT NULL UNIQUE,
+    "invited_by" BIGINT DEFAULT NULL,
+    "expires_at" TIMESTAMP NOT NULL,
+    "accepted_at" TIMESTAMP DEFAULT NULL,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY ("company_id") REFERENCES "companies"("id"),
+    FOREIGN KEY ("invited_by") REFERENCES "users"("id")
+);
+
+CREATE INDEX "member_invitations_company" ON "member_invitations" ("company_id");
+
+ALTER TABLE "sessions" ADD COLUMN "user_id" BIGINT DEFAULT NULL;
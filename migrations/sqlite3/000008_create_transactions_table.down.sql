@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `classifications`;
+DROP TABLE IF EXISTS `transactions`;
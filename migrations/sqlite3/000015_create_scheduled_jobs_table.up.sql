@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `scheduled_jobs` (
+    `id` VARCHAR(255) PRIMARY KEY,
+    `type` VARCHAR(255) NOT NULL,
+    `payload` TEXT,
+    `runs_at` TIMESTAMP NOT NULL,
+    `repeats_every` INTEGER DEFAULT 0,
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
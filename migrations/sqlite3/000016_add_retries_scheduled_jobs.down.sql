@@ -0,0 +1,5 @@
+ALTER TABLE `scheduled_jobs`
+DROP COLUMN `retry_at`;
+
+ALTER TABLE `scheduled_jobs`
+DROP COLUMN `attempts`;
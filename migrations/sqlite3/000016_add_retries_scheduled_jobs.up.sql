@@ -0,0 +1,5 @@
+ALTER TABLE `scheduled_jobs`
+ADD COLUMN `attempts` INTEGER DEFAULT 0;
+
+ALTER TABLE `scheduled_jobs`
+ADD COLUMN `retry_at` TIMESTAMP;
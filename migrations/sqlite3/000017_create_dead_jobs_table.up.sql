@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS `dead_jobs` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `job_id` VARCHAR(255) NOT NULL,
+    `type` VARCHAR(255) NOT NULL,
+    `payload` TEXT,
+    `attempts` INTEGER NOT NULL,
+    `error` TEXT NOT NULL,
+    `failed_at` TIMESTAMP NOT NULL
+);
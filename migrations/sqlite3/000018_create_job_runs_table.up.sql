@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `job_runs` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `name` VARCHAR(255) NOT NULL,
+    `scheduled_at` TIMESTAMP NOT NULL,
+    `started_at` TIMESTAMP NOT NULL,
+    `finished_at` TIMESTAMP NOT NULL,
+    `error` TEXT DEFAULT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE `scheduled_jobs`
+DROP COLUMN `company_id`;
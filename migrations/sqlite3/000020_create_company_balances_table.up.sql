@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS `company_balances` (
+    `company_id` INTEGER PRIMARY KEY,
+    `cash` BIGINT NOT NULL DEFAULT 0,
+    `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
+
+INSERT INTO `company_balances` (`company_id`, `cash`)
+SELECT `company_id`, SUM(`value`) FROM `transactions` GROUP BY `company_id`;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `balance_checkpoints` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `company_id` INTEGER NOT NULL,
+    `cash` BIGINT NOT NULL,
+    `transaction_id` INTEGER NOT NULL DEFAULT 0,
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
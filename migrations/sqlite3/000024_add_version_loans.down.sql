@@ -0,0 +1,2 @@
+ALTER TABLE `loans`
+DROP COLUMN `version`;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE `loans`
+ADD COLUMN `version` INTEGER NOT NULL DEFAULT 1;
\ No newline at end of file
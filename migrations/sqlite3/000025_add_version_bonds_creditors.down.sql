@@ -0,0 +1,2 @@
+ALTER TABLE `bonds_creditors`
+DROP COLUMN `version`;
\ No newline at end of file
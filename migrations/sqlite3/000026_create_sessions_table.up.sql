@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `sessions` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `company_id` INTEGER NOT NULL,
+    `revoked_at` TIMESTAMP DEFAULT NULL,
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `session_id` INTEGER NOT NULL,
+    `token_hash` CHAR(64) NOT NULL UNIQUE,
+    `expires_at` TIMESTAMP NOT NULL,
+    `used_at` TIMESTAMP DEFAULT NULL,
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`session_id`) REFERENCES `sessions`(`id`)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `company_id` INTEGER NOT NULL,
+    `name` VARCHAR(255) NOT NULL,
+    `prefix` VARCHAR(16) NOT NULL,
+    `key_hash` CHAR(64) NOT NULL UNIQUE,
+    `scopes` VARCHAR(255) NOT NULL,
+    `expires_at` TIMESTAMP DEFAULT NULL,
+    `last_used_at` TIMESTAMP DEFAULT NULL,
+    `revoked_at` TIMESTAMP DEFAULT NULL,
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `email_tokens`;
+ALTER TABLE `companies` DROP COLUMN `email_verified_at`;
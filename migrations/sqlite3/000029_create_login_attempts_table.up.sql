@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `login_attempts` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `company_id` INTEGER DEFAULT NULL,
+    `email` VARCHAR(255) NOT NULL,
+    `ip` VARCHAR(45) NOT NULL,
+    `user_agent` VARCHAR(255) NOT NULL,
+    `success` TINYINT NOT NULL DEFAULT 0,
+    `locked` TINYINT NOT NULL DEFAULT 0,
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
+
+CREATE INDEX `login_attempts_email` ON `login_attempts` (`email`, `created_at`);
+CREATE INDEX `login_attempts_ip` ON `login_attempts` (`ip`, `created_at`);
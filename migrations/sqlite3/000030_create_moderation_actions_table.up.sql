@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `moderation_actions` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `company_id` INTEGER NOT NULL,
+    `admin_id` INTEGER NOT NULL,
+    `action` VARCHAR(16) NOT NULL,
+    `reason` VARCHAR(255) NOT NULL,
+    `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`),
+    FOREIGN KEY (`admin_id`) REFERENCES `companies`(`id`)
+);
+
+CREATE INDEX `moderation_actions_company` ON `moderation_actions` (`company_id`, `created_at`);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS `valuations` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `company_id` INTEGER NOT NULL,
+    `cash` BIGINT NOT NULL,
+    `inventory` BIGINT NOT NULL,
+    `terrains` BIGINT NOT NULL,
+    `buildings` BIGINT NOT NULL,
+    `receivables` BIGINT NOT NULL,
+    `debt` BIGINT NOT NULL,
+    `net_worth` BIGINT NOT NULL,
+    `revenue` BIGINT NOT NULL,
+    `growth` DOUBLE NOT NULL DEFAULT 0,
+    `created_at` TIMESTAMP NOT NULL,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
+
+CREATE INDEX `valuations_company` ON `valuations` (`company_id`, `created_at`);
+CREATE INDEX `valuations_created_at` ON `valuations` (`created_at`);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `audit_log` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `company_id` INTEGER NOT NULL,
+    `user_id` INTEGER DEFAULT NULL,
+    `api_key_id` INTEGER DEFAULT NULL,
+    `role` VARCHAR(16) NOT NULL DEFAULT '',
+    `method` VARCHAR(8) NOT NULL,
+    `path` VARCHAR(255) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL,
+    FOREIGN KEY (`company_id`) REFERENCES `companies`(`id`)
+);
+
+CREATE INDEX `audit_log_company` ON `audit_log` (`company_id`, `created_at`);
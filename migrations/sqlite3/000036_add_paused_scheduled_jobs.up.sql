@@ -0,0 +1,2 @@
+ALTER TABLE `scheduled_jobs`
+ADD COLUMN `paused` TINYINT DEFAULT 0;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `period_payments` (
+    `kind` VARCHAR(16) NOT NULL,
+    `company_id` INTEGER NOT NULL,
+    `period` TIMESTAMP NOT NULL,
+    `created_at` TIMESTAMP NOT NULL,
+    PRIMARY KEY (`kind`, `company_id`, `period`)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE `dead_jobs`
+ADD COLUMN `company_id` INTEGER DEFAULT 0;
@@ -0,0 +1,91 @@
+package moderation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	fakeCompany struct {
+		blockedAt *time.Time
+		deletedAt *time.Time
+	}
+
+	fakeRepository struct {
+		mutex     sync.Mutex
+		companies map[uint64]*fakeCompany
+		actions   []*Action
+	}
+)
+
+// Knows the same companies as the company fake repository
+func NewFakeRepository() Repository {
+	return &fakeRepository{
+		companies: map[uint64]*fakeCompany{1: {}, 2: {}, 3: {}},
+		actions:   make([]*Action, 0),
+	}
+}
+
+func (r *fakeRepository) Block(ctx context.Context, companyId uint64, at time.Time) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	company, ok := r.companies[companyId]
+	if !ok || company.deletedAt != nil || company.blockedAt != nil {
+		return false, nil
+	}
+
+	company.blockedAt = &at
+	return true, nil
+}
+
+func (r *fakeRepository) Unblock(ctx context.Context, companyId uint64) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	company, ok := r.companies[companyId]
+	if !ok || company.deletedAt != nil || company.blockedAt == nil {
+		return false, nil
+	}
+
+	company.blockedAt = nil
+	return true, nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, companyId uint64, at time.Time) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	company, ok := r.companies[companyId]
+	if !ok || company.deletedAt != nil {
+		return false, nil
+	}
+
+	company.deletedAt = &at
+	return true, nil
+}
+
+func (r *fakeRepository) SaveAction(ctx context.Context, action *Action) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	saved := *action
+	saved.Id = uint64(len(r.actions) + 1)
+	r.actions = append(r.actions, &saved)
+	return nil
+}
+
+func (r *fakeRepository) GetActions(ctx context.Context, companyId uint64, limit uint) ([]*Action, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	actions := make([]*Action, 0)
+	for i := len(r.actions) - 1; i >= 0 && uint(len(actions)) < limit; i-- {
+		if action := r.actions[i]; companyId == 0 || action.CompanyId == companyId {
+			copied := *action
+			actions = append(actions, &copied)
+		}
+	}
+	return actions, nil
+}
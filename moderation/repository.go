@@ -0,0 +1,92 @@
+package moderation
+
+import (
+	"api/database"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type (
+	Repository interface {
+		// Each returns false when the company doesn't exist or its state
+		// doesn't allow the change, deleted companies can't be changed at all
+		Block(ctx context.Context, companyId uint64, at time.Time) (bool, error)
+		Unblock(ctx context.Context, companyId uint64) (bool, error)
+		Delete(ctx context.Context, companyId uint64, at time.Time) (bool, error)
+
+		SaveAction(ctx context.Context, action *Action) error
+
+		// Returns the latest actions first, only those on the company unless
+		// it's 0
+		GetActions(ctx context.Context, companyId uint64, limit uint) ([]*Action, error)
+	}
+
+	goquRepository struct {
+		builder *goqu.Database
+	}
+)
+
+func NewRepository(conn *database.Connection) Repository {
+	builder := goqu.New(conn.Driver, conn.DB)
+	return &goquRepository{builder}
+}
+
+func (r *goquRepository) Block(ctx context.Context, companyId uint64, at time.Time) (bool, error) {
+	return r.update(ctx, goqu.Record{"blocked_at": at}, goqu.I("id").Eq(companyId), goqu.I("blocked_at").IsNull())
+}
+
+func (r *goquRepository) Unblock(ctx context.Context, companyId uint64) (bool, error) {
+	return r.update(ctx, goqu.Record{"blocked_at": nil}, goqu.I("id").Eq(companyId), goqu.I("blocked_at").IsNotNull())
+}
+
+func (r *goquRepository) Delete(ctx context.Context, companyId uint64, at time.Time) (bool, error) {
+	return r.update(ctx, goqu.Record{"deleted_at": at}, goqu.I("id").Eq(companyId))
+}
+
+func (r *goquRepository) SaveAction(ctx context.Context, action *Action) error {
+	_, err := database.Query(ctx, r.builder).
+		Insert(goqu.T("moderation_actions")).
+		Rows(action).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) GetActions(ctx context.Context, companyId uint64, limit uint) ([]*Action, error) {
+	actions := make([]*Action, 0)
+
+	query := database.Query(ctx, r.builder).
+		From(goqu.T("moderation_actions")).
+		Order(goqu.I("created_at").Desc(), goqu.I("id").Desc()).
+		Limit(limit)
+
+	if companyId != 0 {
+		query = query.Where(goqu.I("company_id").Eq(companyId))
+	}
+
+	if err := query.ScanStructsContext(ctx, &actions); err != nil {
+		return nil, err
+	}
+
+	return actions, nil
+}
+
+// Updates the company unless it's deleted, returning whether it did
+func (r *goquRepository) update(ctx context.Context, record goqu.Record, conditions ...goqu.Expression) (bool, error) {
+	result, err := database.Query(ctx, r.builder).
+		Update(goqu.T("companies")).
+		Set(record).
+		Where(append(conditions, goqu.I("deleted_at").IsNull())...).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
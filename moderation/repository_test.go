@@ -0,0 +1,100 @@
+package moderation_test
+
+import (
+	"api/database"
+	"api/moderation"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestModerationRepository(t *testing.T) {
+	conn, err := database.GetTestConnection("../test.db")
+	if err != nil {
+		t.Fatalf("could not open database: %s", err)
+	}
+
+	if _, err := conn.DB.Exec(`
+        INSERT INTO companies (id, name, email, password, is_admin) VALUES
+        (1, 'Foo', 'foo', 'baz', 0), (2, 'Bar', 'bar', 'baz', 1)
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := conn.DB.Exec(`DELETE FROM moderation_actions`); err != nil {
+			t.Errorf("could not clean up database: %s", err)
+		}
+		if _, err := conn.DB.Exec(`DELETE FROM companies`); err != nil {
+			t.Errorf("could not clean up database: %s", err)
+		}
+	})
+
+	repository := moderation.NewRepository(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	t.Run("Block", func(t *testing.T) {
+		if blocked, err := repository.Block(ctx, 1, now); err != nil || !blocked {
+			t.Fatalf("expected company to be blocked, got %t %v", blocked, err)
+		}
+
+		if blocked, err := repository.Block(ctx, 1, now); err != nil || blocked {
+			t.Errorf("expected company to be blocked once, got %t %v", blocked, err)
+		}
+
+		if blocked, err := repository.Block(ctx, 10, now); err != nil || blocked {
+			t.Errorf("expected unknown company not to be blocked, got %t %v", blocked, err)
+		}
+	})
+
+	t.Run("Unblock", func(t *testing.T) {
+		if unblocked, err := repository.Unblock(ctx, 1); err != nil || !unblocked {
+			t.Fatalf("expected company to be unblocked, got %t %v", unblocked, err)
+		}
+
+		if unblocked, err := repository.Unblock(ctx, 1); err != nil || unblocked {
+			t.Errorf("expected company to be unblocked once, got %t %v", unblocked, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if deleted, err := repository.Delete(ctx, 1, now); err != nil || !deleted {
+			t.Fatalf("expected company to be deleted, got %t %v", deleted, err)
+		}
+
+		if blocked, err := repository.Block(ctx, 1, now); err != nil || blocked {
+			t.Errorf("expected deleted company not to be blocked, got %t %v", blocked, err)
+		}
+	})
+
+	t.Run("GetActions", func(t *testing.T) {
+		for _, action := range []string{moderation.ACTION_BLOCK, moderation.ACTION_DELETE} {
+			if err := repository.SaveAction(ctx, &moderation.Action{
+				CompanyId: 1,
+				AdminId:   2,
+				Action:    action,
+				Reason:    "botting",
+				CreatedAt: time.Now().UTC(),
+			}); err != nil {
+				t.Fatalf("could not save action: %s", err)
+			}
+		}
+
+		actions, err := repository.GetActions(ctx, 1, 10)
+		if err != nil {
+			t.Fatalf("could not get actions: %s", err)
+		}
+
+		if len(actions) != 2 || actions[0].Action != moderation.ACTION_DELETE || actions[1].Action != moderation.ACTION_BLOCK {
+			t.Errorf("expected delete then block, got %+v", actions)
+		}
+
+		if actions, _ := repository.GetActions(ctx, 2, 10); len(actions) != 0 {
+			t.Errorf("expected no actions on company 2, got %d", len(actions))
+		}
+	})
+}
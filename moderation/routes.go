@@ -0,0 +1,67 @@
+package moderation
+
+import (
+	"api/auth"
+	"api/server"
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func CreateEndpoints(e *echo.Echo, service Service) {
+	admin := server.RequireRole(auth.ROLE_ADMIN)
+
+	group := e.Group("/admin/companies/:id", admin)
+
+	moderate := func(action func(ctx context.Context, adminId, companyId uint64, request *Request) error) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			adminId, err := auth.ParseToken(c.Get("user"))
+			if err != nil {
+				return err
+			}
+
+			companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+
+			request := new(Request)
+			if err := c.Bind(request); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+			if err := c.Validate(request); err != nil {
+				return err
+			}
+
+			if err := action(c.Request().Context(), adminId, companyId, request); err != nil {
+				return err
+			}
+
+			return c.NoContent(http.StatusNoContent)
+		}
+	}
+
+	group.POST("/block", moderate(service.Block))
+	group.POST("/unblock", moderate(service.Unblock))
+	group.DELETE("", moderate(service.Delete))
+
+	e.GET("/admin/moderation", func(c echo.Context) error {
+		var companyId uint64
+		if param := c.QueryParam("company_id"); param != "" {
+			id, err := strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+			companyId = id
+		}
+
+		actions, err := service.GetActions(c.Request().Context(), companyId)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, actions)
+	}, admin)
+}
@@ -0,0 +1,106 @@
+package moderation_test
+
+import (
+	"api/auth"
+	"api/clock"
+	"api/company"
+	"api/database"
+	"api/mail"
+	"api/market"
+	"api/moderation"
+	"api/notification"
+	"api/scheduler"
+	"api/server"
+	"api/warehouse"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestModerationRoutes(t *testing.T) {
+	uow := database.NewFakeUnitOfWork()
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), uow, clock.New(), clock.New())
+	marketSvc := market.NewService(market.NewFakeRepository(), companySvc, warehouse.NewService(warehouse.NewFakeRepository()), notification.NoOpNotifier(), log.Default(), market.DEFAULT_TRANSPORT_FEE, uow)
+	timer := scheduler.NewPersistentScheduler(scheduler.NewFakeRepository(), clock.New())
+	service := moderation.NewService(moderation.NewFakeRepository(), companySvc, marketSvc, timer, log.Default(), uow, clock.New())
+
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
+	moderation.CreateEndpoints(svr, service)
+
+	adminToken, err := auth.GenerateToken(3, "secret", auth.ROLE_ADMIN)
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
+	}
+
+	playerToken, err := auth.GenerateToken(1, "secret")
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
+	}
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should only let admins moderate", func(t *testing.T) {
+		rec := send("POST", "/admin/companies/2/block", playerToken, `{"reason":"botting"}`)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+
+		rec = send("GET", "/admin/moderation", playerToken, "")
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("should require a reason", func(t *testing.T) {
+		rec := send("POST", "/admin/companies/2/block", adminToken, `{}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("should block, unblock and delete", func(t *testing.T) {
+		for _, request := range []struct{ method, path string }{
+			{"POST", "/admin/companies/2/block"},
+			{"POST", "/admin/companies/2/unblock"},
+			{"DELETE", "/admin/companies/2"},
+		} {
+			rec := send(request.method, request.path, adminToken, `{"reason":"botting"}`)
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("%s %s: expected status %d, got %d %s", request.method, request.path, http.StatusNoContent, rec.Code, rec.Body.String())
+			}
+		}
+
+		rec := send("POST", "/admin/companies/2/block", adminToken, `{"reason":"botting"}`)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d blocking deleted company, got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("should list actions", func(t *testing.T) {
+		rec := send("GET", "/admin/moderation?company_id=2", adminToken, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var actions []*moderation.Action
+		if err := json.Unmarshal(rec.Body.Bytes(), &actions); err != nil {
+			t.Fatalf("could not parse actions: %s", err)
+		}
+
+		if len(actions) != 3 || actions[0].Action != moderation.ACTION_DELETE || actions[0].AdminId != 3 {
+			t.Errorf("expected 3 actions by admin 3, latest delete, got %+v", actions)
+		}
+	})
+}
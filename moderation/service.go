@@ -0,0 +1,168 @@
+// Package moderation lets admins block, unblock and delete companies that
+// break the rules. Every action is recorded with the admin who took it and
+// why.
+package moderation
+
+import (
+	"api/clock"
+	"api/company"
+	"api/database"
+	"api/market"
+	"api/scheduler"
+	"api/server"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	ACTION_BLOCK   = "block"
+	ACTION_UNBLOCK = "unblock"
+	ACTION_DELETE  = "delete"
+
+	// Actions shown to admins at once
+	ACTIONS_PAGE_SIZE = 100
+)
+
+var pastTense = map[string]string{
+	ACTION_BLOCK:  "blocked",
+	ACTION_DELETE: "deleted",
+}
+
+type (
+	Action struct {
+		Id        uint64    `db:"id" json:"id" goqu:"skipinsert,skipupdate"`
+		CompanyId uint64    `db:"company_id" json:"company_id"`
+		AdminId   uint64    `db:"admin_id" json:"admin_id"`
+		Action    string    `db:"action" json:"action"`
+		Reason    string    `db:"reason" json:"reason"`
+		CreatedAt time.Time `db:"created_at" json:"created_at"`
+	}
+
+	Request struct {
+		Reason string `json:"reason" validate:"required,max=255"`
+	}
+
+	Service interface {
+		// Blocks the company, cancelling its open orders and revoking its
+		// sessions. Its jobs are paused until it's unblocked.
+		Block(ctx context.Context, adminId, companyId uint64, request *Request) error
+
+		// Unblocks the company, resuming its paused jobs
+		Unblock(ctx context.Context, adminId, companyId uint64, request *Request) error
+
+		// Deletes the company, with the same side effects as blocking it,
+		// and removes its jobs
+		Delete(ctx context.Context, adminId, companyId uint64, request *Request) error
+
+		// Returns the latest actions, only those on the company unless it's 0
+		GetActions(ctx context.Context, companyId uint64) ([]*Action, error)
+	}
+
+	service struct {
+		repository Repository
+		companySvc company.Service
+		marketSvc  market.Service
+		timer      *scheduler.Scheduler
+		logger     *log.Logger
+		uow        database.UnitOfWork
+		clock      clock.Clock
+	}
+)
+
+func NewService(repository Repository, companySvc company.Service, marketSvc market.Service, timer *scheduler.Scheduler, logger *log.Logger, uow database.UnitOfWork, clock clock.Clock) Service {
+	return &service{repository, companySvc, marketSvc, timer, logger, uow, clock}
+}
+
+func (s *service) Block(ctx context.Context, adminId, companyId uint64, request *Request) error {
+	return s.shutDown(ctx, adminId, companyId, ACTION_BLOCK, request.Reason, s.repository.Block)
+}
+
+func (s *service) Delete(ctx context.Context, adminId, companyId uint64, request *Request) error {
+	return s.shutDown(ctx, adminId, companyId, ACTION_DELETE, request.Reason, s.repository.Delete)
+}
+
+func (s *service) Unblock(ctx context.Context, adminId, companyId uint64, request *Request) error {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		unblocked, err := s.repository.Unblock(ctx, companyId)
+		if err != nil {
+			return err
+		}
+
+		if !unblocked {
+			return server.NewBusinessRuleError("company not found or not blocked")
+		}
+
+		return s.saveAction(ctx, adminId, companyId, ACTION_UNBLOCK, request.Reason)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	resumed := s.timer.ResumeCompanyJobs(int64(companyId))
+	s.logger.Printf("company %d unblocked by admin %d, %d jobs resumed", companyId, adminId, resumed)
+
+	return nil
+}
+
+func (s *service) GetActions(ctx context.Context, companyId uint64) ([]*Action, error) {
+	return s.repository.GetActions(ctx, companyId, ACTIONS_PAGE_SIZE)
+}
+
+// Takes the company out of the game with the update, so it can't trade,
+// produce or log in anymore
+func (s *service) shutDown(ctx context.Context, adminId, companyId uint64, action, reason string, update func(context.Context, uint64, time.Time) (bool, error)) error {
+	if adminId == companyId {
+		return server.NewBusinessRuleError("admins can't moderate their own company")
+	}
+
+	canceled := 0
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		updated, err := update(ctx, companyId, s.clock.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return server.NewBusinessRuleError("company not found or already " + pastTense[action])
+		}
+
+		if err := s.companySvc.RevokeSessions(ctx, companyId); err != nil {
+			return err
+		}
+
+		if canceled, err = s.marketSvc.CancelCompanyOrders(ctx, companyId); err != nil {
+			return err
+		}
+
+		return s.saveAction(ctx, adminId, companyId, action, reason)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// Blocked companies keep their jobs paused so they carry on where they
+	// were once unblocked
+	var stopped int
+	if action == ACTION_DELETE {
+		stopped = s.timer.RemoveCompanyJobs(int64(companyId))
+	} else {
+		stopped = s.timer.PauseCompanyJobs(int64(companyId))
+	}
+
+	s.logger.Printf("company %d %s by admin %d, %d orders canceled and %d jobs stopped", companyId, pastTense[action], adminId, canceled, stopped)
+
+	return nil
+}
+
+func (s *service) saveAction(ctx context.Context, adminId, companyId uint64, action, reason string) error {
+	return s.repository.SaveAction(ctx, &Action{
+		CompanyId: companyId,
+		AdminId:   adminId,
+		Action:    action,
+		Reason:    reason,
+		CreatedAt: s.clock.Now().UTC(),
+	})
+}
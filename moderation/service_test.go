@@ -0,0 +1,188 @@
+package moderation_test
+
+import (
+	"api/clock"
+	"api/company"
+	"api/company/building/production"
+	"api/database"
+	"api/mail"
+	"api/market"
+	"api/moderation"
+	"api/notification"
+	"api/scheduler"
+	"api/server"
+	"api/warehouse"
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestModerationService(t *testing.T) {
+	uow := database.NewFakeUnitOfWork()
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), uow, clock.New(), clock.New())
+	marketRepo := market.NewFakeRepository()
+	marketSvc := market.NewService(marketRepo, companySvc, warehouse.NewService(warehouse.NewFakeRepository()), notification.NoOpNotifier(), log.Default(), market.DEFAULT_TRANSPORT_FEE, uow)
+
+	now := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	timerClock := clock.NewFakeClock(now)
+
+	var produced atomic.Int32
+	timer := scheduler.NewPersistentScheduler(scheduler.NewFakeRepository(), timerClock)
+	timer.Register("noop", func(job *scheduler.Job) error { return nil })
+	timer.Register(production.COLLECT_RESOURCE_JOB, func(job *scheduler.Job) error {
+		produced.Add(1)
+		return nil
+	})
+	t.Cleanup(timer.Stop)
+
+	service := moderation.NewService(moderation.NewFakeRepository(), companySvc, marketSvc, timer, log.Default(), uow, clock.NewFakeClock(now))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	request := &moderation.Request{Reason: "botting"}
+
+	t.Run("Block", func(t *testing.T) {
+		tokens, err := companySvc.Login(ctx, company.Credentials{Email: "admin@test.com", Pass: "password"})
+		if err != nil {
+			t.Fatalf("could not login: %s", err)
+		}
+
+		job, _ := scheduler.NewJob("NOOP_1", "noop", 1, nil, now.Add(time.Hour))
+		if err := timer.Schedule(ctx, job); err != nil {
+			t.Fatalf("could not schedule job: %s", err)
+		}
+
+		production, _ := scheduler.NewJob("PRODUCTION_1", production.COLLECT_RESOURCE_JOB, 1, nil, now.Add(time.Hour))
+		if err := timer.Schedule(ctx, production); err != nil {
+			t.Fatalf("could not schedule job: %s", err)
+		}
+
+		if err := service.Block(ctx, 3, 1, request); err != nil {
+			t.Fatalf("could not block company: %s", err)
+		}
+
+		t.Run("should cancel open orders", func(t *testing.T) {
+			orders, _ := marketRepo.GetByCompany(ctx, 1)
+			if len(orders) != 0 {
+				t.Errorf("expected no open orders, got %d", len(orders))
+			}
+
+			if orders, _ := marketRepo.GetByCompany(ctx, 2); len(orders) != 1 {
+				t.Errorf("expected orders of others to stay, got %d", len(orders))
+			}
+		})
+
+		t.Run("should keep scheduled jobs paused", func(t *testing.T) {
+			jobs := timer.GetPendingJobs(scheduler.JobFilter{CompanyId: 1})
+			if len(jobs) != 2 {
+				t.Fatalf("expected %d pending jobs, got %d", 2, len(jobs))
+			}
+
+			for _, job := range jobs {
+				if !job.Paused {
+					t.Errorf("expected job %s to be paused", job.Id)
+				}
+			}
+		})
+
+		t.Run("should not fire production jobs", func(t *testing.T) {
+			timerClock.Advance(2 * time.Hour)
+			time.Sleep(50 * time.Millisecond)
+
+			if fired := produced.Load(); fired != 0 {
+				t.Errorf("expected production job not to fire, fired %d times", fired)
+			}
+		})
+
+		t.Run("should revoke sessions", func(t *testing.T) {
+			if _, err := companySvc.Refresh(ctx, tokens.RefreshToken); err == nil {
+				t.Error("expected refresh to fail")
+			}
+		})
+
+		t.Run("should not block twice", func(t *testing.T) {
+			err := service.Block(ctx, 3, 1, request)
+
+			var businessErr server.BusinessRuleError
+			if !errors.As(err, &businessErr) {
+				t.Errorf("expected business rule error, got %v", err)
+			}
+		})
+	})
+
+	t.Run("should not moderate own company", func(t *testing.T) {
+		if err := service.Block(ctx, 3, 3, request); err == nil {
+			t.Error("expected error blocking own company")
+		}
+	})
+
+	t.Run("Unblock", func(t *testing.T) {
+		if err := service.Unblock(ctx, 3, 1, request); err != nil {
+			t.Fatalf("could not unblock company: %s", err)
+		}
+
+		t.Run("should resume jobs that came due", func(t *testing.T) {
+			deadline := time.Now().Add(time.Second)
+			for produced.Load() == 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+
+			if fired := produced.Load(); fired != 1 {
+				t.Errorf("expected production job to fire once, fired %d times", fired)
+			}
+		})
+
+		if err := service.Unblock(ctx, 3, 1, request); err == nil {
+			t.Error("expected error unblocking company that isn't blocked")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		job, _ := scheduler.NewJob("NOOP_2", "noop", 2, nil, now.Add(4*time.Hour))
+		if err := timer.Schedule(ctx, job); err != nil {
+			t.Fatalf("could not schedule job: %s", err)
+		}
+
+		if err := service.Delete(ctx, 3, 2, request); err != nil {
+			t.Fatalf("could not delete company: %s", err)
+		}
+
+		if orders, _ := marketRepo.GetByCompany(ctx, 2); len(orders) != 0 {
+			t.Errorf("expected no open orders, got %d", len(orders))
+		}
+
+		if jobs := timer.GetPendingJobs(scheduler.JobFilter{CompanyId: 2}); len(jobs) != 0 {
+			t.Errorf("expected no pending jobs, got %d", len(jobs))
+		}
+
+		if err := service.Unblock(ctx, 3, 2, request); err == nil {
+			t.Error("expected error unblocking deleted company")
+		}
+	})
+
+	t.Run("GetActions", func(t *testing.T) {
+		actions, err := service.GetActions(ctx, 0)
+		if err != nil {
+			t.Fatalf("could not get actions: %s", err)
+		}
+
+		expected := []string{moderation.ACTION_DELETE, moderation.ACTION_UNBLOCK, moderation.ACTION_BLOCK}
+		if len(actions) != len(expected) {
+			t.Fatalf("expected %d actions, got %d", len(expected), len(actions))
+		}
+
+		for i, action := range actions {
+			if action.Action != expected[i] || action.AdminId != 3 || action.Reason != "botting" || !action.CreatedAt.Equal(now) {
+				t.Errorf("expected %s by admin 3 at %s, got %+v", expected[i], now, action)
+			}
+		}
+
+		if actions, _ := service.GetActions(ctx, 1); len(actions) != 2 {
+			t.Errorf("expected %d actions on company 1, got %d", 2, len(actions))
+		}
+	})
+}
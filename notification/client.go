@@ -1,6 +1,13 @@
 package notification
 
-import "github.com/gorilla/websocket"
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// How long the close frame has to reach the client
+const CLOSE_TIMEOUT = time.Second
 
 type Client struct {
 	Conn *websocket.Conn
@@ -13,6 +20,10 @@ func (s *Client) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// Tells the client the server is going away before closing the connection,
+// so it knows to reconnect instead of treating it as an error
 func (s *Client) Close() error {
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	s.Conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(CLOSE_TIMEOUT))
 	return s.Conn.Close()
 }
@@ -3,6 +3,7 @@ package notification
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"sync"
 	"time"
@@ -18,6 +19,8 @@ const (
 	FinancingRatesUpdated = "financing_rates_updated"
 )
 
+var ErrNotifierClosed = errors.New("notifier is closed")
+
 type (
 	Event struct {
 		Type    EventType `json:"type"`
@@ -30,6 +33,10 @@ type (
 
 		Broadcast(ctx context.Context, message any) error
 		Notify(ctx context.Context, message string, indentifier int64) error
+
+		// Delivers the messages already sent, then disconnects every
+		// client. Messages sent afterwards fail with ErrNotifierClosed.
+		Close(ctx context.Context) error
 	}
 
 	notifier struct {
@@ -37,6 +44,9 @@ type (
 		repository    Repository
 		notifications chan *Notification
 		broadcasts    chan any
+		done          chan struct{}
+		stopped       chan struct{}
+		once          sync.Once
 	}
 
 	noOpNotifier struct {
@@ -49,6 +59,8 @@ func NewNotifier(repository Repository) Notifier {
 		repository:    repository,
 		notifications: make(chan *Notification),
 		broadcasts:    make(chan any),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
 	}
 
 	go notifier.handleMessages()
@@ -57,26 +69,48 @@ func NewNotifier(repository Repository) Notifier {
 }
 
 func (n *notifier) handleMessages() {
+	defer close(n.stopped)
+
 	for {
 		select {
 		case notification := <-n.notifications:
-			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-			defer cancel()
-
-			if err := n.doNotify(ctx, notification); err != nil {
-				println(err.Error())
-			}
+			n.handleNotification(notification)
 		case message := <-n.broadcasts:
-			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-			defer cancel()
-
-			if err := n.doBroadcast(ctx, message); err != nil {
-				println(err.Error())
+			n.handleBroadcast(message)
+		case <-n.done:
+			// Messages whose senders were already waiting still go out
+			for {
+				select {
+				case notification := <-n.notifications:
+					n.handleNotification(notification)
+				case message := <-n.broadcasts:
+					n.handleBroadcast(message)
+				default:
+					return
+				}
 			}
 		}
 	}
 }
 
+func (n *notifier) handleNotification(notification *Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := n.doNotify(ctx, notification); err != nil {
+		println(err.Error())
+	}
+}
+
+func (n *notifier) handleBroadcast(message any) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := n.doBroadcast(ctx, message); err != nil {
+		println(err.Error())
+	}
+}
+
 func (n *notifier) Connect(identifier int64, client io.WriteCloser) {
 	n.clients.Store(identifier, client)
 }
@@ -86,18 +120,60 @@ func (n *notifier) Disconnect(identifier int64) {
 }
 
 func (n *notifier) Notify(ctx context.Context, message string, identifier int64) error {
-	n.notifications <- &Notification{
-		Message:   message,
-		CompanyId: &identifier,
+	if n.isClosed() {
+		return ErrNotifierClosed
+	}
+
+	select {
+	case n.notifications <- &Notification{Message: message, CompanyId: &identifier}:
+		return nil
+	case <-n.done:
+		return ErrNotifierClosed
 	}
-	return nil
 }
 
 func (n *notifier) Broadcast(ctx context.Context, message any) error {
-	n.broadcasts <- message
+	if n.isClosed() {
+		return ErrNotifierClosed
+	}
+
+	select {
+	case n.broadcasts <- message:
+		return nil
+	case <-n.done:
+		return ErrNotifierClosed
+	}
+}
+
+func (n *notifier) Close(ctx context.Context) error {
+	n.once.Do(func() { close(n.done) })
+
+	select {
+	case <-n.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	n.clients.Range(func(identifier, client any) bool {
+		if err := client.(io.WriteCloser).Close(); err != nil {
+			println(err.Error())
+		}
+		n.clients.Delete(identifier)
+		return true
+	})
+
 	return nil
 }
 
+func (n *notifier) isClosed() bool {
+	select {
+	case <-n.done:
+		return true
+	default:
+		return false
+	}
+}
+
 func (n *notifier) doBroadcast(ctx context.Context, message any) error {
 	stream, err := json.Marshal(message)
 	if err != nil {
@@ -151,3 +227,7 @@ func (n *noOpNotifier) Broadcast(ctx context.Context, message any) error {
 func (n *noOpNotifier) Notify(ctx context.Context, message string, identifier int64) error {
 	return nil
 }
+
+func (n *noOpNotifier) Close(ctx context.Context) error {
+	return nil
+}
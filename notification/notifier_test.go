@@ -4,6 +4,7 @@ import (
 	"api/notification"
 	"context"
 	"encoding/json"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 type socket struct {
 	mutex  sync.Mutex
 	buffer any
+	closed bool
 }
 
 func (s *socket) Flush() any {
@@ -42,9 +44,20 @@ func (s *socket) Write(p []byte) (int, error) {
 }
 
 func (s *socket) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
 	return nil
 }
 
+func (s *socket) IsClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closed
+}
+
 func TestNotifier(t *testing.T) {
 	notifier := notification.NewNotifier(notification.NewFakeRepository())
 
@@ -115,3 +128,44 @@ func TestNotifier(t *testing.T) {
 		go notifier.Broadcast(context.TODO(), "hello everyone")
 	})
 }
+
+func TestNotifierClose(t *testing.T) {
+	notifier := notification.NewNotifier(notification.NewFakeRepository())
+
+	s1 := &socket{}
+	notifier.Connect(1, s1)
+
+	t.Run("should deliver sent messages before disconnecting", func(t *testing.T) {
+		if err := notifier.Notify(context.TODO(), "last call", 1); err != nil {
+			t.Fatalf("could not notify: %s", err)
+		}
+
+		if err := notifier.Close(context.TODO()); err != nil {
+			t.Fatalf("could not close notifier: %s", err)
+		}
+
+		if s1.Flush() != "last call" {
+			t.Errorf("expected message %s, got %s", "last call", s1.Flush())
+		}
+
+		if !s1.IsClosed() {
+			t.Error("expected client to be closed")
+		}
+	})
+
+	t.Run("should reject messages once closed", func(t *testing.T) {
+		if err := notifier.Notify(context.TODO(), "anyone?", 1); !errors.Is(err, notification.ErrNotifierClosed) {
+			t.Errorf("expected %s, got %v", notification.ErrNotifierClosed, err)
+		}
+
+		if err := notifier.Broadcast(context.TODO(), "anyone?"); !errors.Is(err, notification.ErrNotifierClosed) {
+			t.Errorf("expected %s, got %v", notification.ErrNotifierClosed, err)
+		}
+	})
+
+	t.Run("should close more than once", func(t *testing.T) {
+		if err := notifier.Close(context.TODO()); err != nil {
+			t.Errorf("expected no error closing again, got %s", err)
+		}
+	})
+}
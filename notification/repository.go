@@ -50,21 +50,14 @@ func (r *goquRepository) GetNotifications(ctx context.Context, companyId int64)
 }
 
 func (r *goquRepository) SaveNotification(ctx context.Context, notification *Notification) (*Notification, error) {
-	result, err := r.builder.
+	id, err := database.InsertId(ctx, r.builder.
 		Insert(goqu.T("notifications")).
-		Rows(notification).
-		Executor().
-		ExecContext(ctx)
+		Rows(notification))
 
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
 	notification.Id = id
 	return notification, nil
 }
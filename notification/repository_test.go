@@ -9,7 +9,7 @@ import (
 )
 
 func TestNotificationRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		t.Fatalf("could not open database: %s", err)
 	}
@@ -23,38 +23,38 @@ func TestNotificationRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password) VALUES
-        (1, "Foo", "bar", "baz"), (2, "Bar", "baz", "foo")
+        (1, 'Foo', 'bar', 'baz'), (2, 'Bar', 'baz', 'foo')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO notifications (id, company_id, message, created_at) VALUES
-        (1, 1, "hi there", "2024-01-08 21:20:50"),
-        (2, 1, "hi there", "2024-01-08 21:20:51"),
-        (3, 1, "hi there", "2024-01-08 21:20:52"),
-        (4, 1, "hi there", "2024-01-08 21:20:53"),
-        (5, 1, "hi there", "2024-01-08 21:20:54"),
-        (6, 1, "hi there", "2024-01-08 21:20:55"),
-        (7, 1, "hi there", "2024-01-08 21:20:56"),
-        (8, 1, "hi there", "2024-01-08 21:20:57"),
-        (9, 1, "hi there", "2024-01-08 21:20:58"),
-        (10, 1, "hi there", "2024-01-08 21:20:59"),
-        (11, 1, "hi there", "2024-01-08 21:21:50"),
-        (12, 1, "hi there", "2024-01-08 21:22:50"),
-        (13, 1, "hi there", "2024-01-08 21:23:50"),
-        (14, 1, "hi there", "2024-01-08 21:24:50"),
-        (15, 1, "hi there", "2024-01-08 21:25:50"),
-        (16, 1, "hi there", "2024-01-08 21:26:50"),
-        (17, 1, "hi there", "2024-01-08 21:27:50"),
-        (18, 1, "hi there", "2024-01-08 21:28:50"),
-        (19, 1, "hi there", "2024-01-08 21:29:50"),
-        (20, 1, "hi there", "2024-01-08 21:30:50"),
-        (21, 2, "hi there", "2024-01-08 21:31:50"),
-        (22, 2, "hi there", "2024-01-08 21:32:50"),
-        (23, 2, "hi there", "2024-01-08 21:33:50"),
-        (24, 2, "hi there", "2024-01-08 21:34:50"),
-        (25, NULL, "broadcast", "2024-01-08 21:35:50")
+        (1, 1, 'hi there', '2024-01-08 21:20:50'),
+        (2, 1, 'hi there', '2024-01-08 21:20:51'),
+        (3, 1, 'hi there', '2024-01-08 21:20:52'),
+        (4, 1, 'hi there', '2024-01-08 21:20:53'),
+        (5, 1, 'hi there', '2024-01-08 21:20:54'),
+        (6, 1, 'hi there', '2024-01-08 21:20:55'),
+        (7, 1, 'hi there', '2024-01-08 21:20:56'),
+        (8, 1, 'hi there', '2024-01-08 21:20:57'),
+        (9, 1, 'hi there', '2024-01-08 21:20:58'),
+        (10, 1, 'hi there', '2024-01-08 21:20:59'),
+        (11, 1, 'hi there', '2024-01-08 21:21:50'),
+        (12, 1, 'hi there', '2024-01-08 21:22:50'),
+        (13, 1, 'hi there', '2024-01-08 21:23:50'),
+        (14, 1, 'hi there', '2024-01-08 21:24:50'),
+        (15, 1, 'hi there', '2024-01-08 21:25:50'),
+        (16, 1, 'hi there', '2024-01-08 21:26:50'),
+        (17, 1, 'hi there', '2024-01-08 21:27:50'),
+        (18, 1, 'hi there', '2024-01-08 21:28:50'),
+        (19, 1, 'hi there', '2024-01-08 21:29:50'),
+        (20, 1, 'hi there', '2024-01-08 21:30:50'),
+        (21, 2, 'hi there', '2024-01-08 21:31:50'),
+        (22, 2, 'hi there', '2024-01-08 21:32:50'),
+        (23, 2, 'hi there', '2024-01-08 21:33:50'),
+        (24, 2, 'hi there', '2024-01-08 21:34:50'),
+        (25, NULL, 'broadcast', '2024-01-08 21:35:50')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -2,6 +2,7 @@ package notification
 
 import (
 	"api/auth"
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -12,7 +13,7 @@ import (
 func CreateEndpoints(e *echo.Echo, service Service, notifier Notifier) {
 	group := e.Group("/notifications")
 
-	group.GET("", func(c echo.Context) error {
+	list := group.GET("", func(c echo.Context) error {
 		companyId, err := auth.ParseToken(c.Get("user"))
 		if err != nil {
 			return err
@@ -61,4 +62,6 @@ func CreateEndpoints(e *echo.Echo, service Service, notifier Notifier) {
 
 		return nil
 	})
+
+	server.AllowMembers("", list)
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"api/accounting"
+	"context"
+	"fmt"
+	"io"
+)
+
+// Runs the reconcile subcommand, which checks every company's cached cash
+// against its ledger and fails if any of them doesn't match
+func runReconcile(ctx context.Context, accountingSvc accounting.Service, out io.Writer) error {
+	mismatches, err := accountingSvc.ReconcileBalances(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, mismatch := range mismatches {
+		fmt.Fprintf(out, "company %d: cached %d, ledger %d\n", mismatch.CompanyId, mismatch.Cached, mismatch.Ledger)
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%d balances don't match the ledger", len(mismatches))
+	}
+
+	fmt.Fprintln(out, "every balance matches the ledger")
+	return nil
+}
@@ -76,7 +76,7 @@ func (r *goquRepository) IsStaffBusy(ctx context.Context, staffIds []uint64, com
 func (r *goquRepository) GetResearch(ctx context.Context, researchId uint64) (*Research, error) {
 	research := new(Research)
 
-	found, err := r.builder.
+	found, err := database.Query(ctx, r.builder).
 		Select(goqu.Star()).
 		From(goqu.T("researches")).
 		Where(goqu.I("id").Eq(researchId)).
@@ -92,7 +92,7 @@ func (r *goquRepository) GetResearch(ctx context.Context, researchId uint64) (*R
 
 	staff := make([]*staff.Staff, 0)
 
-	err = r.builder.
+	err = database.Query(ctx, r.builder).
 		Select(goqu.I("s.id"), goqu.I("s.name"), goqu.I("s.skill")).
 		From(goqu.T("research_staff").As("s")).
 		InnerJoin(
@@ -112,7 +112,7 @@ func (r *goquRepository) GetResearch(ctx context.Context, researchId uint64) (*R
 }
 
 func (r *goquRepository) SaveResearch(ctx context.Context, finishesAt time.Time, investment int, staffIds []uint64, resourceId, companyId uint64) (*Research, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +120,7 @@ func (r *goquRepository) SaveResearch(ctx context.Context, finishesAt time.Time,
 	defer tx.Rollback()
 
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Classification: accounting.RESEARCH,
 			Description:    "Payment of research",
@@ -131,7 +131,7 @@ func (r *goquRepository) SaveResearch(ctx context.Context, finishesAt time.Time,
 		return nil, err
 	}
 
-	result, err := tx.
+	researchId, err := database.InsertId(ctx, tx.
 		Insert(goqu.T("researches")).
 		Rows(goqu.Record{
 			"patents":     0,
@@ -139,15 +139,8 @@ func (r *goquRepository) SaveResearch(ctx context.Context, finishesAt time.Time,
 			"finishes_at": finishesAt,
 			"company_id":  companyId,
 			"resource_id": resourceId,
-		}).
-		Executor().
-		Exec()
-
-	if err != nil {
-		return nil, err
-	}
+		}))
 
-	researchId, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +170,7 @@ func (r *goquRepository) SaveResearch(ctx context.Context, finishesAt time.Time,
 }
 
 func (r *goquRepository) CompleteResearch(ctx context.Context, research *Research) (*Research, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package research_test
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/company"
 	"api/database"
 	"api/research"
@@ -11,7 +12,7 @@ import (
 )
 
 func TestResearchRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -25,14 +26,14 @@ func TestResearchRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password)
-        VALUES (1, "Foo", "", ""), (2, "Bar", "", "")
+        VALUES (1, 'Foo', '', ''), (2, 'Bar', '', '')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO resources (id, name)
-        VALUES (1, "Meat"), (2, "Milk")
+        VALUES (1, 'Meat'), (2, 'Milk')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -48,7 +49,7 @@ func TestResearchRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO research_staff (id, name, company_id)
-        VALUES (1, "john", 1), (2, "jane", 1), (3, "james", 1), (4, "mark", 1)
+        VALUES (1, 'john', 1), (2, 'jane', 1), (3, 'james', 1), (4, 'mark', 1)
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -67,6 +68,13 @@ func TestResearchRepository(t *testing.T) {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
+	if _, err := tx.Exec(`
+        INSERT INTO company_balances (company_id, cash)
+        SELECT company_id, SUM(value) FROM transactions GROUP BY company_id
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
 	if _, err := tx.Exec(`
         INSERT INTO resources_qualities (resource_id, company_id, quality, patents)
         VALUES (1, 1, 0, 99), (2, 1, 2, 1)
@@ -85,6 +93,9 @@ func TestResearchRepository(t *testing.T) {
 		if _, err := conn.DB.Exec(`DELETE FROM transactions`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec(`DELETE FROM company_balances`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
 		if _, err := conn.DB.Exec(`DELETE FROM assigned_staff`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
@@ -102,8 +113,8 @@ func TestResearchRepository(t *testing.T) {
 		}
 	})
 
-	accountingRepo := accounting.NewRepository(conn)
-	companyRepo := company.NewRepository(conn, accountingRepo)
+	accountingRepo := accounting.NewRepository(conn, clock.New())
+	companyRepo := company.NewRepository(conn, accountingRepo, clock.New())
 	repository := research.NewRepository(conn, accountingRepo)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
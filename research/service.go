@@ -2,15 +2,19 @@ package research
 
 import (
 	"api/company"
+	"api/database"
 	"api/research/staff"
 	"api/scheduler"
 	"api/server"
 	"context"
+	"fmt"
 	"math"
 	"math/rand"
 	"time"
 )
 
+const COMPLETE_RESEARCH_JOB = "research.complete"
+
 var (
 	ErrBusyStaff        = server.NewBusinessRuleError("busy staff")
 	ErrNotEnoughCash    = server.NewBusinessRuleError("not enough cash")
@@ -44,12 +48,19 @@ type (
 	service struct {
 		repository Repository
 		companySvc company.Service
+		uow        database.UnitOfWork
 		timer      *scheduler.Scheduler
 	}
+
+	researchJob struct {
+		ResearchId uint64 `json:"research_id"`
+	}
 )
 
-func NewService(repository Repository, companySvc company.Service) Service {
-	return &service{repository, companySvc, scheduler.NewScheduler()}
+func NewService(repository Repository, companySvc company.Service, uow database.UnitOfWork, timer *scheduler.Scheduler) Service {
+	service := &service{repository, companySvc, uow, timer}
+	timer.Register(COMPLETE_RESEARCH_JOB, service.completeResearch)
+	return service
 }
 
 func (s *service) GetQuality(ctx context.Context, resourceId, companyId uint64) (Quality, error) {
@@ -57,6 +68,40 @@ func (s *service) GetQuality(ctx context.Context, resourceId, companyId uint64)
 }
 
 func (s *service) StartResearch(ctx context.Context, staffIds []uint64, resourceId, companyId uint64) (*Research, error) {
+	var research *Research
+
+	// The job is scheduled once the research is committed, so it never
+	// runs for a research that was rolled back
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		research, err = s.startResearch(ctx, staffIds, resourceId, companyId)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := scheduler.NewJob(
+		fmt.Sprintf("RESEARCH_%d", research.Id),
+		COMPLETE_RESEARCH_JOB,
+		int64(companyId),
+		researchJob{research.Id},
+		research.FinishesAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.timer.Schedule(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return research, nil
+}
+
+func (s *service) startResearch(ctx context.Context, staffIds []uint64, resourceId, companyId uint64) (*Research, error) {
 	// Chosen staff should not be already researching
 	busy, err := s.repository.IsStaffBusy(ctx, staffIds, companyId)
 	if err != nil {
@@ -74,34 +119,34 @@ func (s *service) StartResearch(ctx context.Context, staffIds []uint64, resource
 
 	// Time to complete should be relative to current resource quality -  Max(48, L*6)
 	duration := time.Duration(math.Min(48, float64(quality.Quality)*6)) * time.Hour
-	finishesAt := time.Now().Add(duration)
+	finishesAt := s.timer.Now().Add(duration)
 
 	// Investment should be relative to level - 100k * ((L*2) + (1 / L))
 	investment := 10000000 * ((int(quality.Quality) * 2) + (1 / int(math.Max(1, float64(quality.Quality)))))
 
-	investingCompany, err := s.companySvc.GetById(ctx, companyId)
+	held, err := s.companySvc.HoldCash(ctx, companyId, investment)
 	if err != nil {
 		return nil, err
 	}
 
-	if investingCompany.AvailableCash < investment {
+	if !held {
 		return nil, ErrNotEnoughCash
 	}
 
-	research, err := s.repository.SaveResearch(ctx, finishesAt, investment, staffIds, resourceId, companyId)
-	if err != nil {
-		return nil, err
-	}
-
-	s.timer.Add(research.Id, duration, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
+	return s.repository.SaveResearch(ctx, finishesAt, investment, staffIds, resourceId, companyId)
+}
 
-		_, err := s.CompleteResearch(ctx, research.Id)
+func (s *service) completeResearch(job *scheduler.Job) error {
+	var payload researchJob
+	if err := job.Decode(&payload); err != nil {
 		return err
-	})
+	}
 
-	return research, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.CompleteResearch(ctx, payload.ResearchId)
+	return err
 }
 
 func (s *service) CompleteResearch(ctx context.Context, researchId uint64) (*Research, error) {
@@ -116,7 +161,7 @@ func (s *service) CompleteResearch(ctx context.Context, researchId uint64) (*Res
 		totalSkill += int(staff.Skill)
 	}
 
-	now := time.Now()
+	now := s.timer.Now()
 	research.CompletedAt = &now
 
 	randomizer := rand.New(rand.NewSource(time.Now().UnixNano()))
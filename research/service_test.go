@@ -1,17 +1,22 @@
 package research_test
 
 import (
+	"api/clock"
 	"api/company"
+	"api/database"
+	"api/mail"
 	"api/research"
+	"api/scheduler"
 	"context"
+	"log"
 	"testing"
 	"time"
 )
 
 func TestResearchService(t *testing.T) {
 	researchRepo := research.NewFakeRepository()
-	companySvc := company.NewService(company.NewFakeRepository())
-	service := research.NewService(researchRepo, companySvc)
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	service := research.NewService(researchRepo, companySvc, database.NewFakeUnitOfWork(), scheduler.NewScheduler())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
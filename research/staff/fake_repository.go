@@ -163,3 +163,7 @@ func (r *fakeRepository) SaveTraining(ctx context.Context, training *Training) (
 func (r *fakeRepository) UpdateTraining(ctx context.Context, training *Training) error {
 	return nil
 }
+
+func (r *fakeRepository) PaySalaries(ctx context.Context, period time.Time) error {
+	return nil
+}
@@ -2,6 +2,7 @@ package staff
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/database"
 	"context"
 	"errors"
@@ -32,17 +33,20 @@ type (
 		GetTraining(ctx context.Context, trainingId, companyId uint64) (*Training, error)
 		SaveTraining(ctx context.Context, training *Training) (*Training, error)
 		UpdateTraining(ctx context.Context, training *Training) error
+
+		PaySalaries(ctx context.Context, period time.Time) error
 	}
 
 	goquRepository struct {
 		builder        *goqu.Database
 		accountingRepo accounting.Repository
+		clock          clock.Clock
 	}
 )
 
-func NewRepository(conn *database.Connection, accountingRepo accounting.Repository) Repository {
+func NewRepository(conn *database.Connection, accountingRepo accounting.Repository, clock clock.Clock) Repository {
 	builder := goqu.New(conn.Driver, conn.DB)
-	return &goquRepository{builder, accountingRepo}
+	return &goquRepository{builder, accountingRepo, clock}
 }
 
 func (r *goquRepository) GetStaff(ctx context.Context, companyId uint64) ([]*Staff, error) {
@@ -86,22 +90,15 @@ func (r *goquRepository) GetStaffById(ctx context.Context, staffId uint64) (*Sta
 
 func (r *goquRepository) StartSearch(ctx context.Context, finishTime time.Time, companyId uint64) (*Search, error) {
 	search := &Search{
-		StartedAt:  time.Now(),
+		StartedAt:  r.clock.Now(),
 		FinishesAt: finishTime,
 		CompanyId:  companyId,
 	}
 
-	result, err := r.builder.
+	id, err := database.InsertId(ctx, r.builder.
 		Insert(goqu.T("staff_searches")).
-		Rows(search).
-		Executor().
-		ExecContext(ctx)
-
-	if err != nil {
-		return nil, err
-	}
+		Rows(search))
 
-	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +157,7 @@ func (r *goquRepository) RandomStaff(ctx context.Context, companyId uint64) (*St
 }
 
 func (r *goquRepository) SaveStaff(ctx context.Context, staff *Staff, companyId uint64) (*Staff, error) {
-	result, err := r.builder.
+	id, err := database.InsertId(ctx, r.builder.
 		Insert(goqu.T("research_staff")).
 		Rows(goqu.Record{
 			"name":       staff.Name,
@@ -169,15 +166,8 @@ func (r *goquRepository) SaveStaff(ctx context.Context, staff *Staff, companyId
 			"skill":      staff.Skill,
 			"talent":     staff.Talent,
 			"company_id": companyId,
-		}).
-		Executor().
-		ExecContext(ctx)
-
-	if err != nil {
-		return nil, err
-	}
+		}))
 
-	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
@@ -231,7 +221,7 @@ func (r *goquRepository) GetTraining(ctx context.Context, trainingId, companyId
 }
 
 func (r *goquRepository) SaveTraining(ctx context.Context, training *Training) (*Training, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -239,7 +229,7 @@ func (r *goquRepository) SaveTraining(ctx context.Context, training *Training) (
 	defer tx.Rollback()
 
 	if _, err := r.accountingRepo.RegisterTransaction(
-		&database.DB{TxDatabase: tx},
+		tx.DB,
 		accounting.Transaction{
 			Value:          -int(training.Investment),
 			Description:    "Staff training",
@@ -250,17 +240,10 @@ func (r *goquRepository) SaveTraining(ctx context.Context, training *Training) (
 		return nil, err
 	}
 
-	result, err := tx.
+	id, err := database.InsertId(ctx, tx.
 		Insert(goqu.T("trainings")).
-		Rows(training).
-		Executor().
-		Exec()
-
-	if err != nil {
-		return nil, err
-	}
+		Rows(training))
 
-	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
@@ -275,7 +258,7 @@ func (r *goquRepository) SaveTraining(ctx context.Context, training *Training) (
 }
 
 func (r *goquRepository) UpdateTraining(ctx context.Context, training *Training) error {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return err
 	}
@@ -311,3 +294,58 @@ func (r *goquRepository) UpdateTraining(ctx context.Context, training *Training)
 
 	return tx.Commit()
 }
+
+// Pays the salaries of the period starting at period to hired staff.
+// Companies whose payroll for the period was already paid are skipped.
+func (r *goquRepository) PaySalaries(ctx context.Context, period time.Time) error {
+	tx, err := database.BeginTx(ctx, r.builder)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	payrolls := make([]struct {
+		CompanyId uint64 `db:"company_id"`
+		Total     int    `db:"total"`
+	}, 0)
+
+	err = tx.
+		Select(
+			goqu.I("company_id"),
+			goqu.SUM(goqu.I("salary")).As("total"),
+		).
+		From(goqu.T("research_staff")).
+		Where(goqu.I("status").Eq(HIRED)).
+		GroupBy(goqu.I("company_id")).
+		ScanStructsContext(ctx, &payrolls)
+
+	if err != nil {
+		return err
+	}
+
+	for _, payroll := range payrolls {
+		marked, err := r.accountingRepo.MarkPeriodPaid(tx.DB, accounting.PERIOD_PAYROLL, payroll.CompanyId, period)
+		if err != nil {
+			return err
+		}
+
+		if !marked {
+			continue
+		}
+
+		if _, err := r.accountingRepo.RegisterTransaction(
+			tx.DB,
+			accounting.Transaction{
+				Value:          -payroll.Total,
+				Description:    "Staff salaries",
+				Classification: accounting.WAGES,
+			},
+			payroll.CompanyId,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
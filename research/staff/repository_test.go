@@ -2,6 +2,7 @@ package staff_test
 
 import (
 	"api/accounting"
+	"api/clock"
 	"api/company"
 	"api/database"
 	"api/research/staff"
@@ -11,7 +12,7 @@ import (
 )
 
 func TestResearchRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../../test.db")
+	conn, err := database.GetTestConnection("../../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -23,14 +24,14 @@ func TestResearchRepository(t *testing.T) {
 
 	if _, err := tx.Exec(`
         INSERT INTO companies (id, name, email, password)
-        VALUES (1, "Test", "test", "test"), (2, "Other", "other", "other")
+        VALUES (1, 'Test', 'test', 'test'), (2, 'Other', 'other', 'other')
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
 	if _, err := tx.Exec(`
         INSERT INTO research_staff (id, name, salary, company_id, poacher_id, skill)
-        VALUES (1, "Test", 200000, 1, null, 0), (2, "Other", 100000, 2, 1, 90), (3, "T", 500000, 2, null, 0)
+        VALUES (1, 'Test', 200000, 1, null, 0), (2, 'Other', 100000, 2, 1, 90), (3, 'T', 500000, 2, null, 0)
     `); err != nil {
 		t.Fatalf("could not seed database: %s", err)
 	}
@@ -49,6 +50,13 @@ func TestResearchRepository(t *testing.T) {
 		t.Fatalf("could not seed database: %s", err)
 	}
 
+	if _, err := tx.Exec(`
+        INSERT INTO company_balances (company_id, cash)
+        SELECT company_id, SUM(value) FROM transactions GROUP BY company_id
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		t.Fatalf("could not commit transaction: %s", err)
 	}
@@ -57,6 +65,9 @@ func TestResearchRepository(t *testing.T) {
 		if _, err := conn.DB.Exec(`DELETE FROM transactions`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec(`DELETE FROM company_balances`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
 		if _, err := conn.DB.Exec(`DELETE FROM trainings`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
@@ -66,14 +77,17 @@ func TestResearchRepository(t *testing.T) {
 		if _, err := conn.DB.Exec(`DELETE FROM research_staff`); err != nil {
 			t.Fatalf("could not cleanup database: %s", err)
 		}
+		if _, err := conn.DB.Exec(`DELETE FROM period_payments`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	accountingRepo := accounting.NewRepository(conn)
-	repository := staff.NewRepository(conn, accountingRepo)
-	companyRepo := company.NewRepository(conn, accountingRepo)
+	accountingRepo := accounting.NewRepository(conn, clock.New())
+	repository := staff.NewRepository(conn, accountingRepo, clock.New())
+	companyRepo := company.NewRepository(conn, accountingRepo, clock.New())
 
 	t.Run("GetStaff", func(t *testing.T) {
 		t.Run("brings poached as well", func(t *testing.T) {
@@ -150,6 +164,54 @@ func TestResearchRepository(t *testing.T) {
 		})
 	})
 
+	t.Run("PaySalaries", func(t *testing.T) {
+		period := time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)
+
+		t.Run("should only pay hired staff", func(t *testing.T) {
+			if _, err := conn.DB.Exec(`UPDATE research_staff SET status = ? WHERE id = 1`, staff.HIRED); err != nil {
+				t.Fatalf("could not hire staff: %s", err)
+			}
+
+			if err := repository.PaySalaries(ctx, period); err != nil {
+				t.Fatalf("could not pay salaries: %s", err)
+			}
+
+			company, err := companyRepo.GetById(ctx, 1)
+			if err != nil {
+				t.Fatalf("could not get company: %s", err)
+			}
+
+			expectedCash := 300000
+			if company.AvailableCash != expectedCash {
+				t.Errorf("expected cash %d, got %d", expectedCash, company.AvailableCash)
+			}
+
+			other, err := companyRepo.GetById(ctx, 2)
+			if err != nil {
+				t.Fatalf("could not get company: %s", err)
+			}
+
+			if other.AvailableCash != 0 {
+				t.Errorf("expected cash %d, got %d", 0, other.AvailableCash)
+			}
+		})
+
+		t.Run("should not pay the period twice", func(t *testing.T) {
+			if err := repository.PaySalaries(ctx, period); err != nil {
+				t.Fatalf("could not pay salaries: %s", err)
+			}
+
+			company, err := companyRepo.GetById(ctx, 1)
+			if err != nil {
+				t.Fatalf("could not get company: %s", err)
+			}
+
+			if company.AvailableCash != 300000 {
+				t.Errorf("expected cash %d, got %d", 300000, company.AvailableCash)
+			}
+		})
+	})
+
 	t.Run("UpdateTraining", func(t *testing.T) {
 		t.Run("update staff skill", func(t *testing.T) {
 			err := repository.UpdateTraining(ctx, &staff.Training{
@@ -2,6 +2,7 @@ package staff
 
 import (
 	"api/auth"
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -11,7 +12,7 @@ import (
 func CreateEndpoints(e *echo.Echo, service Service) {
 	group := e.Group("/research")
 
-	group.POST("/staff/graduate", func(c echo.Context) error {
+	graduate := group.POST("/staff/graduate", func(c echo.Context) error {
 		companyId, err := auth.ParseToken(c.Get("user"))
 		if err != nil {
 			return err
@@ -25,7 +26,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusOK, search)
 	})
 
-	group.POST("/staff/experienced", func(c echo.Context) error {
+	experienced := group.POST("/staff/experienced", func(c echo.Context) error {
 		companyId, err := auth.ParseToken(c.Get("user"))
 		if err != nil {
 			return err
@@ -39,7 +40,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusOK, search)
 	})
 
-	group.POST("/staff/:staff/hire", func(c echo.Context) error {
+	hire := group.POST("/staff/:staff/hire", func(c echo.Context) error {
 		staffId, err := strconv.ParseUint(c.Param("staff"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -58,7 +59,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusOK, staff)
 	})
 
-	group.DELETE("/staff/searches/:search", func(c echo.Context) error {
+	cancel := group.DELETE("/staff/searches/:search", func(c echo.Context) error {
 		searchId, err := strconv.ParseUint(c.Param("search"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -72,7 +73,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return service.CancelSearch(c.Request().Context(), searchId, companyId)
 	})
 
-	group.POST("/staff/:staff/offer", func(c echo.Context) error {
+	offer := group.POST("/staff/:staff/offer", func(c echo.Context) error {
 		staffId, err := strconv.ParseUint(c.Param("staff"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -99,7 +100,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusOK, staff)
 	})
 
-	group.POST("/staff/:staff/raise", func(c echo.Context) error {
+	raise := group.POST("/staff/:staff/raise", func(c echo.Context) error {
 		staffId, err := strconv.ParseUint(c.Param("staff"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -126,7 +127,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusOK, staff)
 	})
 
-	group.POST("/staff/:staff/train", func(c echo.Context) error {
+	train := group.POST("/staff/:staff/train", func(c echo.Context) error {
 		staffId, err := strconv.ParseUint(c.Param("staff"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest)
@@ -144,4 +145,6 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 
 		return c.JSON(http.StatusOK, training)
 	})
+
+	server.AllowMembers(auth.SCOPE_COMPANY_WRITE, graduate, experienced, hire, cancel, offer, raise, train)
 }
@@ -16,10 +16,8 @@ import (
 )
 
 func TestResearchRoutes(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
-	svr := server.NewServer()
-	svc := staff.NewService(staff.NewFakeRepository(), scheduler.NewScheduler(), notification.NoOpNotifier(), log.Default())
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
+	svc := staff.NewService(staff.NewFakeRepository(), staff.DEFAULT_SEARCH_DURATION, scheduler.NewScheduler(), notification.NoOpNotifier(), log.Default())
 
 	staff.CreateEndpoints(svr, svc)
 	token, err := auth.GenerateToken(1, "secret")
@@ -45,7 +43,7 @@ func TestResearchRoutes(t *testing.T) {
 		}
 
 		duration := search.FinishesAt.Sub(time.Now())
-		diff := staff.SEARCH_DURATION - duration
+		diff := staff.DEFAULT_SEARCH_DURATION - duration
 		if int(diff.Seconds()) != 0 {
 			t.Errorf("expected duration %+v, got %d", search.FinishesAt, int(diff.Seconds()))
 		}
@@ -69,7 +67,7 @@ func TestResearchRoutes(t *testing.T) {
 		}
 
 		duration := search.FinishesAt.Sub(time.Now())
-		diff := staff.SEARCH_DURATION - duration
+		diff := staff.DEFAULT_SEARCH_DURATION - duration
 		if int(diff.Seconds()) != 0 {
 			t.Errorf("expected duration %+v, got %d", search.FinishesAt, int(diff.Seconds()))
 		}
@@ -14,9 +14,17 @@ import (
 
 type Status int
 
-const SEARCH_DURATION = 12 * time.Hour
+// How long looking for new staff takes, unless configured otherwise
+const DEFAULT_SEARCH_DURATION = 12 * time.Hour
 const TRAINING_DURATION = 8 * time.Hour
 
+const (
+	GRADUATE_SEARCH_JOB    = "staff.search.graduate"
+	EXPERIENCED_SEARCH_JOB = "staff.search.experienced"
+	OFFER_JOB              = "staff.offer"
+	TRAINING_JOB           = "staff.training"
+)
+
 const (
 	PENDING Status = iota
 	HIRED
@@ -79,52 +87,89 @@ type (
 		IncreaseSalary(ctx context.Context, salary, staffId, companyId uint64) (*Staff, error)
 		Train(ctx context.Context, staffId, companyId uint64) (*Training, error)
 		FinishTraining(ctx context.Context, trainingId, companyId uint64) error
+
+		PaySalaries(ctx context.Context, period time.Time) error
 	}
 
 	service struct {
-		repository Repository
-		timer      *scheduler.Scheduler
-		notifier   notification.Notifier
-		logger     *log.Logger
+		repository     Repository
+		searchDuration time.Duration
+		timer          *scheduler.Scheduler
+		notifier       notification.Notifier
+		logger         *log.Logger
+	}
+
+	searchJob struct {
+		SearchId  uint64 `json:"search_id"`
+		CompanyId uint64 `json:"company_id"`
+	}
+
+	offerJob struct {
+		StaffId   uint64 `json:"staff_id"`
+		CompanyId uint64 `json:"company_id"`
+	}
+
+	trainingJob struct {
+		TrainingId uint64 `json:"training_id"`
+		CompanyId  uint64 `json:"company_id"`
 	}
 )
 
 func NewService(
 	repository Repository,
+	searchDuration time.Duration,
 	timer *scheduler.Scheduler,
 	notifier notification.Notifier,
 	logger *log.Logger,
 ) Service {
-	return &service{repository, timer, notifier, logger}
+	service := &service{repository, searchDuration, timer, notifier, logger}
+
+	timer.Register(GRADUATE_SEARCH_JOB, service.finishGraduateSearch)
+	timer.Register(EXPERIENCED_SEARCH_JOB, service.finishExperiencedSearch)
+	timer.Register(OFFER_JOB, service.acceptOffer)
+	timer.Register(TRAINING_JOB, service.finishTraining)
+
+	return service
 }
 
 func (s *service) FindGraduate(ctx context.Context, companyId uint64) (*Search, error) {
-	finishTime := time.Now().Add(SEARCH_DURATION)
+	finishTime := s.timer.Now().Add(s.searchDuration)
 	search, err := s.repository.StartSearch(ctx, finishTime, companyId)
 	if err != nil {
 		return nil, err
 	}
 
-	s.timer.Add(search.Id, SEARCH_DURATION, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
+	if err := s.scheduleSearch(ctx, GRADUATE_SEARCH_JOB, search, companyId); err != nil {
+		return nil, err
+	}
 
-		if err := s.repository.DeleteSearch(ctx, search.Id, companyId); err != nil {
-			return err
-		}
+	return search, nil
+}
 
-		graduate, err := s.GetGraduate(ctx, companyId)
+func (s *service) finishGraduateSearch(job *scheduler.Job) error {
+	var payload searchJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
 
-		message := fmt.Sprintf("%s is available for hire", graduate.Name)
-		if err := s.notifier.Notify(ctx, message, int64(companyId)); err != nil {
-			s.logger.Printf("Error notifying graduate available for hire: %s\n", err)
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := s.repository.DeleteSearch(ctx, payload.SearchId, payload.CompanyId); err != nil {
+		return err
+	}
 
+	graduate, err := s.GetGraduate(ctx, payload.CompanyId)
+	if err != nil {
 		return err
+	}
 
-	})
+	message := fmt.Sprintf("%s is available for hire", graduate.Name)
+	if err := s.notifier.Notify(ctx, message, int64(payload.CompanyId)); err != nil {
+		s.logger.Printf("Error notifying graduate available for hire: %s\n", err)
+	}
 
-	return search, nil
+	return nil
 }
 
 func (s *service) GetGraduate(ctx context.Context, companyId uint64) (*Staff, error) {
@@ -148,31 +193,59 @@ func (s *service) GetGraduate(ctx context.Context, companyId uint64) (*Staff, er
 }
 
 func (s *service) FindExperienced(ctx context.Context, companyId uint64) (*Search, error) {
-	finishTime := time.Now().Add(SEARCH_DURATION)
+	finishTime := s.timer.Now().Add(s.searchDuration)
 	search, err := s.repository.StartSearch(ctx, finishTime, companyId)
 	if err != nil {
 		return nil, err
 	}
 
-	s.timer.Add(search.Id, SEARCH_DURATION, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
+	if err := s.scheduleSearch(ctx, EXPERIENCED_SEARCH_JOB, search, companyId); err != nil {
+		return nil, err
+	}
 
-		if err := s.repository.DeleteSearch(ctx, search.Id, companyId); err != nil {
-			return err
-		}
+	return search, nil
+}
 
-		candidate, err := s.GetExperienced(ctx, companyId)
+func (s *service) finishExperiencedSearch(job *scheduler.Job) error {
+	var payload searchJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
 
-		message := fmt.Sprintf("%s is available for hire", candidate.Name)
-		if err := s.notifier.Notify(ctx, message, int64(companyId)); err != nil {
-			s.logger.Printf("Error notifying experienced available for hire: %s\n", err)
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
 
+	if err := s.repository.DeleteSearch(ctx, payload.SearchId, payload.CompanyId); err != nil {
 		return err
-	})
+	}
 
-	return search, nil
+	candidate, err := s.GetExperienced(ctx, payload.CompanyId)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%s is available for hire", candidate.Name)
+	if err := s.notifier.Notify(ctx, message, int64(payload.CompanyId)); err != nil {
+		s.logger.Printf("Error notifying experienced available for hire: %s\n", err)
+	}
+
+	return nil
+}
+
+func (s *service) scheduleSearch(ctx context.Context, jobType string, search *Search, companyId uint64) error {
+	job, err := scheduler.NewJob(
+		searchJobId(search.Id),
+		jobType,
+		int64(companyId),
+		searchJob{search.Id, companyId},
+		search.FinishesAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return s.timer.Schedule(ctx, job)
 }
 
 func (s *service) GetExperienced(ctx context.Context, companyId uint64) (*Staff, error) {
@@ -193,7 +266,7 @@ func (s *service) CancelSearch(ctx context.Context, searchId, companyId uint64)
 		return err
 	}
 
-	s.timer.Remove(searchId)
+	s.timer.Remove(searchJobId(searchId))
 	return nil
 }
 
@@ -261,17 +334,38 @@ func (s *service) MakeOffer(ctx context.Context, offer, staffId, companyId uint6
 		s.logger.Printf("Error notifying offer: %s\n", err)
 	}
 
-	s.timer.Add(staffId, 48*time.Hour, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
+	job, err := scheduler.NewJob(
+		fmt.Sprintf("OFFER_%d", staffId),
+		OFFER_JOB,
+		int64(companyId),
+		offerJob{staffId, companyId},
+		s.timer.Now().Add(48*time.Hour),
+	)
 
-		_, err := s.HireStaff(ctx, staffId, companyId)
-		return err
-	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.timer.Schedule(ctx, job); err != nil {
+		return nil, err
+	}
 
 	return staff, nil
 }
 
+func (s *service) acceptOffer(job *scheduler.Job) error {
+	var payload offerJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err := s.HireStaff(ctx, payload.StaffId, payload.CompanyId)
+	return err
+}
+
 func (s *service) IncreaseSalary(ctx context.Context, salary, staffId, companyId uint64) (*Staff, error) {
 	staff, err := s.repository.GetStaffById(ctx, staffId)
 	if err != nil {
@@ -328,7 +422,7 @@ func (s *service) Train(ctx context.Context, staffId, companyId uint64) (*Traini
 	training, err := s.repository.SaveTraining(ctx, &Training{
 		StaffId:    staffId,
 		CompanyId:  companyId,
-		FinishesAt: time.Now().Add(duration),
+		FinishesAt: s.timer.Now().Add(duration),
 		Investment: 1000000 + (1000000 * (uint64(staff.Skill) / 10)),
 	})
 
@@ -336,16 +430,37 @@ func (s *service) Train(ctx context.Context, staffId, companyId uint64) (*Traini
 		return nil, err
 	}
 
-	s.timer.Add(staffId, TRAINING_DURATION, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
+	job, err := scheduler.NewJob(
+		fmt.Sprintf("TRAINING_%d", training.Id),
+		TRAINING_JOB,
+		int64(companyId),
+		trainingJob{training.Id, companyId},
+		training.FinishesAt,
+	)
 
-		return s.FinishTraining(ctx, training.Id, companyId)
-	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.timer.Schedule(ctx, job); err != nil {
+		return nil, err
+	}
 
 	return training, nil
 }
 
+func (s *service) finishTraining(job *scheduler.Job) error {
+	var payload trainingJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return s.FinishTraining(ctx, payload.TrainingId, payload.CompanyId)
+}
+
 func (s *service) FinishTraining(ctx context.Context, trainingId, companyId uint64) error {
 	training, err := s.repository.GetTraining(ctx, trainingId, companyId)
 	if err != nil {
@@ -358,7 +473,7 @@ func (s *service) FinishTraining(ctx context.Context, trainingId, companyId uint
 	}
 
 	// Complete training
-	training.CompletedAt = time.Now()
+	training.CompletedAt = s.timer.Now()
 
 	// Calculate points (relative to talent, e.g., rand(0, talent / 10))
 	randomizer := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -368,3 +483,11 @@ func (s *service) FinishTraining(ctx context.Context, trainingId, companyId uint
 	// Save new skill
 	return s.repository.UpdateTraining(ctx, training)
 }
+
+func searchJobId(searchId uint64) string {
+	return fmt.Sprintf("SEARCH_%d", searchId)
+}
+
+func (s *service) PaySalaries(ctx context.Context, period time.Time) error {
+	return s.repository.PaySalaries(ctx, period)
+}
@@ -16,7 +16,7 @@ func TestResearchService(t *testing.T) {
 
 	repository := staff.NewFakeRepository()
 	timer := scheduler.NewScheduler()
-	service := staff.NewService(repository, timer, notification.NoOpNotifier(), log.Default())
+	service := staff.NewService(repository, staff.DEFAULT_SEARCH_DURATION, timer, notification.NoOpNotifier(), log.Default())
 
 	t.Run("GetGraduate", func(t *testing.T) {
 		employee, err := service.GetGraduate(ctx, 1)
@@ -298,7 +298,7 @@ func TestResearchService(t *testing.T) {
 		})
 
 		t.Run("should cancel timer", func(t *testing.T) {
-			timer.Add(uint64(42069), 900*time.Millisecond, func() error {
+			timer.Add("SEARCH_42069", 900*time.Millisecond, func() error {
 				t.Fatal("should not execute callback")
 				return nil
 			})
@@ -0,0 +1,79 @@
+package resource
+
+import "context"
+
+type fakeRepository struct {
+	categories map[uint64]*Category
+	data       map[uint64]*Resource
+	reqs       map[uint64][]*Requirement
+}
+
+func NewFakeRepository() Repository {
+	categories := map[uint64]*Category{
+		1: {Id: 1, Name: "Food"},
+	}
+	data := map[uint64]*Resource{
+		1: {Id: 1, Name: "Water", CategoryId: 1, Category: categories[1]},
+		2: {Id: 2, Name: "Seeds", CategoryId: 1, Category: categories[1]},
+		3: {Id: 3, Name: "Apple", CategoryId: 1, Category: categories[1]},
+	}
+	requirements := map[uint64][]*Requirement{
+		2: {
+			{ResourceId: 1, Qty: 10, Resource: data[1]},
+		},
+		3: {
+			{ResourceId: 1, Qty: 50, Resource: data[1]},
+			{ResourceId: 2, Qty: 100, Resource: data[2]},
+		},
+	}
+	return &fakeRepository{categories, data, requirements}
+}
+
+func (r *fakeRepository) FetchCategories(ctx context.Context) ([]*Category, error) {
+	items := make([]*Category, 0)
+	for id := uint64(1); id <= uint64(len(r.categories)); id++ {
+		items = append(items, r.categories[id])
+	}
+	return items, nil
+}
+
+func (r *fakeRepository) SaveCategory(ctx context.Context, category *Category) (*Category, error) {
+	category.Id = uint64(len(r.categories) + 1)
+	r.categories[category.Id] = category
+	return category, nil
+}
+
+func (r *fakeRepository) FetchResources(ctx context.Context) ([]*Resource, error) {
+	items := make([]*Resource, 0)
+	for id := uint64(1); id <= uint64(len(r.data)); id++ {
+		item := r.data[id]
+		item.Requirements = r.reqs[id]
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (r *fakeRepository) GetById(ctx context.Context, id uint64) (*Resource, error) {
+	return r.data[id], nil
+}
+
+func (r *fakeRepository) GetRequirements(ctx context.Context, resourceId uint64) ([]*Requirement, error) {
+	return r.reqs[resourceId], nil
+}
+
+func (r *fakeRepository) SaveResource(ctx context.Context, resource *Resource) (*Resource, error) {
+	id := uint64(len(r.data) + 1)
+	resource.Id = id
+
+	r.data[id] = resource
+	r.reqs[id] = resource.Requirements
+
+	return resource, nil
+}
+
+func (r *fakeRepository) UpdateResource(ctx context.Context, resource *Resource) (*Resource, error) {
+	r.data[resource.Id] = resource
+	r.reqs[resource.Id] = resource.Requirements
+
+	return resource, nil
+}
@@ -8,6 +8,12 @@ import (
 )
 
 type Repository interface {
+	// Returns the list of categories resources belong to
+	FetchCategories(ctx context.Context) ([]*Category, error)
+
+	// Creates a category
+	SaveCategory(ctx context.Context, category *Category) (*Category, error)
+
 	// Returns the list of registered resources
 	FetchResources(ctx context.Context) ([]*Resource, error)
 
@@ -32,10 +38,37 @@ func NewRepository(conn *database.Connection) Repository {
 	return &goquRepository{builder}
 }
 
+func (r *goquRepository) FetchCategories(ctx context.Context) ([]*Category, error) {
+	categories := make([]*Category, 0)
+
+	err := database.Query(ctx, r.builder).
+		Select(goqu.I("id"), goqu.I("name")).
+		From(goqu.T("categories")).
+		Where(goqu.I("deleted_at").IsNull()).
+		Order(goqu.I("id").Asc()).
+		ScanStructsContext(ctx, &categories)
+
+	return categories, err
+}
+
+func (r *goquRepository) SaveCategory(ctx context.Context, category *Category) (*Category, error) {
+	builder := database.Query(ctx, r.builder)
+
+	id, err := database.InsertId(ctx, builder.
+		Insert(goqu.T("categories")).
+		Rows(goqu.Record{"name": category.Name}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Category{Id: uint64(id), Name: category.Name}, nil
+}
+
 func (r *goquRepository) FetchResources(ctx context.Context) ([]*Resource, error) {
 	resources := make([]*Resource, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("r.*"),
 			goqu.I("c.id").As(goqu.C("category.id")),
@@ -63,7 +96,7 @@ func (r *goquRepository) FetchResources(ctx context.Context) ([]*Resource, error
 func (r *goquRepository) GetById(ctx context.Context, id uint64) (*Resource, error) {
 	resource := new(Resource)
 
-	found, err := r.builder.
+	found, err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("r.*"),
 			goqu.I("c.id").As(goqu.C("category.id")),
@@ -98,7 +131,7 @@ func (r *goquRepository) GetById(ctx context.Context, id uint64) (*Resource, err
 func (r *goquRepository) GetRequirements(ctx context.Context, resourceId uint64) ([]*Requirement, error) {
 	requirements := make([]*Requirement, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("req.qty").As("quantity"),
 			goqu.I("r.id").As(goqu.C("resource.id")),
@@ -121,33 +154,26 @@ func (r *goquRepository) GetRequirements(ctx context.Context, resourceId uint64)
 }
 
 func (r *goquRepository) SaveResource(ctx context.Context, resource *Resource) (*Resource, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
 
 	defer tx.Rollback()
 
-	result, err := tx.
+	id, err := database.InsertId(ctx, tx.
 		Insert("resources").
 		Rows(goqu.Record{
 			"name":        resource.Name,
 			"image":       resource.Image,
 			"category_id": resource.CategoryId,
-		}).
-		Executor().
-		Exec()
-
-	if err != nil {
-		return nil, err
-	}
+		}))
 
-	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := r.saveRequirements(tx, id, resource.Requirements); err != nil {
+	if err := r.saveRequirements(tx.DB, id, resource.Requirements); err != nil {
 		return nil, err
 	}
 
@@ -159,7 +185,7 @@ func (r *goquRepository) SaveResource(ctx context.Context, resource *Resource) (
 }
 
 func (r *goquRepository) UpdateResource(ctx context.Context, resource *Resource) (*Resource, error) {
-	tx, err := r.builder.BeginTx(ctx, nil)
+	tx, err := database.BeginTx(ctx, r.builder)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +209,7 @@ func (r *goquRepository) UpdateResource(ctx context.Context, resource *Resource)
 		return nil, err
 	}
 
-	if err := r.saveRequirements(tx, int64(resource.Id), resource.Requirements); err != nil {
+	if err := r.saveRequirements(tx.DB, int64(resource.Id), resource.Requirements); err != nil {
 		return nil, err
 	}
 
@@ -194,7 +220,7 @@ func (r *goquRepository) UpdateResource(ctx context.Context, resource *Resource)
 	return resource, nil
 }
 
-func (r *goquRepository) saveRequirements(tx *goqu.TxDatabase, id int64, requirements []*Requirement) error {
+func (r *goquRepository) saveRequirements(tx *database.DB, id int64, requirements []*Requirement) error {
 	_, err := tx.Delete(goqu.T("resources_requirements")).
 		Where(goqu.I("resource_id").Eq(id)).
 		Executor().
@@ -11,7 +11,7 @@ import (
 )
 
 func TestMain(t *testing.M) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		log.Fatalf("could not connect to database: %s", err)
 	}
@@ -23,11 +23,11 @@ func TestMain(t *testing.M) {
 
 	defer tx.Rollback()
 
-	tx.Exec(`INSERT INTO categories (id, name) VALUES (1, "Food")`)
+	tx.Exec(`INSERT INTO categories (id, name) VALUES (1, 'Food')`)
 
 	tx.Exec(`
         INSERT INTO resources (id, name, category_id)
-        VALUES (1, "Water", 1), (2, "Seeds", 1), (3, "Apple", 1)
+        VALUES (1, 'Water', 1), (2, 'Seeds', 1), (3, 'Apple', 1)
     `)
 
 	tx.Exec(`
@@ -60,7 +60,7 @@ func TestMain(t *testing.M) {
 }
 
 func TestResourceRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		t.Fatalf("could not connect to database: %s", err)
 	}
@@ -161,4 +161,28 @@ func TestResourceRepository(t *testing.T) {
 			t.Errorf("expected %d requirements, got %d", 2, len(resource.Requirements))
 		}
 	})
+
+	t.Run("should save categories", func(t *testing.T) {
+		category, err := repository.SaveCategory(ctx, &resource.Category{Name: "Construction"})
+		if err != nil {
+			t.Fatalf("could not save category: %s", err)
+		}
+
+		if category.Id == 0 {
+			t.Error("should add ID after saving")
+		}
+
+		categories, err := repository.FetchCategories(ctx)
+		if err != nil {
+			t.Fatalf("could not fetch categories: %s", err)
+		}
+
+		if len(categories) != 2 {
+			t.Fatalf("expected %d categories, got %d", 2, len(categories))
+		}
+
+		if categories[1].Name != "Construction" {
+			t.Errorf("expected name %s, got %s", "Construction", categories[1].Name)
+		}
+	})
 }
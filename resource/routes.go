@@ -1,6 +1,8 @@
 package resource
 
 import (
+	"api/auth"
+	"api/server"
 	"net/http"
 	"strconv"
 
@@ -9,8 +11,9 @@ import (
 
 func CreateEndpoints(e *echo.Echo, service Service) {
 	group := e.Group("/resources")
+	admin := server.RequireRole(auth.ROLE_ADMIN)
 
-	group.GET("/", func(c echo.Context) error {
+	list := group.GET("/", func(c echo.Context) error {
 		resources, err := service.GetAll(c.Request().Context())
 		if err != nil {
 			return err
@@ -18,7 +21,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		return c.JSON(http.StatusOK, resources)
 	})
 
-	group.GET("/:id", func(c echo.Context) error {
+	get := group.GET("/:id", func(c echo.Context) error {
 		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -46,7 +49,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 			return err
 		}
 		return c.JSON(http.StatusCreated, resource)
-	})
+	}, admin)
 
 	group.PUT("/:id", func(c echo.Context) error {
 		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -75,5 +78,7 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 		}
 
 		return c.JSON(http.StatusOK, resource)
-	})
+	}, admin)
+
+	server.AllowMembers("", list, get)
 }
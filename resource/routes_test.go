@@ -4,7 +4,6 @@ import (
 	"api/auth"
 	"api/resource"
 	"api/server"
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -12,73 +11,37 @@ import (
 	"testing"
 )
 
-type fakeRepository struct {
-	data map[uint64]*resource.Resource
-	reqs map[uint64][]*resource.Requirement
-}
+func TestService(t *testing.T) {
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
+	svc := resource.NewService(resource.NewFakeRepository())
+	resource.CreateEndpoints(svr, svc)
 
-func NewFakeRepository() resource.Repository {
-	data := map[uint64]*resource.Resource{
-		1: {Id: 1, Name: "Water", Category: &resource.Category{Id: 1, Name: "Food"}},
-		2: {Id: 2, Name: "Seeds", Category: &resource.Category{Id: 1, Name: "Food"}},
-		3: {Id: 3, Name: "Apple", Category: &resource.Category{Id: 1, Name: "Food"}},
-	}
-	requirements := map[uint64][]*resource.Requirement{
-		2: {
-			{ResourceId: 1, Qty: 10, Resource: data[1]},
-		},
-		3: {
-			{ResourceId: 1, Qty: 50, Resource: data[1]},
-			{ResourceId: 2, Qty: 100, Resource: data[2]},
-		},
+	token, err := auth.GenerateToken(1, "secret", auth.ROLE_ADMIN)
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
 	}
-	return &fakeRepository{data, requirements}
-}
 
-func (r *fakeRepository) FetchResources(ctx context.Context) ([]*resource.Resource, error) {
-	items := make([]*resource.Resource, 0)
-	for _, item := range r.data {
-		items = append(items, item)
+	companyToken, err := auth.GenerateToken(2, "secret")
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
 	}
-	return items, nil
-}
-
-func (r *fakeRepository) GetById(ctx context.Context, id uint64) (*resource.Resource, error) {
-	return r.data[id], nil
-}
-
-func (r *fakeRepository) GetRequirements(ctx context.Context, resourceId uint64) ([]*resource.Requirement, error) {
-	return r.reqs[resourceId], nil
-}
 
-func (r *fakeRepository) SaveResource(ctx context.Context, resource *resource.Resource) (*resource.Resource, error) {
-	id := uint64(len(r.data) + 1)
-	resource.Id = id
+	t.Run("should forbid companies without admin role", func(t *testing.T) {
+		for _, method := range []string{"POST /resources/", "PUT /resources/1"} {
+			parts := strings.Split(method, " ")
+			req := httptest.NewRequest(parts[0], parts[1], strings.NewReader(`{"name":"Wood","category_id":1}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json")
+			req.Header.Set("Authorization", "Bearer "+companyToken)
 
-	r.data[id] = resource
-	r.reqs[id] = resource.Requirements
+			rec := httptest.NewRecorder()
+			svr.ServeHTTP(rec, req)
 
-	return resource, nil
-}
-
-func (r *fakeRepository) UpdateResource(ctx context.Context, resource *resource.Resource) (*resource.Resource, error) {
-	r.data[resource.Id] = resource
-	r.reqs[resource.Id] = resource.Requirements
-
-	return resource, nil
-}
-
-func TestService(t *testing.T) {
-	t.Setenv("JWT_SECRET", "secret")
-
-	svr := server.NewServer()
-	svc := resource.NewService(NewFakeRepository())
-	resource.CreateEndpoints(svr, svc)
-
-	token, err := auth.GenerateToken(1, "secret")
-	if err != nil {
-		t.Fatalf("could not generate jwt token: %s", err)
-	}
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d on %s, got %d", http.StatusForbidden, method, rec.Code)
+			}
+		}
+	})
 
 	t.Run("should return 201 when creating resource", func(t *testing.T) {
 		req := httptest.NewRequest("POST", "/resources/", strings.NewReader(`{"name":"Wood","category_id":1,"image":"http://placeimg.com/10","requirements":[]}`))
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"api/accounting"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+const runUsage = "usage: run taxes | rates [-period <date>]"
+
+// Runs the run subcommand, which does the work of a world job right away.
+// By default it covers the last week, like the job would; with a period,
+// the week containing that date.
+func runJob(ctx context.Context, app *app, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(runUsage)
+	}
+
+	var period string
+
+	flags := flag.NewFlagSet("run "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&period, "period", "", "date in the week to run for, as 2006-01-02")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	start, end := accounting.GetPeriod(app.clock.Now())
+	if period != "" {
+		date, err := time.Parse(time.DateOnly, period)
+		if err != nil {
+			return fmt.Errorf("invalid period %q", period)
+		}
+
+		// The week before the next one is the one containing the date
+		start, end = accounting.GetPeriod(date.AddDate(0, 0, 7))
+	}
+
+	switch args[0] {
+	case "taxes":
+		if err := app.accountingSvc.PayTaxes(ctx, start, end); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "paid taxes from %s to %s\n", start.Format(time.DateOnly), end.Format(time.DateOnly))
+	case "rates":
+		rates, err := app.financingSvc.CalculatePeriodRates(ctx, start, end)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "rates from %s to %s: inflation %.4f, interest %.4f\n", start.Format(time.DateOnly), end.Format(time.DateOnly), rates.Inflation, rates.Interest)
+	default:
+		return errors.New(runUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package scheduler_test
+
+import (
+	"api/clock"
+	"api/scheduler"
+	"sync"
+	"testing"
+	"time"
+)
+
+const PENDING_JOBS = 100000
+
+// Fills a scheduler with jobs that are far from being due
+func pendingScheduler(b *testing.B, gameClock clock.Clock) *scheduler.Scheduler {
+	b.Helper()
+
+	timer := scheduler.NewPersistentScheduler(nil, gameClock)
+	b.Cleanup(timer.Stop)
+
+	for i := 0; i < PENDING_JOBS; i++ {
+		timer.Add(i, time.Hour+time.Duration(i)*time.Millisecond, func() error {
+			return nil
+		})
+	}
+
+	return timer
+}
+
+func BenchmarkScheduler(b *testing.B) {
+	b.Run("Add with 100k pending", func(b *testing.B) {
+		timer := pendingScheduler(b, clock.New())
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			timer.Add(PENDING_JOBS+i, time.Hour, func() error {
+				return nil
+			})
+		}
+	})
+
+	b.Run("Remove with 100k pending", func(b *testing.B) {
+		timer := pendingScheduler(b, clock.New())
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			id := i % PENDING_JOBS
+			timer.Remove(id)
+
+			b.StopTimer()
+			timer.Add(id, time.Hour, func() error {
+				return nil
+			})
+			b.StartTimer()
+		}
+	})
+
+	b.Run("Repeat with 100k pending", func(b *testing.B) {
+		timer := pendingScheduler(b, clock.New())
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			timer.Repeat(PENDING_JOBS+i, time.Hour, func() error {
+				return nil
+			})
+		}
+	})
+
+	b.Run("Fire 100k due", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+
+			gameClock := clock.NewFakeClock(time.Now())
+			timer := scheduler.NewPersistentScheduler(nil, gameClock)
+
+			var wg sync.WaitGroup
+			wg.Add(PENDING_JOBS)
+
+			for j := 0; j < PENDING_JOBS; j++ {
+				timer.Add(j, time.Duration(j)*time.Millisecond, func() error {
+					wg.Done()
+					return nil
+				})
+			}
+
+			b.StartTimer()
+
+			gameClock.Advance(time.Duration(PENDING_JOBS) * time.Millisecond)
+			wg.Wait()
+
+			b.StopTimer()
+			timer.Stop()
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Runs at the start of every game week, right after the period returned
+// by accounting.GetPeriod ends
+const WEEKLY = "0 0 * * 0"
+
+var descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  WEEKLY,
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+type (
+	// Cron is a parsed cron expression with the usual five fields: minute,
+	// hour, day of month, month and day of week. Fields accept *, numbers,
+	// lists, ranges and steps. Times are always evaluated in UTC.
+	Cron struct {
+		minute     uint64
+		hour       uint64
+		dayOfMonth uint64
+		month      uint64
+		dayOfWeek  uint64
+
+		// Whether the day fields were restricted, as cron matches either
+		// of them when both are
+		anyDayOfMonth bool
+		anyDayOfWeek  bool
+	}
+
+	cronField struct {
+		name     string
+		min, max int
+	}
+)
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func ParseCron(expression string) (*Cron, error) {
+	if descriptor, ok := descriptors[expression]; ok {
+		expression = descriptor
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("expected %d fields in cron expression %q, got %d", len(cronFields), expression, len(fields))
+	}
+
+	masks := make([]uint64, len(fields))
+	for i, field := range fields {
+		mask, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, err
+		}
+		masks[i] = mask
+	}
+
+	// Both 0 and 7 are sunday
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+
+	return &Cron{
+		minute:        masks[0],
+		hour:          masks[1],
+		dayOfMonth:    masks[2],
+		month:         masks[3],
+		dayOfWeek:     masks[4],
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}, nil
+}
+
+// Returns the first time strictly after the given one that matches the
+// expression, or the zero time if there is none in the next five years
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+
+		if !has(c.month, int(month)) {
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !c.matchesDay(t) {
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !has(c.hour, t.Hour()) {
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !has(c.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c *Cron) matchesDay(t time.Time) bool {
+	dayOfMonth := has(c.dayOfMonth, t.Day())
+	dayOfWeek := has(c.dayOfWeek, int(t.Weekday()))
+
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
+
+func has(mask uint64, value int) bool {
+	return mask&(1<<value) != 0
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var mask uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if value, stepValue, found := strings.Cut(part, "/"); found {
+			parsed, err := strconv.Atoi(stepValue)
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepValue, f.name)
+			}
+			part, step = value, parsed
+		}
+
+		start, end := f.min, f.max
+		if part != "*" {
+			first, last, isRange := strings.Cut(part, "-")
+
+			var err error
+			if start, err = f.value(first); err != nil {
+				return 0, err
+			}
+
+			end = start
+			if isRange {
+				if end, err = f.value(last); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				end = f.max
+			}
+
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q in %s field", part, f.name)
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			mask |= 1 << value
+		}
+	}
+
+	return mask, nil
+}
+
+func (f cronField) value(text string) (int, error) {
+	value, err := strconv.Atoi(text)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field, expected %d-%d", text, f.name, f.min, f.max)
+	}
+	return value, nil
+}
@@ -0,0 +1,77 @@
+package scheduler_test
+
+import (
+	"api/scheduler"
+	"testing"
+	"time"
+)
+
+func TestCron(t *testing.T) {
+	// A monday
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	t.Run("ParseCron", func(t *testing.T) {
+		invalid := []string{
+			"",
+			"* * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"* * * 13 *",
+			"* * * * 8",
+			"5-1 * * * *",
+			"*/0 * * * *",
+			"a * * * *",
+		}
+
+		for _, expression := range invalid {
+			if _, err := scheduler.ParseCron(expression); err == nil {
+				t.Errorf("expected error parsing %q", expression)
+			}
+		}
+	})
+
+	t.Run("Next", func(t *testing.T) {
+		tests := []struct {
+			expression string
+			expected   time.Time
+		}{
+			{"* * * * *", time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)},
+			{"@hourly", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+			{"*/15 * * * *", time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+			{"0 9-17/4 * * *", time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)},
+			{"@daily", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+			{scheduler.WEEKLY, time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+			{"0 0 * * 7", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+			{"0 12 * * 1,3", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)},
+			{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+			{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+			{"0 0 31 * *", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+			{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+			// Either day matches when both are restricted
+			{"0 0 1 * 3", time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)},
+		}
+
+		for _, test := range tests {
+			cron, err := scheduler.ParseCron(test.expression)
+			if err != nil {
+				t.Fatalf("could not parse %q: %s", test.expression, err)
+			}
+
+			if next := cron.Next(now); !next.Equal(test.expected) {
+				t.Errorf("expected %q to run at %s, got %s", test.expression, test.expected, next)
+			}
+		}
+	})
+
+	t.Run("never", func(t *testing.T) {
+		cron, err := scheduler.ParseCron("0 0 30 2 *")
+		if err != nil {
+			t.Fatalf("could not parse cron: %s", err)
+		}
+
+		if next := cron.Next(now); !next.IsZero() {
+			t.Errorf("expected no next run, got %s", next)
+		}
+	})
+}
@@ -0,0 +1,308 @@
+package scheduler
+
+import (
+	"api/clock"
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// How many callbacks may run at the same time
+const WORKERS = 16
+
+type (
+	// engine keeps every pending timer in a min-heap ordered by due time.
+	// A single goroutine sleeps until the earliest one is due and hands
+	// it to a fixed pool of workers, so the number of goroutines doesn't
+	// grow with the number of jobs.
+	engine struct {
+		mutex    sync.Mutex
+		clock    clock.Clock
+		queue    entryQueue
+		entries  map[any]*entry
+		sequence uint64
+
+		timer clock.Timer
+		wake  chan struct{}
+		work  chan *entry
+		done  chan struct{}
+		once  sync.Once
+
+		// Held for reading by every running callback, so shutting down
+		// can wait for them by taking it for writing
+		busy sync.RWMutex
+	}
+
+	entry struct {
+		id       any
+		at       time.Time
+		every    time.Duration
+		callback func()
+		index    int
+		sequence uint64
+
+		// Repeating entries skip their turn while the previous run is
+		// still going, like a ticker drops ticks nobody received
+		running bool
+	}
+
+	entryQueue []*entry
+)
+
+func newEngine(clock clock.Clock, workers int) *engine {
+	e := &engine{
+		clock:   clock,
+		queue:   make(entryQueue, 0),
+		entries: make(map[any]*entry),
+		wake:    make(chan struct{}, 1),
+		work:    make(chan *entry),
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		go e.worker()
+	}
+
+	go e.dispatch()
+
+	return e
+}
+
+// Schedules callback to run at the given time and then every so often if
+// every is positive. An entry with the same id is replaced.
+func (e *engine) schedule(id any, at time.Time, every time.Duration, callback func()) {
+	e.mutex.Lock()
+
+	if previous, found := e.entries[id]; found {
+		e.remove(previous)
+	}
+
+	e.sequence++
+	next := &entry{
+		id:       id,
+		at:       at,
+		every:    every,
+		callback: callback,
+		sequence: e.sequence,
+	}
+
+	e.entries[id] = next
+	heap.Push(&e.queue, next)
+
+	isHead := e.queue[0] == next
+	e.mutex.Unlock()
+
+	if isHead {
+		e.signal()
+	}
+}
+
+// Removes the entry with the given id. Returns false if there was no such
+// entry, including when it is already running.
+func (e *engine) cancel(id any) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	current, found := e.entries[id]
+	if !found {
+		return false
+	}
+
+	e.remove(current)
+	return true
+}
+
+// Returns how many entries are waiting to run
+func (e *engine) pending() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.queue)
+}
+
+func (e *engine) stop() {
+	e.once.Do(func() {
+		close(e.done)
+
+		e.mutex.Lock()
+		if e.timer != nil {
+			e.timer.Stop()
+		}
+		e.mutex.Unlock()
+	})
+}
+
+// Reports whether the engine was stopped
+func (e *engine) stopped() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stops the engine and waits for the running callbacks to return, or for
+// the context to be done. Entries that haven't started are dropped.
+func (e *engine) shutdown(ctx context.Context) error {
+	e.stop()
+
+	finished := make(chan struct{})
+	go func() {
+		e.busy.Lock()
+		e.busy.Unlock()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *engine) remove(current *entry) {
+	delete(e.entries, current.id)
+	if current.index >= 0 {
+		heap.Remove(&e.queue, current.index)
+	}
+}
+
+func (e *engine) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *engine) dispatch() {
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-e.wake:
+		}
+
+		for _, due := range e.due() {
+			select {
+			case <-e.done:
+				return
+			case e.work <- due:
+			}
+		}
+	}
+}
+
+// Pops every entry that is due, putting repeating ones back in the queue
+// for their next run, and sets the timer to wake up for the next entry
+func (e *engine) due() []*entry {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := e.clock.Now()
+	due := make([]*entry, 0)
+	repeating := make([]*entry, 0)
+
+	for len(e.queue) > 0 && !e.queue[0].at.After(now) {
+		next := heap.Pop(&e.queue).(*entry)
+
+		if next.every <= 0 {
+			delete(e.entries, next.id)
+			due = append(due, next)
+			continue
+		}
+
+		if !next.running {
+			next.running = true
+			due = append(due, next)
+		}
+
+		for !next.at.After(now) {
+			next.at = next.at.Add(next.every)
+		}
+		repeating = append(repeating, next)
+	}
+
+	for _, next := range repeating {
+		heap.Push(&e.queue, next)
+	}
+
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+
+	if len(e.queue) > 0 {
+		e.timer = e.clock.AfterFunc(e.queue[0].at.Sub(now), e.signal)
+	}
+
+	return due
+}
+
+func (e *engine) worker() {
+	for {
+		select {
+		case <-e.done:
+			return
+		case next := <-e.work:
+			if !e.run(next) {
+				return
+			}
+		}
+	}
+}
+
+// Runs the entry's callback unless the engine was stopped after it was
+// handed over, returning whether the worker should keep going
+func (e *engine) run(next *entry) bool {
+	e.busy.RLock()
+	defer e.busy.RUnlock()
+
+	if e.stopped() {
+		return false
+	}
+
+	next.callback()
+
+	if next.every > 0 {
+		e.mutex.Lock()
+		next.running = false
+		e.mutex.Unlock()
+	}
+
+	return true
+}
+
+func (q entryQueue) Len() int {
+	return len(q)
+}
+
+func (q entryQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].sequence < q[j].sequence
+	}
+	return q[i].at.Before(q[j].at)
+}
+
+func (q entryQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *entryQueue) Push(value any) {
+	next := value.(*entry)
+	next.index = len(*q)
+	*q = append(*q, next)
+}
+
+func (q *entryQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	old[len(old)-1] = nil
+	last.index = -1
+	*q = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type fakeRepository struct {
+	mutex    sync.Mutex
+	jobs     map[string]Job
+	deadJobs map[int64]DeadJob
+	runs     []JobRun
+	lastId   int64
+}
+
+func NewFakeRepository() Repository {
+	return &fakeRepository{
+		jobs:     make(map[string]Job),
+		deadJobs: make(map[int64]DeadJob),
+	}
+}
+
+func (r *fakeRepository) GetJobs(ctx context.Context) ([]*Job, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	jobs := make([]*Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		job := job
+		jobs = append(jobs, &job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].RunsAt.Before(jobs[j].RunsAt)
+	})
+
+	return jobs, nil
+}
+
+func (r *fakeRepository) SaveJob(ctx context.Context, job *Job) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.jobs[job.Id] = *job
+	return nil
+}
+
+func (r *fakeRepository) DeleteJob(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.jobs, id)
+	return nil
+}
+
+func (r *fakeRepository) GetDeadJobs(ctx context.Context) ([]*DeadJob, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deadJobs := make([]*DeadJob, 0, len(r.deadJobs))
+	for _, deadJob := range r.deadJobs {
+		deadJob := deadJob
+		deadJobs = append(deadJobs, &deadJob)
+	}
+
+	sort.Slice(deadJobs, func(i, j int) bool {
+		return deadJobs[i].FailedAt.After(deadJobs[j].FailedAt)
+	})
+
+	return deadJobs, nil
+}
+
+func (r *fakeRepository) GetDeadJob(ctx context.Context, id int64) (*DeadJob, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deadJob, found := r.deadJobs[id]
+	if !found {
+		return nil, nil
+	}
+
+	return &deadJob, nil
+}
+
+func (r *fakeRepository) SaveDeadJob(ctx context.Context, deadJob *DeadJob) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if deadJob.Id == 0 {
+		r.lastId++
+		deadJob.Id = r.lastId
+	}
+
+	r.deadJobs[deadJob.Id] = *deadJob
+	return nil
+}
+
+func (r *fakeRepository) DeleteDeadJob(ctx context.Context, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.deadJobs, id)
+	return nil
+}
+
+func (r *fakeRepository) GetRuns(ctx context.Context, page, limit uint) ([]*JobRun, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	runs := make([]*JobRun, 0)
+	for i := len(r.runs) - 1 - int(page*limit); i >= 0 && len(runs) < int(limit); i-- {
+		run := r.runs[i]
+		runs = append(runs, &run)
+	}
+
+	return runs, nil
+}
+
+func (r *fakeRepository) GetLastSuccessfulRun(ctx context.Context, name string) (*JobRun, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var last *JobRun
+	for _, run := range r.runs {
+		run := run
+		if run.Name == name && run.Error == nil && (last == nil || run.ScheduledAt.After(last.ScheduledAt)) {
+			last = &run
+		}
+	}
+
+	return last, nil
+}
+
+func (r *fakeRepository) SaveRun(ctx context.Context, run *JobRun) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	run.Id = int64(len(r.runs) + 1)
+	r.runs = append(r.runs, *run)
+	return nil
+}
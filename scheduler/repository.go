@@ -0,0 +1,254 @@
+package scheduler
+
+import (
+	"api/database"
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type (
+	Repository interface {
+		GetJobs(ctx context.Context) ([]*Job, error)
+		SaveJob(ctx context.Context, job *Job) error
+		DeleteJob(ctx context.Context, id string) error
+
+		GetDeadJobs(ctx context.Context) ([]*DeadJob, error)
+		GetDeadJob(ctx context.Context, id int64) (*DeadJob, error)
+		SaveDeadJob(ctx context.Context, deadJob *DeadJob) error
+		DeleteDeadJob(ctx context.Context, id int64) error
+
+		GetRuns(ctx context.Context, page, limit uint) ([]*JobRun, error)
+		GetLastSuccessfulRun(ctx context.Context, name string) (*JobRun, error)
+		SaveRun(ctx context.Context, run *JobRun) error
+	}
+
+	goquRepository struct {
+		builder *goqu.Database
+	}
+)
+
+func NewRepository(conn *database.Connection) Repository {
+	builder := goqu.New(conn.Driver, conn.DB)
+	return &goquRepository{builder}
+}
+
+func (r *goquRepository) GetJobs(ctx context.Context) ([]*Job, error) {
+	jobs := make([]*Job, 0)
+
+	err := r.builder.
+		Select(
+			goqu.I("id"),
+			goqu.I("type"),
+			goqu.I("company_id"),
+			goqu.I("payload"),
+			goqu.I("runs_at"),
+			goqu.I("repeats_every"),
+			goqu.I("attempts"),
+			goqu.I("retry_at"),
+			goqu.I("paused"),
+		).
+		From(goqu.T("scheduled_jobs")).
+		Order(goqu.I("runs_at").Asc()).
+		ScanStructsContext(ctx, &jobs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (r *goquRepository) SaveJob(ctx context.Context, job *Job) error {
+	result, err := r.builder.
+		Update(goqu.T("scheduled_jobs")).
+		Set(goqu.Record{
+			"type":          job.Type,
+			"company_id":    job.CompanyId,
+			"payload":       job.Payload,
+			"runs_at":       job.RunsAt,
+			"repeats_every": job.RepeatsEvery,
+			"attempts":      job.Attempts,
+			"retry_at":      job.RetryAt,
+			"paused":        job.Paused,
+		}).
+		Where(goqu.I("id").Eq(job.Id)).
+		Executor().
+		ExecContext(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+		return err
+	}
+
+	_, err = r.builder.
+		Insert(goqu.T("scheduled_jobs")).
+		Rows(goqu.Record{
+			"id":            job.Id,
+			"type":          job.Type,
+			"company_id":    job.CompanyId,
+			"payload":       job.Payload,
+			"runs_at":       job.RunsAt,
+			"repeats_every": job.RepeatsEvery,
+			"attempts":      job.Attempts,
+			"retry_at":      job.RetryAt,
+			"paused":        job.Paused,
+		}).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) DeleteJob(ctx context.Context, id string) error {
+	_, err := r.builder.
+		Delete(goqu.T("scheduled_jobs")).
+		Where(goqu.I("id").Eq(id)).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) GetDeadJobs(ctx context.Context) ([]*DeadJob, error) {
+	deadJobs := make([]*DeadJob, 0)
+
+	err := r.builder.
+		Select(
+			goqu.I("id"),
+			goqu.I("job_id"),
+			goqu.I("type"),
+			goqu.I("company_id"),
+			goqu.I("payload"),
+			goqu.I("attempts"),
+			goqu.I("error"),
+			goqu.I("failed_at"),
+		).
+		From(goqu.T("dead_jobs")).
+		Order(goqu.I("failed_at").Desc()).
+		ScanStructsContext(ctx, &deadJobs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return deadJobs, nil
+}
+
+func (r *goquRepository) GetDeadJob(ctx context.Context, id int64) (*DeadJob, error) {
+	deadJob := new(DeadJob)
+
+	found, err := r.builder.
+		Select(
+			goqu.I("id"),
+			goqu.I("job_id"),
+			goqu.I("type"),
+			goqu.I("company_id"),
+			goqu.I("payload"),
+			goqu.I("attempts"),
+			goqu.I("error"),
+			goqu.I("failed_at"),
+		).
+		From(goqu.T("dead_jobs")).
+		Where(goqu.I("id").Eq(id)).
+		ScanStructContext(ctx, deadJob)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return deadJob, nil
+}
+
+func (r *goquRepository) SaveDeadJob(ctx context.Context, deadJob *DeadJob) error {
+	if deadJob.Id != 0 {
+		_, err := r.builder.
+			Update(goqu.T("dead_jobs")).
+			Set(goqu.Record{
+				"attempts":  deadJob.Attempts,
+				"error":     deadJob.Error,
+				"failed_at": deadJob.FailedAt,
+			}).
+			Where(goqu.I("id").Eq(deadJob.Id)).
+			Executor().
+			ExecContext(ctx)
+
+		return err
+	}
+
+	id, err := database.InsertId(ctx, r.builder.
+		Insert(goqu.T("dead_jobs")).
+		Rows(deadJob))
+
+	if err != nil {
+		return err
+	}
+
+	deadJob.Id = id
+	return nil
+}
+
+func (r *goquRepository) DeleteDeadJob(ctx context.Context, id int64) error {
+	_, err := r.builder.
+		Delete(goqu.T("dead_jobs")).
+		Where(goqu.I("id").Eq(id)).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) GetRuns(ctx context.Context, page, limit uint) ([]*JobRun, error) {
+	runs := make([]*JobRun, 0)
+
+	err := r.builder.
+		Select(goqu.Star()).
+		From(goqu.T("job_runs")).
+		Order(goqu.I("started_at").Desc(), goqu.I("id").Desc()).
+		Limit(limit).
+		Offset(page*limit).
+		ScanStructsContext(ctx, &runs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func (r *goquRepository) GetLastSuccessfulRun(ctx context.Context, name string) (*JobRun, error) {
+	run := new(JobRun)
+
+	found, err := r.builder.
+		Select(goqu.Star()).
+		From(goqu.T("job_runs")).
+		Where(goqu.And(
+			goqu.I("name").Eq(name),
+			goqu.I("error").IsNull(),
+		)).
+		Order(goqu.I("scheduled_at").Desc()).
+		Limit(1).
+		ScanStructContext(ctx, run)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func (r *goquRepository) SaveRun(ctx context.Context, run *JobRun) error {
+	id, err := database.InsertId(ctx, r.builder.
+		Insert(goqu.T("job_runs")).
+		Rows(run))
+
+	if err != nil {
+		return err
+	}
+
+	run.Id = id
+	return nil
+}
@@ -0,0 +1,304 @@
+package scheduler_test
+
+import (
+	"api/database"
+	"api/scheduler"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRepository(t *testing.T) {
+	conn, err := database.GetTestConnection("../test.db")
+	if err != nil {
+		t.Fatalf("could not connect to database: %s", err)
+	}
+
+	if _, err := conn.DB.Exec(`
+        INSERT INTO scheduled_jobs (id, type, payload, runs_at, repeats_every) VALUES
+        ('LOAN_1', 'loans.interest', '{"loan_id":1}', '2024-01-15 10:00:00', 604800000000000),
+        ('PRODUCTION_1', 'production.collect', '{"production_id":1}', '2024-01-08 10:00:00', 0)
+    `); err != nil {
+		t.Fatalf("could not seed database: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := conn.DB.Exec(`DELETE FROM scheduled_jobs`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec(`DELETE FROM dead_jobs`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+		if _, err := conn.DB.Exec(`DELETE FROM job_runs`); err != nil {
+			t.Fatalf("could not cleanup database: %s", err)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repository := scheduler.NewRepository(conn)
+
+	t.Run("GetJobs", func(t *testing.T) {
+		jobs, err := repository.GetJobs(ctx)
+		if err != nil {
+			t.Fatalf("could not get jobs: %s", err)
+		}
+
+		if len(jobs) != 2 {
+			t.Fatalf("expected %d jobs, got %d", 2, len(jobs))
+		}
+
+		if jobs[0].Id != "PRODUCTION_1" {
+			t.Errorf("expected earliest job first, got %s", jobs[0].Id)
+		}
+
+		if jobs[1].RepeatsEvery != 7*24*time.Hour {
+			t.Errorf("expected interval %s, got %s", 7*24*time.Hour, jobs[1].RepeatsEvery)
+		}
+
+		var payload struct {
+			LoanId int64 `json:"loan_id"`
+		}
+
+		if err := jobs[1].Decode(&payload); err != nil {
+			t.Fatalf("could not decode payload: %s", err)
+		}
+
+		if payload.LoanId != 1 {
+			t.Errorf("expected loan id %d, got %d", 1, payload.LoanId)
+		}
+	})
+
+	t.Run("SaveJob", func(t *testing.T) {
+		runsAt := time.Date(2024, 1, 22, 10, 0, 0, 0, time.UTC)
+
+		job, err := scheduler.NewJob("LOAN_1", "loans.interest", 0, map[string]int{"loan_id": 1}, runsAt)
+		if err != nil {
+			t.Fatalf("could not create job: %s", err)
+		}
+
+		if err := repository.SaveJob(ctx, job); err != nil {
+			t.Fatalf("could not update job: %s", err)
+		}
+
+		job, err = scheduler.NewJob("RESEARCH_1", "research.complete", 1, map[string]int{"research_id": 1}, runsAt)
+		if err != nil {
+			t.Fatalf("could not create job: %s", err)
+		}
+
+		if err := repository.SaveJob(ctx, job); err != nil {
+			t.Fatalf("could not insert job: %s", err)
+		}
+
+		jobs, err := repository.GetJobs(ctx)
+		if err != nil {
+			t.Fatalf("could not get jobs: %s", err)
+		}
+
+		if len(jobs) != 3 {
+			t.Fatalf("expected %d jobs, got %d", 3, len(jobs))
+		}
+
+		for _, job := range jobs {
+			if job.Id == "LOAN_1" && !job.RunsAt.Equal(runsAt) {
+				t.Errorf("expected runs at %s, got %s", runsAt, job.RunsAt)
+			}
+			if job.Id == "RESEARCH_1" && job.CompanyId != 1 {
+				t.Errorf("expected company %d, got %d", 1, job.CompanyId)
+			}
+		}
+	})
+
+	t.Run("SaveJob retry", func(t *testing.T) {
+		retryAt := time.Date(2024, 1, 22, 10, 5, 0, 0, time.UTC)
+
+		job, err := scheduler.NewJob("LOAN_1", "loans.interest", 0, map[string]int{"loan_id": 1}, retryAt.Add(-5*time.Minute))
+		if err != nil {
+			t.Fatalf("could not create job: %s", err)
+		}
+
+		job.Attempts = 2
+		job.RetryAt = &retryAt
+
+		if err := repository.SaveJob(ctx, job); err != nil {
+			t.Fatalf("could not update job: %s", err)
+		}
+
+		jobs, err := repository.GetJobs(ctx)
+		if err != nil {
+			t.Fatalf("could not get jobs: %s", err)
+		}
+
+		for _, job := range jobs {
+			if job.Id != "LOAN_1" {
+				continue
+			}
+
+			if job.Attempts != 2 {
+				t.Errorf("expected %d attempts, got %d", 2, job.Attempts)
+			}
+
+			if job.RetryAt == nil || !job.RetryAt.Equal(retryAt) {
+				t.Errorf("expected retry at %s, got %v", retryAt, job.RetryAt)
+			}
+		}
+	})
+
+	t.Run("DeleteJob", func(t *testing.T) {
+		if err := repository.DeleteJob(ctx, "RESEARCH_1"); err != nil {
+			t.Fatalf("could not delete job: %s", err)
+		}
+
+		jobs, err := repository.GetJobs(ctx)
+		if err != nil {
+			t.Fatalf("could not get jobs: %s", err)
+		}
+
+		if len(jobs) != 2 {
+			t.Errorf("expected %d jobs, got %d", 2, len(jobs))
+		}
+	})
+
+	t.Run("DeadJobs", func(t *testing.T) {
+		deadJob := &scheduler.DeadJob{
+			JobId:     "LOAN_1",
+			Type:      "loans.interest",
+			CompanyId: 1,
+			Payload:   `{"loan_id":1}`,
+			Attempts:  5,
+			Error:     "not enough cash",
+			FailedAt:  time.Date(2024, 1, 22, 11, 0, 0, 0, time.UTC),
+		}
+
+		t.Run("SaveDeadJob", func(t *testing.T) {
+			if err := repository.SaveDeadJob(ctx, deadJob); err != nil {
+				t.Fatalf("could not save dead job: %s", err)
+			}
+
+			if deadJob.Id == 0 {
+				t.Fatal("should set dead job id")
+			}
+
+			deadJob.Attempts = 6
+			deadJob.Error = "still not enough cash"
+
+			if err := repository.SaveDeadJob(ctx, deadJob); err != nil {
+				t.Fatalf("could not update dead job: %s", err)
+			}
+
+			found, err := repository.GetDeadJob(ctx, deadJob.Id)
+			if err != nil {
+				t.Fatalf("could not get dead job: %s", err)
+			}
+
+			if found.Attempts != 6 {
+				t.Errorf("expected %d attempts, got %d", 6, found.Attempts)
+			}
+
+			if found.Error != "still not enough cash" {
+				t.Errorf("expected error %s, got %s", "still not enough cash", found.Error)
+			}
+
+			if found.CompanyId != 1 {
+				t.Errorf("expected company %d, got %d", 1, found.CompanyId)
+			}
+		})
+
+		t.Run("GetDeadJobs", func(t *testing.T) {
+			deadJobs, err := repository.GetDeadJobs(ctx)
+			if err != nil {
+				t.Fatalf("could not get dead jobs: %s", err)
+			}
+
+			if len(deadJobs) != 1 {
+				t.Fatalf("expected %d dead job, got %d", 1, len(deadJobs))
+			}
+
+			if deadJobs[0].JobId != "LOAN_1" {
+				t.Errorf("expected job id %s, got %s", "LOAN_1", deadJobs[0].JobId)
+			}
+		})
+
+		t.Run("DeleteDeadJob", func(t *testing.T) {
+			if err := repository.DeleteDeadJob(ctx, deadJob.Id); err != nil {
+				t.Fatalf("could not delete dead job: %s", err)
+			}
+
+			found, err := repository.GetDeadJob(ctx, deadJob.Id)
+			if err != nil {
+				t.Fatalf("could not get dead job: %s", err)
+			}
+
+			if found != nil {
+				t.Errorf("should have deleted dead job, got %+v", found)
+			}
+		})
+	})
+
+	t.Run("Runs", func(t *testing.T) {
+		failure := "no prices"
+		runs := []*scheduler.JobRun{
+			{Name: "taxes", ScheduledAt: time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+			{Name: "taxes", ScheduledAt: time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC), Error: &failure},
+			{Name: "rates", ScheduledAt: time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		}
+
+		for i, run := range runs {
+			run.StartedAt = run.ScheduledAt.Add(time.Duration(i) * time.Second)
+			run.FinishedAt = run.StartedAt.Add(time.Second)
+
+			if err := repository.SaveRun(ctx, run); err != nil {
+				t.Fatalf("could not save run: %s", err)
+			}
+
+			if run.Id == 0 {
+				t.Fatal("should set run id")
+			}
+		}
+
+		t.Run("GetLastSuccessfulRun", func(t *testing.T) {
+			last, err := repository.GetLastSuccessfulRun(ctx, "taxes")
+			if err != nil {
+				t.Fatalf("could not get last run: %s", err)
+			}
+
+			if !last.ScheduledAt.Equal(runs[0].ScheduledAt) {
+				t.Errorf("expected last successful run at %s, got %s", runs[0].ScheduledAt, last.ScheduledAt)
+			}
+
+			last, err = repository.GetLastSuccessfulRun(ctx, "payroll")
+			if err != nil {
+				t.Fatalf("could not get last run: %s", err)
+			}
+
+			if last != nil {
+				t.Errorf("expected no run, got %+v", last)
+			}
+		})
+
+		t.Run("GetRuns", func(t *testing.T) {
+			found, err := repository.GetRuns(ctx, 0, 2)
+			if err != nil {
+				t.Fatalf("could not get runs: %s", err)
+			}
+
+			if len(found) != 2 {
+				t.Fatalf("expected %d runs, got %d", 2, len(found))
+			}
+
+			if found[0].Name != "rates" {
+				t.Errorf("expected latest run first, got %s", found[0].Name)
+			}
+
+			found, err = repository.GetRuns(ctx, 1, 2)
+			if err != nil {
+				t.Fatalf("could not get runs: %s", err)
+			}
+
+			if len(found) != 1 {
+				t.Errorf("expected %d run, got %d", 1, len(found))
+			}
+		})
+	})
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how many times a failing job runs and how long
+// the scheduler waits between attempts. Delays grow exponentially from
+// InitialDelay up to MaxDelay, and Jitter (0 to 1) randomly shortens
+// them so jobs failing together don't retry all at once.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  5,
+	InitialDelay: time.Second,
+	MaxDelay:     time.Hour,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// Returns how long to wait before the next attempt after the given
+// number of failed attempts
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	multiplier := math.Max(1, p.Multiplier)
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(max(0, attempts-1)))
+
+	if p.MaxDelay > 0 {
+		delay = math.Min(delay, float64(p.MaxDelay))
+	}
+
+	if p.Jitter > 0 {
+		delay -= delay * math.Min(1, p.Jitter) * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
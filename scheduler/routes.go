@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"api/auth"
+	"api/server"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func CreateEndpoints(e *echo.Echo, timer *Scheduler) {
+	pending := e.GET("/companies/:id/jobs", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest)
+		}
+
+		jobs := timer.GetPendingJobs(JobFilter{CompanyId: int64(companyId)})
+		return c.JSON(http.StatusOK, jobs)
+	}, server.RequireOwner(":id"))
+
+	server.AllowMembers(auth.SCOPE_COMPANY_READ, pending)
+
+	group := e.Group("/admin/jobs")
+	group.Use(server.RequireRole(auth.ROLE_ADMIN))
+
+	group.GET("", func(c echo.Context) error {
+		filter := JobFilter{Type: c.QueryParam("type")}
+
+		if param := c.QueryParam("company_id"); param != "" {
+			companyId, err := strconv.ParseInt(param, 10, 64)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+			filter.CompanyId = companyId
+		}
+
+		if param := c.QueryParam("due_before"); param != "" {
+			dueBefore, err := time.Parse(time.RFC3339, param)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+			filter.DueBefore = dueBefore
+		}
+
+		if param := c.QueryParam("failing"); param != "" {
+			failing, err := strconv.ParseBool(param)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+			filter.Failing = failing
+		}
+
+		return c.JSON(http.StatusOK, timer.GetPendingJobs(filter))
+	})
+
+	group.GET("/runs", func(c echo.Context) error {
+		page, err := strconv.ParseUint(c.QueryParam("page"), 10, 64)
+		if err != nil || page == 0 {
+			page = 1
+		}
+
+		limit, err := strconv.ParseUint(c.QueryParam("limit"), 10, 64)
+		if err != nil || limit == 0 {
+			limit = 50
+		}
+
+		runs, err := timer.GetRuns(c.Request().Context(), uint(page-1), uint(limit))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, runs)
+	})
+
+	group.GET("/dead", func(c echo.Context) error {
+		deadJobs, err := timer.GetDeadJobs(c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, deadJobs)
+	})
+
+	group.POST("/dead/:id/retry", func(c echo.Context) error {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest)
+		}
+
+		if err := timer.RetryDeadJob(c.Request().Context(), id); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	group.DELETE("/dead/:id", func(c echo.Context) error {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest)
+		}
+
+		if err := timer.DiscardDeadJob(c.Request().Context(), id); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+}
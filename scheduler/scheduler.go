@@ -1,76 +1,577 @@
 package scheduler
 
 import (
+	"api/clock"
+	"api/server"
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 )
 
 type (
+	// Handler runs a persisted job. Handlers are looked up by the job's
+	// type when it fires, so they must be registered before jobs are
+	// restored from the repository.
+	Handler func(job *Job) error
+
+	Job struct {
+		Id           string        `db:"id" json:"id"`
+		Type         string        `db:"type" json:"type"`
+		CompanyId    int64         `db:"company_id" json:"company_id"`
+		Payload      string        `db:"payload" json:"payload"`
+		RunsAt       time.Time     `db:"runs_at" json:"runs_at"`
+		RepeatsEvery time.Duration `db:"repeats_every" json:"repeats_every"`
+		Attempts     int           `db:"attempts" json:"attempts"`
+		RetryAt      *time.Time    `db:"retry_at" json:"retry_at"`
+
+		// Paused jobs stay persisted but don't fire until they're resumed
+		Paused bool `db:"paused" json:"paused"`
+	}
+
+	// DeadJob is a job that kept failing after every attempt its retry
+	// policy allowed. It stays around until an admin retries or discards it.
+	DeadJob struct {
+		Id        int64     `db:"id" goqu:"skipinsert" json:"id"`
+		JobId     string    `db:"job_id" json:"job_id"`
+		Type      string    `db:"type" json:"type"`
+		CompanyId int64     `db:"company_id" json:"company_id"`
+		Payload   string    `db:"payload" json:"payload"`
+		Attempts  int       `db:"attempts" json:"attempts"`
+		Error     string    `db:"error" json:"error"`
+		FailedAt  time.Time `db:"failed_at" json:"failed_at"`
+	}
+
+	// PendingJob describes a job waiting to run
+	PendingJob struct {
+		Id           string        `json:"id"`
+		Type         string        `json:"type"`
+		CompanyId    int64         `json:"company_id"`
+		DueAt        time.Time     `json:"due_at"`
+		Attempts     int           `json:"attempts"`
+		RepeatsEvery time.Duration `json:"repeats_every"`
+		Paused       bool          `json:"paused"`
+	}
+
+	// JobFilter narrows the pending jobs listed. Zero values match any job.
+	JobFilter struct {
+		CompanyId int64
+		Type      string
+		DueBefore time.Time
+		Failing   bool
+	}
+
 	Scheduler struct {
-		retries *sync.Map
-		timers  *sync.Map
-		tickers *sync.Map
+		// Guards the fields of jobs changed after a run so they can be
+		// listed while the jobs are running
+		mutex sync.RWMutex
+
+		engine     *engine
+		jobs       *sync.Map
+		handlers   *sync.Map
+		world      *sync.Map
+		repository Repository
+		clock      clock.Clock
+	}
+
+	registration struct {
+		handler Handler
+		policy  RetryPolicy
 	}
 )
 
+var ErrDeadJobNotFound = server.NewBusinessRuleError("dead job not found")
+
+// Creates a job of the given type, owned by companyId, whose payload is
+// encoded as JSON. World jobs that belong to no company use zero.
+func NewJob(id, jobType string, companyId int64, payload any, runsAt time.Time) (*Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Job{
+		Id:        id,
+		Type:      jobType,
+		CompanyId: companyId,
+		Payload:   string(encoded),
+		RunsAt:    runsAt,
+	}, nil
+}
+
+// Decodes the job's payload into v
+func (j *Job) Decode(v any) error {
+	return json.Unmarshal([]byte(j.Payload), v)
+}
+
+// Creates an in-memory scheduler running on real time. Jobs are lost when
+// the process exits.
 func NewScheduler() *Scheduler {
+	return NewPersistentScheduler(nil, clock.New())
+}
+
+// Creates a scheduler that stores its jobs in the given repository so
+// they can be restored after a restart. Durations are measured in the
+// given clock's time.
+func NewPersistentScheduler(repository Repository, clock clock.Clock) *Scheduler {
 	return &Scheduler{
-		retries: &sync.Map{},
-		timers:  &sync.Map{},
-		tickers: &sync.Map{},
+		engine:     newEngine(clock, WORKERS),
+		jobs:       &sync.Map{},
+		handlers:   &sync.Map{},
+		world:      &sync.Map{},
+		repository: repository,
+		clock:      clock,
 	}
 }
 
-func (s *Scheduler) Add(id any, duration time.Duration, callback func() error) {
-	s.timers.Store(id, time.AfterFunc(duration, func() {
-		s.timers.Delete(id)
+// Returns the current time according to the scheduler's clock
+func (s *Scheduler) Now() time.Time {
+	return s.clock.Now()
+}
 
-		if err := callback(); err != nil {
-			log.Println("error, retrying")
+// Registers the handler responsible for running jobs of the given type,
+// retrying them with the default policy
+func (s *Scheduler) Register(jobType string, handler Handler) {
+	s.RegisterWithPolicy(jobType, handler, DefaultRetryPolicy)
+}
 
-			s.retries.Store(id, time.AfterFunc(time.Second, func() {
-				s.retries.Delete(id)
+// Registers the handler responsible for running jobs of the given type,
+// retrying failed jobs according to policy
+func (s *Scheduler) RegisterWithPolicy(jobType string, handler Handler, policy RetryPolicy) {
+	s.handlers.Store(jobType, registration{handler, policy})
+}
 
-				if err := callback(); err != nil {
-					log.Printf("could not run callback: %s", id)
-				}
-			}))
+// Persists the job and arms its timer. A job with the same id replaces
+// the previous one.
+func (s *Scheduler) Schedule(ctx context.Context, job *Job) error {
+	if _, found := s.handlers.Load(job.Type); !found {
+		return fmt.Errorf("no handler registered for job type %s", job.Type)
+	}
+
+	if s.repository != nil {
+		if err := s.repository.SaveJob(ctx, job); err != nil {
+			return err
 		}
-	}))
+	}
+
+	s.stop(job.Id)
+	s.jobs.Store(job.Id, job)
+	s.arm(job)
+
+	return nil
+}
+
+// Loads every pending job from the repository and arms its timer. Jobs
+// that should have run while the server was down run immediately.
+func (s *Scheduler) Restore(ctx context.Context) error {
+	if s.repository == nil {
+		return nil
+	}
+
+	jobs, err := s.repository.GetJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if _, found := s.handlers.Load(job.Type); !found {
+			log.Printf("no handler registered for job type %s, skipping %s", job.Type, job.Id)
+			continue
+		}
+
+		s.jobs.Store(job.Id, job)
+		s.arm(job)
+	}
+
+	return nil
+}
+
+// Runs callback once after duration, retrying it with the default policy
+// if it fails. Callbacks are not persisted.
+func (s *Scheduler) Add(id any, duration time.Duration, callback func() error) {
+	s.add(id, duration, 1, callback)
 }
 
 func (s *Scheduler) Remove(id any) {
-	if retry, found := s.retries.LoadAndDelete(id); found {
-		timer := retry.(*time.Timer)
-		if !timer.Stop() {
-			<-timer.C
+	s.stop(id)
+
+	if _, found := s.jobs.LoadAndDelete(id); found && s.repository != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		if err := s.repository.DeleteJob(ctx, id.(string)); err != nil {
+			log.Printf("could not delete job %s: %s", id, err)
 		}
 	}
+}
 
-	if timer, found := s.timers.LoadAndDelete(id); found {
-		if !timer.(*time.Timer).Stop() {
-			<-timer.(*time.Timer).C
+// Removes the persisted jobs owned by the company, returning how many
+// there were. World jobs can't be removed this way.
+func (s *Scheduler) RemoveCompanyJobs(companyId int64) int {
+	if companyId == 0 {
+		return 0
+	}
+
+	ids := make([]string, 0)
+	s.jobs.Range(func(_, value any) bool {
+		if job := value.(*Job); job.CompanyId == companyId {
+			ids = append(ids, job.Id)
 		}
+		return true
+	})
+
+	for _, id := range ids {
+		s.Remove(id)
 	}
 
-	if ticker, found := s.tickers.LoadAndDelete(id); found {
-		ticker.(*time.Ticker).Stop()
+	return len(ids)
+}
+
+// Stops the timers of the jobs owned by the company but keeps them, so
+// they're armed again when resumed. Returns how many were paused.
+func (s *Scheduler) PauseCompanyJobs(companyId int64) int {
+	return s.setPaused(companyId, true)
+}
+
+// Arms the paused jobs owned by the company again, returning how many
+// there were. Jobs that came due while paused run right away.
+func (s *Scheduler) ResumeCompanyJobs(companyId int64) int {
+	return s.setPaused(companyId, false)
+}
+
+func (s *Scheduler) setPaused(companyId int64, paused bool) int {
+	if companyId == 0 {
+		return 0
+	}
+
+	jobs := make([]*Job, 0)
+	s.jobs.Range(func(_, value any) bool {
+		if job := value.(*Job); job.CompanyId == companyId {
+			jobs = append(jobs, job)
+		}
+		return true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	changed := 0
+	for _, job := range jobs {
+		s.mutex.Lock()
+		if job.Paused == paused {
+			s.mutex.Unlock()
+			continue
+		}
+		job.Paused = paused
+		s.mutex.Unlock()
+
+		if s.repository != nil {
+			if err := s.repository.SaveJob(ctx, job); err != nil {
+				log.Printf("could not save job %s: %s", job.Id, err)
+			}
+		}
+
+		if paused {
+			s.stop(job.Id)
+		} else {
+			s.arm(job)
+		}
+
+		changed++
 	}
+
+	return changed
 }
 
+// Runs callback every duration until the id is removed. Runs are skipped
+// while the previous one is still going.
 func (s *Scheduler) Repeat(id any, duration time.Duration, callback func() error) {
-	ticker := time.NewTicker(duration)
-	s.tickers.Store(id, ticker)
-
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				if err := callback(); err != nil {
-					s.Remove(id)
+	s.engine.schedule(id, s.clock.Now().Add(duration), duration, func() {
+		if err := callback(); err != nil {
+			log.Printf("could not run repeating callback %v: %s", id, err)
+		}
+	})
+}
+
+// Returns how many timers are waiting to fire
+func (s *Scheduler) Pending() int {
+	return s.engine.pending()
+}
+
+// Returns the persisted jobs waiting to run that match the filter, the
+// ones due first coming first
+func (s *Scheduler) GetPendingJobs(filter JobFilter) []*PendingJob {
+	pending := make([]*PendingJob, 0)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	s.jobs.Range(func(_, value any) bool {
+		job := value.(*Job)
+
+		dueAt := job.RunsAt
+		if job.RetryAt != nil {
+			dueAt = *job.RetryAt
+		}
+
+		if filter.CompanyId != 0 && job.CompanyId != filter.CompanyId {
+			return true
+		}
+		if filter.Type != "" && job.Type != filter.Type {
+			return true
+		}
+		if !filter.DueBefore.IsZero() && !dueAt.Before(filter.DueBefore) {
+			return true
+		}
+		if filter.Failing && job.Attempts == 0 {
+			return true
+		}
+
+		pending = append(pending, &PendingJob{
+			Id:           job.Id,
+			Type:         job.Type,
+			CompanyId:    job.CompanyId,
+			DueAt:        dueAt,
+			Attempts:     job.Attempts,
+			RepeatsEvery: job.RepeatsEvery,
+			Paused:       job.Paused,
+		})
+
+		return true
+	})
+
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].DueAt.Equal(pending[j].DueAt) {
+			return pending[i].Id < pending[j].Id
+		}
+		return pending[i].DueAt.Before(pending[j].DueAt)
+	})
+
+	return pending
+}
+
+// Stops firing timers. Persisted jobs are kept and can be restored by
+// another scheduler.
+func (s *Scheduler) Stop() {
+	s.engine.stop()
+}
+
+// Stops firing timers and waits for the callbacks already running to
+// return, so their changes are saved before the database is closed. Jobs
+// that didn't get to run stay persisted and are restored on next start.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	return s.engine.shutdown(ctx)
+}
+
+func (s *Scheduler) add(id any, duration time.Duration, attempt int, callback func() error) {
+	s.engine.schedule(id, s.clock.Now().Add(duration), 0, func() {
+		err := callback()
+		if err == nil {
+			return
+		}
+
+		if attempt >= DefaultRetryPolicy.MaxAttempts {
+			log.Printf("could not run callback %v after %d attempts: %s", id, attempt, err)
+			return
+		}
+
+		delay := DefaultRetryPolicy.Backoff(attempt)
+		log.Printf("could not run callback %v, retrying in %s: %s", id, delay, err)
+
+		s.add(id, delay, attempt+1, callback)
+	})
+}
+
+func (s *Scheduler) stop(id any) {
+	s.engine.cancel(id)
+}
+
+// Sets the job's timer unless it's paused, a job paused while running
+// is left alone once it completes
+func (s *Scheduler) arm(job *Job) {
+	s.mutex.RLock()
+	paused := job.Paused
+	runsAt := job.RunsAt
+	if job.RetryAt != nil {
+		runsAt = *job.RetryAt
+	}
+	s.mutex.RUnlock()
+
+	if paused {
+		return
+	}
+
+	s.engine.schedule(job.Id, runsAt, 0, func() {
+		s.complete(job, s.run(job))
+	})
+}
+
+func (s *Scheduler) run(job *Job) error {
+	registered, found := s.handlers.Load(job.Type)
+	if !found {
+		return fmt.Errorf("no handler registered for job type %s", job.Type)
+	}
+	return registered.(registration).handler(job)
+}
+
+func (s *Scheduler) policy(jobType string) RetryPolicy {
+	if registered, found := s.handlers.Load(jobType); found {
+		return registered.(registration).policy
+	}
+	return DefaultRetryPolicy
+}
+
+// Retries failed jobs, reschedules repeating jobs and forgets about the
+// ones that are done. Jobs removed while running are not brought back.
+func (s *Scheduler) complete(job *Job, err error) {
+	if current, found := s.jobs.Load(job.Id); !found || current != job {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err != nil {
+		s.mutex.Lock()
+		job.Attempts++
+		attempts := job.Attempts
+		s.mutex.Unlock()
+
+		if policy := s.policy(job.Type); attempts < policy.MaxAttempts {
+			retryAt := s.clock.Now().Add(policy.Backoff(attempts))
+
+			s.mutex.Lock()
+			job.RetryAt = &retryAt
+			s.mutex.Unlock()
+
+			log.Printf("could not run job %s, retrying at %s: %s", job.Id, retryAt, err)
+
+			if s.repository != nil {
+				if err := s.repository.SaveJob(ctx, job); err != nil {
+					log.Printf("could not save job %s: %s", job.Id, err)
 				}
 			}
+
+			s.arm(job)
+			return
 		}
-	}()
+
+		log.Printf("could not run job %s after %d attempts: %s", job.Id, job.Attempts, err)
+		s.bury(ctx, job, err)
+	}
+
+	// A repeating job that failed for good only loses this run, the next
+	// ones are still due
+	if job.RepeatsEvery > 0 {
+		s.mutex.Lock()
+		job.RunsAt = job.RunsAt.Add(job.RepeatsEvery)
+		job.RetryAt = nil
+		job.Attempts = 0
+		s.mutex.Unlock()
+
+		if s.repository != nil {
+			if err := s.repository.SaveJob(ctx, job); err != nil {
+				log.Printf("could not reschedule job %s: %s", job.Id, err)
+			}
+		}
+
+		s.arm(job)
+		return
+	}
+
+	s.jobs.Delete(job.Id)
+
+	if s.repository != nil {
+		if err := s.repository.DeleteJob(ctx, job.Id); err != nil {
+			log.Printf("could not delete job %s: %s", job.Id, err)
+		}
+	}
+}
+
+// Moves a job that failed for good to the dead letter table
+func (s *Scheduler) bury(ctx context.Context, job *Job, err error) {
+	if s.repository == nil {
+		return
+	}
+
+	deadJob := &DeadJob{
+		JobId:     job.Id,
+		Type:      job.Type,
+		CompanyId: job.CompanyId,
+		Payload:   job.Payload,
+		Attempts:  job.Attempts,
+		Error:     err.Error(),
+		FailedAt:  s.clock.Now(),
+	}
+
+	if err := s.repository.SaveDeadJob(ctx, deadJob); err != nil {
+		log.Printf("could not save dead job %s: %s", job.Id, err)
+	}
+}
+
+// Returns the jobs that failed after every attempt
+func (s *Scheduler) GetDeadJobs(ctx context.Context) ([]*DeadJob, error) {
+	if s.repository == nil {
+		return []*DeadJob{}, nil
+	}
+	return s.repository.GetDeadJobs(ctx)
+}
+
+// Runs a dead job right away, removing it from the dead letter table if
+// it succeeds. Otherwise its error is updated and returned.
+func (s *Scheduler) RetryDeadJob(ctx context.Context, id int64) error {
+	deadJob, err := s.getDeadJob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	job := &Job{
+		Id:        deadJob.JobId,
+		Type:      deadJob.Type,
+		CompanyId: deadJob.CompanyId,
+		Payload:   deadJob.Payload,
+		RunsAt:    s.clock.Now(),
+	}
+
+	if runErr := s.run(job); runErr != nil {
+		deadJob.Attempts++
+		deadJob.Error = runErr.Error()
+		deadJob.FailedAt = s.clock.Now()
+
+		if err := s.repository.SaveDeadJob(ctx, deadJob); err != nil {
+			return err
+		}
+
+		return runErr
+	}
+
+	return s.repository.DeleteDeadJob(ctx, id)
+}
+
+// Removes a dead job without running it
+func (s *Scheduler) DiscardDeadJob(ctx context.Context, id int64) error {
+	if _, err := s.getDeadJob(ctx, id); err != nil {
+		return err
+	}
+	return s.repository.DeleteDeadJob(ctx, id)
+}
+
+func (s *Scheduler) getDeadJob(ctx context.Context, id int64) (*DeadJob, error) {
+	if s.repository == nil {
+		return nil, ErrDeadJobNotFound
+	}
+
+	deadJob, err := s.repository.GetDeadJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadJob == nil {
+		return nil, ErrDeadJobNotFound
+	}
+
+	return deadJob, nil
 }
@@ -1,8 +1,12 @@
 package scheduler_test
 
 import (
+	"api/clock"
 	"api/scheduler"
+	"context"
 	"errors"
+	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -64,20 +68,719 @@ func TestScheduler(t *testing.T) {
 	})
 
 	t.Run("Repeat", func(t *testing.T) {
-		counter := make(chan int, 1)
-		scheduler := scheduler.NewScheduler()
+		var runs atomic.Int32
+		gameClock := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		scheduler := scheduler.NewPersistentScheduler(nil, gameClock)
+		defer scheduler.Stop()
 
 		scheduler.Repeat(1, 10*time.Millisecond, func() error {
-			counter <- <-counter + 1
+			runs.Add(1)
 			return nil
 		})
 
-		counter <- 0
-		time.Sleep(105 * time.Millisecond)
+		for i := int32(1); i <= 10; i++ {
+			gameClock.Advance(10 * time.Millisecond)
+			waitFor(t, func() bool { return runs.Load() == i })
+		}
 
-		total := <-counter
-		if total != 10 {
+		if total := runs.Load(); total != 10 {
 			t.Errorf("expected %d, got %d", 10, total)
 		}
 	})
+
+	t.Run("Repeat keeps going after errors", func(t *testing.T) {
+		var runs atomic.Int32
+		gameClock := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		scheduler := scheduler.NewPersistentScheduler(nil, gameClock)
+		defer scheduler.Stop()
+
+		scheduler.Repeat(2, 10*time.Millisecond, func() error {
+			runs.Add(1)
+			return errors.New("nope")
+		})
+
+		for i := int32(1); i <= 2; i++ {
+			gameClock.Advance(10 * time.Millisecond)
+			waitFor(t, func() bool { return runs.Load() == i })
+		}
+	})
+
+	t.Run("Pending", func(t *testing.T) {
+		scheduler := scheduler.NewScheduler()
+		defer scheduler.Stop()
+
+		goroutines := runtime.NumGoroutine()
+
+		for i := 0; i < 1000; i++ {
+			scheduler.Add(i, time.Hour, func() error { return nil })
+			scheduler.Repeat(-i-1, time.Hour, func() error { return nil })
+		}
+
+		if pending := scheduler.Pending(); pending != 2000 {
+			t.Errorf("expected %d pending timers, got %d", 2000, pending)
+		}
+
+		if created := runtime.NumGoroutine() - goroutines; created > 0 {
+			t.Errorf("should not spawn goroutines per timer, got %d new", created)
+		}
+
+		for i := 0; i < 1000; i++ {
+			scheduler.Remove(i)
+			scheduler.Remove(-i - 1)
+		}
+
+		if pending := scheduler.Pending(); pending != 0 {
+			t.Errorf("expected no pending timers, got %d", pending)
+		}
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		t.Run("should wait for running callbacks", func(t *testing.T) {
+			started := make(chan bool)
+			finished := make(chan bool, 1)
+			scheduler := scheduler.NewScheduler()
+
+			scheduler.Add(1, 0, func() error {
+				started <- true
+				time.Sleep(30 * time.Millisecond)
+				finished <- true
+				return nil
+			})
+
+			<-started
+			if err := scheduler.Shutdown(context.Background()); err != nil {
+				t.Fatalf("could not shut down: %s", err)
+			}
+
+			select {
+			case <-finished:
+			default:
+				t.Error("expected callback to finish before shutdown returns")
+			}
+		})
+
+		t.Run("should keep pending jobs persisted", func(t *testing.T) {
+			repository := scheduler.NewFakeRepository()
+			timer := scheduler.NewPersistentScheduler(repository, clock.New())
+			timer.Register("later", func(job *scheduler.Job) error { return nil })
+
+			job, _ := scheduler.NewJob("LATER", "later", 0, nil, time.Now().Add(time.Hour))
+			if err := timer.Schedule(context.Background(), job); err != nil {
+				t.Fatalf("could not schedule job: %s", err)
+			}
+
+			if err := timer.Shutdown(context.Background()); err != nil {
+				t.Fatalf("could not shut down: %s", err)
+			}
+
+			jobs, _ := repository.GetJobs(context.Background())
+			if len(jobs) != 1 || jobs[0].Id != "LATER" {
+				t.Errorf("expected pending job to stay persisted, got %+v", jobs)
+			}
+		})
+
+		t.Run("should give up when the context is done", func(t *testing.T) {
+			started := make(chan bool)
+			release := make(chan bool)
+			scheduler := scheduler.NewScheduler()
+			defer close(release)
+
+			scheduler.Add(1, 0, func() error {
+				started <- true
+				<-release
+				return nil
+			})
+
+			<-started
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			if err := scheduler.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected deadline exceeded, got %v", err)
+			}
+		})
+	})
+
+	t.Run("Schedule", func(t *testing.T) {
+		t.Run("should persist and run job", func(t *testing.T) {
+			ran := make(chan string)
+			repository := scheduler.NewFakeRepository()
+			timer := scheduler.NewPersistentScheduler(repository, clock.New())
+
+			timer.Register("greet", func(job *scheduler.Job) error {
+				var payload struct{ Name string }
+				if err := job.Decode(&payload); err != nil {
+					return err
+				}
+				ran <- payload.Name
+				return nil
+			})
+
+			job, err := scheduler.NewJob("GREET_1", "greet", 0, map[string]string{"Name": "John"}, time.Now().Add(50*time.Millisecond))
+			if err != nil {
+				t.Fatalf("could not create job: %s", err)
+			}
+
+			if err := timer.Schedule(context.Background(), job); err != nil {
+				t.Fatalf("could not schedule job: %s", err)
+			}
+
+			jobs, _ := repository.GetJobs(context.Background())
+			if len(jobs) != 1 {
+				t.Fatalf("expected %d persisted job, got %d", 1, len(jobs))
+			}
+
+			if name := <-ran; name != "John" {
+				t.Errorf("expected payload %s, got %s", "John", name)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+
+			jobs, _ = repository.GetJobs(context.Background())
+			if len(jobs) != 0 {
+				t.Errorf("expected job to be deleted, got %d jobs", len(jobs))
+			}
+		})
+
+		t.Run("should follow game clock", func(t *testing.T) {
+			ran := make(chan bool, 1)
+			gameClock := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			timer := scheduler.NewPersistentScheduler(scheduler.NewFakeRepository(), gameClock)
+
+			timer.Register("harvest", func(job *scheduler.Job) error {
+				ran <- true
+				return nil
+			})
+
+			job, _ := scheduler.NewJob("HARVEST_1", "harvest", 0, nil, gameClock.Now().Add(7*24*time.Hour))
+			if err := timer.Schedule(context.Background(), job); err != nil {
+				t.Fatalf("could not schedule job: %s", err)
+			}
+
+			gameClock.Advance(6 * 24 * time.Hour)
+
+			select {
+			case <-ran:
+				t.Fatal("should not run before the week is over")
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			gameClock.Advance(24 * time.Hour)
+
+			select {
+			case <-ran:
+			case <-time.After(100 * time.Millisecond):
+				t.Error("should run once the week is over")
+			}
+		})
+
+		t.Run("should not schedule unknown type", func(t *testing.T) {
+			timer := scheduler.NewScheduler()
+
+			job, err := scheduler.NewJob("UNKNOWN_1", "unknown", 0, nil, time.Now())
+			if err != nil {
+				t.Fatalf("could not create job: %s", err)
+			}
+
+			if err := timer.Schedule(context.Background(), job); err == nil {
+				t.Error("expected error scheduling job without handler")
+			}
+		})
+
+		t.Run("should remove persisted job", func(t *testing.T) {
+			ran := make(chan bool)
+			repository := scheduler.NewFakeRepository()
+			timer := scheduler.NewPersistentScheduler(repository, clock.New())
+
+			timer.Register("noop", func(job *scheduler.Job) error {
+				ran <- true
+				return nil
+			})
+
+			job, _ := scheduler.NewJob("NOOP_1", "noop", 0, nil, time.Now().Add(50*time.Millisecond))
+			if err := timer.Schedule(context.Background(), job); err != nil {
+				t.Fatalf("could not schedule job: %s", err)
+			}
+
+			timer.Remove("NOOP_1")
+
+			select {
+			case <-time.After(100 * time.Millisecond):
+			case <-ran:
+				t.Error("should not run removed job")
+			}
+
+			jobs, _ := repository.GetJobs(context.Background())
+			if len(jobs) != 0 {
+				t.Errorf("expected job to be deleted, got %d jobs", len(jobs))
+			}
+		})
+
+		t.Run("should remove jobs of the company", func(t *testing.T) {
+			repository := scheduler.NewFakeRepository()
+			timer := scheduler.NewPersistentScheduler(repository, clock.New())
+			timer.Register("noop", func(job *scheduler.Job) error { return nil })
+
+			for id, companyId := range map[string]int64{"NOOP_1": 1, "NOOP_2": 1, "NOOP_3": 2, "NOOP_4": 0} {
+				job, _ := scheduler.NewJob(id, "noop", companyId, nil, time.Now().Add(time.Hour))
+				if err := timer.Schedule(context.Background(), job); err != nil {
+					t.Fatalf("could not schedule job: %s", err)
+				}
+			}
+
+			if removed := timer.RemoveCompanyJobs(1); removed != 2 {
+				t.Errorf("expected %d jobs removed, got %d", 2, removed)
+			}
+
+			if removed := timer.RemoveCompanyJobs(0); removed != 0 {
+				t.Errorf("expected world jobs to stay, got %d removed", removed)
+			}
+
+			jobs, _ := repository.GetJobs(context.Background())
+			if len(jobs) != 2 {
+				t.Errorf("expected %d jobs left, got %d", 2, len(jobs))
+			}
+		})
+
+		t.Run("should pause jobs of the company until resumed", func(t *testing.T) {
+			ran := make(chan string, 2)
+			repository := scheduler.NewFakeRepository()
+			gameClock := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			timer := scheduler.NewPersistentScheduler(repository, gameClock)
+
+			timer.Register("noop", func(job *scheduler.Job) error {
+				ran <- job.Id
+				return nil
+			})
+
+			for id, companyId := range map[string]int64{"NOOP_1": 1, "NOOP_2": 2} {
+				job, _ := scheduler.NewJob(id, "noop", companyId, nil, gameClock.Now().Add(time.Hour))
+				if err := timer.Schedule(context.Background(), job); err != nil {
+					t.Fatalf("could not schedule job: %s", err)
+				}
+			}
+
+			if paused := timer.PauseCompanyJobs(1); paused != 1 {
+				t.Errorf("expected %d job paused, got %d", 1, paused)
+			}
+
+			// Paused jobs stay paused when restored after a restart
+			timer.Stop()
+			timer = scheduler.NewPersistentScheduler(repository, gameClock)
+			t.Cleanup(timer.Stop)
+
+			timer.Register("noop", func(job *scheduler.Job) error {
+				ran <- job.Id
+				return nil
+			})
+
+			if err := timer.Restore(context.Background()); err != nil {
+				t.Fatalf("could not restore jobs: %s", err)
+			}
+
+			gameClock.Advance(2 * time.Hour)
+
+			if id := <-ran; id != "NOOP_2" {
+				t.Errorf("expected job of other company to run, got %s", id)
+			}
+
+			select {
+			case id := <-ran:
+				t.Fatalf("expected paused job not to run, got %s", id)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			if resumed := timer.ResumeCompanyJobs(1); resumed != 1 {
+				t.Errorf("expected %d job resumed, got %d", 1, resumed)
+			}
+
+			select {
+			case id := <-ran:
+				if id != "NOOP_1" {
+					t.Errorf("expected resumed job to run, got %s", id)
+				}
+			case <-time.After(100 * time.Millisecond):
+				t.Fatal("should run resumed job that came due")
+			}
+		})
+
+		t.Run("should reschedule repeating job", func(t *testing.T) {
+			ran := make(chan bool)
+			repository := scheduler.NewFakeRepository()
+			timer := scheduler.NewPersistentScheduler(repository, clock.New())
+
+			timer.Register("tick", func(job *scheduler.Job) error {
+				ran <- true
+				return nil
+			})
+
+			runsAt := time.Now().Add(10 * time.Millisecond)
+			job, _ := scheduler.NewJob("TICK_1", "tick", 0, nil, runsAt)
+			job.RepeatsEvery = time.Hour
+
+			if err := timer.Schedule(context.Background(), job); err != nil {
+				t.Fatalf("could not schedule job: %s", err)
+			}
+
+			<-ran
+			time.Sleep(10 * time.Millisecond)
+
+			jobs, _ := repository.GetJobs(context.Background())
+			if len(jobs) != 1 {
+				t.Fatalf("expected %d persisted job, got %d", 1, len(jobs))
+			}
+
+			if !jobs[0].RunsAt.Equal(runsAt.Add(time.Hour)) {
+				t.Errorf("expected next run at %s, got %s", runsAt.Add(time.Hour), jobs[0].RunsAt)
+			}
+
+			timer.Remove("TICK_1")
+		})
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		ran := make(chan string, 2)
+		repository := scheduler.NewFakeRepository()
+
+		overdue, _ := scheduler.NewJob("OVERDUE", "restore", 0, nil, time.Now().Add(-time.Hour))
+		pending, _ := scheduler.NewJob("PENDING", "restore", 0, nil, time.Now().Add(50*time.Millisecond))
+		orphan, _ := scheduler.NewJob("ORPHAN", "orphan", 0, nil, time.Now().Add(-time.Hour))
+
+		repository.SaveJob(context.Background(), overdue)
+		repository.SaveJob(context.Background(), pending)
+		repository.SaveJob(context.Background(), orphan)
+
+		timer := scheduler.NewPersistentScheduler(repository, clock.New())
+		timer.Register("restore", func(job *scheduler.Job) error {
+			ran <- job.Id
+			return nil
+		})
+
+		if err := timer.Restore(context.Background()); err != nil {
+			t.Fatalf("could not restore jobs: %s", err)
+		}
+
+		select {
+		case id := <-ran:
+			if id != "OVERDUE" {
+				t.Errorf("expected overdue job to run first, got %s", id)
+			}
+		case <-time.After(20 * time.Millisecond):
+			t.Fatal("should run overdue job immediately")
+		}
+
+		if id := <-ran; id != "PENDING" {
+			t.Errorf("expected pending job to run, got %s", id)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		jobs, _ := repository.GetJobs(context.Background())
+		if len(jobs) != 1 || jobs[0].Id != "ORPHAN" {
+			t.Errorf("expected only job without handler to remain, got %+v", jobs)
+		}
+	})
+
+	t.Run("RetryPolicy", func(t *testing.T) {
+		policy := scheduler.RetryPolicy{
+			MaxAttempts:  5,
+			InitialDelay: time.Second,
+			MaxDelay:     5 * time.Second,
+			Multiplier:   2,
+		}
+
+		t.Run("should grow exponentially", func(t *testing.T) {
+			expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+
+			for i, delay := range expected {
+				if backoff := policy.Backoff(i + 1); backoff != delay {
+					t.Errorf("expected delay %s after %d attempts, got %s", delay, i+1, backoff)
+				}
+			}
+		})
+
+		t.Run("should add jitter", func(t *testing.T) {
+			policy.Jitter = 0.5
+
+			for i := 0; i < 100; i++ {
+				backoff := policy.Backoff(2)
+				if backoff < time.Second || backoff > 2*time.Second {
+					t.Fatalf("expected delay between %s and %s, got %s", time.Second, 2*time.Second, backoff)
+				}
+			}
+		})
+	})
+
+	t.Run("Dead letter", func(t *testing.T) {
+		policy := scheduler.RetryPolicy{
+			MaxAttempts:  2,
+			InitialDelay: time.Minute,
+			Multiplier:   2,
+		}
+
+		t.Run("should retry with backoff", func(t *testing.T) {
+			attempts := make(chan int, 2)
+			repository := scheduler.NewFakeRepository()
+			gameClock := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			timer := scheduler.NewPersistentScheduler(repository, gameClock)
+
+			count := 0
+			timer.RegisterWithPolicy("flaky", func(job *scheduler.Job) error {
+				count++
+				attempts <- count
+				if count == 1 {
+					return errors.New("not yet")
+				}
+				return nil
+			}, policy)
+
+			job, _ := scheduler.NewJob("FLAKY_1", "flaky", 0, nil, gameClock.Now().Add(time.Hour))
+			if err := timer.Schedule(context.Background(), job); err != nil {
+				t.Fatalf("could not schedule job: %s", err)
+			}
+
+			gameClock.Advance(time.Hour)
+			<-attempts
+
+			waitFor(t, func() bool {
+				jobs, _ := repository.GetJobs(context.Background())
+				return len(jobs) == 1 && jobs[0].RetryAt != nil
+			})
+
+			gameClock.Advance(time.Minute)
+
+			select {
+			case count := <-attempts:
+				if count != 2 {
+					t.Errorf("expected attempt %d, got %d", 2, count)
+				}
+			case <-time.After(100 * time.Millisecond):
+				t.Fatal("should retry after backoff")
+			}
+
+			waitFor(t, func() bool {
+				jobs, _ := repository.GetJobs(context.Background())
+				return len(jobs) == 0
+			})
+		})
+
+		t.Run("should keep repeating after giving up", func(t *testing.T) {
+			repository := scheduler.NewFakeRepository()
+			gameClock := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			timer := scheduler.NewPersistentScheduler(repository, gameClock)
+
+			failures := make(chan bool, 2)
+			timer.RegisterWithPolicy("interest", func(job *scheduler.Job) error {
+				failures <- true
+				return errors.New("not enough cash")
+			}, policy)
+
+			runsAt := gameClock.Now().Add(time.Hour)
+			job, _ := scheduler.NewJob("INTEREST_1", "interest", 7, nil, runsAt)
+			job.RepeatsEvery = 7 * 24 * time.Hour
+
+			if err := timer.Schedule(context.Background(), job); err != nil {
+				t.Fatalf("could not schedule job: %s", err)
+			}
+
+			gameClock.Advance(time.Hour)
+			<-failures
+
+			waitFor(t, func() bool {
+				jobs, _ := repository.GetJobs(context.Background())
+				return len(jobs) == 1 && jobs[0].RetryAt != nil
+			})
+
+			gameClock.Advance(time.Minute)
+			<-failures
+
+			waitFor(t, func() bool {
+				deadJobs, _ := timer.GetDeadJobs(context.Background())
+				return len(deadJobs) == 1
+			})
+
+			deadJobs, _ := timer.GetDeadJobs(context.Background())
+			if deadJobs[0].Error != "not enough cash" {
+				t.Errorf("expected error %s, got %s", "not enough cash", deadJobs[0].Error)
+			}
+
+			if deadJobs[0].Attempts != 2 {
+				t.Errorf("expected %d attempts, got %d", 2, deadJobs[0].Attempts)
+			}
+
+			if deadJobs[0].CompanyId != 7 {
+				t.Errorf("expected company %d, got %d", 7, deadJobs[0].CompanyId)
+			}
+
+			waitFor(t, func() bool {
+				jobs, _ := repository.GetJobs(context.Background())
+				return len(jobs) == 1 && jobs[0].RetryAt == nil
+			})
+
+			jobs, _ := repository.GetJobs(context.Background())
+			if next := runsAt.Add(7 * 24 * time.Hour); !jobs[0].RunsAt.Equal(next) {
+				t.Errorf("expected next run at %s, got %s", next, jobs[0].RunsAt)
+			}
+
+			if jobs[0].Attempts != 0 {
+				t.Errorf("expected attempts to reset, got %d", jobs[0].Attempts)
+			}
+
+			timer.Remove("INTEREST_1")
+		})
+
+		t.Run("RetryDeadJob", func(t *testing.T) {
+			repository := scheduler.NewFakeRepository()
+			timer := scheduler.NewPersistentScheduler(repository, clock.New())
+
+			fail := true
+			var companyId int64
+			timer.Register("payment", func(job *scheduler.Job) error {
+				companyId = job.CompanyId
+				if fail {
+					return errors.New("bank is closed")
+				}
+				return nil
+			})
+
+			deadJob := &scheduler.DeadJob{JobId: "PAYMENT_1", Type: "payment", CompanyId: 7, Attempts: 5, Error: "bank is closed"}
+			repository.SaveDeadJob(context.Background(), deadJob)
+
+			if err := timer.RetryDeadJob(context.Background(), deadJob.Id); err == nil {
+				t.Fatal("expected error retrying failing job")
+			}
+
+			found, _ := repository.GetDeadJob(context.Background(), deadJob.Id)
+			if found.Attempts != 6 {
+				t.Errorf("expected %d attempts, got %d", 6, found.Attempts)
+			}
+
+			fail = false
+			if err := timer.RetryDeadJob(context.Background(), deadJob.Id); err != nil {
+				t.Fatalf("could not retry dead job: %s", err)
+			}
+
+			if found, _ := repository.GetDeadJob(context.Background(), deadJob.Id); found != nil {
+				t.Error("should remove dead job after it succeeds")
+			}
+
+			if companyId != 7 {
+				t.Errorf("expected job of company %d, got %d", 7, companyId)
+			}
+
+			if err := timer.RetryDeadJob(context.Background(), deadJob.Id); err != scheduler.ErrDeadJobNotFound {
+				t.Errorf("expected error \"%s\", got \"%s\"", scheduler.ErrDeadJobNotFound, err)
+			}
+		})
+
+		t.Run("DiscardDeadJob", func(t *testing.T) {
+			repository := scheduler.NewFakeRepository()
+			timer := scheduler.NewPersistentScheduler(repository, clock.New())
+
+			deadJob := &scheduler.DeadJob{JobId: "PAYMENT_1", Type: "payment", Attempts: 5, Error: "bank is closed"}
+			repository.SaveDeadJob(context.Background(), deadJob)
+
+			if err := timer.DiscardDeadJob(context.Background(), deadJob.Id); err != nil {
+				t.Fatalf("could not discard dead job: %s", err)
+			}
+
+			deadJobs, _ := timer.GetDeadJobs(context.Background())
+			if len(deadJobs) != 0 {
+				t.Errorf("expected no dead jobs, got %d", len(deadJobs))
+			}
+
+			if err := timer.DiscardDeadJob(context.Background(), deadJob.Id); err != scheduler.ErrDeadJobNotFound {
+				t.Errorf("expected error \"%s\", got \"%s\"", scheduler.ErrDeadJobNotFound, err)
+			}
+		})
+	})
+}
+
+// Polls condition until it holds, since jobs complete on their own goroutine
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGetPendingJobs(t *testing.T) {
+	gameClock := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	timer := scheduler.NewPersistentScheduler(scheduler.NewFakeRepository(), gameClock)
+	t.Cleanup(timer.Stop)
+
+	ran := make(chan bool, 1)
+	timer.RegisterWithPolicy("flaky", func(job *scheduler.Job) error {
+		ran <- true
+		return errors.New("not yet")
+	}, scheduler.RetryPolicy{MaxAttempts: 5, InitialDelay: time.Hour, Multiplier: 1})
+
+	jobs := []struct {
+		id        string
+		companyId int64
+		runsAt    time.Duration
+	}{
+		{"FLAKY_1", 1, 3 * time.Hour},
+		{"FLAKY_2", 1, time.Minute},
+		{"FLAKY_3", 2, 2 * time.Hour},
+	}
+
+	for _, data := range jobs {
+		job, _ := scheduler.NewJob(data.id, "flaky", data.companyId, nil, gameClock.Now().Add(data.runsAt))
+		if err := timer.Schedule(context.Background(), job); err != nil {
+			t.Fatalf("could not schedule job: %s", err)
+		}
+	}
+
+	t.Run("should sort by due time", func(t *testing.T) {
+		pending := timer.GetPendingJobs(scheduler.JobFilter{})
+		if len(pending) != 3 {
+			t.Fatalf("expected %d jobs, got %d", 3, len(pending))
+		}
+
+		for i, id := range []string{"FLAKY_2", "FLAKY_3", "FLAKY_1"} {
+			if pending[i].Id != id {
+				t.Errorf("expected job %s at %d, got %s", id, i, pending[i].Id)
+			}
+		}
+	})
+
+	t.Run("should filter", func(t *testing.T) {
+		pending := timer.GetPendingJobs(scheduler.JobFilter{CompanyId: 1})
+		if len(pending) != 2 {
+			t.Errorf("expected %d jobs, got %d", 2, len(pending))
+		}
+
+		pending = timer.GetPendingJobs(scheduler.JobFilter{DueBefore: gameClock.Now().Add(2 * time.Hour)})
+		if len(pending) != 1 {
+			t.Errorf("expected %d jobs, got %d", 1, len(pending))
+		}
+	})
+
+	t.Run("should list failing jobs by their retry time", func(t *testing.T) {
+		gameClock.Advance(time.Minute)
+		<-ran
+
+		waitFor(t, func() bool {
+			return len(timer.GetPendingJobs(scheduler.JobFilter{Failing: true})) == 1
+		})
+
+		pending := timer.GetPendingJobs(scheduler.JobFilter{Failing: true})
+		if pending[0].Id != "FLAKY_2" {
+			t.Errorf("expected job %s, got %s", "FLAKY_2", pending[0].Id)
+		}
+
+		if pending[0].Attempts != 1 {
+			t.Errorf("expected %d attempts, got %d", 1, pending[0].Attempts)
+		}
+
+		if dueAt := gameClock.Now().Add(time.Hour); !pending[0].DueAt.Equal(dueAt) {
+			t.Errorf("expected job due at %s, got %s", dueAt, pending[0].DueAt)
+		}
+	})
 }
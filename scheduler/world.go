@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+type (
+	// WorldFunc runs a world job for the occurrence scheduled at runAt.
+	// During catch up runAt is in the past, so periods should be derived
+	// from it instead of the current time.
+	WorldFunc func(ctx context.Context, runAt time.Time) error
+
+	// JobRun records an occurrence of a world job and its outcome
+	JobRun struct {
+		Id          int64     `db:"id" goqu:"skipinsert" json:"id"`
+		Name        string    `db:"name" json:"name"`
+		ScheduledAt time.Time `db:"scheduled_at" json:"scheduled_at"`
+		StartedAt   time.Time `db:"started_at" json:"started_at"`
+		FinishedAt  time.Time `db:"finished_at" json:"finished_at"`
+		Error       *string   `db:"error" json:"error"`
+	}
+
+	worldJob struct {
+		name     string
+		schedule *Cron
+		run      WorldFunc
+	}
+)
+
+// How long a single world job run may take
+const WORLD_JOB_TIMEOUT = time.Minute
+
+// Registers a job that runs for the whole world on a cron schedule, such
+// as collecting taxes. It only starts running after StartWorld is called.
+func (s *Scheduler) RegisterWorld(name, expression string, run WorldFunc) error {
+	schedule, err := ParseCron(expression)
+	if err != nil {
+		return err
+	}
+
+	if _, found := s.world.LoadOrStore(name, &worldJob{name, schedule, run}); found {
+		return fmt.Errorf("world job %s already registered", name)
+	}
+
+	return nil
+}
+
+// Starts every registered world job. Occurrences missed since the last
+// successful run, like when the server was down, run first and in order.
+// Catching up runs on the scheduler's workers, so shutting down waits for
+// the occurrence in progress.
+func (s *Scheduler) StartWorld(ctx context.Context) error {
+	var err error
+
+	s.world.Range(func(_, value any) bool {
+		job := value.(*worldJob)
+		from := s.clock.Now()
+
+		if s.repository != nil {
+			var lastRun *JobRun
+			if lastRun, err = s.repository.GetLastSuccessfulRun(ctx, job.name); err != nil {
+				return false
+			}
+
+			if lastRun != nil {
+				from = lastRun.ScheduledAt
+			}
+		}
+
+		s.engine.schedule(worldJobId(job.name), s.clock.Now(), 0, func() {
+			s.catchUp(job, from)
+		})
+		return true
+	})
+
+	return err
+}
+
+// Returns the most recent world job runs
+func (s *Scheduler) GetRuns(ctx context.Context, page, limit uint) ([]*JobRun, error) {
+	if s.repository == nil {
+		return []*JobRun{}, nil
+	}
+	return s.repository.GetRuns(ctx, page, limit)
+}
+
+// Runs every occurrence after from that is already due, then waits for
+// the next one. Stops early if the scheduler is shut down, the remaining
+// occurrences are caught up on next start.
+func (s *Scheduler) catchUp(job *worldJob, from time.Time) {
+	next := job.schedule.Next(from)
+	if next.IsZero() {
+		log.Printf("world job %s will never run again", job.name)
+		return
+	}
+
+	for !next.After(s.clock.Now()) {
+		if s.engine.stopped() {
+			return
+		}
+
+		s.runWorld(job, next)
+		next = job.schedule.Next(next)
+	}
+
+	s.engine.schedule(worldJobId(job.name), next, 0, func() {
+		s.runWorld(job, next)
+		s.catchUp(job, next)
+	})
+}
+
+func (s *Scheduler) runWorld(job *worldJob, runAt time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), WORLD_JOB_TIMEOUT)
+	defer cancel()
+
+	run := &JobRun{
+		Name:        job.name,
+		ScheduledAt: runAt,
+		StartedAt:   s.clock.Now(),
+	}
+
+	if err := job.run(ctx, runAt); err != nil {
+		log.Printf("could not run world job %s scheduled at %s: %s", job.name, runAt, err)
+
+		message := err.Error()
+		run.Error = &message
+	}
+
+	run.FinishedAt = s.clock.Now()
+
+	if s.repository != nil {
+		if err := s.repository.SaveRun(ctx, run); err != nil {
+			log.Printf("could not record world job %s run: %s", job.name, err)
+		}
+	}
+}
+
+func worldJobId(name string) string {
+	return fmt.Sprintf("WORLD_%s", name)
+}
@@ -0,0 +1,219 @@
+package scheduler_test
+
+import (
+	"api/clock"
+	"api/scheduler"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorld(t *testing.T) {
+	// A wednesday
+	now := time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC)
+
+	t.Run("should not register twice", func(t *testing.T) {
+		timer := scheduler.NewScheduler()
+		noop := func(ctx context.Context, runAt time.Time) error { return nil }
+
+		if err := timer.RegisterWorld("taxes", scheduler.WEEKLY, noop); err != nil {
+			t.Fatalf("could not register world job: %s", err)
+		}
+
+		if err := timer.RegisterWorld("taxes", scheduler.WEEKLY, noop); err == nil {
+			t.Error("expected error registering world job twice")
+		}
+
+		if err := timer.RegisterWorld("rates", "every week", noop); err == nil {
+			t.Error("expected error with invalid expression")
+		}
+	})
+
+	t.Run("should run on schedule", func(t *testing.T) {
+		ran := make(chan time.Time, 1)
+		repository := scheduler.NewFakeRepository()
+		gameClock := clock.NewFakeClock(now)
+		timer := scheduler.NewPersistentScheduler(repository, gameClock)
+
+		timer.RegisterWorld("taxes", scheduler.WEEKLY, func(ctx context.Context, runAt time.Time) error {
+			ran <- runAt
+			return nil
+		})
+
+		if err := timer.StartWorld(context.Background()); err != nil {
+			t.Fatalf("could not start world jobs: %s", err)
+		}
+
+		select {
+		case runAt := <-ran:
+			t.Fatalf("should not run without previous runs, ran at %s", runAt)
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		gameClock.Set(time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC))
+
+		select {
+		case runAt := <-ran:
+			if expected := time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC); !runAt.Equal(expected) {
+				t.Errorf("expected run at %s, got %s", expected, runAt)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("should run at the start of the week")
+		}
+
+		waitFor(t, func() bool {
+			runs, _ := timer.GetRuns(context.Background(), 0, 10)
+			return len(runs) == 1
+		})
+	})
+
+	t.Run("should catch up skipped runs", func(t *testing.T) {
+		ran := make(chan time.Time, 3)
+		repository := scheduler.NewFakeRepository()
+		gameClock := clock.NewFakeClock(now)
+		timer := scheduler.NewPersistentScheduler(repository, gameClock)
+
+		// Last ran three weeks ago
+		repository.SaveRun(context.Background(), &scheduler.JobRun{
+			Name:        "rates",
+			ScheduledAt: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		})
+
+		timer.RegisterWorld("rates", scheduler.WEEKLY, func(ctx context.Context, runAt time.Time) error {
+			ran <- runAt
+			if runAt.Day() == 7 {
+				return errors.New("no prices")
+			}
+			return nil
+		})
+
+		if err := timer.StartWorld(context.Background()); err != nil {
+			t.Fatalf("could not start world jobs: %s", err)
+		}
+
+		expected := []time.Time{
+			time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC),
+		}
+
+		for _, expectedRun := range expected {
+			select {
+			case runAt := <-ran:
+				if !runAt.Equal(expectedRun) {
+					t.Errorf("expected run at %s, got %s", expectedRun, runAt)
+				}
+			case <-time.After(100 * time.Millisecond):
+				t.Fatalf("should catch up run at %s", expectedRun)
+			}
+		}
+
+		waitFor(t, func() bool {
+			runs, _ := timer.GetRuns(context.Background(), 0, 10)
+			return len(runs) == 3
+		})
+
+		runs, _ := timer.GetRuns(context.Background(), 0, 10)
+		if runs[0].Error != nil {
+			t.Errorf("expected latest run to succeed, got %s", *runs[0].Error)
+		}
+
+		if runs[1].Error == nil || *runs[1].Error != "no prices" {
+			t.Errorf("expected failed run to be recorded, got %v", runs[1].Error)
+		}
+
+		select {
+		case runAt := <-ran:
+			t.Errorf("should not run again before next week, ran at %s", runAt)
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+
+	t.Run("should resume from the last successful run", func(t *testing.T) {
+		ran := make(chan time.Time, 3)
+		repository := scheduler.NewFakeRepository()
+		timer := scheduler.NewPersistentScheduler(repository, clock.NewFakeClock(now))
+		t.Cleanup(timer.Stop)
+
+		failure := "no prices"
+		repository.SaveRun(context.Background(), &scheduler.JobRun{
+			Name:        "rates",
+			ScheduledAt: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		})
+		repository.SaveRun(context.Background(), &scheduler.JobRun{
+			Name:        "rates",
+			ScheduledAt: time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+			Error:       &failure,
+		})
+
+		timer.RegisterWorld("rates", scheduler.WEEKLY, func(ctx context.Context, runAt time.Time) error {
+			ran <- runAt
+			return nil
+		})
+
+		if err := timer.StartWorld(context.Background()); err != nil {
+			t.Fatalf("could not start world jobs: %s", err)
+		}
+
+		select {
+		case runAt := <-ran:
+			if expected := time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC); !runAt.Equal(expected) {
+				t.Errorf("expected failed run at %s to run again, got %s", expected, runAt)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("should run the failed occurrence again")
+		}
+	})
+
+	t.Run("should wait for catch up on shutdown", func(t *testing.T) {
+		started := make(chan time.Time, 3)
+		release := make(chan bool)
+		repository := scheduler.NewFakeRepository()
+		timer := scheduler.NewPersistentScheduler(repository, clock.NewFakeClock(now))
+
+		repository.SaveRun(context.Background(), &scheduler.JobRun{
+			Name:        "taxes",
+			ScheduledAt: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		})
+
+		timer.RegisterWorld("taxes", scheduler.WEEKLY, func(ctx context.Context, runAt time.Time) error {
+			started <- runAt
+			<-release
+			return nil
+		})
+
+		if err := timer.StartWorld(context.Background()); err != nil {
+			t.Fatalf("could not start world jobs: %s", err)
+		}
+
+		<-started
+
+		stopped := make(chan error)
+		go func() {
+			stopped <- timer.Shutdown(context.Background())
+		}()
+
+		select {
+		case <-stopped:
+			t.Fatal("should wait for the catch up run in progress")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(release)
+
+		if err := <-stopped; err != nil {
+			t.Fatalf("could not shut down: %s", err)
+		}
+
+		select {
+		case runAt := <-started:
+			t.Errorf("should stop catching up once shut down, ran at %s", runAt)
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		waitFor(t, func() bool {
+			runs, _ := timer.GetRuns(context.Background(), 0, 10)
+			return len(runs) == 2
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"api/catalog"
+	"api/database"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const seedUsage = "usage: seed <fixture.sql | catalog.json | catalog.yaml>..."
+
+// Runs the seed subcommand in a single unit of work, so a failing fixture
+// leaves the database untouched. SQL fixtures are executed as they are and
+// catalogs are imported, updating what already exists.
+func runSeed(ctx context.Context, uow database.UnitOfWork, catalogSvc catalog.Service, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(seedUsage)
+	}
+
+	return uow.Do(ctx, func(ctx context.Context) error {
+		for _, path := range args {
+			fixture, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("could not read fixture: %w", err)
+			}
+
+			if filepath.Ext(path) == ".sql" {
+				_, err = database.FromContext(ctx).ExecContext(ctx, string(fixture))
+			} else {
+				err = importCatalog(ctx, catalogSvc, path, fixture)
+			}
+
+			if err != nil {
+				return fmt.Errorf("could not seed %s: %w", path, err)
+			}
+
+			fmt.Fprintf(out, "seeded %s\n", path)
+		}
+
+		return nil
+	})
+}
+
+func importCatalog(ctx context.Context, catalogSvc catalog.Service, path string, data []byte) error {
+	format, err := catalog.FormatOf(path)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := catalog.Parse(data, format)
+	if err != nil {
+		return err
+	}
+
+	return catalogSvc.Import(ctx, parsed)
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"api/auth"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Header API keys are sent in, instead of a bearer token
+const API_KEY_HEADER = "X-API-Key"
+
+// Scope API keys need to call each route, by method and path
+var apiKeyScopes sync.Map
+
+// Scope members need to call each route, by method and path
+var memberScopes sync.Map
+
+// Lets API keys holding the scope call the routes. API keys are refused
+// on every other route, so new endpoints aren't opened to them unless
+// they're meant to be.
+func AllowApiKeys(scope string, routes ...*echo.Route) {
+	for _, route := range routes {
+		apiKeyScopes.Store(route.Method+" "+route.Path, scope)
+	}
+}
+
+// Lets members whose role holds the scope call the routes, or every member
+// when the scope is empty. Members are refused on every other route unless
+// they own the company, so new endpoints are left to owners unless they're
+// meant for other roles too.
+func AllowMembers(scope string, routes ...*echo.Route) {
+	for _, route := range routes {
+		memberScopes.Store(route.Method+" "+route.Path, scope)
+	}
+}
+
+// Refuses members the routes their role doesn't allow, and records the
+// changes companies make once they succeed, along with who made them
+func members(config Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, err := auth.ParseClaims(c.Get("user"))
+			if err != nil || claims.IsSystem() {
+				return next(c)
+			}
+
+			if claims.Member != "" && claims.Member != auth.MEMBER_OWNER {
+				scope, allowed := memberScopes.Load(c.Request().Method + " " + c.Path())
+				if !allowed {
+					return echo.NewHTTPError(http.StatusForbidden, "members with role "+claims.Member+" can't call this endpoint")
+				}
+
+				if scope != "" && !claims.HasScope(scope.(string)) {
+					return echo.NewHTTPError(http.StatusForbidden, "role "+claims.Member+" is missing scope "+scope.(string))
+				}
+			}
+
+			if err := next(c); err != nil {
+				return err
+			}
+
+			if config.RecordAction != nil && c.Request().Method != http.MethodGet && c.Response().Status < http.StatusBadRequest {
+				config.RecordAction(c.Request().Context(), claims, c.Request().Method, c.Request().URL.Path)
+			}
+
+			return nil
+		}
+	}
+}
+
+// Authenticates requests sent with an API key, storing its claims where the
+// JWT middleware stores a token's, so handlers don't tell them apart
+func apiKeys(config Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(API_KEY_HEADER)
+			if key == "" {
+				return next(c)
+			}
+
+			if config.AuthenticateApiKey == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "API keys are not accepted")
+			}
+
+			claims, err := config.AuthenticateApiKey(c.Request().Context(), key)
+			if err != nil {
+				return err
+			}
+
+			if claims == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired API key")
+			}
+
+			scope, allowed := apiKeyScopes.Load(c.Request().Method + " " + c.Path())
+			if !allowed {
+				return echo.NewHTTPError(http.StatusForbidden, "API keys can't call this endpoint")
+			}
+
+			if !claims.HasScope(scope.(string)) {
+				return echo.NewHTTPError(http.StatusForbidden, "API key is missing scope "+scope.(string))
+			}
+
+			c.Set("user", &jwt.Token{Claims: claims, Valid: true})
+			return next(c)
+		}
+	}
+}
+
+// Lets through tokens holding the role and forbids the others. The system
+// token holds every role.
+func RequireRole(role string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, err := auth.ParseClaims(c.Get("user"))
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+
+			if !claims.HasRole(role) {
+				return echo.NewHTTPError(http.StatusForbidden)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// Lets through the company whose id is in the path param, given as "id" or
+// ":id", and forbids the others
+func RequireOwner(param string) echo.MiddlewareFunc {
+	name := strings.TrimPrefix(param, ":")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			companyId, err := strconv.ParseUint(c.Param(name), 10, 64)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+
+			authenticated, err := auth.ParseToken(c.Get("user"))
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+
+			if authenticated == 0 || authenticated != companyId {
+				return echo.NewHTTPError(http.StatusForbidden)
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"api/auth"
+	"api/database"
+	"context"
+	"errors"
 	"net/http"
-	"os"
 	"reflect"
 	"strings"
 
@@ -19,12 +22,26 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 )
 
-const (
-	JWT_SECRET_KEY    = "JWT_SECRET"
-	CLIENT_ORIGIN_KEY = "CLIENT_ORIGIN"
-)
-
 type (
+	// Config is what the server needs to authenticate requests and answer
+	// the web client
+	Config struct {
+		JwtSecret    string
+		ClientOrigin string
+
+		// Reports whether a token was revoked, like on logout. Tokens are
+		// accepted until they expire when it's nil.
+		IsRevoked func(ctx context.Context, claims *jwt.RegisteredClaims) (bool, error)
+
+		// Returns the claims of the API key, or nil when it isn't valid.
+		// API keys are refused when it's nil.
+		AuthenticateApiKey func(ctx context.Context, key string) (*auth.Claims, error)
+
+		// Records the change made with the claims. Changes aren't recorded
+		// when it's nil.
+		RecordAction func(ctx context.Context, claims *auth.Claims, method, path string)
+	}
+
 	Validator struct{}
 
 	ValidationErrors struct {
@@ -36,12 +53,23 @@ type (
 	}
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Paths that can be called without a token
+var publicPaths = map[string]bool{
+	"/companies/login":           true,
+	"/companies/register":        true,
+	"/companies/token/refresh":   true,
+	"/companies/email/verify":    true,
+	"/companies/password/forgot": true,
+	"/companies/password/reset":  true,
+	"/users/login":               true,
+	"/users/invitations/accept":  true,
+	"/notifications/ws":          true,
 }
 
-func GetJwtSecret() string {
-	return os.Getenv(JWT_SECRET_KEY)
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
 func NewBusinessRuleError(message string) BusinessRuleError {
@@ -52,7 +80,7 @@ func (e BusinessRuleError) Error() string {
 	return e.Message
 }
 
-func NewServer() *echo.Echo {
+func NewServer(config Config) *echo.Echo {
 	e := echo.New()
 
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
@@ -60,30 +88,60 @@ func NewServer() *echo.Echo {
 		if be, ok := err.(BusinessRuleError); ok {
 			he = echo.NewHTTPError(http.StatusUnprocessableEntity, be.Message)
 		}
+		if database.IsConflict(err) {
+			he = echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		e.DefaultHTTPErrorHandler(he, c)
 	}
 
+	// Clients' addresses are only read from X-Forwarded-For when it's set by
+	// a proxy on a private network, so clients can't spoof their own
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+
 	// e.Use(middleware.Logger())
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowCredentials: true,
-		AllowOrigins:     []string{os.Getenv(CLIENT_ORIGIN_KEY)},
+		AllowOrigins:     []string{config.ClientOrigin},
 	}))
 
+	e.Use(apiKeys(config))
+
 	e.Use(echojwt.WithConfig(echojwt.Config{
 		Skipper: func(c echo.Context) bool {
-			isLogin := c.Request().URL.Path == "/companies/login"
-			isRegister := c.Request().URL.Path == "/companies/register"
-			isWebsocket := c.Request().URL.Path == "/notifications/ws"
+			if c.Get("user") != nil {
+				return true
+			}
 
-			return isLogin || isRegister || isWebsocket
+			return publicPaths[c.Request().URL.Path]
 		},
-		SigningKey: []byte(GetJwtSecret()),
-		NewClaimsFunc: func(c echo.Context) jwt.Claims {
-			return new(jwt.RegisteredClaims)
+		ParseTokenFunc: func(c echo.Context, signed string) (any, error) {
+			claims := new(auth.Claims)
+			token, err := jwt.ParseWithClaims(signed, claims, func(t *jwt.Token) (any, error) {
+				return []byte(config.JwtSecret), nil
+			}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+			if err != nil {
+				return nil, err
+			}
+
+			if config.IsRevoked != nil {
+				revoked, err := config.IsRevoked(c.Request().Context(), &claims.RegisteredClaims)
+				if err != nil {
+					return nil, err
+				}
+
+				if revoked {
+					return nil, ErrTokenRevoked
+				}
+			}
+
+			return token, nil
 		},
 	}))
 
+	e.Use(members(config))
+
 	e.Validator = new(Validator)
 
 	return e
@@ -0,0 +1,150 @@
+package valuation
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type fakeRepository struct {
+	mutex      sync.Mutex
+	positions  []*Position
+	levels     []*BuildingLevel
+	prices     []*ReferencePrice
+	valuations []*Valuation
+}
+
+// Knows the companies and inventories of the company and warehouse fake
+// repositories
+func NewFakeRepository() Repository {
+	return &fakeRepository{
+		positions: []*Position{
+			{CompanyId: 1, Cash: 720, Receivables: 1000, Revenue: 500},
+			{CompanyId: 2, Cash: 255720, Debt: 100000},
+		},
+		levels: []*BuildingLevel{
+			{CompanyId: 1, BuildingId: 1, Level: 2},
+			{CompanyId: 2, BuildingId: 2, Level: 1},
+		},
+		prices: []*ReferencePrice{
+			{ResourceId: 1, Quality: 0, Price: 200},
+		},
+		valuations: make([]*Valuation, 0),
+	}
+}
+
+func (r *fakeRepository) GetPositions(ctx context.Context, companyId uint64, since, until time.Time) ([]*Position, error) {
+	positions := make([]*Position, 0)
+	for _, position := range r.positions {
+		if companyId == 0 || position.CompanyId == companyId {
+			copied := *position
+			positions = append(positions, &copied)
+		}
+	}
+	return positions, nil
+}
+
+func (r *fakeRepository) GetBuildingLevels(ctx context.Context, companyId uint64) ([]*BuildingLevel, error) {
+	levels := make([]*BuildingLevel, 0)
+	for _, level := range r.levels {
+		if companyId == 0 || level.CompanyId == companyId {
+			levels = append(levels, level)
+		}
+	}
+	return levels, nil
+}
+
+func (r *fakeRepository) GetReferencePrices(ctx context.Context) ([]*ReferencePrice, error) {
+	return r.prices, nil
+}
+
+func (r *fakeRepository) GetLastSnapshot(ctx context.Context) (*time.Time, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.valuations) == 0 {
+		return nil, nil
+	}
+
+	last := r.valuations[len(r.valuations)-1].CreatedAt
+	return &last, nil
+}
+
+func (r *fakeRepository) GetNetWorths(ctx context.Context, at time.Time) (map[uint64]int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	netWorths := make(map[uint64]int64)
+	for _, valuation := range r.valuations {
+		if valuation.CreatedAt.Equal(at) {
+			netWorths[valuation.CompanyId] = valuation.NetWorth
+		}
+	}
+	return netWorths, nil
+}
+
+func (r *fakeRepository) SaveValuations(ctx context.Context, valuations []*Valuation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, valuation := range valuations {
+		saved := *valuation
+		saved.Id = uint64(len(r.valuations) + 1)
+		r.valuations = append(r.valuations, &saved)
+	}
+	return nil
+}
+
+func (r *fakeRepository) GetLeaderboard(ctx context.Context, metric string, limit uint) ([]*LeaderboardEntry, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entries := make([]*LeaderboardEntry, 0)
+	if len(r.valuations) == 0 {
+		return entries, nil
+	}
+
+	latest := r.valuations[len(r.valuations)-1].CreatedAt
+	for _, valuation := range r.valuations {
+		if valuation.CreatedAt.Equal(latest) {
+			entries = append(entries, &LeaderboardEntry{
+				CompanyId: valuation.CompanyId,
+				NetWorth:  valuation.NetWorth,
+				Revenue:   valuation.Revenue,
+				Growth:    valuation.Growth,
+			})
+		}
+	}
+
+	value := func(entry *LeaderboardEntry) float64 {
+		switch metric {
+		case METRIC_REVENUE:
+			return float64(entry.Revenue)
+		case METRIC_GROWTH:
+			return entry.Growth
+		}
+		return float64(entry.NetWorth)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return value(entries[i]) > value(entries[j]) })
+	return entries[:min(int(limit), len(entries))], nil
+}
+
+func (r *fakeRepository) GetHistory(ctx context.Context, companyId uint64, since time.Time) ([]*Point, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	points := make([]*Point, 0)
+	for _, valuation := range r.valuations {
+		if valuation.CompanyId == companyId && !valuation.CreatedAt.Before(since) {
+			points = append(points, &Point{
+				NetWorth:  valuation.NetWorth,
+				Revenue:   valuation.Revenue,
+				Growth:    valuation.Growth,
+				CreatedAt: valuation.CreatedAt,
+			})
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,284 @@
+package valuation
+
+import (
+	"api/accounting"
+	"api/database"
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+type (
+	// Position is what the company holds and owes besides its stock and
+	// buildings, with what it earned in the period asked for
+	Position struct {
+		CompanyId   uint64 `db:"company_id"`
+		Cash        int64  `db:"cash"`
+		Receivables int64  `db:"receivables"`
+		Debt        int64  `db:"debt"`
+		Revenue     int64  `db:"revenue"`
+	}
+
+	BuildingLevel struct {
+		CompanyId  uint64 `db:"company_id"`
+		BuildingId uint64 `db:"building_id"`
+		Level      int64  `db:"level"`
+	}
+
+	ReferencePrice struct {
+		ResourceId uint64 `db:"resource_id"`
+		Quality    uint8  `db:"quality"`
+		Price      int64  `db:"price"`
+	}
+
+	priceKey struct {
+		resourceId uint64
+		quality    uint8
+	}
+
+	Repository interface {
+		// Return those of the company, or of every active company when it's 0
+		GetPositions(ctx context.Context, companyId uint64, since, until time.Time) ([]*Position, error)
+		GetBuildingLevels(ctx context.Context, companyId uint64) ([]*BuildingLevel, error)
+
+		GetReferencePrices(ctx context.Context) ([]*ReferencePrice, error)
+
+		// Returns when the last snapshot was taken, nil before the first
+		GetLastSnapshot(ctx context.Context) (*time.Time, error)
+
+		// Returns the net worth of every company in the snapshot taken at
+		GetNetWorths(ctx context.Context, at time.Time) (map[uint64]int64, error)
+		SaveValuations(ctx context.Context, valuations []*Valuation) error
+		GetLeaderboard(ctx context.Context, metric string, limit uint) ([]*LeaderboardEntry, error)
+		GetHistory(ctx context.Context, companyId uint64, since time.Time) ([]*Point, error)
+	}
+
+	goquRepository struct {
+		builder *goqu.Database
+	}
+)
+
+// Transactions counted as revenue
+var REVENUE_CLASSIFICATIONS = []int{
+	accounting.MARKET_SALE,
+	accounting.BOND_INTEREST_INCOME,
+}
+
+func NewRepository(conn *database.Connection) Repository {
+	builder := goqu.New(conn.Driver, conn.DB)
+	return &goquRepository{builder}
+}
+
+func (r *goquRepository) GetPositions(ctx context.Context, companyId uint64, since, until time.Time) ([]*Position, error) {
+	positions := make([]*Position, 0)
+
+	receivables := r.builder.
+		From(goqu.T("bonds_creditors").As("bc")).
+		Select(goqu.COALESCE(goqu.SUM(goqu.L("? - ?", goqu.I("bc.principal"), goqu.I("bc.principal_paid"))), 0)).
+		Where(goqu.I("bc.company_id").Eq(goqu.I("c.id")))
+
+	loans := r.builder.
+		From(goqu.T("loans").As("l")).
+		Select(goqu.COALESCE(goqu.SUM(goqu.L("? - ?", goqu.I("l.principal"), goqu.I("l.principal_paid"))), 0)).
+		Where(goqu.I("l.company_id").Eq(goqu.I("c.id")))
+
+	bonds := r.builder.
+		From(goqu.T("bonds_creditors").As("bc")).
+		InnerJoin(goqu.T("bonds").As("b"), goqu.On(goqu.I("b.id").Eq(goqu.I("bc.bond_id")))).
+		Select(goqu.COALESCE(goqu.SUM(goqu.L("? - ?", goqu.I("bc.principal"), goqu.I("bc.principal_paid"))), 0)).
+		Where(goqu.I("b.company_id").Eq(goqu.I("c.id")))
+
+	revenue := r.builder.
+		From(goqu.T("transactions").As("t")).
+		Select(goqu.COALESCE(goqu.SUM(goqu.I("t.value")), 0)).
+		Where(
+			goqu.I("t.company_id").Eq(goqu.I("c.id")),
+			goqu.I("t.classification_id").In(REVENUE_CLASSIFICATIONS),
+			goqu.I("t.created_at").Gte(since.Format(time.DateTime)),
+			goqu.I("t.created_at").Lt(until.Format(time.DateTime)),
+		)
+
+	condition := goqu.And(
+		goqu.I("c.blocked_at").IsNull(),
+		goqu.I("c.deleted_at").IsNull(),
+	)
+	if companyId != 0 {
+		condition = condition.Append(goqu.I("c.id").Eq(companyId))
+	}
+
+	err := database.Query(ctx, r.builder).
+		Select(
+			goqu.I("c.id").As("company_id"),
+			goqu.COALESCE(goqu.I("cb.cash"), 0).As("cash"),
+			receivables.As("receivables"),
+			goqu.L("(?) + (?)", loans, bonds).As("debt"),
+			revenue.As("revenue"),
+		).
+		From(goqu.T("companies").As("c")).
+		LeftJoin(
+			goqu.T("company_balances").As("cb"),
+			goqu.On(goqu.I("cb.company_id").Eq(goqu.I("c.id"))),
+		).
+		Where(condition).
+		Order(goqu.I("c.id").Asc()).
+		ScanStructsContext(ctx, &positions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return positions, nil
+}
+
+func (r *goquRepository) GetBuildingLevels(ctx context.Context, companyId uint64) ([]*BuildingLevel, error) {
+	levels := make([]*BuildingLevel, 0)
+
+	conditions := []exp.Expression{goqu.I("demolished_at").IsNull()}
+	if companyId != 0 {
+		conditions = append(conditions, goqu.I("company_id").Eq(companyId))
+	}
+
+	err := database.Query(ctx, r.builder).
+		From(goqu.T("companies_buildings")).
+		Select(goqu.I("company_id"), goqu.I("building_id"), goqu.COALESCE(goqu.I("level"), 1).As("level")).
+		Where(conditions...).
+		ScanStructsContext(ctx, &levels)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+func (r *goquRepository) GetReferencePrices(ctx context.Context) ([]*ReferencePrice, error) {
+	prices := make([]*ReferencePrice, 0)
+
+	err := database.Query(ctx, r.builder).
+		From(goqu.T("orders")).
+		Select(
+			goqu.I("resource_id"),
+			goqu.I("quality"),
+			database.IntDiv(
+				r.builder.Dialect(),
+				goqu.SUM(goqu.L("? * ?", goqu.I("price"), goqu.I("quantity"))),
+				goqu.SUM(goqu.I("quantity")),
+			).As("price"),
+		).
+		Where(
+			goqu.I("canceled_at").IsNull(),
+			goqu.I("quantity").Gt(0),
+		).
+		GroupBy(goqu.I("resource_id"), goqu.I("quality")).
+		ScanStructsContext(ctx, &prices)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+func (r *goquRepository) GetLastSnapshot(ctx context.Context) (*time.Time, error) {
+	var last time.Time
+
+	found, err := database.Query(ctx, r.builder).
+		From(goqu.T("valuations")).
+		Select(goqu.I("created_at")).
+		Order(goqu.I("created_at").Desc()).
+		Limit(1).
+		ScanValContext(ctx, &last)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &last, nil
+}
+
+func (r *goquRepository) GetNetWorths(ctx context.Context, at time.Time) (map[uint64]int64, error) {
+	var worths []struct {
+		CompanyId uint64 `db:"company_id"`
+		NetWorth  int64  `db:"net_worth"`
+	}
+
+	if err := database.Query(ctx, r.builder).
+		From(goqu.T("valuations")).
+		Select(goqu.I("company_id"), goqu.I("net_worth")).
+		Where(goqu.I("created_at").Eq(at)).
+		ScanStructsContext(ctx, &worths); err != nil {
+		return nil, err
+	}
+
+	netWorths := make(map[uint64]int64, len(worths))
+	for _, worth := range worths {
+		netWorths[worth.CompanyId] = worth.NetWorth
+	}
+
+	return netWorths, nil
+}
+
+func (r *goquRepository) SaveValuations(ctx context.Context, valuations []*Valuation) error {
+	_, err := database.Query(ctx, r.builder).
+		Insert(goqu.T("valuations")).
+		Rows(valuations).
+		Executor().
+		ExecContext(ctx)
+
+	return err
+}
+
+func (r *goquRepository) GetLeaderboard(ctx context.Context, metric string, limit uint) ([]*LeaderboardEntry, error) {
+	entries := make([]*LeaderboardEntry, 0)
+
+	latest := r.builder.
+		From(goqu.T("valuations")).
+		Select(goqu.MAX("created_at"))
+
+	err := database.Query(ctx, r.builder).
+		Select(
+			goqu.I("v.company_id"),
+			goqu.I("c.name"),
+			goqu.I("v.net_worth"),
+			goqu.I("v.revenue"),
+			goqu.I("v.growth"),
+		).
+		From(goqu.T("valuations").As("v")).
+		InnerJoin(goqu.T("companies").As("c"), goqu.On(goqu.I("c.id").Eq(goqu.I("v.company_id")))).
+		Where(
+			goqu.I("v.created_at").Eq(latest),
+			goqu.I("c.blocked_at").IsNull(),
+			goqu.I("c.deleted_at").IsNull(),
+		).
+		Order(goqu.I("v."+metric).Desc(), goqu.I("v.company_id").Asc()).
+		Limit(limit).
+		ScanStructsContext(ctx, &entries)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *goquRepository) GetHistory(ctx context.Context, companyId uint64, since time.Time) ([]*Point, error) {
+	points := make([]*Point, 0)
+
+	err := database.Query(ctx, r.builder).
+		From(goqu.T("valuations")).
+		Select(goqu.I("net_worth"), goqu.I("revenue"), goqu.I("growth"), goqu.I("created_at")).
+		Where(
+			goqu.I("company_id").Eq(companyId),
+			goqu.I("created_at").Gte(since),
+		).
+		Order(goqu.I("created_at").Asc()).
+		ScanStructsContext(ctx, &points)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
@@ -0,0 +1,151 @@
+package valuation_test
+
+import (
+	"api/accounting"
+	"api/database"
+	"api/valuation"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestValuationRepository(t *testing.T) {
+	conn, err := database.GetTestConnection("../test.db")
+	if err != nil {
+		t.Fatalf("could not open database: %s", err)
+	}
+
+	seeds := []string{
+		`INSERT INTO companies (id, name, email, password, available_terrains, blocked_at) VALUES
+        (1, 'Coca-Cola', 'coke@email.com', 'aoeu', 3, NULL),
+        (2, 'Pepsi', 'pepsi@email.com', 'aoeu', 4, NULL),
+        (3, 'Blocked', 'blocked@email.com', 'aoeu', 3, '2023-10-22T01:11:53Z')`,
+		`INSERT INTO company_balances (company_id, cash) VALUES (1, 5000), (2, 7000)`,
+		`INSERT INTO orders (id, company_id, resource_id, quality, quantity, price, sourcing_cost, transport_fee, canceled_at) VALUES
+        (1, 2, 1, 0, 10, 100, 50, 0, NULL),
+        (2, 2, 1, 0, 30, 200, 50, 0, NULL),
+        (3, 2, 1, 0, 30, 900, 50, 0, '2023-11-11'),
+        (4, 2, 1, 1, 0, 900, 50, 0, NULL)`,
+		`INSERT INTO loans (company_id, principal, principal_paid, interest_rate, payable_from) VALUES (1, 1000, 200, 0.1, '2023-11-11')`,
+		`INSERT INTO bonds (id, company_id, amount, interest_rate) VALUES (1, 1, 5000, 0.1)`,
+		`INSERT INTO bonds_creditors (company_id, bond_id, interest_rate, payable_from, principal, principal_paid) VALUES (2, 1, 0.1, '2023-11-11', 3000, 1000)`,
+		fmt.Sprintf(`INSERT INTO transactions (company_id, value, classification_id) VALUES (2, 700, %d), (2, 50, %d), (2, -100, %d)`,
+			accounting.MARKET_SALE, accounting.BOND_INTEREST_INCOME, accounting.MARKET_PURCHASE),
+		`INSERT INTO companies_buildings (company_id, building_id, name, level, demolished_at) VALUES
+        (1, 1, 'Plantation', 2, NULL),
+        (1, 1, 'Plantation', 1, '2023-11-11')`,
+	}
+
+	for _, seed := range seeds {
+		if _, err := conn.DB.Exec(seed); err != nil {
+			t.Fatalf("could not seed database: %s", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		for _, table := range []string{"valuations", "companies_buildings", "transactions", "bonds_creditors", "bonds", "loans", "orders", "company_balances", "companies"} {
+			if _, err := conn.DB.Exec("DELETE FROM " + table); err != nil {
+				t.Errorf("could not clean up database: %s", err)
+			}
+		}
+	})
+
+	repository := valuation.NewRepository(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	t.Run("GetPositions", func(t *testing.T) {
+		positions, err := repository.GetPositions(ctx, 0, now.Add(-time.Hour), now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("could not get positions: %s", err)
+		}
+
+		if len(positions) != 2 {
+			t.Fatalf("expected positions of active companies only, got %d", len(positions))
+		}
+
+		coke, pepsi := positions[0], positions[1]
+		if coke.Cash != 5000 || coke.Debt != 800+2000 || coke.Receivables != 0 {
+			t.Errorf("expected cash and loan and bond debt, got %+v", coke)
+		}
+
+		if pepsi.Receivables != 2000 || pepsi.Debt != 0 || pepsi.Revenue != 750 {
+			t.Errorf("expected bond receivables and revenue, got %+v", pepsi)
+		}
+
+		positions, _ = repository.GetPositions(ctx, 2, now.Add(time.Hour), now.Add(2*time.Hour))
+		if len(positions) != 1 || positions[0].Revenue != 0 {
+			t.Errorf("expected no revenue outside the period, got %+v", positions)
+		}
+	})
+
+	t.Run("GetReferencePrices", func(t *testing.T) {
+		prices, err := repository.GetReferencePrices(ctx)
+		if err != nil {
+			t.Fatalf("could not get prices: %s", err)
+		}
+
+		if len(prices) != 1 || prices[0].Price != (10*100+30*200)/40 {
+			t.Errorf("expected average price of open orders, got %+v", prices)
+		}
+	})
+
+	t.Run("GetBuildingLevels", func(t *testing.T) {
+		levels, err := repository.GetBuildingLevels(ctx, 1)
+		if err != nil {
+			t.Fatalf("could not get building levels: %s", err)
+		}
+
+		if len(levels) != 1 || levels[0].Level != 2 {
+			t.Errorf("expected the standing building, got %+v", levels)
+		}
+	})
+
+	t.Run("Snapshots", func(t *testing.T) {
+		if last, err := repository.GetLastSnapshot(ctx); err != nil || last != nil {
+			t.Fatalf("expected no snapshot yet, got %v %v", last, err)
+		}
+
+		first, second := now.Add(-24*time.Hour).Truncate(time.Second), now.Truncate(time.Second)
+		for _, valuations := range [][]*valuation.Valuation{
+			{{CompanyId: 1, NetWorth: 100, CreatedAt: first}, {CompanyId: 2, NetWorth: 200, CreatedAt: first}},
+			{{CompanyId: 1, NetWorth: 300, Revenue: 10, Growth: 2, CreatedAt: second}, {CompanyId: 2, NetWorth: 250, Revenue: 20, Growth: 0.25, CreatedAt: second}},
+		} {
+			if err := repository.SaveValuations(ctx, valuations); err != nil {
+				t.Fatalf("could not save valuations: %s", err)
+			}
+		}
+
+		last, err := repository.GetLastSnapshot(ctx)
+		if err != nil || last == nil || !last.Equal(second) {
+			t.Fatalf("expected last snapshot at %s, got %v %v", second, last, err)
+		}
+
+		netWorths, err := repository.GetNetWorths(ctx, first)
+		if err != nil || netWorths[1] != 100 || netWorths[2] != 200 {
+			t.Errorf("expected net worths of the first snapshot, got %v %v", netWorths, err)
+		}
+
+		entries, err := repository.GetLeaderboard(ctx, valuation.METRIC_REVENUE, 10)
+		if err != nil {
+			t.Fatalf("could not get leaderboard: %s", err)
+		}
+
+		if len(entries) != 2 || entries[0].CompanyId != 2 || entries[0].Name != "Pepsi" || entries[1].Growth != 2 {
+			t.Errorf("expected latest snapshot by revenue, got %+v", entries)
+		}
+
+		points, err := repository.GetHistory(ctx, 1, now.Add(-48*time.Hour))
+		if err != nil {
+			t.Fatalf("could not get history: %s", err)
+		}
+
+		if len(points) != 2 || points[0].NetWorth != 100 || points[1].NetWorth != 300 {
+			t.Errorf("expected both snapshots oldest first, got %+v", points)
+		}
+	})
+}
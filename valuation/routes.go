@@ -0,0 +1,66 @@
+package valuation
+
+import (
+	"api/auth"
+	"api/server"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func CreateEndpoints(e *echo.Echo, service Service) {
+	leaderboards := e.GET("/leaderboards/:metric", func(c echo.Context) error {
+		limit, err := strconv.ParseUint(c.QueryParam("limit"), 10, 64)
+		if err != nil {
+			limit = LEADERBOARD_SIZE
+		}
+
+		entries, err := service.GetLeaderboard(c.Request().Context(), c.Param("metric"), uint(limit))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, entries)
+	})
+
+	valuation := e.GET("/companies/:id/valuation", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		evaluated, err := service.Evaluate(c.Request().Context(), companyId)
+		if err != nil {
+			return err
+		}
+
+		if evaluated == nil {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+
+		return c.JSON(http.StatusOK, evaluated)
+	}, server.RequireOwner(":id"))
+
+	history := e.GET("/companies/:id/valuations", func(c echo.Context) error {
+		companyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		days, err := strconv.ParseUint(c.QueryParam("days"), 10, 64)
+		if err != nil {
+			days = HISTORY_DAYS
+		}
+
+		points, err := service.GetHistory(c.Request().Context(), companyId, uint(days))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, points)
+	})
+
+	server.AllowMembers("", leaderboards, history)
+	server.AllowMembers(auth.SCOPE_FINANCE_READ, valuation)
+}
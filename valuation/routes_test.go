@@ -0,0 +1,84 @@
+package valuation_test
+
+import (
+	"api/auth"
+	"api/clock"
+	"api/server"
+	"api/valuation"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValuationRoutes(t *testing.T) {
+	service := newService(clock.New())
+	if err := service.Snapshot(context.Background()); err != nil {
+		t.Fatalf("could not take snapshot: %s", err)
+	}
+
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
+	valuation.CreateEndpoints(svr, service)
+
+	token, err := auth.GenerateToken(1, "secret")
+	if err != nil {
+		t.Fatalf("could not generate jwt token: %s", err)
+	}
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		svr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should rank companies", func(t *testing.T) {
+		rec := send("/leaderboards/revenue")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var entries []*valuation.LeaderboardEntry
+		if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("could not parse leaderboard: %s", err)
+		}
+
+		if len(entries) != 2 || entries[0].Revenue != 500 {
+			t.Errorf("expected company with revenue first, got %+v", entries)
+		}
+
+		if rec := send("/leaderboards/cash"); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d for unknown leaderboard, got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("should only show the breakdown to owners", func(t *testing.T) {
+		if rec := send("/companies/1/valuation"); rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		if rec := send("/companies/2/valuation"); rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("should chart any company", func(t *testing.T) {
+		rec := send("/companies/2/valuations?days=7")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var points []*valuation.Point
+		if err := json.Unmarshal(rec.Body.Bytes(), &points); err != nil {
+			t.Fatalf("could not parse history: %s", err)
+		}
+
+		if len(points) != 1 || points[0].NetWorth == 0 {
+			t.Errorf("expected the snapshot, got %+v", points)
+		}
+	})
+}
@@ -0,0 +1,325 @@
+// Package valuation tells how much companies are worth. Every company is
+// valued daily, and leaderboards and charts are drawn from those snapshots.
+package valuation
+
+import (
+	"api/building"
+	"api/clock"
+	"api/company"
+	"api/server"
+	"api/warehouse"
+	"context"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	METRIC_NET_WORTH = "net_worth"
+	METRIC_REVENUE   = "revenue"
+	METRIC_GROWTH    = "growth"
+
+	LEADERBOARD_SIZE     = 50
+	MAX_LEADERBOARD_SIZE = 100
+
+	// Days charted unless asked otherwise
+	HISTORY_DAYS     = 30
+	MAX_HISTORY_DAYS = 365
+)
+
+type (
+	// Valuation is what the company is worth: its cash, inventory, terrains,
+	// buildings and bonds it holds, minus the principal it still owes on
+	// loans and bonds. Revenue is what it earned since the previous
+	// valuation and growth how much its net worth changed since then.
+	Valuation struct {
+		Id          uint64    `db:"id" json:"-" goqu:"skipinsert,skipupdate"`
+		CompanyId   uint64    `db:"company_id" json:"company_id"`
+		Cash        int64     `db:"cash" json:"cash"`
+		Inventory   int64     `db:"inventory" json:"inventory"`
+		Terrains    int64     `db:"terrains" json:"terrains"`
+		Buildings   int64     `db:"buildings" json:"buildings"`
+		Receivables int64     `db:"receivables" json:"receivables"`
+		Debt        int64     `db:"debt" json:"debt"`
+		NetWorth    int64     `db:"net_worth" json:"net_worth"`
+		Revenue     int64     `db:"revenue" json:"revenue"`
+		Growth      float64   `db:"growth" json:"growth"`
+		CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	}
+
+	// Point is a valuation as charted for everyone, without the breakdown
+	// only the owner sees
+	Point struct {
+		NetWorth  int64     `db:"net_worth" json:"net_worth"`
+		Revenue   int64     `db:"revenue" json:"revenue"`
+		Growth    float64   `db:"growth" json:"growth"`
+		CreatedAt time.Time `db:"created_at" json:"created_at"`
+	}
+
+	LeaderboardEntry struct {
+		Rank      int     `db:"-" json:"rank"`
+		CompanyId uint64  `db:"company_id" json:"company_id"`
+		Name      string  `db:"name" json:"name"`
+		NetWorth  int64   `db:"net_worth" json:"net_worth"`
+		Revenue   int64   `db:"revenue" json:"revenue"`
+		Growth    float64 `db:"growth" json:"growth"`
+	}
+
+	Service interface {
+		// Values the company as it stands, nil when it doesn't exist
+		Evaluate(ctx context.Context, companyId uint64) (*Valuation, error)
+
+		// Values and saves every company
+		Snapshot(ctx context.Context) error
+
+		// Ranks companies by the metric in the latest snapshot
+		GetLeaderboard(ctx context.Context, metric string, limit uint) ([]*LeaderboardEntry, error)
+		GetHistory(ctx context.Context, companyId uint64, days uint) ([]*Point, error)
+	}
+
+	service struct {
+		repository   Repository
+		companySvc   company.Service
+		warehouseSvc warehouse.Service
+		buildingSvc  building.Service
+		logger       *log.Logger
+		clock        clock.Clock
+	}
+)
+
+var ErrUnknownMetric = server.NewBusinessRuleError("unknown leaderboard")
+
+var metrics = map[string]bool{
+	METRIC_NET_WORTH: true,
+	METRIC_REVENUE:   true,
+	METRIC_GROWTH:    true,
+}
+
+func NewService(repository Repository, companySvc company.Service, warehouseSvc warehouse.Service, buildingSvc building.Service, logger *log.Logger, clock clock.Clock) Service {
+	return &service{repository, companySvc, warehouseSvc, buildingSvc, logger, clock}
+}
+
+func (s *service) Evaluate(ctx context.Context, companyId uint64) (*Valuation, error) {
+	last, err := s.repository.GetLastSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	valuations, err := s.evaluate(ctx, companyId, last, s.clock.Now().UTC())
+	if err != nil || len(valuations) == 0 {
+		return nil, err
+	}
+
+	return valuations[0], nil
+}
+
+func (s *service) Snapshot(ctx context.Context) error {
+	now := s.clock.Now().UTC().Truncate(time.Second)
+
+	last, err := s.repository.GetLastSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Already taken, when the job is retried right away
+	if last != nil && !now.After(*last) {
+		return nil
+	}
+
+	valuations, err := s.evaluate(ctx, 0, last, now)
+	if err != nil {
+		return err
+	}
+
+	if len(valuations) == 0 {
+		return nil
+	}
+
+	for _, valuation := range valuations {
+		valuation.CreatedAt = now
+	}
+
+	if err := s.repository.SaveValuations(ctx, valuations); err != nil {
+		return err
+	}
+
+	s.logger.Printf("valued %d companies", len(valuations))
+	return nil
+}
+
+func (s *service) GetLeaderboard(ctx context.Context, metric string, limit uint) ([]*LeaderboardEntry, error) {
+	if !metrics[metric] {
+		return nil, ErrUnknownMetric
+	}
+
+	if limit == 0 {
+		limit = LEADERBOARD_SIZE
+	}
+
+	entries, err := s.repository.GetLeaderboard(ctx, metric, min(limit, MAX_LEADERBOARD_SIZE))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		entry.Rank = i + 1
+	}
+
+	return entries, nil
+}
+
+func (s *service) GetHistory(ctx context.Context, companyId uint64, days uint) ([]*Point, error) {
+	if days == 0 {
+		days = HISTORY_DAYS
+	}
+
+	since := s.clock.Now().UTC().AddDate(0, 0, -int(min(days, MAX_HISTORY_DAYS)))
+	return s.repository.GetHistory(ctx, companyId, since)
+}
+
+// Values the company, or every company when it's 0, with the revenue
+// earned since the last snapshot
+func (s *service) evaluate(ctx context.Context, companyId uint64, last *time.Time, now time.Time) ([]*Valuation, error) {
+	var since time.Time
+	if last != nil {
+		since = *last
+	}
+
+	positions, err := s.repository.GetPositions(ctx, companyId, since, now)
+	if err != nil || len(positions) == 0 {
+		return nil, err
+	}
+
+	prices, err := s.getReferencePrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buildings, err := s.getBuildingValues(ctx, companyId, prices)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make(map[uint64]int64)
+	if last != nil {
+		if previous, err = s.repository.GetNetWorths(ctx, *last); err != nil {
+			return nil, err
+		}
+	}
+
+	valuations := make([]*Valuation, 0, len(positions))
+	for _, position := range positions {
+		inventory, err := s.getInventoryValue(ctx, position.CompanyId, prices)
+		if err != nil {
+			return nil, err
+		}
+
+		terrains, err := s.getTerrainsValue(ctx, position.CompanyId)
+		if err != nil {
+			return nil, err
+		}
+
+		valuation := &Valuation{
+			CompanyId:   position.CompanyId,
+			Cash:        position.Cash,
+			Inventory:   inventory,
+			Terrains:    terrains,
+			Buildings:   buildings[position.CompanyId],
+			Receivables: position.Receivables,
+			Debt:        position.Debt,
+			Revenue:     position.Revenue,
+			CreatedAt:   now,
+		}
+
+		valuation.NetWorth = valuation.Cash + valuation.Inventory + valuation.Terrains +
+			valuation.Buildings + valuation.Receivables - valuation.Debt
+
+		if worth, ok := previous[position.CompanyId]; ok && worth != 0 {
+			valuation.Growth = float64(valuation.NetWorth-worth) / math.Abs(float64(worth))
+		}
+
+		valuations = append(valuations, valuation)
+	}
+
+	return valuations, nil
+}
+
+// Returns the average price resources are offered at on the market, by
+// resource and quality
+func (s *service) getReferencePrices(ctx context.Context) (map[priceKey]int64, error) {
+	referencePrices, err := s.repository.GetReferencePrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[priceKey]int64, len(referencePrices))
+	for _, price := range referencePrices {
+		prices[priceKey{price.ResourceId, price.Quality}] = price.Price
+	}
+
+	return prices, nil
+}
+
+// Values stock at its market price, or what it cost when it isn't on the
+// market
+func (s *service) getInventoryValue(ctx context.Context, companyId uint64, prices map[priceKey]int64) (int64, error) {
+	inventory, err := s.warehouseSvc.GetInventory(ctx, companyId)
+	if err != nil {
+		return 0, err
+	}
+
+	var value int64
+	for _, item := range inventory.Items {
+		price, ok := prices[priceKey{item.Resource.Id, item.Quality}]
+		if !ok {
+			price = int64(item.Cost)
+		}
+		value += int64(item.Qty) * price
+	}
+
+	return value, nil
+}
+
+// Values the plots the company owns at what they'd cost at their position
+func (s *service) getTerrainsValue(ctx context.Context, companyId uint64) (int64, error) {
+	terrains, err := s.companySvc.GetTerrains(ctx, companyId)
+	if err != nil {
+		return 0, err
+	}
+
+	var value int64
+	for _, terrain := range terrains {
+		value += s.companySvc.TerrainValue(int8(terrain.Position))
+	}
+
+	return value, nil
+}
+
+// Values buildings at the market price of the resources needed to build
+// them, once for every level. Resources that aren't on the market don't
+// add to it.
+func (s *service) getBuildingValues(ctx context.Context, companyId uint64, prices map[priceKey]int64) (map[uint64]int64, error) {
+	buildings, err := s.buildingSvc.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	costs := make(map[uint64]int64, len(buildings))
+	for _, building := range buildings {
+		for _, requirement := range building.Requirements {
+			costs[building.Id] += int64(requirement.Qty) * prices[priceKey{requirement.Resource.Id, requirement.Quality}]
+		}
+	}
+
+	levels, err := s.repository.GetBuildingLevels(ctx, companyId)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[uint64]int64)
+	for _, level := range levels {
+		values[level.CompanyId] += level.Level * costs[level.BuildingId]
+	}
+
+	return values, nil
+}
@@ -0,0 +1,163 @@
+package valuation_test
+
+import (
+	"api/building"
+	"api/clock"
+	"api/company"
+	"api/database"
+	"api/mail"
+	"api/valuation"
+	"api/warehouse"
+	"context"
+	"log"
+	"testing"
+	"time"
+)
+
+func newService(gameClock clock.Clock) valuation.Service {
+	companySvc := company.NewService(company.NewFakeRepository(), "secret", company.DEFAULT_TERRAIN_PRICING, mail.NewOutbox(), log.Default(), database.NewFakeUnitOfWork(), clock.New(), clock.New())
+	warehouseSvc := warehouse.NewService(warehouse.NewFakeRepository())
+	buildingSvc := building.NewService(building.NewFakeRepository())
+
+	return valuation.NewService(valuation.NewFakeRepository(), companySvc, warehouseSvc, buildingSvc, log.Default(), gameClock)
+}
+
+func TestValuationService(t *testing.T) {
+	service := newService(clock.New())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	t.Run("Evaluate", func(t *testing.T) {
+		t.Run("should value every asset minus debt", func(t *testing.T) {
+			valuation, err := service.Evaluate(ctx, 1)
+			if err != nil {
+				t.Fatalf("could not evaluate company: %s", err)
+			}
+
+			// Resource 1 at its market price, the rest at what it cost
+			if valuation.Inventory != 100*200+1000*47+700*1553+1700*153553 {
+				t.Errorf("expected inventory at market price or cost, got %d", valuation.Inventory)
+			}
+
+			terrains := company.DEFAULT_TERRAIN_PRICING.BaseValue*3 + company.DEFAULT_TERRAIN_PRICING.PositionValue*(1+2+3)
+			if valuation.Terrains != terrains {
+				t.Errorf("expected terrains worth %d, got %d", terrains, valuation.Terrains)
+			}
+
+			if valuation.Buildings != 2*50*200 {
+				t.Errorf("expected buildings worth %d, got %d", 2*50*200, valuation.Buildings)
+			}
+
+			expected := valuation.Cash + valuation.Inventory + valuation.Terrains + valuation.Buildings + valuation.Receivables - valuation.Debt
+			if valuation.NetWorth != expected {
+				t.Errorf("expected net worth %d, got %d", expected, valuation.NetWorth)
+			}
+		})
+
+		t.Run("should subtract debt", func(t *testing.T) {
+			valuation, err := service.Evaluate(ctx, 2)
+			if err != nil {
+				t.Fatalf("could not evaluate company: %s", err)
+			}
+
+			if valuation.Debt != 100000 || valuation.NetWorth != 255720+50*525+700*1553+valuation.Terrains+150*200-100000 {
+				t.Errorf("expected debt subtracted, got %+v", valuation)
+			}
+		})
+
+		t.Run("should return nil for unknown companies", func(t *testing.T) {
+			if valuation, err := service.Evaluate(ctx, 10); err != nil || valuation != nil {
+				t.Errorf("expected no valuation, got %v %v", valuation, err)
+			}
+		})
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		if err := service.Snapshot(ctx); err != nil {
+			t.Fatalf("could not take snapshot: %s", err)
+		}
+
+		if err := service.Snapshot(ctx); err != nil {
+			t.Fatalf("could not retry snapshot: %s", err)
+		}
+
+		points, err := service.GetHistory(ctx, 1, 0)
+		if err != nil {
+			t.Fatalf("could not get history: %s", err)
+		}
+
+		if len(points) != 1 {
+			t.Fatalf("expected a single snapshot, got %d", len(points))
+		}
+
+		if points[0].Revenue != 500 || points[0].Growth != 0 {
+			t.Errorf("expected revenue without growth on the first snapshot, got %+v", points[0])
+		}
+	})
+
+	t.Run("GetLeaderboard", func(t *testing.T) {
+		entries, err := service.GetLeaderboard(ctx, valuation.METRIC_NET_WORTH, 0)
+		if err != nil {
+			t.Fatalf("could not get leaderboard: %s", err)
+		}
+
+		if len(entries) != 2 || entries[0].CompanyId != 1 || entries[0].Rank != 1 || entries[1].Rank != 2 {
+			t.Errorf("expected company 1 ranked first, got %+v", entries)
+		}
+
+		if entries, _ := service.GetLeaderboard(ctx, valuation.METRIC_NET_WORTH, 1); len(entries) != 1 {
+			t.Errorf("expected leaderboard limited to 1, got %d", len(entries))
+		}
+
+		if _, err := service.GetLeaderboard(ctx, "cash", 0); err != valuation.ErrUnknownMetric {
+			t.Errorf("expected unknown metric, got %v", err)
+		}
+	})
+}
+
+func TestValuationServiceGameTime(t *testing.T) {
+	now := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	gameClock := clock.NewFakeClock(now)
+	service := newService(gameClock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	t.Run("should date snapshots on the game clock", func(t *testing.T) {
+		if err := service.Snapshot(ctx); err != nil {
+			t.Fatalf("could not take snapshot: %s", err)
+		}
+
+		gameClock.Advance(24 * time.Hour)
+		if err := service.Snapshot(ctx); err != nil {
+			t.Fatalf("could not take snapshot: %s", err)
+		}
+
+		points, err := service.GetHistory(ctx, 1, 0)
+		if err != nil {
+			t.Fatalf("could not get history: %s", err)
+		}
+
+		if len(points) != 2 {
+			t.Fatalf("expected a snapshot per game day, got %d", len(points))
+		}
+
+		if !points[0].CreatedAt.Equal(now) || !points[1].CreatedAt.Equal(now.Add(24*time.Hour)) {
+			t.Errorf("expected snapshots at game time, got %s and %s", points[0].CreatedAt, points[1].CreatedAt)
+		}
+	})
+
+	t.Run("should chart history up to the game clock", func(t *testing.T) {
+		gameClock.Advance(24 * time.Hour)
+
+		points, err := service.GetHistory(ctx, 1, 1)
+		if err != nil {
+			t.Fatalf("could not get history: %s", err)
+		}
+
+		if len(points) != 1 {
+			t.Errorf("expected the last game day only, got %d points", len(points))
+		}
+	})
+}
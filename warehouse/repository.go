@@ -5,7 +5,6 @@ import (
 	"context"
 
 	"github.com/doug-martin/goqu/v9"
-	_ "github.com/doug-martin/goqu/v9/dialect/sqlite3"
 )
 
 type Repository interface {
@@ -29,7 +28,7 @@ func NewRepository(conn *database.Connection) Repository {
 func (r *goquRepository) FetchInventory(ctx context.Context, companyId uint64) (*Inventory, error) {
 	items := make([]*StockItem, 0)
 
-	err := r.builder.
+	err := database.Query(ctx, r.builder).
 		Select(
 			goqu.I("i.quality").As("quality"),
 			goqu.SUM("i.quantity").As("quantity"),
@@ -38,7 +37,12 @@ func (r *goquRepository) FetchInventory(ctx context.Context, companyId uint64) (
 			goqu.I("r.image").As(goqu.C("resource.image")),
 			goqu.I("c.id").As(goqu.C("resource.category.id")),
 			goqu.I("c.name").As(goqu.C("resource.category.name")),
-			goqu.L("? / ?", goqu.SUM(goqu.L("? * ?", goqu.I("i.sourcing_cost"), goqu.I("i.quantity"))), goqu.SUM(goqu.I("i.quantity"))).As("sourcing_cost"),
+			database.IntDiv(
+				r.builder.Dialect(),
+				goqu.SUM(goqu.L("? * ?", goqu.I("i.sourcing_cost"), goqu.I("i.quantity"))),
+				goqu.SUM(goqu.I("i.quantity")),
+			).As("sourcing_cost"),
+			goqu.MAX("i.version").As("version"),
 		).
 		From(goqu.T("inventories").As("i")).
 		InnerJoin(goqu.T("resources").As("r"), goqu.On(goqu.I("i.resource_id").Eq(goqu.I("r.id")))).
@@ -49,7 +53,7 @@ func (r *goquRepository) FetchInventory(ctx context.Context, companyId uint64) (
 			),
 		)).
 		Where(goqu.I("i.company_id").Eq(companyId)).
-		GroupBy(goqu.I("r.id"), goqu.I("i.quality")).
+		GroupBy(goqu.I("r.id"), goqu.I("c.id"), goqu.I("i.quality")).
 		// make sure q0 comes before q1 so that it's consumed first
 		Order(goqu.I("i.quality").Asc()).
 		ScanStructsContext(ctx, &items)
@@ -63,52 +67,71 @@ func (r *goquRepository) FetchInventory(ctx context.Context, companyId uint64) (
 
 func (r *goquRepository) UpdateInventory(db *database.DB, inventory *Inventory) error {
 	for _, item := range inventory.Items {
-		if item.Qty == 0 {
-			if err := r.removeStock(db, inventory.CompanyId, item); err != nil {
-				return err
-			}
-		} else {
-			if updated, err := r.updateStock(db, inventory.CompanyId, item); err != nil || updated == 0 {
-				if err := r.insertStock(db, inventory.CompanyId, item); err != nil {
-					return err
-				}
-			}
+		var err error
+
+		switch {
+		case item.Version == 0 && item.Qty == 0:
+			continue
+		case item.Version == 0:
+			err = r.insertStock(db, inventory.CompanyId, item)
+		case item.Qty == 0:
+			err = r.removeStock(db, inventory.CompanyId, item)
+		default:
+			err = r.updateStock(db, inventory.CompanyId, item)
+		}
+
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
 func (r *goquRepository) removeStock(tx *database.DB, companyId uint64, item *StockItem) error {
-	_, err := tx.
+	result, err := tx.
 		Delete(goqu.T("inventories")).
 		Where(goqu.And(
 			goqu.I("quality").Eq(item.Quality),
 			goqu.I("company_id").Eq(companyId),
 			goqu.I("resource_id").Eq(item.Resource.Id),
+			goqu.I("version").Eq(item.Version),
 		)).
 		Executor().
 		Exec()
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	return database.CheckVersion(result, "inventory")
 }
 
-func (r *goquRepository) updateStock(tx *database.DB, companyId uint64, item *StockItem) (int64, error) {
+func (r *goquRepository) updateStock(tx *database.DB, companyId uint64, item *StockItem) error {
 	result, err := tx.
 		Update(goqu.T("inventories")).
-		Set(goqu.Record{"quantity": item.Qty}).
+		Set(goqu.Record{
+			"quantity": item.Qty,
+			"version":  item.Version + 1,
+		}).
 		Where(goqu.And(
 			goqu.I("quality").Eq(item.Quality),
 			goqu.I("company_id").Eq(companyId),
 			goqu.I("resource_id").Eq(item.Resource.Id),
+			goqu.I("version").Eq(item.Version),
 		)).
 		Executor().
 		Exec()
 
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	return result.RowsAffected()
+	if err := database.CheckVersion(result, "inventory"); err != nil {
+		return err
+	}
+
+	item.Version++
+	return nil
 }
 
 func (r *goquRepository) insertStock(tx *database.DB, companyId uint64, item *StockItem) error {
@@ -124,5 +147,10 @@ func (r *goquRepository) insertStock(tx *database.DB, companyId uint64, item *St
 		Executor().
 		Exec()
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	item.Version = 1
+	return nil
 }
@@ -8,10 +8,12 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/doug-martin/goqu/v9"
 )
 
 func TestMain(t *testing.M) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -21,11 +23,11 @@ func TestMain(t *testing.M) {
 		log.Fatalf("could not start transaction: %s", err)
 	}
 
-	tx.Exec(`INSERT INTO categories (id, name) VALUES (1, "Food"), (2, "Infrastructure")`)
+	tx.Exec(`INSERT INTO categories (id, name) VALUES (1, 'Food'), (2, 'Infrastructure')`)
 
 	tx.Exec(`
         INSERT INTO resources (id, name, category_id)
-        VALUES (1, "Wood", 2), (2, "Window", 2), (3, "Tools", 2)
+        VALUES (1, 'Wood', 2), (2, 'Window', 2), (3, 'Tools', 2)
     `)
 
 	tx.Exec(`
@@ -58,7 +60,7 @@ func TestMain(t *testing.M) {
 }
 
 func TestWarehouseRepository(t *testing.T) {
-	conn, err := database.GetConnection(database.SQLITE, "../test.db")
+	conn, err := database.GetTestConnection("../test.db")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -160,4 +162,53 @@ func TestWarehouseRepository(t *testing.T) {
 			}
 		})
 	})
+	t.Run("UpdateInventory", func(t *testing.T) {
+		conflicting, err := repository.FetchInventory(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		update := func(inventory *warehouse.Inventory) error {
+			tx, err := database.BeginTx(ctx, goqu.New(conn.Driver, conn.DB))
+			if err != nil {
+				return err
+			}
+
+			defer tx.Rollback()
+
+			if err := repository.UpdateInventory(tx.DB, inventory); err != nil {
+				return err
+			}
+
+			return tx.Commit()
+		}
+
+		t.Run("should bump the version", func(t *testing.T) {
+			inventory, err := repository.FetchInventory(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			inventory.Items[0].Qty += 10
+			if err := update(inventory); err != nil {
+				t.Fatalf("could not update inventory: %s", err)
+			}
+
+			inventory, err = repository.FetchInventory(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if inventory.Items[0].Version != conflicting.Items[0].Version+1 {
+				t.Errorf("expected version %d, got %d", conflicting.Items[0].Version+1, inventory.Items[0].Version)
+			}
+		})
+
+		t.Run("should fail on stale stock", func(t *testing.T) {
+			conflicting.Items[0].Qty = 0
+			if err := update(conflicting); !database.IsConflict(err) {
+				t.Fatalf("expected conflict, got %v", err)
+			}
+		})
+	})
 }
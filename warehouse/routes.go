@@ -2,6 +2,7 @@ package warehouse
 
 import (
 	"api/auth"
+	"api/server"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -10,7 +11,7 @@ import (
 func CreateEndpoints(e *echo.Echo, service Service) {
 	group := e.Group("/warehouse")
 
-	group.GET("", func(c echo.Context) error {
+	inventory := group.GET("", func(c echo.Context) error {
 		companyId, err := auth.ParseToken(c.Get("user"))
 		if err != nil {
 			return err
@@ -23,4 +24,6 @@ func CreateEndpoints(e *echo.Echo, service Service) {
 
 		return c.JSON(http.StatusOK, resources)
 	})
+
+	server.AllowMembers(auth.SCOPE_COMPANY_READ, inventory)
 }
@@ -11,14 +11,12 @@ import (
 )
 
 func TestWarehouseService(t *testing.T) {
-	t.Setenv(server.JWT_SECRET_KEY, "secret")
-
 	token, err := auth.GenerateToken(1, "secret")
 	if err != nil {
 		t.Fatalf("could not generate token: %s", err)
 	}
 
-	svr := server.NewServer()
+	svr := server.NewServer(server.Config{JwtSecret: "secret"})
 	svc := warehouse.NewService(warehouse.NewFakeRepository())
 	warehouse.CreateEndpoints(svr, svc)
 
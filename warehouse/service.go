@@ -14,6 +14,9 @@ type (
 	StockItem struct {
 		*resource.Item
 		Cost uint64 `db:"sourcing_cost" json:"cost"`
+
+		// Version of the row the item was read from, zero if it isn't stored yet
+		Version uint64 `db:"version" json:"-"`
 	}
 
 	Service interface {